@@ -0,0 +1,183 @@
+// Package acl enforces the access control rules of the config, so one server can expose a shared database to several teams with different privileges.
+package acl
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/audit"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/pin"
+)
+
+const (
+	Read  = "read"  // read keys and dump databases
+	Write = "write" // modify keys and buckets, includes Read
+	Admin = "admin" // manage handles of the server, includes Write
+)
+
+// levels orders the operations, an operation includes all lower ones.
+var levels = map[string]int{Read: 1, Write: 2, Admin: 3}
+
+// readEndpoints and writeEndpoints are the paths below the API endpoint that need Read or Write, all others need Admin.
+var readEndpoints = []string{"", "/page", "/seek", "/get", "/exists", "/scan", "/join", "/lookup", "/analyze", "/keyspace", "/histogram", "/largest", "/tail", "/sample", "/diff", "/export/sqlite", "/export/parquet", "/export/ndjson", "/export/shards", "/snapshots", "/snapshots/diff", "/pins", "/pins/release", "/backups", "/backups/download", "/journal", "/journal/verify", "/databases", "/buckets", "/info", "/fragmentation"}
+var writeEndpoints = []string{"/import", "/merge", "/move", "/delete", "/buckets/rename"}
+
+// Acl is a struct representing the access control rules of the server. A nil *Acl allows everything.
+type Acl struct {
+	rules       []config.AclRule
+	databases   []config.RegisteredDb
+	apiEndpoint string
+}
+
+// New returns the Acl of cfg, or nil if access control is disabled. apiEndpoint is the path the endpoints are registered below, e.g. "/bbolt".
+func New(cfg config.Config, apiEndpoint string) *Acl {
+	if !cfg.Acl.Enabled {
+		return nil
+	}
+	return &Acl{
+		rules:       cfg.Acl.Rules,
+		databases:   cfg.Databases,
+		apiEndpoint: apiEndpoint,
+	}
+}
+
+// matches reports whether name matches one of patterns, every name matches if there are no patterns.
+func matches(patterns []string, names ...string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		for _, name := range names {
+			if matched, _ := path.Match(pattern, name); matched && name != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Allows reports whether identity may perform operation on the bucket bucketName of the database db, which is a db path or the name of a registered database.
+// An empty bucketName asks for the whole database, only rules without buckets allow that.
+func (a *Acl) Allows(identity string, db string, bucketName string, operation string) bool {
+	if a == nil {
+		return true
+	}
+	return a.allows(identity, db, bucketName, false, operation)
+}
+
+// allows is Allows, if anyBucket is set rules for any bucket of db are enough, for requests that only get access to buckets later like beginning a transaction.
+func (a *Acl) allows(identity string, db string, bucketName string, anyBucket bool, operation string) bool {
+	// pins are protected like the database they were taken of
+	db = pin.Source(db)
+
+	// rules may name a registered database by its name or its path
+	dbNames := []string{db}
+	for _, registeredDb := range a.databases {
+		if registeredDb.Path == db {
+			dbNames = append(dbNames, registeredDb.Name)
+		} else if registeredDb.Name == db {
+			dbNames = append(dbNames, registeredDb.Path)
+		}
+	}
+
+	for _, rule := range a.rules {
+		if rule.Identity != "*" && rule.Identity != identity {
+			continue
+		}
+		if !matches(rule.Databases, dbNames...) {
+			continue
+		}
+		if !anyBucket && (bucketName == "" && len(rule.Buckets) > 0 || !matches(rule.Buckets, bucketName)) {
+			continue
+		}
+		for _, allowed := range rule.Operations {
+			if levels[allowed] >= levels[operation] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// operation returns the operation the request for urlPath needs.
+func (a *Acl) operation(urlPath string, target audit.Target) string {
+	if target.TxOp != "" {
+		switch target.TxOp {
+		case "get", "rollback":
+			return Read
+		}
+		return Write
+	}
+	if strings.HasPrefix(urlPath, "/v1/dbs/") {
+		if target.Writable {
+			return Write
+		}
+		return Read
+	}
+
+	endpoint, found := strings.CutPrefix(urlPath, a.apiEndpoint)
+	if !found {
+		return Admin
+	}
+	for _, readEndpoint := range readEndpoints {
+		if endpoint == readEndpoint {
+			return Read
+		}
+	}
+	for _, writeEndpoint := range writeEndpoints {
+		if endpoint == writeEndpoint {
+			return Write
+		}
+	}
+	return Admin
+}
+
+// allowsRequest reports whether the client of r may make the request.
+func (a *Acl) allowsRequest(r *http.Request) bool {
+	identity := audit.Who(r)
+	target := audit.ReadTarget(r)
+	operation := a.operation(r.URL.Path, target)
+
+	// transactions are checked per key, beginning and committing them only needs access to some bucket
+	if strings.HasPrefix(r.URL.Path, "/v1/dbs/") && target.Bucket == "" {
+		return a.allows(identity, target.Db, "", true, operation)
+	}
+	// batch reads are checked per bucket they read from
+	if target.Bucket == "" && len(target.Buckets) > 0 {
+		for _, bucketName := range target.Buckets {
+			if !a.Allows(identity, target.Db, bucketName, operation) {
+				return false
+			}
+		}
+		return true
+	}
+	if !a.Allows(identity, target.Db, target.Bucket, operation) {
+		return false
+	}
+	// joins also read the bucket the references point to
+	for _, bucketName := range target.Buckets {
+		if !a.Allows(identity, target.Db, bucketName, operation) {
+			return false
+		}
+	}
+	if target.ToBucket != "" && !a.Allows(identity, target.Db, target.ToBucket, Write) {
+		return false
+	}
+	if target.Other != "" && !a.Allows(identity, target.Other, "", Read) {
+		return false
+	}
+	return true
+}
+
+// Handler rejects the requests that no rule allows with 403 before they reach next. The static files of the UI and the API docs are always served, the data they show is not.
+func (a *Acl) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a != nil && !strings.HasPrefix(r.URL.Path, "/ui") && !strings.HasPrefix(r.URL.Path, "/docs") && !a.allowsRequest(r) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
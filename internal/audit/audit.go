@@ -0,0 +1,267 @@
+// Package audit records who accessed which database, bucket and operation, either as lines of a file or as entries of a bucket in a bbolt database.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// Anonymous is the identity of clients that present neither a certificate nor an API key, like all RESP clients.
+const Anonymous = "anonymous"
+
+// Bucket is the bucket of the audit database that events are appended to. Its keys are the big endian sequence numbers of the events, its values the events as JSON.
+const Bucket = "__audit"
+
+// Event is a struct representing one audited operation.
+type Event struct {
+	Time       time.Time `json:"time"`
+	Who        string    `json:"who"`              // "cert:" and the subject of the client certificate, "key:" and the start of the SHA-256 of the API key, or "anonymous"
+	RemoteAddr string    `json:"remoteAddr"`       // address the request came from
+	Operation  string    `json:"operation"`        // method and path of the request like "POST /bbolt/page", or the RESP command like "RESP GET"
+	Db         string    `json:"db,omitempty"`     // path or registered name of the database that was accessed
+	Bucket     string    `json:"bucket,omitempty"` // bucket that was accessed
+	Status     int       `json:"status,omitempty"` // HTTP status code of the response
+}
+
+// Log is a struct representing the destinations audit events are written to. A nil *Log records nothing.
+type Log struct {
+	mu     sync.Mutex // keeps the lines of concurrent events apart
+	file   *os.File
+	dbPath string
+}
+
+// Open opens the audit file and checks the audit database configured in cfg. It returns nil if auditing is not configured.
+func Open(cfg config.AuditConfig) (*Log, error) {
+	if cfg.File == "" && cfg.Db == "" {
+		return nil, nil
+	}
+	auditLog := &Log{dbPath: cfg.Db}
+	if cfg.File != "" {
+		file, err := os.OpenFile(cfg.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open audit file: %v\n", err)
+		}
+		auditLog.file = file
+	}
+	if cfg.Db != "" {
+		dbInstance, closeDb, err := bboltdump.OpenDbForWriting(cfg.Db)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open audit database: %v\n", err)
+		}
+		defer closeDb()
+		err = dbInstance.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists([]byte(Bucket))
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create audit bucket: %v\n", err)
+		}
+	}
+	return auditLog, nil
+}
+
+// Record appends event to the audit file and the audit database. Failures are printed since the audited operation already happened.
+func (l *Log) Record(event Event) {
+	if l == nil {
+		return
+	}
+	eventJson, err := json.Marshal(event)
+	if err != nil {
+		fmt.Println("ERROR: Failed to encode audit event:", err)
+		return
+	}
+
+	if l.file != nil {
+		l.mu.Lock()
+		_, err = l.file.Write(append(eventJson, '\n'))
+		l.mu.Unlock()
+		if err != nil {
+			fmt.Println("ERROR: Failed to write audit event to file:", err)
+		}
+	}
+
+	if l.dbPath != "" {
+		dbInstance, closeDb, err := bboltdump.OpenDbForWriting(l.dbPath)
+		if err == nil {
+			err = dbInstance.Update(func(tx *bolt.Tx) error {
+				b, err := tx.CreateBucketIfNotExists([]byte(Bucket))
+				if err != nil {
+					return err
+				}
+				sequence, err := b.NextSequence()
+				if err != nil {
+					return err
+				}
+				key := make([]byte, 8)
+				binary.BigEndian.PutUint64(key, sequence)
+				return b.Put(key, eventJson)
+			})
+			closeDb()
+		}
+		if err != nil {
+			fmt.Println("ERROR: Failed to write audit event to database:", err)
+		}
+	}
+}
+
+// Who identifies the client of r by its certificate or API key, the key itself is never recorded.
+func Who(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		return "cert:" + r.TLS.PeerCertificates[0].Subject.String()
+	}
+	apiKey := r.Header.Get("X-Api-Key")
+	if apiKey == "" {
+		apiKey, _ = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if apiKey != "" {
+		keyHash := sha256.Sum256([]byte(apiKey))
+		return "key:" + hex.EncodeToString(keyHash[:8])
+	}
+	return Anonymous
+}
+
+// statusRecorder is an http.ResponseWriter that remembers the status code of the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader remembers status and sends it.
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Write sends p, which implies status 200 if no status was sent yet.
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the Flush of the wrapped writer, which streamed dumps rely on.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Target is a struct representing what a request accesses, as far as it can be told from its path and JSON payload.
+type Target struct {
+	Operation string   // method and path of the request, with the token of a transaction replaced by "{token}"
+	Db        string   // path or registered name of the database, empty if the request does not name one
+	Other     string   // path of the second database of a diff
+	Bucket    string   // bucket that is read or written
+	Buckets   []string // buckets a batch read or a join reads from
+	ToBucket  string   // bucket a move or rename writes to
+	TxOp      string   // operation of a transaction request like "get" or "commit", empty for other requests
+	Writable  bool     // the request begins a read-write transaction
+}
+
+// targetPayload is a struct representing the fields of a request payload that tell which database and bucket are accessed.
+type targetPayload struct {
+	Input      string `json:"input"`
+	Db         string `json:"db"`
+	Other      string `json:"other"`
+	Bucket     string `json:"bucket"`
+	ToBucket   string `json:"toBucket"`
+	JoinBucket string `json:"joinBucket"`
+	Writable   bool   `json:"writable"`
+	Lookups    []struct {
+		Bucket string `json:"bucket"`
+	} `json:"lookups"`
+	Buckets []string `json:"buckets"`
+}
+
+// ReadTarget returns what r accesses. It peeks at the JSON payload Payload returns, uploads are left alone.
+func ReadTarget(r *http.Request) Target {
+	target := Target{Operation: r.Method + " " + r.URL.Path}
+
+	if payloadBytes, err := Payload(r); err == nil && payloadBytes != nil {
+		var payload targetPayload
+		json.Unmarshal(payloadBytes, &payload) // invalid payloads are rejected by the handler
+		target.Db = payload.Input
+		if target.Db == "" {
+			target.Db = payload.Db
+		}
+		target.Other = payload.Other
+		target.Bucket = payload.Bucket
+		target.ToBucket = payload.ToBucket
+		for _, lookup := range payload.Lookups {
+			payload.Buckets = append(payload.Buckets, lookup.Bucket)
+		}
+		if payload.JoinBucket != "" {
+			payload.Buckets = append(payload.Buckets, payload.JoinBucket)
+		}
+		for _, bucketName := range payload.Buckets {
+			if !slices.Contains(target.Buckets, bucketName) {
+				target.Buckets = append(target.Buckets, bucketName)
+			}
+		}
+		target.Writable = payload.Writable
+	}
+
+	// HEAD and GET requests cannot carry a payload, they name what they access in the query
+	if r.Method == http.MethodHead || r.Method == http.MethodGet {
+		query := r.URL.Query()
+		target.Db = query.Get("input")
+		target.Bucket = query.Get("bucket")
+	}
+
+	// transactions name the database in the path, their token is a secret
+	if rest, found := strings.CutPrefix(r.URL.Path, "/v1/dbs/"); found {
+		segments := strings.Split(rest, "/")
+		target.Db = segments[0]
+		if len(segments) >= 3 {
+			segments[2] = "{token}"
+		}
+		if len(segments) >= 4 {
+			target.TxOp = segments[3]
+		}
+		target.Operation = r.Method + " /v1/dbs/" + strings.Join(segments, "/")
+	}
+	return target
+}
+
+// Handler records an Event for every request next handles, except for the static files of the UI and the API docs.
+func (l *Log) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l == nil || strings.HasPrefix(r.URL.Path, "/ui") || strings.HasPrefix(r.URL.Path, "/docs") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		target := ReadTarget(r)
+		event := Event{
+			Time:       time.Now().UTC(),
+			Who:        Who(r),
+			RemoteAddr: r.RemoteAddr,
+			Operation:  target.Operation,
+			Db:         target.Db,
+			Bucket:     target.Bucket,
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		event.Status = recorder.status
+		if event.Status == 0 {
+			event.Status = http.StatusOK // the handler sent an empty response
+		}
+		l.Record(event)
+	})
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+)
+
+type payloadKey struct{}
+
+// isUpload reports whether r carries a multipart form, uploads are not JSON payloads and are not limited by the payload limit.
+func isUpload(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/")
+}
+
+// LimitPayload reads the payload of every request once, before next sees it, and rejects payloads larger than maxPayloadBytes with 413. Uploads are passed on unread.
+// It has to come before every middleware that looks at the payload, they and the handlers get the copy it read through Payload, so a payload is never buffered beyond the limit or more than once.
+func LimitPayload(next http.Handler, maxPayloadBytes int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil || isUpload(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		payloadBytes, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadBytes))
+		r.Body.Close()
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("Request Entity Too Large: payloads are limited to %v bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, WithPayload(r, payloadBytes))
+	})
+}
+
+// WithPayload returns r with payloadBytes as its payload, middlewares that rewrite the payload pass the request on with it.
+func WithPayload(r *http.Request, payloadBytes []byte) *http.Request {
+	r = r.WithContext(context.WithValue(r.Context(), payloadKey{}, payloadBytes))
+	r.Body = io.NopCloser(bytes.NewReader(payloadBytes))
+	r.ContentLength = int64(len(payloadBytes))
+	r.Header.Del("Content-Length")
+	return r
+}
+
+// Payload returns the payload of r as LimitPayload read it and rewinds r.Body, so the next middleware or the handler reads it from the start again. Uploads have no payload.
+// Without LimitPayload in front the body is read up to config.DefaultMaxPayloadBytes, a larger payload is left in r.Body for the handler to reject and Payload returns an *http.MaxBytesError.
+func Payload(r *http.Request) ([]byte, error) {
+	if payloadBytes, found := r.Context().Value(payloadKey{}).([]byte); found {
+		r.Body = io.NopCloser(bytes.NewReader(payloadBytes))
+		return payloadBytes, nil
+	}
+	if r.Body == nil || isUpload(r) {
+		return nil, nil
+	}
+
+	payloadBytes, err := io.ReadAll(io.LimitReader(r.Body, config.DefaultMaxPayloadBytes+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(payloadBytes), r.Body), r.Body}
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(payloadBytes)) > config.DefaultMaxPayloadBytes {
+		return nil, &http.MaxBytesError{Limit: config.DefaultMaxPayloadBytes}
+	}
+	return payloadBytes, nil
+}
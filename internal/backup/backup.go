@@ -0,0 +1,235 @@
+// Package backup copies registered databases into a backup directory on a cron schedule and prunes old copies per the retention policy of the schedule.
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/cron"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/remote"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+	bolt "go.etcd.io/bbolt"
+)
+
+const IdFormat = "20060102T150405Z" // backups are named after the database and the UTC time they were taken at
+
+// Backup is a struct representing a stored copy of a registered database.
+type Backup struct {
+	Id   string    `json:"id"`   // identifies the backup of its database, pass it to the download endpoint
+	Name string    `json:"name"` // name of the backup file, like "app-20240210T120000Z.db"
+	Time time.Time `json:"time"` // time the backup was taken at
+	Size int64     `json:"size"` // size of the backup file in bytes
+}
+
+// FileName returns the name of the backup file with the given id of the database dbName.
+func FileName(dbName string, id string) string {
+	return dbName + "-" + id + ".db"
+}
+
+// Path returns the path of the backup file with the given id of the database dbName in the backup directory dir.
+func Path(dir string, dbName string, id string) string {
+	return filepath.Join(dir, FileName(dbName, id))
+}
+
+// Take copies the registered database into the backup directory dir from within a read transaction, so the copy is consistent even while the database is in use.
+func Take(dir string, registeredDb config.RegisteredDb) (Backup, error) {
+	backupTime := time.Now().UTC()
+	backup := Backup{
+		Id:   backupTime.Format(IdFormat),
+		Time: backupTime,
+	}
+	backup.Name = FileName(registeredDb.Name, backup.Id)
+
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return backup, fmt.Errorf("Failed to create backup directory: %v\n", err)
+	}
+
+	// open database
+	dbInstance, closeDb, err := bboltdump.OpenLiveDb(registeredDb.Path)
+	if err != nil {
+		return backup, err
+	}
+	defer closeDb()
+
+	// write to a temporary file first so a half written backup is never listed
+	finalPath := Path(dir, registeredDb.Name, backup.Id)
+	tempPath := finalPath + ".tmp"
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		backup.Size = tx.Size()
+		return tx.CopyFile(tempPath, 0600)
+	})
+	if err != nil {
+		os.Remove(tempPath)
+		return backup, fmt.Errorf("Failed to back up database %v: %v\n", registeredDb.Name, err)
+	}
+	err = os.Rename(tempPath, finalPath)
+	if err != nil {
+		os.Remove(tempPath)
+		return backup, fmt.Errorf("Failed to store backup of database %v: %v\n", registeredDb.Name, err)
+	}
+
+	return backup, nil
+}
+
+// List returns the backups of the database dbName stored in the backup directory dir, newest first.
+func List(dir string, dbName string) ([]Backup, error) {
+	backups := []Backup{}
+
+	dirEntries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return backups, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list backups of database %v: %v\n", dbName, err)
+	}
+
+	for _, dirEntry := range dirEntries {
+		// the id never contains a "-", so the backups of a database named like "app-eu" are not taken for those of "app"
+		id, isBackup := strings.CutPrefix(dirEntry.Name(), dbName+"-")
+		id, isDb := strings.CutSuffix(id, ".db")
+		backupTime, err := time.Parse(IdFormat, id)
+		if !isBackup || !isDb || err != nil {
+			continue // not a backup of this database, e.g. a leftover temporary file
+		}
+		fileInfo, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{
+			Id:   id,
+			Name: dirEntry.Name(),
+			Time: backupTime,
+			Size: fileInfo.Size(),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// expired returns the backups, newest first, that the retention policy of backupSchedule does not keep.
+// A backup is kept if it is one of the newest KeepLast backups, or the newest backup of one of the newest KeepDaily days or KeepWeekly weeks that have a backup. Days and weeks are those of the local time of the server, like the cron schedule.
+func expired(backups []Backup, backupSchedule config.BackupSchedule) []Backup {
+	kept := make([]bool, len(backups))
+	for i := 0; i < len(backups) && i < backupSchedule.KeepLast; i++ {
+		kept[i] = true
+	}
+	keepNewestPer := func(amount int, period func(t time.Time) string) {
+		seen := make(map[string]bool)
+		for i, backup := range backups {
+			if len(seen) == amount {
+				return
+			}
+			key := period(backup.Time.Local())
+			if !seen[key] {
+				seen[key] = true
+				kept[i] = true
+			}
+		}
+	}
+	keepNewestPer(backupSchedule.KeepDaily, func(t time.Time) string {
+		return t.Format("2006-01-02")
+	})
+	keepNewestPer(backupSchedule.KeepWeekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	})
+
+	var expiredBackups []Backup
+	for i, backup := range backups {
+		if !kept[i] {
+			expiredBackups = append(expiredBackups, backup)
+		}
+	}
+	return expiredBackups
+}
+
+// prune deletes the backups of the database of backupSchedule that its retention policy does not keep.
+func prune(dir string, backupSchedule config.BackupSchedule) error {
+	backups, err := List(dir, backupSchedule.Db)
+	if err != nil {
+		return err
+	}
+	for _, backup := range expired(backups, backupSchedule) {
+		bboltdump.CloseCachedHandle(filepath.Join(dir, backup.Name)) // an older read replica may still be held open
+		err = os.Remove(filepath.Join(dir, backup.Name))
+		if err != nil {
+			return fmt.Errorf("Failed to delete old backup %v of database %v: %v\n", backup.Id, backupSchedule.Db, err)
+		}
+	}
+	return nil
+}
+
+// serveReadsFrom makes reads of the registered database open its backup, if the schedule asks for it.
+func serveReadsFrom(dir string, backupSchedule config.BackupSchedule, registeredDb config.RegisteredDb, backup Backup) {
+	if backupSchedule.ReadReplica {
+		bboltdump.SetReadReplica(registeredDb.Path, filepath.Join(dir, backup.Name))
+	}
+}
+
+// Run backs up the registered databases whenever their schedule in cfg is due, checking once per minute, uploads the backups if an S3 url is configured and prunes the old ones afterwards. It never returns.
+// Databases that are read replicas are read from their newest backup right away, as far as there is one from an earlier run.
+func Run(cfg config.Config) {
+	var uploader *remote.Uploader
+	if cfg.Backups.S3.Url != "" {
+		var err error
+		uploader, err = remote.NewUploader(cfg.S3, cfg.Backups.S3)
+		if err != nil {
+			fmt.Println("ERROR: Backups are not uploaded:", err)
+		}
+	}
+	schedules := make([]*cron.Schedule, len(cfg.Backups.Schedules))
+	for i, backupSchedule := range cfg.Backups.Schedules {
+		schedules[i], _ = cron.Parse(backupSchedule.Cron) // validated by config.Load
+		registeredDb, _ := cfg.LookupDb(backupSchedule.Db)
+		backups, err := List(cfg.Backups.Dir, backupSchedule.Db)
+		if err != nil {
+			fmt.Println("ERROR:", err)
+		} else if len(backups) > 0 {
+			serveReadsFrom(cfg.Backups.Dir, backupSchedule, registeredDb, backups[0])
+		}
+	}
+
+	for {
+		// wake up at the start of every minute, a schedule is due for the whole minute
+		now := time.Now()
+		time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		due := time.Now().Truncate(time.Minute)
+
+		for i, backupSchedule := range cfg.Backups.Schedules {
+			if !schedules[i].Matches(due) {
+				continue
+			}
+			registeredDb, _ := cfg.LookupDb(backupSchedule.Db)
+			backup, err := Take(cfg.Backups.Dir, registeredDb)
+			if err != nil {
+				fmt.Println("ERROR:", err)
+				continue
+			}
+			fmt.Println("Took backup", backup.Name, "of database", registeredDb.Name)
+			serveReadsFrom(cfg.Backups.Dir, backupSchedule, registeredDb, backup)
+
+			if uploader != nil {
+				objectUrl, err := uploader.Upload(filepath.Join(cfg.Backups.Dir, backup.Name), backup.Name)
+				if err != nil {
+					fmt.Println("ERROR:", err)
+				} else {
+					fmt.Println("Uploaded backup", backup.Name, "to", objectUrl)
+				}
+			}
+
+			err = prune(cfg.Backups.Dir, backupSchedule)
+			if err != nil {
+				fmt.Println("ERROR:", err)
+			}
+		}
+	}
+}
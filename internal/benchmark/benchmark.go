@@ -0,0 +1,248 @@
+// Package benchmark runs synthetic read workloads against a database and reports the throughput and latencies they reach, to size deployments with.
+package benchmark
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+	bolt "go.etcd.io/bbolt"
+)
+
+// workloads a benchmark can run
+const (
+	WorkloadGet  = "get"  // reads random keys of a bucket one at a time, like /bbolt/get with one lookup
+	WorkloadScan = "scan" // reads the keys of a bucket that start with the prefix of a random key
+	WorkloadDump = "dump" // dumps the database like /bbolt
+)
+
+const (
+	DefaultDuration     = 10 * time.Second // how long a benchmark runs if the caller does not say
+	MaxDuration         = 5 * time.Minute
+	DefaultConcurrency  = 4 // amount of operations running at the same time if the caller does not say
+	MaxConcurrency      = 64
+	DefaultPrefixLength = 2 // leading bytes of a random key a scan reads the keys of
+
+	keySampleSize = 1000 // amount of keys gets and scans pick from
+)
+
+// Options is a struct representing what a benchmark runs.
+type Options struct {
+	Workload     string        // WorkloadGet, WorkloadScan or WorkloadDump
+	Bucket       string        // bucket gets and scans read from, a dump only dumps this bucket if it is set
+	Duration     time.Duration // how long operations are started, 0 means DefaultDuration
+	Concurrency  int           // amount of operations running at the same time, 0 means DefaultConcurrency
+	PrefixLength int           // leading bytes of a random key a scan reads the keys of, 0 means DefaultPrefixLength
+	ScanLimit    int           // most entries one scan reads, 0 means bboltdump.DefaultPageLimit
+}
+
+// IsValidWorkload reports whether workload is a workload a benchmark can run.
+func IsValidWorkload(workload string) bool {
+	return workload == WorkloadGet || workload == WorkloadScan || workload == WorkloadDump
+}
+
+// Latency is a struct representing how long the operations of a benchmark took, in milliseconds.
+type Latency struct {
+	Min  float64 `json:"minMs"`
+	Mean float64 `json:"meanMs"`
+	P50  float64 `json:"p50Ms"`
+	P90  float64 `json:"p90Ms"`
+	P99  float64 `json:"p99Ms"`
+	Max  float64 `json:"maxMs"`
+}
+
+// Result is a struct representing what a benchmark reached.
+type Result struct {
+	Workload       string  `json:"workload"`
+	Concurrency    int     `json:"concurrency"`
+	Duration       string  `json:"duration"`             // time from the first operation until the last one finished, like "10.002s"
+	Operations     int     `json:"operations"`           // operations that succeeded
+	Errors         int     `json:"errors"`               // operations that failed
+	FirstError     string  `json:"firstError,omitempty"` // message of the first operation that failed
+	OpsPerSecond   float64 `json:"opsPerSecond"`
+	BytesPerSecond float64 `json:"bytesPerSecond"` // value bytes read by gets and scans, bytes of JSON written by dumps
+	Latency        Latency `json:"latency"`        // of the operations that succeeded
+}
+
+// operation runs one operation of a workload and returns the amount of bytes it read.
+type operation func() (int64, error)
+
+// countingWriter is an io.Writer that throws away what is written to it and counts its bytes.
+type countingWriter struct {
+	written int64
+}
+
+// Write counts the bytes of p.
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	cw.written += int64(len(p))
+	return len(p), nil
+}
+
+// Run runs options.Workload against the database at dbPath with options.Concurrency operations at the same time, starting new ones until options.Duration passed or ctx is done.
+// Every operation opens the database like a request to the API does, so with the handle cache enabled the results include its effect.
+func Run(ctx context.Context, dbPath string, options Options) (Result, error) {
+	if options.Duration <= 0 {
+		options.Duration = DefaultDuration
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultConcurrency
+	}
+	if options.PrefixLength <= 0 {
+		options.PrefixLength = DefaultPrefixLength
+	}
+	if options.ScanLimit <= 0 {
+		options.ScanLimit = bboltdump.DefaultPageLimit
+	}
+	if options.Duration > MaxDuration || options.Concurrency > MaxConcurrency {
+		return Result{}, fmt.Errorf("A benchmark runs at most %v with at most %v operations at the same time\n", MaxDuration, MaxConcurrency)
+	}
+	run, err := newOperation(dbPath, options)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var mu sync.Mutex
+	latencies := []time.Duration{}
+	var bytesRead int64
+	result := Result{Workload: options.Workload, Concurrency: options.Concurrency}
+	ctx, cancel := context.WithTimeout(ctx, options.Duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for range options.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every worker keeps its own numbers, so measuring does not make the operations wait for each other
+			workerLatencies := []time.Duration{}
+			var workerBytesRead int64
+			var workerErrors []error
+			for ctx.Err() == nil {
+				operationStart := time.Now()
+				n, err := run()
+				latency := time.Since(operationStart)
+				if err != nil {
+					workerErrors = append(workerErrors, err)
+					continue
+				}
+				workerLatencies = append(workerLatencies, latency)
+				workerBytesRead += n
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			latencies = append(latencies, workerLatencies...)
+			bytesRead += workerBytesRead
+			result.Errors += len(workerErrors)
+			if len(workerErrors) > 0 && result.FirstError == "" {
+				result.FirstError = workerErrors[0].Error()
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	result.Duration = elapsed.Round(time.Millisecond).String()
+	result.Operations = len(latencies)
+	result.OpsPerSecond = float64(len(latencies)) / elapsed.Seconds()
+	result.BytesPerSecond = float64(bytesRead) / elapsed.Seconds()
+	if len(latencies) == 0 {
+		return result, nil
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	milliseconds := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	percentile := func(p int) float64 { return milliseconds(latencies[(len(latencies)-1)*p/100]) }
+	result.Latency = Latency{
+		Min:  milliseconds(latencies[0]),
+		Mean: milliseconds(total / time.Duration(len(latencies))),
+		P50:  percentile(50),
+		P90:  percentile(90),
+		P99:  percentile(99),
+		Max:  milliseconds(latencies[len(latencies)-1]),
+	}
+	return result, nil
+}
+
+// newOperation returns the operation of options.Workload. Gets and scans pick from a random sample of the keys of the bucket, which is taken once up front.
+func newOperation(dbPath string, options Options) (operation, error) {
+	if options.Workload == WorkloadDump {
+		dumpOptions := bboltdump.DumpOptions{}
+		if options.Bucket != "" {
+			dumpOptions.Include = []string{options.Bucket}
+		}
+		return func() (int64, error) {
+			out := &countingWriter{}
+			err := bboltdump.WriteDbContentAsJson(out, dbPath, dumpOptions)
+			return out.written, err
+		}, nil
+	}
+	if !IsValidWorkload(options.Workload) {
+		return nil, fmt.Errorf("Unknown workload %v, use get, scan or dump\n", options.Workload)
+	}
+	if options.Bucket == "" {
+		return nil, fmt.Errorf("Workload %v needs a bucket\n", options.Workload)
+	}
+
+	sample, err := bboltdump.SampleBucket(dbPath, options.Bucket, keySampleSize, bboltdump.KeysHex)
+	if err != nil {
+		return nil, err
+	}
+	if len(sample.Entries) == 0 {
+		return nil, fmt.Errorf("Bucket %v has no keys to read\n", options.Bucket)
+	}
+	keys := make([][]byte, len(sample.Entries))
+	for i, entry := range sample.Entries {
+		keys[i], _ = hex.DecodeString(entry.Key) // the sample encoded them
+	}
+
+	if options.Workload == WorkloadGet {
+		return func() (int64, error) {
+			batchEntries, err := bboltdump.GetEntries(dbPath, []bboltdump.KeyRef{{Bucket: options.Bucket, Key: keys[rand.IntN(len(keys))]}}, bboltdump.ValuesRaw, bboltdump.KeysHex)
+			if err != nil {
+				return 0, err
+			}
+			return int64(len(batchEntries[0].Value)), nil
+		}, nil
+	}
+	return func() (int64, error) {
+		keyBytes := keys[rand.IntN(len(keys))]
+		return scanPrefix(dbPath, options.Bucket, keyBytes[:min(options.PrefixLength, len(keyBytes))], options.ScanLimit)
+	}, nil
+}
+
+// scanPrefix reads up to limit values of the bucket bucketName whose keys start with prefix and returns the amount of value bytes read.
+func scanPrefix(dbPath string, bucketName string, prefix []byte, limit int) (int64, error) {
+	// open database
+	dbInstance, closeDb, err := bboltdump.OpenDb(dbPath)
+	if err != nil {
+		return 0, err
+	}
+	defer closeDb()
+
+	var bytesRead int64
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		b := bboltdump.ResolveBucket(tx, bucketName)
+		if b == nil {
+			return fmt.Errorf("Bucket %v does not exist\n", bucketName)
+		}
+		cursor := b.Cursor()
+		read := 0
+		for keyBytes, valueBytes := cursor.Seek(prefix); keyBytes != nil && bytes.HasPrefix(keyBytes, prefix) && read < limit; keyBytes, valueBytes = cursor.Next() {
+			// copying the value is what reading it costs a client
+			bytesRead += int64(len(bytes.Clone(valueBytes)))
+			read++
+		}
+		return nil
+	})
+	return bytesRead, err
+}
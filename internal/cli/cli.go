@@ -0,0 +1,198 @@
+// Package cli implements subcommands that run the read and export logic without starting the HTTP server.
+package cli
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/export"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/importer"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/sdkgen"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/server"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// cliCommands maps each subcommand to the function running it.
+var cliCommands = map[string]func(args []string) error{
+	"dump":           runDumpCommand,
+	"diff":           runDiffCommand,
+	"export-sqlite":  runExportSqliteCommand,
+	"export-parquet": runExportParquetCommand,
+	"import":         runImportCommand,
+	"sdk":            runSdkCommand,
+}
+
+// runDumpCommand runs "dump --db path [--bucket name] [--format json|ndjson] [--keys text|hex]".
+func runDumpCommand(args []string) error {
+	flagSet := flag.NewFlagSet("dump", flag.ExitOnError)
+	dbPath := flagSet.String("db", "", "path to db file")
+	bucketName := flagSet.String("bucket", "", "only dump this bucket")
+	format := flagSet.String("format", "json", "output format, json or ndjson")
+	keys := keysFlag(flagSet)
+	flagSet.Parse(args)
+	if *dbPath == "" {
+		return fmt.Errorf("--db is required\n")
+	}
+	if *keys != bboltdump.KeysText && *keys != bboltdump.KeysHex {
+		return fmt.Errorf("--keys must be text or hex\n")
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	return bboltdump.Dump(out, *dbPath, *bucketName, *format, *keys)
+}
+
+// keysFlag defines the --keys flag of the commands that write keys, it takes bboltdump.KeysText or bboltdump.KeysHex.
+func keysFlag(flagSet *flag.FlagSet) *string {
+	return flagSet.String("keys", bboltdump.KeysText, "text to write printable keys as they are and others hex encoded with 0x in front, hex to hex encode all keys")
+}
+
+// runDiffCommand runs "diff --db path --other path [--keys text|hex]".
+func runDiffCommand(args []string) error {
+	flagSet := flag.NewFlagSet("diff", flag.ExitOnError)
+	dbPath := flagSet.String("db", "", "path to the base db file")
+	otherPath := flagSet.String("other", "", "path to the db file to compare against")
+	keys := keysFlag(flagSet)
+	flagSet.Parse(args)
+	if *dbPath == "" || *otherPath == "" {
+		return fmt.Errorf("--db and --other are required\n")
+	}
+	if *keys != bboltdump.KeysText && *keys != bboltdump.KeysHex {
+		return fmt.Errorf("--keys must be text or hex\n")
+	}
+
+	dbDiffJson, err := bboltdump.DiffDbsAsJson(*dbPath, *otherPath, *keys)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(dbDiffJson))
+	return nil
+}
+
+// parseWhere parses the filter expression of the --where flag, nil if it is empty.
+func parseWhere(where string) (*bboltdump.Filter, error) {
+	if where == "" {
+		return nil, nil
+	}
+	return bboltdump.ParseFilter(where)
+}
+
+// runExportSqliteCommand runs "export-sqlite --db path --out path [--where filter] [--internal]".
+func runExportSqliteCommand(args []string) error {
+	flagSet := flag.NewFlagSet("export-sqlite", flag.ExitOnError)
+	dbPath := flagSet.String("db", "", "path to db file")
+	outPath := flagSet.String("out", "", "path of the SQLite file to create")
+	where := flagSet.String("where", "", `only export the entries that pass this filter expression like 'json.status = "failed"'`)
+	internal := flagSet.Bool("internal", false, "also export the buckets kept beside the data like expiries or the journal")
+	flagSet.Parse(args)
+	if *dbPath == "" || *outPath == "" {
+		return fmt.Errorf("--db and --out are required\n")
+	}
+	filter, err := parseWhere(*where)
+	if err != nil {
+		return err
+	}
+
+	return export.ToSqlite(*dbPath, *outPath, filter, *internal)
+}
+
+// runExportParquetCommand runs "export-parquet --db path --out path [--where filter] [--internal]".
+func runExportParquetCommand(args []string) error {
+	flagSet := flag.NewFlagSet("export-parquet", flag.ExitOnError)
+	dbPath := flagSet.String("db", "", "path to db file")
+	outPath := flagSet.String("out", "", "path of the Parquet file to create")
+	where := flagSet.String("where", "", `only export the entries that pass this filter expression like 'json.status = "failed"'`)
+	internal := flagSet.Bool("internal", false, "also export the buckets kept beside the data like expiries or the journal")
+	flagSet.Parse(args)
+	if *dbPath == "" || *outPath == "" {
+		return fmt.Errorf("--db and --out are required\n")
+	}
+	filter, err := parseWhere(*where)
+	if err != nil {
+		return err
+	}
+
+	parquetFile, err := os.Create(*outPath)
+	if err != nil {
+		return fmt.Errorf("Failed to create Parquet file: %v\n", err)
+	}
+	defer parquetFile.Close()
+	out := bufio.NewWriter(parquetFile)
+	err = export.ToParquet(*dbPath, out, filter, *internal)
+	if err != nil {
+		return err
+	}
+	err = out.Flush()
+	if err != nil {
+		return fmt.Errorf("Failed to write Parquet file: %v\n", err)
+	}
+	return parquetFile.Close()
+}
+
+// runImportCommand runs "import --db path --bucket name --format leveldb|badger --source dir [--fill-percent 1.0] [--dry-run]".
+func runImportCommand(args []string) error {
+	flagSet := flag.NewFlagSet("import", flag.ExitOnError)
+	dbPath := flagSet.String("db", "", "path to the bbolt db file to import into")
+	bucketName := flagSet.String("bucket", "", "bucket to import into")
+	format := flagSet.String("format", "", "format of the source database, leveldb or badger")
+	sourceDir := flagSet.String("source", "", "directory of the source database")
+	fillPercent := flagSet.Float64("fill-percent", 0, "how full bolt packs the pages, between 0.1 and 1.0, 1.0 gives the smallest file")
+	dryRun := flagSet.Bool("dry-run", false, "roll every batch back instead of committing it and report what would change")
+	flagSet.Parse(args)
+	if *dbPath == "" || *bucketName == "" || *sourceDir == "" {
+		return fmt.Errorf("--db, --bucket and --source are required\n")
+	}
+
+	importResult, err := importer.Import(*dbPath, *bucketName, *format, *sourceDir, *fillPercent, *dryRun, "cli")
+	if err != nil {
+		return err
+	}
+	if importResult.DryRun {
+		fmt.Println("Would import", importResult.Imported, "keys into bucket", importResult.Bucket, "writing", importResult.Preview.BytesWritten, "bytes and overwriting", importResult.Preview.BytesDeleted)
+		return nil
+	}
+	fmt.Println("Imported", importResult.Imported, "keys into bucket", importResult.Bucket)
+	return nil
+}
+
+// runSdkCommand runs "sdk --lang swift|typescript|openapi [--out path]".
+func runSdkCommand(args []string) error {
+	flagSet := flag.NewFlagSet("sdk", flag.ExitOnError)
+	language := flagSet.String("lang", "", "language of the client, swift or typescript, or openapi for the OpenAPI document")
+	outPath := flagSet.String("out", "", "path of the file to write, stdout if empty")
+	flagSet.Parse(args)
+	generate, found := sdkgen.Languages[*language]
+	if !found {
+		return fmt.Errorf("--lang must be swift, typescript or openapi\n")
+	}
+
+	sdkBytes, err := generate(server.Routes)
+	if err != nil {
+		return err
+	}
+	if *outPath == "" {
+		_, err = os.Stdout.Write(sdkBytes)
+		return err
+	}
+	return os.WriteFile(*outPath, sdkBytes, 0644)
+}
+
+// Run runs the subcommand named in args[0] and reports whether args named a subcommand at all. If not, the caller should start the server instead.
+func Run(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	command, found := cliCommands[args[0]]
+	if !found {
+		return false
+	}
+
+	err := command(args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ERROR:", err)
+		os.Exit(1)
+	}
+	return true
+}
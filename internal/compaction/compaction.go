@@ -0,0 +1,201 @@
+// Package compaction compacts registered databases on a cron schedule, at times when nobody else is writing them.
+package compaction
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/cron"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/metrics"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// outcomes of a scheduled compaction
+const (
+	Compacted = "compacted"
+	Skipped   = "skipped" // the database was in use or compacting it was not worthwhile
+	Failed    = "failed"
+)
+
+// Attempt is a struct representing one scheduled compaction of a database.
+type Attempt struct {
+	Time       time.Time `json:"time"`                 // time the compaction was due
+	Outcome    string    `json:"outcome"`              // Compacted, Skipped or Failed
+	Reason     string    `json:"reason,omitempty"`     // why it was skipped or failed
+	SizeBefore int64     `json:"sizeBefore,omitempty"` // size of the file in bytes before it was compacted
+	SizeAfter  int64     `json:"sizeAfter,omitempty"`  // size of the file in bytes after it was compacted
+	Duration   string    `json:"duration,omitempty"`   // time the compaction took, like "1.5s"
+}
+
+// Status is a struct representing the compaction schedule of a registered database and how it went so far.
+type Status struct {
+	Db             string    `json:"db"`                    // name of the registered database
+	Cron           string    `json:"cron"`                  // when the database is compacted
+	NextRun        time.Time `json:"nextRun"`               // next time the compaction is due
+	LastAttempt    *Attempt  `json:"lastAttempt,omitempty"` // nil until the compaction was due once
+	Compactions    int       `json:"compactions"`           // amount of compactions since the server started
+	Skips          int       `json:"skips"`                 // amount of compactions skipped since the server started
+	Failures       int       `json:"failures"`              // amount of compactions failed since the server started
+	ReclaimedBytes int64     `json:"reclaimedBytes"`        // bytes all compactions since the server started gave back
+}
+
+// statuses holds the Status of every scheduled database by name, it is filled by Run.
+var statuses = struct {
+	mu   sync.Mutex
+	byDb map[string]*Status
+}{byDb: make(map[string]*Status)}
+
+// Run compacts the registered databases whenever their schedule in cfg is due, checking once per minute. It never returns.
+func Run(cfg config.Config) {
+	schedules := make([]*cron.Schedule, len(cfg.Compaction.Schedules))
+	statuses.mu.Lock()
+	for i, compactionSchedule := range cfg.Compaction.Schedules {
+		schedules[i], _ = cron.Parse(compactionSchedule.Cron) // validated by config.Load
+		statuses.byDb[compactionSchedule.Db] = &Status{Db: compactionSchedule.Db, Cron: compactionSchedule.Cron}
+	}
+	statuses.mu.Unlock()
+	metrics.Register(writeMetrics)
+
+	for {
+		// wake up at the start of every minute, a schedule is due for the whole minute
+		now := time.Now()
+		time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		due := time.Now().Truncate(time.Minute)
+
+		for i, compactionSchedule := range cfg.Compaction.Schedules {
+			if !schedules[i].Matches(due) {
+				continue
+			}
+			registeredDb, _ := cfg.LookupDb(compactionSchedule.Db)
+			attempt := compactIfIdle(registeredDb, compactionSchedule)
+			attempt.Time = due
+			record(compactionSchedule.Db, attempt)
+			if attempt.Outcome == Compacted {
+				fmt.Printf("Compacted database %v from %v to %v bytes in %v\n", registeredDb.Name, attempt.SizeBefore, attempt.SizeAfter, attempt.Duration)
+			} else {
+				fmt.Printf("Compaction of database %v %v: %v\n", registeredDb.Name, attempt.Outcome, attempt.Reason)
+			}
+		}
+	}
+}
+
+// compactIfIdle compacts registeredDb unless another process is writing it or the compaction would reclaim less than the schedule asks for.
+func compactIfIdle(registeredDb config.RegisteredDb, compactionSchedule config.CompactionSchedule) Attempt {
+	writerActivity := bboltdump.ProbeWriters(registeredDb.Path)
+	if writerActivity == nil {
+		return Attempt{Outcome: Failed, Reason: "not a local database file"}
+	}
+	if writerActivity.Active {
+		reason := "written within the last " + bboltdump.RecentWriteWindow.String()
+		if len(writerActivity.LockHolders) > 0 {
+			reason = "in use by " + strings.Join(writerActivity.LockHolders, ", ")
+		}
+		return Attempt{Outcome: Skipped, Reason: reason}
+	}
+
+	if compactionSchedule.MinReclaimable > 0 {
+		report, err := bboltdump.GetFragmentationReport(registeredDb.Path)
+		if err != nil {
+			return attemptFailed(err)
+		}
+		if report.ReclaimableBytes < compactionSchedule.MinReclaimable {
+			return Attempt{Outcome: Skipped, Reason: fmt.Sprintf("only %v bytes are estimated to be reclaimed", report.ReclaimableBytes)}
+		}
+	}
+
+	compactResult, err := bboltdump.Compact(registeredDb.Path, compactionSchedule.TxMaxSize)
+	if err != nil {
+		return attemptFailed(err)
+	}
+	return Attempt{
+		Outcome:    Compacted,
+		SizeBefore: compactResult.SizeBefore,
+		SizeAfter:  compactResult.SizeAfter,
+		Duration:   compactResult.Duration,
+	}
+}
+
+// attemptFailed returns the Attempt that failed with err, a database that got locked meanwhile is only skipped.
+func attemptFailed(err error) Attempt {
+	var lockedError *bboltdump.LockedError
+	if errors.As(err, &lockedError) {
+		return Attempt{Outcome: Skipped, Reason: strings.TrimSpace(err.Error())}
+	}
+	return Attempt{Outcome: Failed, Reason: strings.TrimSpace(err.Error())}
+}
+
+// record adds attempt to the Status of the database dbName.
+func record(dbName string, attempt Attempt) {
+	statuses.mu.Lock()
+	defer statuses.mu.Unlock()
+	status := statuses.byDb[dbName]
+	status.LastAttempt = &attempt
+	switch attempt.Outcome {
+	case Compacted:
+		status.Compactions++
+		status.ReclaimedBytes += max(attempt.SizeBefore-attempt.SizeAfter, 0)
+	case Skipped:
+		status.Skips++
+	case Failed:
+		status.Failures++
+	}
+}
+
+// Statuses returns the Status of every database with a compaction schedule in cfg, in the order of the schedules.
+func Statuses(cfg config.Config) []Status {
+	statuses.mu.Lock()
+	defer statuses.mu.Unlock()
+	result := []Status{}
+	for _, compactionSchedule := range cfg.Compaction.Schedules {
+		status := Status{Db: compactionSchedule.Db, Cron: compactionSchedule.Cron}
+		if recorded, found := statuses.byDb[compactionSchedule.Db]; found {
+			status = *recorded
+		}
+		if schedule, err := cron.Parse(compactionSchedule.Cron); err == nil {
+			status.NextRun = schedule.Next(time.Now())
+		}
+		result = append(result, status)
+	}
+	return result
+}
+
+// writeMetrics writes the compaction metrics of every scheduled database.
+func writeMetrics(w *bytes.Buffer) {
+	statuses.mu.Lock()
+	defer statuses.mu.Unlock()
+	dbNames := make([]string, 0, len(statuses.byDb))
+	for dbName := range statuses.byDb {
+		dbNames = append(dbNames, dbName)
+	}
+	sort.Strings(dbNames)
+
+	for _, metric := range []struct {
+		name, metricType, help string
+		value                  func(status *Status) (float64, bool)
+	}{
+		{"bbolt_compactions_total", "counter", "Scheduled compactions that compacted the database.", func(status *Status) (float64, bool) { return float64(status.Compactions), true }},
+		{"bbolt_compaction_skips_total", "counter", "Scheduled compactions skipped since the database was in use or compacting it was not worthwhile.", func(status *Status) (float64, bool) { return float64(status.Skips), true }},
+		{"bbolt_compaction_failures_total", "counter", "Scheduled compactions that failed.", func(status *Status) (float64, bool) { return float64(status.Failures), true }},
+		{"bbolt_compaction_reclaimed_bytes_total", "counter", "Bytes given back by scheduled compactions.", func(status *Status) (float64, bool) { return float64(status.ReclaimedBytes), true }},
+		{"bbolt_compaction_last_attempt_timestamp_seconds", "gauge", "Unix time the last scheduled compaction was due.", func(status *Status) (float64, bool) {
+			if status.LastAttempt == nil {
+				return 0, false
+			}
+			return float64(status.LastAttempt.Time.Unix()), true
+		}},
+	} {
+		metrics.WriteHelp(w, metric.name, metric.metricType, metric.help)
+		for _, dbName := range dbNames {
+			status := statuses.byDb[dbName]
+			if value, known := metric.value(status); known {
+				metrics.WriteSample(w, metric.name, status.Db, value)
+			}
+		}
+	}
+}
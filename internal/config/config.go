@@ -0,0 +1,730 @@
+// Package config loads the JSON config file of the server.
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/cron"
+)
+
+const DefaultWatchInterval = 5 * time.Second       // how often watched files are checked if the config does not say otherwise
+const DefaultHandleIdleTimeout = time.Minute       // how long an unused cached handle stays open if the config does not say otherwise
+const DefaultTxIdleTimeout = 30 * time.Second      // how long a transaction session may go without requests if the config does not say otherwise
+const DefaultTxMaxDuration = 5 * time.Minute       // how long a transaction session may stay open at all if the config does not say otherwise
+const DefaultMaxTxSessions = 32                    // how many transaction sessions may be open at once if the config does not say otherwise
+const DefaultReplicationInterval = time.Minute     // how often replicated databases are checked for changes if the config does not say otherwise
+const DefaultWebhookRetries = 5                    // how often a failed webhook notification is retried if the config does not say otherwise
+const DefaultExportRetention = 24 * time.Hour      // how long a resumable export can be resumed if the config does not say otherwise
+const DefaultIdempotencyRetention = 24 * time.Hour // how long the response of a write sent with an idempotency key is replayed if the config does not say otherwise
+const DefaultPinIdleTimeout = 10 * time.Minute     // how long a pin may go without reads if the config does not say otherwise
+const DefaultMaxPins = 16                          // how many pins the server holds at most if the config does not say otherwise
+
+// timeouts of the HTTP listener if the config does not say otherwise
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = 10 * time.Minute // uploads of databases to compare against can be large
+	DefaultWriteTimeout      = 5 * time.Minute
+	DefaultIdleTimeout       = 2 * time.Minute
+	DefaultMaxRequestTimeout = 10 * time.Minute // longest timeout a client may give a request, and the timeout of requests without one
+)
+
+const DefaultMaxPayloadBytes = 1 << 20 // largest JSON payload an endpoint reads if the config does not say otherwise, uploads are not limited by it
+
+const DefaultMemoryLimit = 256 << 20 // bytes all dumps together buffer at most if the config does not say otherwise, like bboltdump.DefaultMemoryLimit
+
+// Duration is a time.Duration that is written as a string like "90s" or "1h" in the config file.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON parses a duration string like "1h30m".
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var durationString string
+	err := json.Unmarshal(data, &durationString)
+	if err != nil {
+		return fmt.Errorf("Duration must be a string like \"1h\": %v\n", err)
+	}
+	d.Duration, err = time.ParseDuration(durationString)
+	return err
+}
+
+// MarshalJSON writes the duration as a string like "1h30m0s".
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// RegisteredDb is a struct representing a database that is known to the server by name.
+type RegisteredDb struct {
+	Name    string       `json:"name"`              // name clients use to refer to the database
+	Path    string       `json:"path"`              // path to db file
+	Options *BoltOptions `json:"options,omitempty"` // how the database is opened, bolt's defaults if nil
+	Journal bool         `json:"journal"`           // record every write through the API in the bucket bboltdump.JournalBucket
+	Fixture string       `json:"fixture,omitempty"` // path to a JSON fixture the database is created from when the server starts and which is deleted when it stops, in a temporary file if path is empty
+}
+
+// BoltOptions is a struct representing the advanced bolt.Options a registered database is opened with.
+type BoltOptions struct {
+	PageSize        int    `json:"pageSize"`        // page size in bytes of the file if it is created, existing files keep theirs
+	NoFreelistSync  bool   `json:"noFreelistSync"`  // do not write the freelist to the file, which makes commits faster but opening slower
+	FreelistType    string `json:"freelistType"`    // "array" or "hashmap", defaults to "array"
+	Mlock           bool   `json:"mlock"`           // keep the mapped file in memory, needs the privilege to lock that much memory
+	InitialMmapSize int    `json:"initialMmapSize"` // initial size of the memory map in bytes
+	PreLoadFreelist bool   `json:"preLoadFreelist"` // load the freelist when the file is opened, even read-only
+}
+
+// SnapshotConfig is a struct representing the settings of the periodic snapshots of registered databases.
+type SnapshotConfig struct {
+	Dir       string   `json:"dir"`       // directory the snapshots are stored in, snapshots are disabled if empty
+	Interval  Duration `json:"interval"`  // time between two snapshots of the same database
+	Retention int      `json:"retention"` // amount of snapshots kept per database, older ones are deleted
+}
+
+// CompactionSchedule is a struct representing when a registered database is compacted.
+type CompactionSchedule struct {
+	Db             string `json:"db"`             // name of the registered database to compact
+	Cron           string `json:"cron"`           // when to compact in the local time of the server, like "30 3 * * *" for every night at 3:30
+	MinReclaimable int64  `json:"minReclaimable"` // bytes compaction is estimated to reclaim at least, it is skipped otherwise. 0 always compacts
+	TxMaxSize      int64  `json:"txMaxSize"`      // bytes copied per transaction, defaults to bboltdump.DefaultCompactTxMaxSize
+}
+
+// CompactionConfig is a struct representing the scheduled compaction of registered databases.
+type CompactionConfig struct {
+	Schedules []CompactionSchedule `json:"schedules"` // databases to compact and when
+}
+
+// BackupSchedule is a struct representing when a registered database is backed up and which of its backups are kept.
+type BackupSchedule struct {
+	Db         string `json:"db"`         // name of the registered database to back up
+	Cron       string `json:"cron"`       // when to back up in the local time of the server, like "0 * * * *" for every hour
+	KeepLast   int    `json:"keepLast"`   // amount of newest backups kept
+	KeepDaily  int    `json:"keepDaily"`  // amount of days whose newest backup is kept
+	KeepWeekly int    `json:"keepWeekly"` // amount of weeks whose newest backup is kept
+
+	ReadReplica bool `json:"readReplica"` // serve reads of the database from its newest backup instead of the live file
+}
+
+// BackupS3Config is a struct representing where in S3 or another S3 compatible object storage backups are uploaded to. The connection settings are those of S3Config.
+type BackupS3Config struct {
+	Url      string `json:"url"`      // bucket and prefix the backups are uploaded to like "s3://backups/bbolt/", nothing is uploaded if empty
+	Gzip     bool   `json:"gzip"`     // compress the backups before uploading them, their objects end in ".gz"
+	Checksum bool   `json:"checksum"` // store the SHA-256 of every object in its "sha256" metadata and have S3 verify every part on arrival
+	PartSize int64  `json:"partSize"` // bytes per part of a multipart upload, larger backups are uploaded in parts. Defaults to remote.DefaultPartSize
+}
+
+// BackupConfig is a struct representing the scheduled backups of registered databases.
+type BackupConfig struct {
+	Dir       string           `json:"dir"`       // directory the backups of all databases are stored in
+	Schedules []BackupSchedule `json:"schedules"` // databases to back up and when
+	S3        BackupS3Config   `json:"s3"`        // where backups are uploaded to besides Dir
+}
+
+// ReplicationPeer is a struct representing a standby instance a registered database is pushed to.
+type ReplicationPeer struct {
+	Db     string `json:"db"`     // name of the registered database to push
+	Url    string `json:"url"`    // API endpoint of the standby instance, like "http://standby:8085/bbolt"
+	PeerDb string `json:"peerDb"` // name of the registered database on the standby instance, defaults to Db
+	ApiKey string `json:"apiKey"` // sent as X-Api-Key, so the ACL of the standby instance can allow the push
+
+	Internal bool `json:"internal"` // also push the journal and the stored responses of idempotent writes, the standby has neither of them otherwise
+}
+
+// ReplicationConfig is a struct representing the pushing of registered databases to standby instances.
+type ReplicationConfig struct {
+	Interval Duration          `json:"interval"` // time between two checks for changes, defaults to DefaultReplicationInterval
+	Peers    []ReplicationPeer `json:"peers"`    // databases to push and where to
+}
+
+// WebhookConfig is a struct representing the subscribers that are notified when a registered database changes.
+type WebhookConfig struct {
+	Db          string   `json:"db"`          // name of the registered database to watch
+	Urls        []string `json:"urls"`        // URLs a notification is POSTed to on every change
+	DiffSummary bool     `json:"diffSummary"` // include how many keys were added, removed or changed per bucket
+	Writes      bool     `json:"writes"`      // notify after every write the server commits with the changed buckets and keys, instead of polling the file
+	Secret      string   `json:"secret"`      // key of the HMAC-SHA256 signature sent with every notification, unsigned if empty
+}
+
+// WatchConfig is a struct representing the settings of the file watcher.
+type WatchConfig struct {
+	Interval Duration        `json:"interval"` // time between two checks of the watched files, defaults to DefaultWatchInterval
+	Webhooks []WebhookConfig `json:"webhooks"` // databases to watch and who to notify
+	Retries  int             `json:"retries"`  // how often a failed notification is retried, defaults to DefaultWebhookRetries
+}
+
+// RespConfig is a struct representing the settings of the Redis protocol listener.
+type RespConfig struct {
+	Addr string `json:"addr"` // address to listen on like "localhost:6380", the listener is disabled if empty
+	Db   string `json:"db"`   // name of the registered database the listener serves
+}
+
+// ServerConfig is a struct representing the timeouts and limits of the HTTP listener.
+type ServerConfig struct {
+	ReadHeaderTimeout Duration `json:"readHeaderTimeout"` // time a client has to send the request headers, defaults to DefaultReadHeaderTimeout
+	ReadTimeout       Duration `json:"readTimeout"`       // time a client has to send the whole request including uploads, defaults to DefaultReadTimeout
+	WriteTimeout      Duration `json:"writeTimeout"`      // time a response may take, streamed dumps and exports only end once the client stopped reading for that long, defaults to DefaultWriteTimeout
+	IdleTimeout       Duration `json:"idleTimeout"`       // time an unused keep-alive connection stays open, defaults to DefaultIdleTimeout
+	MaxPayloadBytes   int64    `json:"maxPayloadBytes"`   // largest JSON payload an endpoint reads, larger ones are rejected with 413, defaults to DefaultMaxPayloadBytes
+	MaxRequestTimeout Duration `json:"maxRequestTimeout"` // longest timeout a client may give a request with ?timeout=, longer ones are cut down to it and requests without one get it, defaults to DefaultMaxRequestTimeout
+	MemoryLimit       int64    `json:"memoryLimit"`       // soft limit of the bytes all dumps together buffer instead of streaming, defaults to DefaultMemoryLimit
+}
+
+// LockConfig is a struct representing how long opening a database waits while another process or handle holds its file lock, the defaults of bboltdump.DefaultLockPolicy apply unless timeout is set.
+type LockConfig struct {
+	Timeout Duration `json:"timeout"` // how long one attempt waits for the lock
+	Retries int      `json:"retries"` // attempts after the first one timed out
+	Backoff Duration `json:"backoff"` // pause before the first retry, doubled before every further retry
+}
+
+// Http3Config is a struct representing the settings of the HTTP/3 listener, QUIC always needs a TLS certificate.
+type Http3Config struct {
+	Addr     string `json:"addr"`     // UDP address to listen on like ":8443", the listener is disabled if empty
+	CertFile string `json:"certFile"` // path of the PEM encoded certificate
+	KeyFile  string `json:"keyFile"`  // path of the PEM encoded private key of the certificate
+}
+
+// WithDefaults returns the timeouts and limits with the defaults filled in for those that are not set.
+func (s ServerConfig) WithDefaults() ServerConfig {
+	defaults := []time.Duration{DefaultReadHeaderTimeout, DefaultReadTimeout, DefaultWriteTimeout, DefaultIdleTimeout, DefaultMaxRequestTimeout}
+	for i, timeout := range []*Duration{&s.ReadHeaderTimeout, &s.ReadTimeout, &s.WriteTimeout, &s.IdleTimeout, &s.MaxRequestTimeout} {
+		if timeout.Duration == 0 {
+			timeout.Duration = defaults[i]
+		}
+	}
+	if s.MaxPayloadBytes == 0 {
+		s.MaxPayloadBytes = DefaultMaxPayloadBytes
+	}
+	if s.MemoryLimit == 0 {
+		s.MemoryLimit = DefaultMemoryLimit
+	}
+	return s
+}
+
+// HandleCacheConfig is a struct representing the settings of the database handle cache.
+type HandleCacheConfig struct {
+	Enabled     bool     `json:"enabled"`     // keep databases open between requests instead of opening them for every request
+	IdleTimeout Duration `json:"idleTimeout"` // time after which an unused handle is closed, defaults to DefaultHandleIdleTimeout
+}
+
+// TransactionConfig is a struct representing the settings of the HTTP transaction sessions.
+// A session keeps its database open, without the handle cache it also keeps the file locked for every other request until it ends, so sessions are limited in number and duration.
+type TransactionConfig struct {
+	IdleTimeout Duration `json:"idleTimeout"` // time without requests after which a session is rolled back, defaults to DefaultTxIdleTimeout
+	MaxDuration Duration `json:"maxDuration"` // time after its begin after which a session is rolled back even if it is used, defaults to DefaultTxMaxDuration
+	MaxSessions int      `json:"maxSessions"` // amount of sessions open at once, defaults to DefaultMaxTxSessions
+}
+
+// WithDefaults returns the transaction settings with the defaults filled in for all settings that are not set.
+func (t TransactionConfig) WithDefaults() TransactionConfig {
+	if t.IdleTimeout.Duration == 0 {
+		t.IdleTimeout.Duration = DefaultTxIdleTimeout
+	}
+	if t.MaxDuration.Duration == 0 {
+		t.MaxDuration.Duration = DefaultTxMaxDuration
+	}
+	if t.MaxSessions == 0 {
+		t.MaxSessions = DefaultMaxTxSessions
+	}
+	return t
+}
+
+// BandwidthConfig is a struct representing how fast streamed dumps, exports and backup downloads are sent at most, in bytes per second. 0 means no limit.
+type BandwidthConfig struct {
+	Global     int64 `json:"global"`     // all streamed responses together
+	PerRequest int64 `json:"perRequest"` // every single streamed response
+}
+
+// ExportConfig is a struct representing where the copies resumable exports are read from are kept.
+type ExportConfig struct {
+	Dir       string   `json:"dir"`       // directory the copies are stored in, defaults to bbolt-exports in the temp directory
+	Retention Duration `json:"retention"` // time after which a copy is deleted and its export cannot be resumed anymore, defaults to DefaultExportRetention
+}
+
+// WithDefaults returns the export settings with the defaults filled in for all settings that are not set.
+func (e ExportConfig) WithDefaults() ExportConfig {
+	if e.Dir == "" {
+		e.Dir = filepath.Join(os.TempDir(), "bbolt-exports")
+	}
+	if e.Retention.Duration == 0 {
+		e.Retention.Duration = DefaultExportRetention
+	}
+	return e
+}
+
+// PinConfig is a struct representing where the copies of pinned databases are kept and for how long.
+type PinConfig struct {
+	Dir         string   `json:"dir"`         // directory the copies are stored in, defaults to bbolt-pins in the temp directory
+	IdleTimeout Duration `json:"idleTimeout"` // time without reads after which a pin is released, defaults to DefaultPinIdleTimeout
+	MaxPins     int      `json:"maxPins"`     // amount of pins held at once, defaults to DefaultMaxPins
+}
+
+// WithDefaults returns the pin settings with the defaults filled in for all settings that are not set.
+func (p PinConfig) WithDefaults() PinConfig {
+	if p.Dir == "" {
+		p.Dir = filepath.Join(os.TempDir(), "bbolt-pins")
+	}
+	if p.IdleTimeout.Duration == 0 {
+		p.IdleTimeout.Duration = DefaultPinIdleTimeout
+	}
+	if p.MaxPins == 0 {
+		p.MaxPins = DefaultMaxPins
+	}
+	return p
+}
+
+// ViewConfig is a struct representing a materialized view of a bucket of a registered database, see bboltdump.ViewDefinition.
+type ViewConfig struct {
+	Db        string `json:"db"`        // name of the registered database
+	Name      string `json:"name"`      // name of the view, it is read as the bucket "__views/" followed by the name
+	Bucket    string `json:"bucket"`    // bucket the view is derived from
+	Kind      string `json:"kind"`      // "count" of the keys per prefix or "latest" value per prefix
+	Separator string `json:"separator"` // the prefix of a key is its part before the first separator
+	Refresh   string `json:"refresh"`   // "write" (default) to update the view on every write through the API, "demand" to only compute it when it is refreshed
+}
+
+// IdempotencyConfig is a struct representing how long the responses of writes sent with an Idempotency-Key header are kept.
+type IdempotencyConfig struct {
+	Retention Duration `json:"retention"` // time a retry with the same key gets the stored response instead of writing again, defaults to DefaultIdempotencyRetention
+}
+
+// TtlConfig is a struct representing the settings of the sweeper that deletes expired keys.
+type TtlConfig struct {
+	SweepInterval Duration `json:"sweepInterval"` // time between two sweeps of the registered databases, the sweeper is disabled if zero
+}
+
+// S3Config is a struct representing the settings of databases that are read from S3 or another S3 compatible object storage.
+// Credentials are taken from the environment, the shared AWS config files or the instance role like the AWS CLI does.
+type S3Config struct {
+	Enabled      bool   `json:"enabled"`      // accept db paths like "s3://bucket/app.db"
+	Region       string `json:"region"`       // region of the buckets, defaults to the one of the AWS config
+	Endpoint     string `json:"endpoint"`     // URL of an S3 compatible object storage like MinIO, empty for AWS
+	UsePathStyle bool   `json:"usePathStyle"` // address buckets as "endpoint/bucket" instead of "bucket.endpoint", most object storages other than AWS need this
+	CacheDir     string `json:"cacheDir"`     // directory the downloaded databases are kept in, defaults to a directory below the system temp directory
+}
+
+// AuditConfig is a struct representing where the audit log of data accesses is written to, auditing is disabled if both are empty.
+type AuditConfig struct {
+	File string `json:"file"` // path of a file every access is appended to as one JSON object per line
+	Db   string `json:"db"`   // path of a bbolt database every access is appended to in the bucket audit.Bucket
+}
+
+// AclRule is a struct representing what an identity may do with some databases and buckets.
+type AclRule struct {
+	Identity   string   `json:"identity"`   // client as recorded in the audit log like "key:2bb80d537b1da3e3" or "cert:CN=team-a", "*" for every client
+	Databases  []string `json:"databases"`  // db paths, registered names or globs the rule applies to, all databases if empty
+	Buckets    []string `json:"buckets"`    // bucket names, paths or globs the rule applies to, all buckets if empty. Only rules without buckets allow requests that are not limited to one bucket, like a full dump
+	Operations []string `json:"operations"` // "read", "write" (includes read) and "admin" (includes write)
+}
+
+// AclConfig is a struct representing the access control of the server.
+type AclConfig struct {
+	Enabled bool      `json:"enabled"` // deny every request no rule allows
+	Rules   []AclRule `json:"rules"`
+}
+
+// TenantConfig is a struct representing a customer sharing the server with others, whose clients only reach the databases below its Root.
+type TenantConfig struct {
+	Name       string         `json:"name"`       // prefix of the names its databases are registered under, like "acme" for "acme.app"
+	Identities []string       `json:"identities"` // its clients as recorded in the audit log like "key:2bb80d537b1da3e3"
+	Root       string         `json:"root"`       // directory the paths its clients send are resolved in, they cannot leave it
+	Databases  []RegisteredDb `json:"databases"`  // databases registered for the tenant, named without the prefix and with paths below Root
+}
+
+// Path returns where path as a client of the tenant sent it lies on the server. Paths are resolved as if Root was the root directory, so neither absolute paths nor ".." lead out of it.
+func (t TenantConfig) Path(path string) string {
+	return filepath.Join(t.Root, filepath.Clean("/"+path))
+}
+
+// TransformerConfig is a struct representing a Go plugin that turns the values of some buckets into JSON in full dumps.
+type TransformerConfig struct {
+	Plugin  string   `json:"plugin"`  // path of a plugin built with -buildmode=plugin that exports "func Transform(value []byte) ([]byte, error)"
+	Buckets []string `json:"buckets"` // bucket names, paths or globs whose values are transformed
+}
+
+// GobTypeConfig is a struct representing the type gob encoded values of some buckets are decoded into in full dumps.
+type GobTypeConfig struct {
+	Buckets []string        `json:"buckets"` // bucket names, paths or globs whose values are gob encoded
+	Type    json.RawMessage `json:"type"`    // type hint like {"Name": "string", "Tags": ["string"], "Address": {"City": "string"}}, see transform.GobType
+}
+
+// Config is a struct representing the content of the config file.
+type Config struct {
+	Databases    []RegisteredDb      `json:"databases"`    // databases known to the server
+	Server       ServerConfig        `json:"server"`       // timeouts of the HTTP listener
+	Snapshots    SnapshotConfig      `json:"snapshots"`    // periodic snapshots of the registered databases
+	Compaction   CompactionConfig    `json:"compaction"`   // scheduled compaction of the registered databases
+	Backups      BackupConfig        `json:"backups"`      // scheduled backups of the registered databases
+	Replication  ReplicationConfig   `json:"replication"`  // pushing of the registered databases to standby instances
+	Watch        WatchConfig         `json:"watch"`        // webhooks fired when a registered database changes
+	Resp         RespConfig          `json:"resp"`         // Redis protocol listener
+	Http3        Http3Config         `json:"http3"`        // API listener over QUIC
+	HandleCache  HandleCacheConfig   `json:"handleCache"`  // sharing of open databases between requests
+	Lock         LockConfig          `json:"lock"`         // waiting for databases locked by another process
+	Transactions TransactionConfig   `json:"transactions"` // transactions spanning several requests
+	Bandwidth    BandwidthConfig     `json:"bandwidth"`    // throttling of streamed responses
+	Exports      ExportConfig        `json:"exports"`      // copies resumable exports are read from
+	Idempotency  IdempotencyConfig   `json:"idempotency"`  // replaying the responses of retried writes
+	Pins         PinConfig           `json:"pins"`         // copies frozen in time for paging through a database
+	Views        []ViewConfig        `json:"views"`        // buckets derived from other buckets
+	Ttl          TtlConfig           `json:"ttl"`          // deletion of expired keys
+	S3           S3Config            `json:"s3"`           // databases stored in object storage
+	Audit        AuditConfig         `json:"audit"`        // log of who accessed which database
+	Acl          AclConfig           `json:"acl"`          // who may access which database
+	Tenants      []TenantConfig      `json:"tenants"`      // customers isolated from each other by directory
+	Keyring      map[string]string   `json:"keyring"`      // base64 encoded AES keys by key id, dumps can refer to them to decrypt values
+	Transformers []TransformerConfig `json:"transformers"` // decoders for values stored in app specific serializations
+	GobTypes     []GobTypeConfig     `json:"gobTypes"`     // decoders for values stored as gob
+}
+
+// Load reads and validates the config file at configPath.
+func Load(configPath string) (Config, error) {
+	var config Config
+
+	configBytes, err := os.ReadFile(configPath)
+	if err != nil {
+		return config, fmt.Errorf("Failed to read config file: %v\n", err)
+	}
+	err = json.Unmarshal(configBytes, &config)
+	if err != nil {
+		return config, fmt.Errorf("Failed to parse config file: %v\n", err)
+	}
+
+	// register the databases of the tenants under their prefixed names, so the registry checks them like all others
+	tenantOf := make(map[string]string)
+	for i, tenant := range config.Tenants {
+		if tenant.Name == "" || tenant.Root == "" || len(tenant.Identities) == 0 {
+			return config, fmt.Errorf("Every tenant needs a name, a root and identities\n")
+		}
+		if strings.ContainsAny(tenant.Name, "./") {
+			return config, fmt.Errorf("Tenant name %v must not contain . or /\n", tenant.Name)
+		}
+		root, err := filepath.Abs(tenant.Root)
+		if err != nil {
+			return config, fmt.Errorf("Invalid root of tenant %v: %v\n", tenant.Name, err)
+		}
+		config.Tenants[i].Root = root
+		for _, identity := range tenant.Identities {
+			if identity == "*" || tenantOf[identity] != "" {
+				return config, fmt.Errorf("Identity %v of tenant %v must be a single client that belongs to no other tenant\n", identity, tenant.Name)
+			}
+			tenantOf[identity] = tenant.Name
+		}
+		for _, registeredDb := range tenant.Databases {
+			if registeredDb.Name == "" {
+				return config, fmt.Errorf("Every database of tenant %v needs a name\n", tenant.Name)
+			}
+			registeredDb.Name = tenant.Name + "." + registeredDb.Name
+			if registeredDb.Path != "" {
+				registeredDb.Path = config.Tenants[i].Path(registeredDb.Path)
+			}
+			config.Databases = append(config.Databases, registeredDb)
+		}
+	}
+
+	// validate registered databases
+	names := make(map[string]bool)
+	for _, registeredDb := range config.Databases {
+		if registeredDb.Name == "" || (registeredDb.Path == "" && registeredDb.Fixture == "") {
+			return config, fmt.Errorf("Every registered database needs a name and a path or fixture\n")
+		}
+		if names[registeredDb.Name] {
+			return config, fmt.Errorf("Database %v is registered more than once\n", registeredDb.Name)
+		}
+		names[registeredDb.Name] = true
+		if options := registeredDb.Options; options != nil {
+			if options.PageSize < 0 || options.InitialMmapSize < 0 {
+				return config, fmt.Errorf("Page size and initial mmap size of database %v must not be negative\n", registeredDb.Name)
+			}
+			if options.PageSize != 0 && (options.PageSize < 1024 || options.PageSize&(options.PageSize-1) != 0) {
+				return config, fmt.Errorf("Page size of database %v must be a power of two of at least 1024\n", registeredDb.Name)
+			}
+			if options.FreelistType != "" && options.FreelistType != "array" && options.FreelistType != "hashmap" {
+				return config, fmt.Errorf("Database %v has unknown freelist type %v, use array or hashmap\n", registeredDb.Name, options.FreelistType)
+			}
+		}
+	}
+
+	// validate snapshot settings
+	if config.Snapshots.Dir != "" {
+		if config.Snapshots.Interval.Duration <= 0 {
+			return config, fmt.Errorf("Snapshot interval must be positive\n")
+		}
+		if config.Snapshots.Retention <= 0 {
+			return config, fmt.Errorf("Snapshot retention must be at least 1\n")
+		}
+	}
+
+	// validate compaction schedules
+	for _, schedule := range config.Compaction.Schedules {
+		if !names[schedule.Db] {
+			return config, fmt.Errorf("Compaction schedule refers to unknown database %v\n", schedule.Db)
+		}
+		_, err = cron.Parse(schedule.Cron)
+		if err != nil {
+			return config, err
+		}
+		if schedule.MinReclaimable < 0 || schedule.TxMaxSize < 0 {
+			return config, fmt.Errorf("minReclaimable and txMaxSize of the compaction of database %v must not be negative\n", schedule.Db)
+		}
+	}
+
+	// validate backup schedules
+	if len(config.Backups.Schedules) > 0 && config.Backups.Dir == "" {
+		return config, fmt.Errorf("Backup schedules need a backup dir\n")
+	}
+	if config.Backups.S3.Url != "" && !strings.HasPrefix(config.Backups.S3.Url, "s3://") {
+		return config, fmt.Errorf("Backup upload url %v must look like s3://bucket/prefix/\n", config.Backups.S3.Url)
+	}
+	if config.Backups.S3.PartSize != 0 && config.Backups.S3.PartSize < 5<<20 {
+		return config, fmt.Errorf("Backup part size must be at least 5 MiB, S3 rejects smaller parts\n")
+	}
+	backedUp := make(map[string]bool)
+	for _, schedule := range config.Backups.Schedules {
+		if !names[schedule.Db] {
+			return config, fmt.Errorf("Backup schedule refers to unknown database %v\n", schedule.Db)
+		}
+		if backedUp[schedule.Db] {
+			return config, fmt.Errorf("Database %v has more than one backup schedule\n", schedule.Db)
+		}
+		backedUp[schedule.Db] = true
+		_, err = cron.Parse(schedule.Cron)
+		if err != nil {
+			return config, err
+		}
+		if schedule.KeepLast < 0 || schedule.KeepDaily < 0 || schedule.KeepWeekly < 0 {
+			return config, fmt.Errorf("keepLast, keepDaily and keepWeekly of the backups of database %v must not be negative\n", schedule.Db)
+		}
+		if schedule.KeepLast+schedule.KeepDaily+schedule.KeepWeekly == 0 {
+			return config, fmt.Errorf("Backups of database %v need keepLast, keepDaily or keepWeekly\n", schedule.Db)
+		}
+	}
+
+	// validate replication
+	if config.Replication.Interval.Duration < 0 {
+		return config, fmt.Errorf("Replication interval must be positive\n")
+	}
+	if config.Replication.Interval.Duration == 0 {
+		config.Replication.Interval.Duration = DefaultReplicationInterval
+	}
+	for i, peer := range config.Replication.Peers {
+		if !names[peer.Db] {
+			return config, fmt.Errorf("Replication refers to unknown database %v\n", peer.Db)
+		}
+		if peer.Url == "" {
+			return config, fmt.Errorf("Replication of database %v has no url\n", peer.Db)
+		}
+		if peer.PeerDb == "" {
+			config.Replication.Peers[i].PeerDb = peer.Db
+		}
+	}
+
+	// validate webhooks
+	if config.Watch.Interval.Duration < 0 {
+		return config, fmt.Errorf("Watch interval must be positive\n")
+	}
+	if config.Watch.Interval.Duration == 0 {
+		config.Watch.Interval.Duration = DefaultWatchInterval
+	}
+	if config.Watch.Retries < 0 {
+		return config, fmt.Errorf("Webhook retries must not be negative\n")
+	}
+	if config.Watch.Retries == 0 {
+		config.Watch.Retries = DefaultWebhookRetries
+	}
+	for _, webhook := range config.Watch.Webhooks {
+		if !names[webhook.Db] {
+			return config, fmt.Errorf("Webhook refers to unknown database %v\n", webhook.Db)
+		}
+		if len(webhook.Urls) == 0 {
+			return config, fmt.Errorf("Webhook for database %v has no urls\n", webhook.Db)
+		}
+	}
+
+	// validate RESP listener
+	if config.Resp.Addr != "" && !names[config.Resp.Db] {
+		return config, fmt.Errorf("RESP listener refers to unknown database %v\n", config.Resp.Db)
+	}
+
+	// validate listener timeouts
+	for _, timeout := range []*Duration{&config.Server.ReadHeaderTimeout, &config.Server.ReadTimeout, &config.Server.WriteTimeout, &config.Server.IdleTimeout, &config.Server.MaxRequestTimeout} {
+		if timeout.Duration < 0 {
+			return config, fmt.Errorf("Server timeouts must be positive\n")
+		}
+	}
+	if config.Server.MaxPayloadBytes < 0 {
+		return config, fmt.Errorf("Server maxPayloadBytes must be positive\n")
+	}
+	if config.Server.MemoryLimit < 0 {
+		return config, fmt.Errorf("Server memoryLimit must be positive\n")
+	}
+	config.Server = config.Server.WithDefaults()
+
+	// validate HTTP/3 listener
+	if config.Http3.Addr != "" && (config.Http3.CertFile == "" || config.Http3.KeyFile == "") {
+		return config, fmt.Errorf("HTTP/3 listener needs a certFile and a keyFile\n")
+	}
+
+	// validate lock settings
+	if config.Lock.Timeout.Duration < 0 || config.Lock.Retries < 0 || config.Lock.Backoff.Duration < 0 {
+		return config, fmt.Errorf("Lock timeout, retries and backoff must not be negative\n")
+	}
+	if config.Lock.Timeout.Duration == 0 && (config.Lock.Retries > 0 || config.Lock.Backoff.Duration > 0) {
+		return config, fmt.Errorf("Lock retries need a lock timeout\n")
+	}
+
+	// validate handle cache settings
+	if config.HandleCache.IdleTimeout.Duration < 0 {
+		return config, fmt.Errorf("Handle cache idle timeout must be positive\n")
+	}
+	if config.HandleCache.IdleTimeout.Duration == 0 {
+		config.HandleCache.IdleTimeout.Duration = DefaultHandleIdleTimeout
+	}
+
+	// validate transaction settings
+	if config.Transactions.IdleTimeout.Duration < 0 || config.Transactions.MaxDuration.Duration < 0 || config.Transactions.MaxSessions < 0 {
+		return config, fmt.Errorf("Transaction idle timeout, maxDuration and maxSessions must be positive\n")
+	}
+	config.Transactions = config.Transactions.WithDefaults()
+
+	// validate bandwidth limits
+	if config.Bandwidth.Global < 0 || config.Bandwidth.PerRequest < 0 {
+		return config, fmt.Errorf("Bandwidth limits must not be negative\n")
+	}
+
+	// validate export settings
+	if config.Exports.Retention.Duration < 0 {
+		return config, fmt.Errorf("Export retention must be positive\n")
+	}
+	config.Exports = config.Exports.WithDefaults()
+
+	// validate idempotency settings
+	if config.Idempotency.Retention.Duration < 0 {
+		return config, fmt.Errorf("Idempotency retention must be positive\n")
+	}
+	if config.Idempotency.Retention.Duration == 0 {
+		config.Idempotency.Retention.Duration = DefaultIdempotencyRetention
+	}
+
+	// validate pin settings
+	if config.Pins.IdleTimeout.Duration < 0 || config.Pins.MaxPins < 0 {
+		return config, fmt.Errorf("Pin idle timeout and maxPins must be positive\n")
+	}
+	config.Pins = config.Pins.WithDefaults()
+
+	// validate views
+	viewNames := make(map[string]bool)
+	for i, view := range config.Views {
+		if !names[view.Db] {
+			return config, fmt.Errorf("View %v refers to unknown database %v\n", view.Name, view.Db)
+		}
+		if view.Name == "" || strings.Contains(view.Name, "/") || view.Bucket == "" || view.Separator == "" {
+			return config, fmt.Errorf("Every view needs a name without / and a bucket and separator\n")
+		}
+		if viewNames[view.Db+"/"+view.Name] {
+			return config, fmt.Errorf("View %v of database %v is defined more than once\n", view.Name, view.Db)
+		}
+		viewNames[view.Db+"/"+view.Name] = true
+		if view.Kind != "count" && view.Kind != "latest" {
+			return config, fmt.Errorf("View %v has unknown kind %v, use count or latest\n", view.Name, view.Kind)
+		}
+		if view.Refresh != "" && view.Refresh != "write" && view.Refresh != "demand" {
+			return config, fmt.Errorf("View %v has unknown refresh %v, use write or demand\n", view.Name, view.Refresh)
+		}
+		if view.Refresh == "" {
+			config.Views[i].Refresh = "write"
+		}
+	}
+
+	// validate ttl settings
+	if config.Ttl.SweepInterval.Duration < 0 {
+		return config, fmt.Errorf("TTL sweep interval must be positive\n")
+	}
+
+	// validate access control rules
+	for _, rule := range config.Acl.Rules {
+		if rule.Identity == "" {
+			return config, fmt.Errorf("Every ACL rule needs an identity\n")
+		}
+		if len(rule.Operations) == 0 {
+			return config, fmt.Errorf("ACL rule for %v allows no operations\n", rule.Identity)
+		}
+		for _, operation := range rule.Operations {
+			if operation != "read" && operation != "write" && operation != "admin" {
+				return config, fmt.Errorf("ACL rule for %v has unknown operation %v, use read, write or admin\n", rule.Identity, operation)
+			}
+		}
+		for _, pattern := range append(append([]string{}, rule.Databases...), rule.Buckets...) {
+			_, err := path.Match(pattern, "")
+			if err != nil {
+				return config, fmt.Errorf("ACL rule for %v has invalid pattern %q: %v\n", rule.Identity, pattern, err)
+			}
+		}
+	}
+
+	// validate keyring
+	for keyId := range config.Keyring {
+		_, err := config.LookupKey(keyId)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	// validate transformers
+	for _, transformer := range config.Transformers {
+		if transformer.Plugin == "" {
+			return config, fmt.Errorf("Every transformer needs a plugin\n")
+		}
+		if len(transformer.Buckets) == 0 {
+			return config, fmt.Errorf("Transformer %v applies to no buckets\n", transformer.Plugin)
+		}
+		for _, pattern := range transformer.Buckets {
+			_, err := path.Match(pattern, "")
+			if err != nil {
+				return config, fmt.Errorf("Transformer %v has invalid pattern %q: %v\n", transformer.Plugin, pattern, err)
+			}
+		}
+	}
+
+	// validate gob types, the type hints themselves are checked when they are loaded
+	for _, gobType := range config.GobTypes {
+		if len(gobType.Buckets) == 0 || len(gobType.Type) == 0 {
+			return config, fmt.Errorf("Every gob type needs buckets and a type\n")
+		}
+		for _, pattern := range gobType.Buckets {
+			_, err := path.Match(pattern, "")
+			if err != nil {
+				return config, fmt.Errorf("Gob type has invalid pattern %q: %v\n", pattern, err)
+			}
+		}
+	}
+
+	return config, nil
+}
+
+// LookupKey returns the AES key with the given id from the keyring.
+func (c Config) LookupKey(keyId string) ([]byte, error) {
+	encodedKey, found := c.Keyring[keyId]
+	if !found {
+		return nil, fmt.Errorf("Unknown key id %v\n", keyId)
+	}
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil || (len(key) != 16 && len(key) != 24 && len(key) != 32) {
+		return nil, fmt.Errorf("Key %v of the keyring must be 16, 24 or 32 base64 encoded bytes\n", keyId)
+	}
+	return key, nil
+}
+
+// LookupDb returns the registered database with the given name.
+func (c Config) LookupDb(name string) (RegisteredDb, bool) {
+	for _, registeredDb := range c.Databases {
+		if registeredDb.Name == name {
+			return registeredDb, true
+		}
+	}
+	return RegisteredDb{}, false
+}
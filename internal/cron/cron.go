@@ -0,0 +1,147 @@
+// Package cron parses cron expressions like "30 3 * * 1-5" and tells when they are due.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression of five fields: minute, hour, day of month, month and day of week (0 or 7 is Sunday).
+// Every field is "*", a number, a range like "1-5", a step like "*/15" or "0-30/10", or a comma separated list of those.
+// Like in cron, if both the day of month and the day of week are restricted a day matches if either of them does.
+type Schedule struct {
+	expression string
+	minutes    [60]bool
+	hours      [24]bool
+	days       [32]bool
+	months     [13]bool
+	weekdays   [8]bool
+	anyDay     bool // day of month is "*"
+	anyWeekday bool // day of week is "*"
+}
+
+// Parse parses the cron expression expression.
+func Parse(expression string) (*Schedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Cron expression %q needs five fields: minute, hour, day of month, month and day of week\n", expression)
+	}
+	schedule := &Schedule{
+		expression: expression,
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+	for i, field := range []struct {
+		allowed  []bool
+		min, max int
+	}{
+		{schedule.minutes[:], 0, 59},
+		{schedule.hours[:], 0, 23},
+		{schedule.days[:], 1, 31},
+		{schedule.months[:], 1, 12},
+		{schedule.weekdays[:], 0, 7},
+	} {
+		err := parseField(fields[i], field.allowed, field.min, field.max)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid cron expression %q: %v\n", expression, err)
+		}
+	}
+	if schedule.weekdays[7] {
+		schedule.weekdays[0] = true
+	}
+	return schedule, nil
+}
+
+// parseField marks the values field allows in allowed, min and max are the bounds of the field.
+func parseField(field string, allowed []bool, min int, max int) error {
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		first, last := min, max
+		if rangePart != "*" {
+			firstPart, lastPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			first, err = strconv.Atoi(firstPart)
+			if err != nil {
+				return fmt.Errorf("invalid value %q", rangePart)
+			}
+			last = first
+			if isRange {
+				last, err = strconv.Atoi(lastPart)
+				if err != nil {
+					return fmt.Errorf("invalid value %q", rangePart)
+				}
+			} else if hasStep {
+				last = max // "5/15" means from 5 on
+			}
+		}
+		if first < min || last > max || first > last {
+			return fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for value := first; value <= last; value += step {
+			allowed[value] = true
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the schedule is due in the minute of t.
+func (s *Schedule) Matches(t time.Time) bool {
+	if !s.minutes[t.Minute()] || !s.hours[t.Hour()] || !s.months[t.Month()] {
+		return false
+	}
+	dayMatches, weekdayMatches := s.days[t.Day()], s.weekdays[t.Weekday()]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekdayMatches
+	case s.anyWeekday:
+		return dayMatches
+	}
+	return dayMatches || weekdayMatches
+}
+
+// Next returns the first minute after t the schedule is due in, or the zero time if it is not due within the next five years, like for "0 0 31 2 *".
+func (s *Schedule) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	for end := next.AddDate(5, 0, 0); next.Before(end); {
+		switch {
+		case !s.months[next.Month()]:
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+		case !s.Matches(time.Date(next.Year(), next.Month(), next.Day(), firstAllowed(s.hours[:]), firstAllowed(s.minutes[:]), 0, 0, next.Location())):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+		case !s.hours[next.Hour()]:
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+		case !s.minutes[next.Minute()]:
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// firstAllowed returns the lowest value allowed allows.
+func firstAllowed(allowed []bool) int {
+	for value, isAllowed := range allowed {
+		if isAllowed {
+			return value
+		}
+	}
+	return 0
+}
+
+func (s *Schedule) String() string {
+	return s.expression
+}
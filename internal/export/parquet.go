@@ -0,0 +1,81 @@
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+	"github.com/parquet-go/parquet-go"
+	bolt "go.etcd.io/bbolt"
+)
+
+// parquetRowGroupSize is the amount of rows buffered before they are written as a row group, which bounds the memory an export needs.
+const parquetRowGroupSize = 10000
+
+// ParquetRow is a struct representing one key-value pair of an exported database as a row of the Parquet file.
+type ParquetRow struct {
+	Bucket    string `parquet:"bucket,dict"` // path of the bucket like "config/devices", nested buckets are separated by slashes
+	Key       []byte `parquet:"key"`
+	Value     []byte `parquet:"value"`
+	ValueSize int64  `parquet:"value_size"` // length of value in bytes, so sizes can be aggregated without reading the values
+}
+
+// ToParquet takes the path to a bbolt database and writes its content as a Snappy compressed Parquet file with the columns of ParquetRow to out.
+// Parquet only needs the file to be seekable for reading, so the file is written front to back and can be streamed to a client while the database is read.
+// Only the entries that pass filter are written, a nil filter passes everything. The meta buckets are only exported if internal is set, like for ToSqlite.
+func ToParquet(dbPath string, out io.Writer, filter *bboltdump.Filter, internal bool) error {
+	// open database
+	dbInstance, closeDb, err := bboltdump.OpenDb(dbPath)
+	if err != nil {
+		return err
+	}
+	defer closeDb()
+
+	parquetWriter := parquet.NewGenericWriter[ParquetRow](out, parquet.Compression(&parquet.Snappy), parquet.MaxRowsPerRowGroup(parquetRowGroupSize))
+	rows := make([]ParquetRow, 0, parquetRowGroupSize)
+	writeRows := func() error {
+		_, err := parquetWriter.Write(rows)
+		rows = rows[:0]
+		return err
+	}
+
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		err := tx.ForEach(func(bucketName []byte, b *bolt.Bucket) error {
+			if !internal && bboltdump.IsMetaBucket(string(bucketName)) {
+				return nil
+			}
+			return bboltdump.WalkBucket(b, "", func(path string, keyBytes []byte, valueBytes []byte) error {
+				if bboltdump.IsExpired(tx, bucketPath(string(bucketName), path), keyBytes) {
+					return nil
+				}
+				if !filter.Matches(bucketPath(string(bucketName), path), keyBytes, valueBytes) {
+					return nil
+				}
+				// keys and values point into bolt's memory map, so all rows are written before the transaction ends
+				rows = append(rows, ParquetRow{
+					Bucket:    bucketPath(string(bucketName), path),
+					Key:       keyBytes,
+					Value:     valueBytes,
+					ValueSize: int64(len(valueBytes)),
+				})
+				if len(rows) == parquetRowGroupSize {
+					return writeRows()
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+		return writeRows()
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to export database to Parquet due to error: %v\n", err)
+	}
+
+	err = parquetWriter.Close()
+	if err != nil {
+		return fmt.Errorf("Failed to finish Parquet file: %v\n", err)
+	}
+	return nil
+}
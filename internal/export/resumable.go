@@ -0,0 +1,256 @@
+package export
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+	bolt "go.etcd.io/bbolt"
+)
+
+// checkpointInterval is the amount of bytes of a resumable export after which another checkpoint is recorded, so resuming never rereads more than that.
+const checkpointInterval = 1 << 20
+
+// ErrUnknownExport is returned when a resumable export does not exist or was already deleted.
+var ErrUnknownExport = errors.New("export does not exist")
+
+// Checkpoint is a struct representing a position in a resumable export.
+type Checkpoint struct {
+	Offset int64  `json:"offset"` // byte offset of the line that follows the entry
+	Bucket string `json:"bucket"` // bucket of the last entry before offset
+	Key    string `json:"key"`    // hex encoded key of the last entry before offset
+}
+
+// Resumable is a struct representing an NDJSON export of a copy of a database that can be continued at any byte offset.
+// The copy never changes and keys expire as of Time, so every pass over it writes the same bytes and an offset always points to the same place.
+type Resumable struct {
+	Id          string       `json:"id"`          // identifies the export, pass it to resume
+	Input       string       `json:"input"`       // path to the db file the copy was taken of
+	Bucket      string       `json:"bucket"`      // the only bucket exported, all top level buckets if empty
+	Where       string       `json:"where"`       // filter expression the exported entries pass, see bboltdump.Filter. All entries are exported if empty
+	Keys        string       `json:"keys"`        // bboltdump.KeysText or bboltdump.KeysHex, exports saved before keys could be rendered as text have none and are continued hex encoded
+	Time        time.Time    `json:"time"`        // time the copy was taken at
+	Size        int64        `json:"size"`        // length of the whole export in bytes
+	Checkpoints []Checkpoint `json:"checkpoints"` // positions an export can be continued from without reading the entries before, in order
+}
+
+// resumableDir returns the directory of the export with the given id below the export directory dir.
+func resumableDir(dir string, id string) string {
+	return filepath.Join(dir, id)
+}
+
+// copyPath returns the path of the copy the export reads from.
+func (e Resumable) copyPath(dir string) string {
+	return filepath.Join(resumableDir(dir, e.Id), "copy.db")
+}
+
+// StartResumable copies the database at dbPath below the export directory dir from within a read transaction and records the size and checkpoints of its NDJSON export of bucketName, or of all top level buckets if bucketName is empty.
+// Only the entries that pass the filter expression where are exported, all of them if it is empty. Keys are exported hex encoded if keys is bboltdump.KeysHex, as bboltdump.KeyText renders them otherwise. Exports that were started longer than retention ago are deleted first.
+func StartResumable(dir string, retention time.Duration, dbPath string, bucketName string, where string, keys string) (Resumable, error) {
+	pruneResumables(dir, retention)
+
+	idBytes := make([]byte, 16)
+	_, err := rand.Read(idBytes)
+	if err != nil {
+		return Resumable{}, fmt.Errorf("Failed to generate export id: %v\n", err)
+	}
+	if keys != bboltdump.KeysHex {
+		keys = bboltdump.KeysText // always recorded, see Keys
+	}
+	resumable := Resumable{
+		Id:          hex.EncodeToString(idBytes),
+		Input:       dbPath,
+		Bucket:      bucketName,
+		Where:       where,
+		Keys:        keys,
+		Time:        time.Now().UTC(),
+		Checkpoints: []Checkpoint{{}},
+	}
+	err = os.MkdirAll(resumableDir(dir, resumable.Id), 0700)
+	if err != nil {
+		return resumable, fmt.Errorf("Failed to create export directory: %v\n", err)
+	}
+
+	// open database
+	dbInstance, closeDb, err := bboltdump.OpenDb(dbPath)
+	if err != nil {
+		os.RemoveAll(resumableDir(dir, resumable.Id))
+		return resumable, err
+	}
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(resumable.copyPath(dir), 0600)
+	})
+	closeDb()
+	if err != nil {
+		os.RemoveAll(resumableDir(dir, resumable.Id))
+		return resumable, fmt.Errorf("Failed to copy database %v: %v\n", dbPath, err)
+	}
+
+	// one pass without sending anything, so the size is known from the start and every part of the export can be asked for
+	err = resumable.forEachLine(dir, Checkpoint{}, func(line []byte, bucketName string, keyBytes []byte) error {
+		resumable.Size += int64(len(line))
+		if resumable.Size-resumable.Checkpoints[len(resumable.Checkpoints)-1].Offset >= checkpointInterval {
+			resumable.Checkpoints = append(resumable.Checkpoints, Checkpoint{Offset: resumable.Size, Bucket: bucketName, Key: hex.EncodeToString(keyBytes)})
+		}
+		return nil
+	})
+	if err == nil {
+		err = resumable.save(dir)
+	}
+	if err != nil {
+		os.RemoveAll(resumableDir(dir, resumable.Id))
+		return resumable, err
+	}
+	return resumable, nil
+}
+
+// LoadResumable returns the export with the given id below the export directory dir, or ErrUnknownExport.
+func LoadResumable(dir string, id string) (Resumable, error) {
+	var resumable Resumable
+	// the id ends up in a file path, so only accept well formed ids
+	idBytes, err := hex.DecodeString(id)
+	if err != nil || len(idBytes) != 16 {
+		return resumable, ErrUnknownExport
+	}
+	stateBytes, err := os.ReadFile(filepath.Join(resumableDir(dir, id), "export.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return resumable, ErrUnknownExport
+	}
+	if err != nil {
+		return resumable, fmt.Errorf("Failed to read export %v: %v\n", id, err)
+	}
+	err = json.Unmarshal(stateBytes, &resumable)
+	if err != nil {
+		return resumable, fmt.Errorf("Failed to parse export %v: %v\n", id, err)
+	}
+	return resumable, nil
+}
+
+// save writes the state of the export next to its copy, through a temporary file so a half written state is never read.
+func (e Resumable) save(dir string) error {
+	stateBytes, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("Failed to serialize export %v: %v\n", e.Id, err)
+	}
+	statePath := filepath.Join(resumableDir(dir, e.Id), "export.json")
+	err = os.WriteFile(statePath+".tmp", stateBytes, 0600)
+	if err == nil {
+		err = os.Rename(statePath+".tmp", statePath)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to store export %v: %v\n", e.Id, err)
+	}
+	return nil
+}
+
+// forEachLine calls fn with the NDJSON line of every entry of the export after the checkpoint from.
+func (e Resumable) forEachLine(dir string, from Checkpoint, fn func(line []byte, bucketName string, keyBytes []byte) error) error {
+	afterKey, err := hex.DecodeString(from.Key)
+	if err != nil {
+		return fmt.Errorf("Invalid checkpoint of export %v\n", e.Id)
+	}
+	var filter *bboltdump.Filter
+	if e.Where != "" {
+		filter, err = bboltdump.ParseFilter(e.Where)
+		if err != nil {
+			return err
+		}
+	}
+	keys := e.Keys
+	if keys == "" {
+		keys = bboltdump.KeysHex
+	}
+	var line bytes.Buffer
+	encoder := json.NewEncoder(&line)
+	return bboltdump.ForEachEntryAfter(e.copyPath(dir), e.Bucket, from.Bucket, afterKey, e.Time, func(bucketName string, keyBytes []byte, valueBytes []byte) error {
+		if !filter.Matches(bucketName, keyBytes, valueBytes) {
+			return nil
+		}
+		line.Reset()
+		err := encoder.Encode(bboltdump.NdjsonEntry{
+			Bucket: bucketName,
+			Key:    bboltdump.RenderKey(keyBytes, keys),
+			Value:  string(valueBytes),
+		})
+		if err != nil {
+			return err
+		}
+		return fn(line.Bytes(), bucketName, keyBytes)
+	})
+}
+
+// WriteRange writes the bytes from start up to but not including end of the export to out. It starts reading the copy at the last checkpoint before start, so resuming a large export late does not reread it from the beginning.
+func (e Resumable) WriteRange(out io.Writer, dir string, start int64, end int64) error {
+	if start < 0 || end > e.Size || start > end {
+		return fmt.Errorf("Range %v-%v is outside of export %v\n", start, end, e.Id)
+	}
+	if start == end {
+		return nil
+	}
+	from := e.Checkpoints[0]
+	for _, checkpoint := range e.Checkpoints {
+		if checkpoint.Offset <= start {
+			from = checkpoint
+		}
+	}
+
+	errDone := errors.New("range written")
+	offset := from.Offset
+	err := e.forEachLine(dir, from, func(line []byte, bucketName string, keyBytes []byte) error {
+		lineStart := offset
+		offset += int64(len(line))
+		if offset <= start {
+			return nil
+		}
+		_, err := out.Write(line[max(start-lineStart, 0) : min(end, offset)-lineStart])
+		if err != nil {
+			return err
+		}
+		if offset >= end {
+			return errDone
+		}
+		return nil
+	})
+	if err == errDone {
+		return nil
+	}
+	if err == nil && offset < end {
+		return fmt.Errorf("Copy of export %v ended at %v instead of %v\n", e.Id, offset, end)
+	}
+	return err
+}
+
+// pruneResumables deletes the exports below dir that were started longer than retention ago, a failure to delete one is logged and it is tried again next time.
+func pruneResumables(dir string, retention time.Duration) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return // nothing was exported yet
+	}
+	for _, dirEntry := range dirEntries {
+		resumable, err := LoadResumable(dir, dirEntry.Name())
+		var started time.Time
+		if err == nil {
+			started = resumable.Time
+		} else if info, infoErr := dirEntry.Info(); infoErr == nil {
+			started = info.ModTime() // an export that failed to be started
+		}
+		if time.Since(started) < retention {
+			continue
+		}
+		if err == nil {
+			bboltdump.CloseCachedHandle(resumable.copyPath(dir)) // the handle cache may still hold the copy open
+		}
+		err = os.RemoveAll(filepath.Join(dir, dirEntry.Name()))
+		if err != nil {
+			fmt.Println("ERROR: Failed to delete export:", err)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package export
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+	bolt "go.etcd.io/bbolt"
+)
+
+// partitions of ToShards
+const (
+	ShardByBucket = "bucket" // every top level bucket goes to one shard as a whole
+	ShardByKey    = "key"    // every key goes to the shard the FNV-1a hash of the key picks, each shard has all buckets
+)
+
+// ShardPartitions are all partitions of ToShards.
+var ShardPartitions = []string{ShardByBucket, ShardByKey}
+
+// MaxShards is the upper bound for the amount of files a database can be split into.
+const MaxShards = 256
+
+const shardBatchSize = 10000 // amount of keys written per transaction of a shard, keeps single transactions from growing unbounded
+
+// ShardManifestName is the name of the manifest in the archive WriteShardsTar writes, it comes before the shards.
+const ShardManifestName = "manifest.json"
+
+// Shard is a struct representing one bbolt file of a sharded export.
+type Shard struct {
+	Name    string   `json:"name"`    // file name in the archive like "shard-000.db"
+	Buckets []string `json:"buckets"` // top level buckets that have keys or are empty in the shard
+	Keys    int64    `json:"keys"`    // amount of keys in the shard, including those of nested buckets
+	Size    int64    `json:"size"`    // size of the file in bytes
+}
+
+// ShardManifest is a struct representing how a database was split by ToShards.
+type ShardManifest struct {
+	Source    string  `json:"source"`    // path to the db file that was split
+	Partition string  `json:"partition"` // ShardByBucket or ShardByKey
+	Shards    []Shard `json:"shards"`
+}
+
+// shardOfKey returns the shard of keyBytes among shards with ShardByKey.
+func shardOfKey(keyBytes []byte, shards int) int {
+	hash := fnv.New32a()
+	hash.Write(keyBytes)
+	return int(hash.Sum32() % uint32(shards))
+}
+
+// ToShards takes the path to a bbolt database and splits its content into shards new bbolt files in dir, named like "shard-000.db".
+// With ShardByBucket every top level bucket goes to one shard along with its nested buckets, the largest buckets are placed first, each on the shard with the least data so far, so the shards end up about equally big. With ShardByKey every shard has all buckets and every key goes to the shard of the FNV-1a hash of the key modulo shards, so downstream processing can find a key without reading every shard.
+// Only the entries that pass filter are written, a nil filter passes everything. Expired keys and the buckets the package keeps beside the data, like expiries or the journal, are left out, so the keys of the shards have no expiry.
+func ToShards(dbPath string, dir string, shards int, partition string, filter *bboltdump.Filter) (ShardManifest, error) {
+	manifest := ShardManifest{Source: dbPath, Partition: partition, Shards: []Shard{}}
+	if !slices.Contains(ShardPartitions, partition) {
+		return manifest, fmt.Errorf("Unsupported partition %v, use one of %v\n", partition, ShardPartitions)
+	}
+	if shards < 1 || shards > MaxShards {
+		return manifest, fmt.Errorf("Amount of shards must be between 1 and %v\n", MaxShards)
+	}
+
+	// open database
+	dbInstance, closeDb, err := bboltdump.OpenDb(dbPath)
+	if err != nil {
+		return manifest, err
+	}
+	defer closeDb()
+
+	// create shards, nobody else sees them before they are complete so they are not synced after every transaction
+	shardDbs := make([]*bolt.DB, shards)
+	for i := range shardDbs {
+		manifest.Shards = append(manifest.Shards, Shard{Name: fmt.Sprintf("shard-%03d.db", i), Buckets: []string{}})
+		shardDbs[i], err = bolt.Open(filepath.Join(dir, manifest.Shards[i].Name), 0600, &bolt.Options{NoSync: true, Timeout: time.Second})
+		if err != nil {
+			for _, shardDb := range shardDbs[:i] {
+				shardDb.Close()
+			}
+			return manifest, fmt.Errorf("Failed to create shard %v: %v\n", manifest.Shards[i].Name, err)
+		}
+	}
+	closeShards := func() error {
+		var closeErr error
+		for _, shardDb := range shardDbs {
+			err := shardDb.Close()
+			if err != nil && closeErr == nil {
+				closeErr = err
+			}
+		}
+		return closeErr
+	}
+
+	// a nil key creates the bucket without writing a key
+	type shardEntry struct {
+		bucket string
+		key    []byte
+		value  []byte
+	}
+	batches := make([][]shardEntry, shards)
+	// writes the collected batch of a shard in one transaction, the keys and values point into bolt's memory map so this happens before the read transaction ends
+	flush := func(shard int) error {
+		err := shardDbs[shard].Update(func(shardTx *bolt.Tx) error {
+			buckets := make(map[string]*bolt.Bucket) // resolving a nested bucket path walks all its parents
+			for _, entry := range batches[shard] {
+				b, found := buckets[entry.bucket]
+				if !found {
+					var err error
+					b, err = bboltdump.CreateBucketPath(shardTx, entry.bucket)
+					if err != nil {
+						return err
+					}
+					buckets[entry.bucket] = b
+				}
+				if entry.key == nil {
+					continue
+				}
+				err := b.Put(entry.key, entry.value)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to write shard %v: %v\n", manifest.Shards[shard].Name, err)
+		}
+		batches[shard] = batches[shard][:0]
+		return nil
+	}
+	add := func(shard int, entry shardEntry) error {
+		batches[shard] = append(batches[shard], entry)
+		if entry.key != nil {
+			manifest.Shards[shard].Keys++
+		}
+		if len(batches[shard]) == shardBatchSize {
+			return flush(shard)
+		}
+		return nil
+	}
+
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		bucketNames := []string{}
+		bucketSizes := make(map[string]int)
+		err := tx.ForEach(func(bucketName []byte, b *bolt.Bucket) error {
+			if bboltdump.IsMetaBucket(string(bucketName)) {
+				return nil
+			}
+			bucketNames = append(bucketNames, string(bucketName))
+			if partition == ShardByBucket {
+				stats := b.Stats()
+				bucketSizes[string(bucketName)] = stats.LeafInuse + stats.BranchInuse + stats.InlineBucketInuse
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// place the largest buckets first, each on the shard with the least data so far
+		bucketShards := make(map[string]int)
+		if partition == ShardByBucket {
+			bySize := slices.Clone(bucketNames)
+			sort.SliceStable(bySize, func(i, j int) bool { return bucketSizes[bySize[i]] > bucketSizes[bySize[j]] })
+			shardSizes := make([]int, shards)
+			for _, bucketName := range bySize {
+				smallest := 0
+				for shard := range shardSizes {
+					if shardSizes[shard] < shardSizes[smallest] {
+						smallest = shard
+					}
+				}
+				bucketShards[bucketName] = smallest
+				shardSizes[smallest] += bucketSizes[bucketName]
+				manifest.Shards[smallest].Buckets = append(manifest.Shards[smallest].Buckets, bucketName)
+			}
+		} else {
+			for shard := range manifest.Shards {
+				manifest.Shards[shard].Buckets = bucketNames
+			}
+		}
+
+		// adds the bucket bucketPath and everything in it to the shards
+		var addBucket func(topLevelBucket string, b *bolt.Bucket, bucketPath string) error
+		addBucket = func(topLevelBucket string, b *bolt.Bucket, bucketPath string) error {
+			if partition == ShardByBucket {
+				err := add(bucketShards[topLevelBucket], shardEntry{bucket: bucketPath})
+				if err != nil {
+					return err
+				}
+			} else {
+				for shard := range shards {
+					err := add(shard, shardEntry{bucket: bucketPath})
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return b.ForEach(func(keyBytes []byte, valueBytes []byte) error {
+				if valueBytes == nil {
+					return addBucket(topLevelBucket, b.Bucket(keyBytes), bucketPath+"/"+string(keyBytes))
+				}
+				if bboltdump.IsExpired(tx, bucketPath, keyBytes) || !filter.Matches(bucketPath, keyBytes, valueBytes) {
+					return nil
+				}
+				shard := bucketShards[topLevelBucket]
+				if partition == ShardByKey {
+					shard = shardOfKey(keyBytes, shards)
+				}
+				return add(shard, shardEntry{bucket: bucketPath, key: keyBytes, value: valueBytes})
+			})
+		}
+
+		for _, bucketName := range bucketNames {
+			err = addBucket(bucketName, tx.Bucket([]byte(bucketName)), bucketName)
+			if err != nil {
+				return err
+			}
+		}
+		for shard := range batches {
+			if len(batches[shard]) > 0 {
+				err = flush(shard)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		closeShards()
+		return manifest, fmt.Errorf("Failed to split database into shards due to error: %v\n", err)
+	}
+
+	err = closeShards()
+	if err != nil {
+		return manifest, fmt.Errorf("Failed to close shards: %v\n", err)
+	}
+	for shard := range manifest.Shards {
+		fileInfo, err := os.Stat(filepath.Join(dir, manifest.Shards[shard].Name))
+		if err != nil {
+			return manifest, fmt.Errorf("Failed to read size of shard: %v\n", err)
+		}
+		manifest.Shards[shard].Size = fileInfo.Size()
+	}
+	return manifest, nil
+}
+
+// WriteShardsTar writes the manifest followed by the shards it lists, which ToShards wrote to dir, as a tar archive to out.
+// Every shard is written as soon as it is read, so the archive can be streamed to a client.
+func WriteShardsTar(out io.Writer, dir string, manifest ShardManifest) error {
+	tarWriter := tar.NewWriter(out)
+	modTime := time.Now()
+
+	manifestJson, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Failed to serialize object to json: %v\n", err)
+	}
+	err = tarWriter.WriteHeader(&tar.Header{Name: ShardManifestName, Mode: 0600, Size: int64(len(manifestJson)), ModTime: modTime})
+	if err == nil {
+		_, err = tarWriter.Write(manifestJson)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to write manifest: %v\n", err)
+	}
+
+	for _, shard := range manifest.Shards {
+		err = writeTarFile(tarWriter, filepath.Join(dir, shard.Name), shard.Name, modTime)
+		if err != nil {
+			return fmt.Errorf("Failed to write shard %v: %v\n", shard.Name, err)
+		}
+	}
+	return tarWriter.Close()
+}
+
+// writeTarFile writes the file at path to tarWriter under name.
+func writeTarFile(tarWriter *tar.Writer, path string, name string, modTime time.Time) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	err = tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: fileInfo.Size(), ModTime: modTime})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tarWriter, file)
+	return err
+}
@@ -0,0 +1,115 @@
+// Package export converts bbolt databases into other file formats.
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+	bolt "go.etcd.io/bbolt"
+	_ "modernc.org/sqlite" // registers the "sqlite" driver, pure Go so no cgo is needed
+)
+
+// quoteSqlIdentifier quotes name so it can be used as a table name no matter which characters it contains.
+func quoteSqlIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// uniqueTableName returns name as a table name no table in usedNames has yet, with "_2", "_3" and so on appended if it is taken, and adds it to usedNames.
+// SQLite compares table names without regard to ASCII case, so usedNames holds them folded to lower case.
+func uniqueTableName(name string, usedNames map[string]bool) string {
+	tableName := name
+	for i := 2; usedNames[foldSqlIdentifier(tableName)]; i++ {
+		tableName = fmt.Sprintf("%v_%v", name, i)
+	}
+	usedNames[foldSqlIdentifier(tableName)] = true
+	return tableName
+}
+
+// foldSqlIdentifier returns name with ASCII letters in lower case, SQLite treats identifiers that only differ in them as the same.
+func foldSqlIdentifier(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, name)
+}
+
+// bucketPath returns the path of the bucket path nested in the top level bucket bucketName, like "config/devices".
+func bucketPath(bucketName string, path string) string {
+	if path == "" {
+		return bucketName
+	}
+	return bucketName + "/" + path
+}
+
+// ToSqlite takes the path to a bbolt database and writes its content to a new SQLite database at sqlitePath.
+// Every top level bucket becomes a table with the columns path, key and value. Buckets whose names only differ in case, which SQLite does not tell apart, get "_2", "_3" and so on appended to the table names of all but the first in bbolt's order. Entries of nested buckets are stored in the table of their top level bucket with path set to the slash separated names of the nested buckets, top level entries have an empty path.
+// Only the entries that pass filter are written, it sees the path of nested buckets like "config/devices" as their bucket. Buckets without matching entries still get their table. A nil filter passes everything.
+// The meta buckets like expiries or the journal are only exported if internal is set, see bboltdump.IsMetaBucket.
+func ToSqlite(dbPath string, sqlitePath string, filter *bboltdump.Filter, internal bool) error {
+	// open database
+	dbInstance, closeDb, err := bboltdump.OpenDb(dbPath)
+	if err != nil {
+		return err
+	}
+	defer closeDb()
+
+	// open sqlite database
+	sqliteDb, err := sql.Open("sqlite", sqlitePath)
+	if err != nil {
+		return fmt.Errorf("Failed to create SQLite database: %v\n", err)
+	}
+	defer sqliteDb.Close()
+
+	sqliteTx, err := sqliteDb.Begin()
+	if err != nil {
+		return fmt.Errorf("Failed to begin SQLite transaction: %v\n", err)
+	}
+	defer sqliteTx.Rollback()
+
+	usedTableNames := make(map[string]bool)
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(bucketName []byte, b *bolt.Bucket) error {
+			if !internal && bboltdump.IsMetaBucket(string(bucketName)) {
+				return nil
+			}
+			tableName := quoteSqlIdentifier(uniqueTableName(string(bucketName), usedTableNames))
+			_, err := sqliteTx.Exec("CREATE TABLE " + tableName + " (path TEXT NOT NULL, key BLOB NOT NULL, value BLOB NOT NULL, PRIMARY KEY (path, key))")
+			if err != nil {
+				return fmt.Errorf("Failed to create table for bucket %v: %v\n", string(bucketName), err)
+			}
+
+			insertStatement, err := sqliteTx.Prepare("INSERT INTO " + tableName + " (path, key, value) VALUES (?, ?, ?)")
+			if err != nil {
+				return fmt.Errorf("Failed to prepare insert for bucket %v: %v\n", string(bucketName), err)
+			}
+			defer insertStatement.Close()
+
+			return bboltdump.WalkBucket(b, "", func(path string, keyBytes []byte, valueBytes []byte) error {
+				if bboltdump.IsExpired(tx, bucketPath(string(bucketName), path), keyBytes) {
+					return nil
+				}
+				if !filter.Matches(bucketPath(string(bucketName), path), keyBytes, valueBytes) {
+					return nil
+				}
+				_, err := insertStatement.Exec(path, keyBytes, valueBytes)
+				if err != nil {
+					return fmt.Errorf("Failed to insert key %x of bucket %v: %v\n", keyBytes, string(bucketName), err)
+				}
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to export database to SQLite due to error: %v\n", err)
+	}
+
+	err = sqliteTx.Commit()
+	if err != nil {
+		return fmt.Errorf("Failed to commit SQLite transaction: %v\n", err)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+// Package idempotency replays the responses of writes that are sent again with the same Idempotency-Key header, so a client on a flaky network can retry a write without writing twice.
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/audit"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// Header is the request header that carries the idempotency key chosen by the client, like a random UUID per write.
+const Header = "Idempotency-Key"
+
+// maxKeyLength is the longest idempotency key accepted.
+const maxKeyLength = 255
+
+// writeEndpoints are the paths below the API endpoint whose responses are stored, transactions store the response of their commit.
+var writeEndpoints = []string{"/import", "/merge", "/move", "/delete", "/buckets/rename"}
+
+// Idempotency is a struct representing the writes of the server that are in progress and how long their responses are kept.
+type Idempotency struct {
+	databases   []config.RegisteredDb
+	retention   time.Duration
+	apiEndpoint string
+
+	mu         sync.Mutex
+	inProgress map[string]bool // by db path, client and idempotency key
+}
+
+// New returns the Idempotency of cfg. apiEndpoint is the path the endpoints are registered below, e.g. "/bbolt".
+func New(cfg config.Config, apiEndpoint string) *Idempotency {
+	retention := cfg.Idempotency.Retention.Duration
+	if retention == 0 {
+		retention = config.DefaultIdempotencyRetention // Load fills it in, but the server also runs without a config file
+	}
+	return &Idempotency{
+		databases:   cfg.Databases,
+		retention:   retention,
+		apiEndpoint: apiEndpoint,
+		inProgress:  make(map[string]bool),
+	}
+}
+
+// isWrite reports whether the request for urlPath is a write whose response is stored.
+func (i *Idempotency) isWrite(urlPath string, target audit.Target) bool {
+	if strings.HasPrefix(urlPath, "/v1/dbs/") {
+		return target.TxOp == "commit"
+	}
+	endpoint, found := strings.CutPrefix(urlPath, i.apiEndpoint)
+	if !found {
+		return false
+	}
+	for _, writeEndpoint := range writeEndpoints {
+		if endpoint == writeEndpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// dbPath returns the path of the database target writes to, transactions name a registered database.
+func (i *Idempotency) dbPath(target audit.Target) string {
+	if target.TxOp == "" {
+		return target.Db
+	}
+	for _, registeredDb := range i.databases {
+		if registeredDb.Name == target.Db {
+			return registeredDb.Path
+		}
+	}
+	return ""
+}
+
+// begin marks the write with inProgressKey as in progress and reports whether no other request was working on it already.
+func (i *Idempotency) begin(inProgressKey string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.inProgress[inProgressKey] {
+		return false
+	}
+	i.inProgress[inProgressKey] = true
+	return true
+}
+
+// end marks the write with inProgressKey as done.
+func (i *Idempotency) end(inProgressKey string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.inProgress, inProgressKey)
+}
+
+// responseRecorder is an http.ResponseWriter that keeps a copy of the response it sends.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader remembers status and sends it.
+func (s *responseRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Write keeps a copy of p and sends it, which implies status 200 if no status was sent yet.
+func (s *responseRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	s.body.Write(p)
+	return s.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer.
+func (s *responseRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Handler sends the stored response to writes whose Idempotency-Key was already used by the same client, and stores the response of successful writes in the bucket bboltdump.IdempotencyBucket of the database they wrote to.
+// A key that is reused for a different write is rejected with 422, a retry that arrives while the first attempt is still running with 409. Requests without the header pass unchanged.
+// Before a write into an existing database begins, a pending response is stored for its key. A write whose response could not be stored after it committed, since the server stopped or the database got locked, keeps that marker, and its retries get 409 instead of writing a second time.
+// Transactions are not marked, their commit cannot run twice since the transaction is gone once it committed.
+func (i *Idempotency) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		target := audit.ReadTarget(r)
+		dbPath := i.dbPath(target)
+		if !i.isWrite(r.URL.Path, target) || dbPath == "" {
+			next.ServeHTTP(w, r) // reads are safe to retry, requests without database are rejected by the handler
+			return
+		}
+		if len(key) > maxKeyLength {
+			http.Error(w, fmt.Sprintf("Bad Request: %v must be at most %v characters long", Header, maxKeyLength), http.StatusBadRequest)
+			return
+		}
+
+		// the payload and the path including the token of a transaction tell writes apart
+		payloadBytes, err := audit.Payload(r)
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		payloadHash := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\n"), payloadBytes...))
+		client := audit.Who(r)
+
+		inProgressKey := dbPath + "\x00" + client + "\x00" + key
+		if !i.begin(inProgressKey) {
+			http.Error(w, "Conflict: a request with this Idempotency-Key is still in progress", http.StatusConflict)
+			return
+		}
+		defer i.end(inProgressKey)
+
+		// a database that does not exist yet has no responses, imports create it
+		_, err = os.Stat(dbPath)
+		dbExists := err == nil
+		if dbExists {
+			stored, found, err := bboltdump.LookupResponse(dbPath, client, key, i.retention)
+			var lockedError *bboltdump.LockedError
+			if errors.As(err, &lockedError) {
+				http.Error(w, strings.TrimSpace(lockedError.Error()), http.StatusLocked)
+				return
+			}
+			if err != nil {
+				fmt.Println("ERROR:", err)
+				http.Error(w, "Failed to look up Idempotency-Key", http.StatusInternalServerError)
+				return
+			}
+			if found && stored.PayloadHash != hex.EncodeToString(payloadHash[:]) {
+				http.Error(w, "Unprocessable Entity: the Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity)
+				return
+			}
+			if found && stored.IsPending() {
+				http.Error(w, "Conflict: an earlier request with this Idempotency-Key did not finish, whether it wrote is unknown", http.StatusConflict)
+				return
+			}
+			if found {
+				if stored.ContentType != "" {
+					w.Header().Set("Content-Type", stored.ContentType)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.Status)
+				w.Write(stored.Body)
+				return
+			}
+		}
+
+		// mark the write as begun, so it is not repeated if its response cannot be stored
+		marked := false
+		if dbExists && target.TxOp == "" {
+			err = bboltdump.StoreResponse(dbPath, client, key, bboltdump.StoredResponse{
+				Operation:   target.Operation,
+				PayloadHash: hex.EncodeToString(payloadHash[:]),
+			}, i.retention)
+			var lockedError *bboltdump.LockedError
+			switch {
+			case errors.As(err, &lockedError):
+				http.Error(w, strings.TrimSpace(lockedError.Error()), http.StatusLocked)
+				return
+			case errors.Is(err, bboltdump.ErrReadOnlyDb):
+				// the write is refused by the handler
+			case err != nil:
+				fmt.Println("ERROR:", err)
+				http.Error(w, "Failed to store Idempotency-Key", http.StatusInternalServerError)
+				return
+			default:
+				marked = true
+			}
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		if recorder.status < 200 || recorder.status >= 300 {
+			// failed writes did not happen, a retry may try again
+			if marked {
+				err = bboltdump.ForgetResponse(dbPath, client, key)
+				if err != nil {
+					fmt.Println("ERROR: Failed to remove pending Idempotency-Key:", err) // retries get 409 until it expires
+				}
+			}
+			return
+		}
+		err = bboltdump.StoreResponse(dbPath, client, key, bboltdump.StoredResponse{
+			Operation:   target.Operation,
+			PayloadHash: hex.EncodeToString(payloadHash[:]),
+			Status:      recorder.status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		}, i.retention)
+		if err != nil {
+			fmt.Println("ERROR: Failed to store response of Idempotency-Key:", err) // the write already happened, retries get 409 from its pending marker
+		}
+	})
+}
@@ -0,0 +1,189 @@
+// Package importer loads the content of other embedded key-value stores into bbolt databases.
+package importer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	bolt "go.etcd.io/bbolt"
+)
+
+const importBatchSize = 10000 // amount of keys written per bolt transaction, keeps single transactions from growing unbounded
+
+// importSource reads every key-value pair of the store in dir and hands it to fn.
+type importSource func(dir string, fn func(keyBytes []byte, valueBytes []byte) error) error
+
+// importSources maps each supported source format to the function that reads it.
+var importSources = map[string]importSource{
+	"leveldb": readLevelDb,
+	"badger":  readBadgerDb,
+}
+
+// readLevelDb reads every key-value pair of the LevelDB database in dir.
+func readLevelDb(dir string, fn func(keyBytes []byte, valueBytes []byte) error) error {
+	levelDb, err := leveldb.OpenFile(dir, &opt.Options{ReadOnly: true, ErrorIfMissing: true})
+	if err != nil {
+		return fmt.Errorf("Failed to open LevelDB database: %v\n", err)
+	}
+	defer levelDb.Close()
+
+	iterator := levelDb.NewIterator(nil, nil)
+	defer iterator.Release()
+	for iterator.Next() {
+		err = fn(iterator.Key(), iterator.Value())
+		if err != nil {
+			return err
+		}
+	}
+	return iterator.Error()
+}
+
+// readBadgerDb reads every key-value pair of the Badger database in dir.
+func readBadgerDb(dir string, fn func(keyBytes []byte, valueBytes []byte) error) error {
+	badgerDb, err := badger.Open(badger.DefaultOptions(dir).WithReadOnly(true).WithLogger(nil))
+	if err != nil {
+		return fmt.Errorf("Failed to open Badger database: %v\n", err)
+	}
+	defer badgerDb.Close()
+
+	return badgerDb.View(func(txn *badger.Txn) error {
+		iterator := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			item := iterator.Item()
+			valueBytes, err := item.ValueCopy(nil)
+			if err != nil {
+				return fmt.Errorf("Failed to read value of key %x: %v\n", item.Key(), err)
+			}
+			err = fn(item.KeyCopy(nil), valueBytes)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Result is a struct representing the outcome of an import.
+type Result struct {
+	Path     string `json:"path"`     // path to the bbolt db file that was written to
+	Bucket   string `json:"bucket"`   // bucket the keys were written to
+	Imported int    `json:"imported"` // amount of key-value pairs written, or that would be written in a dry run
+
+	DryRun  bool               `json:"dryRun"`            // true if nothing was actually written
+	Preview *bboltdump.Preview `json:"preview,omitempty"` // what the dry run would have changed, only set with DryRun
+}
+
+// Import reads the store of the given format in sourceDir and writes all its key-value pairs into bucketName of the bbolt database at dbPath, creating both if necessary.
+// The keys are written in batches of importBatchSize per transaction, so a failed import may leave the keys of the already committed batches behind.
+// fillPercent is used as the Bucket.FillPercent of the writes, zero keeps bolt's default. Both sources return their keys in sorted order, so 1.0 gives the smallest file when importing into an empty bucket.
+// If the journal is enabled for the database every imported key is journaled by actor. With dryRun set every batch is rolled back instead of committed and the result has the Preview of all of them.
+func Import(dbPath string, bucketName string, format string, sourceDir string, fillPercent float64, dryRun bool, actor string) (Result, error) {
+	importResult := Result{
+		Path:   dbPath,
+		Bucket: bucketName,
+		DryRun: dryRun,
+	}
+	if dryRun {
+		importResult.Preview = &bboltdump.Preview{}
+	}
+
+	readSource, found := importSources[format]
+	if !found {
+		return importResult, fmt.Errorf("Unsupported import format %v\n", format)
+	}
+	if !bboltdump.IsValidFillPercent(fillPercent) {
+		return importResult, fmt.Errorf("Fill percent must be between 0.1 and 1.0\n")
+	}
+
+	// open database
+	dbInstance, closeDb, err := bboltdump.OpenDbForWriting(dbPath)
+	if err != nil {
+		return importResult, err
+	}
+	defer closeDb()
+
+	// writes the collected batch in one transaction
+	type keyValuePair struct{ key, value []byte }
+	batch := make([]keyValuePair, 0, importBatchSize)
+	flush := func() error {
+		preview, err := bboltdump.UpdateOrDryRun(dbInstance, dryRun, func(tx *bolt.Tx) error {
+			b, err := bboltdump.CreateBucketPath(tx, bucketName)
+			if err != nil {
+				return err
+			}
+			if fillPercent != 0 {
+				b.FillPercent = fillPercent
+			}
+			keysBytes := make([][]byte, 0, len(batch))
+			for _, pair := range batch {
+				err = bboltdump.Journal(tx, actor, bboltdump.ChangePut, bucketName, pair.key, b.Get(pair.key), pair.value)
+				if err == nil {
+					err = b.Put(pair.key, pair.value)
+				}
+				if err == nil {
+					// imported keys never expire, an earlier expiry of the key must not remove them
+					err = bboltdump.SetExpiry(tx, bucketName, pair.key, time.Time{})
+				}
+				if err != nil {
+					return fmt.Errorf("Failed to write key %x: %v\n", pair.key, err)
+				}
+				keysBytes = append(keysBytes, pair.key)
+			}
+			bboltdump.RecordChanges(tx, bboltdump.NewChange(bboltdump.ChangePut, bucketName, keysBytes...))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if preview != nil {
+			importResult.Preview.Keys += preview.Keys
+			importResult.Preview.BytesWritten += preview.BytesWritten
+			importResult.Preview.BytesDeleted += preview.BytesDeleted
+		}
+		importResult.Imported += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	err = readSource(sourceDir, func(keyBytes []byte, valueBytes []byte) error {
+		// the source may reuse its buffers, so keep copies
+		batch = append(batch, keyValuePair{
+			key:   append([]byte(nil), keyBytes...),
+			value: append([]byte(nil), valueBytes...),
+		})
+		if len(batch) == importBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil && len(batch) > 0 {
+		err = flush()
+	}
+	if err != nil {
+		return importResult, fmt.Errorf("Failed to import %v database after %v keys due to error: %v\n", format, importResult.Imported, err)
+	}
+
+	return importResult, nil
+}
+
+// IsSupported reports whether format names a supported source format.
+func IsSupported(format string) bool {
+	_, found := importSources[format]
+	return found
+}
+
+// SupportedFormats returns the names of all supported source formats.
+func SupportedFormats() []string {
+	formats := make([]string, 0, len(importSources))
+	for format := range importSources {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
@@ -0,0 +1,57 @@
+// Package metrics serves the metrics of the server in the Prometheus text format.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+var mu sync.Mutex
+var collectors []func(w *bytes.Buffer)
+
+// Register adds collector to the metrics that are served, it writes its metrics in the Prometheus text format whenever they are scraped.
+func Register(collector func(w *bytes.Buffer)) {
+	mu.Lock()
+	defer mu.Unlock()
+	collectors = append(collectors, collector)
+}
+
+// Handler returns the handler serving all registered metrics to GET requests.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed. Please use GET.", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var output bytes.Buffer
+		mu.Lock()
+		for _, collector := range collectors {
+			collector(&output)
+		}
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, err := w.Write(output.Bytes())
+		if err != nil {
+			fmt.Println("ERROR: Failed to send metrics:", err)
+		}
+	})
+}
+
+// WriteHelp writes the HELP and TYPE lines of the metric name, which must come before its samples.
+func WriteHelp(w *bytes.Buffer, name string, metricType string, help string) {
+	fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v %v\n", name, help, name, metricType)
+}
+
+// WriteSample writes a sample of the metric name with a db label.
+func WriteSample(w *bytes.Buffer, name string, db string, value float64) {
+	fmt.Fprintf(w, "%v{db=%q} %v\n", name, db, value)
+}
+
+// WriteQuantile writes a sample of the metric name with a db and a quantile label, like "0.99" for the 99th percentile.
+func WriteQuantile(w *bytes.Buffer, name string, db string, quantile string, value float64) {
+	fmt.Fprintf(w, "%v{db=%q,quantile=%q} %v\n", name, db, quantile, value)
+}
@@ -0,0 +1,244 @@
+// Package pin keeps read-only copies of databases as they were at one point in time, so a client paging through a database over many requests sees the same data in every request.
+// A pin is read like any other database by sending its path "pin://<id>" as the input of the read endpoints.
+package pin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Scheme is the scheme of the db paths that read from a pin, like "pin://4f1c...".
+const Scheme = "pin"
+
+// ErrUnknownPin is returned when a pin does not exist, was released or expired.
+var ErrUnknownPin = errors.New("pin does not exist or expired")
+
+// ErrTooManyPins is returned when a pin is created while the maximum amount of pins is held.
+var ErrTooManyPins = errors.New("too many pins")
+
+// Pin is a struct representing a copy of a database taken at one point in time.
+type Pin struct {
+	Id        string    `json:"id"`        // identifies the pin, pass it to release
+	Input     string    `json:"input"`     // db path that reads from the pin, send it as input to the read endpoints
+	Db        string    `json:"db"`        // path to the db file the copy was taken of
+	Time      time.Time `json:"time"`      // time the copy was taken at
+	Size      int64     `json:"size"`      // size of the copy in bytes
+	ExpiresAt time.Time `json:"expiresAt"` // time the pin is released at unless it is read before, every read moves it by the idle timeout
+}
+
+// pins is a struct representing the pins the server holds.
+type pins struct {
+	mu       sync.Mutex
+	cfg      config.PinConfig
+	all      map[string]*Pin // by id
+	creating int             // pins being copied, they count against MaxPins before they are in all
+}
+
+// copyName matches the names of the copies copyPath returns, nothing else in the pin directory is touched.
+var copyName = regexp.MustCompile(`^[0-9a-f]{32}\.db$`)
+
+// removeStaleCopies removes the copies in dir that were not read within idleTimeout, an earlier run left them behind or they expired anyway.
+// fetch touches a copy on every read, so the live pins of another instance sharing dir are left alone, as is every other file in dir.
+func removeStaleCopies(dir string, idleTimeout time.Duration) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !copyName.MatchString(entry.Name()) {
+			continue
+		}
+		fileInfo, err := entry.Info()
+		if err != nil || time.Since(fileInfo.ModTime()) < idleTimeout {
+			continue
+		}
+		err = os.Remove(filepath.Join(dir, entry.Name()))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+var held *pins // nil until Enable is called
+
+// Enable makes the package create pins with the settings of cfg and makes all db paths starting with "pin://" read from them. Copies left over from a previous run are deleted once they were not read within the idle timeout.
+// It has to be called before the first database is opened.
+func Enable(cfg config.PinConfig) error {
+	cfg = cfg.WithDefaults()
+	err := removeStaleCopies(cfg.Dir, cfg.IdleTimeout.Duration)
+	if err != nil {
+		return fmt.Errorf("Failed to clean pin directory: %v\n", err)
+	}
+	err = os.MkdirAll(cfg.Dir, 0700)
+	if err != nil {
+		return fmt.Errorf("Failed to create pin directory: %v\n", err)
+	}
+	held = &pins{cfg: cfg, all: make(map[string]*Pin)}
+	bboltdump.RegisterFetcher(Scheme, held.fetch)
+	go held.expire()
+	return nil
+}
+
+// copyPath returns the path of the copy of the pin with the given id.
+func (p *pins) copyPath(id string) string {
+	return filepath.Join(p.cfg.Dir, id+".db")
+}
+
+// fetch is the bboltdump.Fetcher of pin paths, it returns the path of the copy and keeps the pin alive.
+// The modification time of the copy is moved along, so another instance sharing the pin directory sees it is still read, see removeStaleCopies.
+func (p *pins) fetch(dbUrl string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pin, found := p.all[strings.TrimPrefix(dbUrl, Scheme+"://")]
+	if !found {
+		return "", ErrUnknownPin
+	}
+	now := time.Now()
+	pin.ExpiresAt = now.UTC().Add(p.cfg.IdleTimeout.Duration)
+	os.Chtimes(p.copyPath(pin.Id), now, now)
+	return p.copyPath(pin.Id), nil
+}
+
+// expire releases the pins that were not read within the idle timeout, checking once per second.
+func (p *pins) expire() {
+	for range time.Tick(time.Second) {
+		now := time.Now()
+		p.mu.Lock()
+		for id, pin := range p.all {
+			if now.After(pin.ExpiresAt) {
+				p.remove(id)
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// remove forgets the pin with the given id and deletes its copy. The caller must hold p.mu.
+func (p *pins) remove(id string) {
+	delete(p.all, id)
+	bboltdump.CloseCachedHandle(p.copyPath(id)) // the handle cache may still hold it open
+	os.Remove(p.copyPath(id))
+}
+
+// Create copies the database at dbPath from within a read transaction and returns the pin that reads from the copy.
+func Create(dbPath string) (Pin, error) {
+	if held == nil {
+		return Pin{}, fmt.Errorf("Pins are not enabled\n")
+	}
+	if strings.HasPrefix(dbPath, Scheme+"://") {
+		return Pin{}, fmt.Errorf("A pin cannot be pinned again\n")
+	}
+	// the slot is taken before copying, so concurrent creates do not exceed MaxPins
+	held.mu.Lock()
+	if len(held.all)+held.creating >= held.cfg.MaxPins {
+		held.mu.Unlock()
+		return Pin{}, ErrTooManyPins
+	}
+	held.creating++
+	held.mu.Unlock()
+	created := false
+	defer func() {
+		if !created {
+			held.mu.Lock()
+			held.creating--
+			held.mu.Unlock()
+		}
+	}()
+
+	idBytes := make([]byte, 16)
+	_, err := rand.Read(idBytes)
+	if err != nil {
+		return Pin{}, fmt.Errorf("Failed to generate pin id: %v\n", err)
+	}
+	pin := Pin{
+		Id:    hex.EncodeToString(idBytes),
+		Db:    dbPath,
+		Input: Scheme + "://" + hex.EncodeToString(idBytes),
+		Time:  time.Now().UTC(),
+	}
+
+	// open database
+	dbInstance, closeDb, err := bboltdump.OpenDb(dbPath)
+	if err != nil {
+		return Pin{}, err
+	}
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		pin.Size = tx.Size()
+		return tx.CopyFile(held.copyPath(pin.Id), 0600)
+	})
+	closeDb()
+	if err != nil {
+		os.Remove(held.copyPath(pin.Id))
+		return Pin{}, fmt.Errorf("Failed to pin database %v: %v\n", dbPath, err)
+	}
+
+	held.mu.Lock()
+	defer held.mu.Unlock()
+	pin.ExpiresAt = time.Now().UTC().Add(held.cfg.IdleTimeout.Duration)
+	held.all[pin.Id] = &pin
+	held.creating--
+	created = true
+	return pin, nil
+}
+
+// List returns the pins the server holds, oldest first.
+func List() []Pin {
+	pinList := []Pin{}
+	if held == nil {
+		return pinList
+	}
+	held.mu.Lock()
+	defer held.mu.Unlock()
+	for _, pin := range held.all {
+		pinList = append(pinList, *pin)
+	}
+	sort.Slice(pinList, func(i, j int) bool {
+		return pinList[i].Time.Before(pinList[j].Time)
+	})
+	return pinList
+}
+
+// Release deletes the pin with the given id, or returns ErrUnknownPin.
+func Release(id string) error {
+	if held == nil {
+		return ErrUnknownPin
+	}
+	held.mu.Lock()
+	defer held.mu.Unlock()
+	if _, found := held.all[id]; !found {
+		return ErrUnknownPin
+	}
+	held.remove(id)
+	return nil
+}
+
+// Source returns the path of the database the pin at dbPath was taken of, so it is protected like that database. Other db paths are returned unchanged.
+func Source(dbPath string) string {
+	id, isPin := strings.CutPrefix(dbPath, Scheme+"://")
+	if !isPin || held == nil {
+		return dbPath
+	}
+	held.mu.Lock()
+	defer held.mu.Unlock()
+	if pin, found := held.all[id]; found {
+		return pin.Db
+	}
+	return dbPath
+}
@@ -0,0 +1,187 @@
+// Package remote makes databases that are stored in object storage, like the device backups in S3, readable by downloading them into a local cache.
+package remote
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// localCopy is a struct representing a downloaded database.
+type localCopy struct {
+	path string // path of the downloaded file
+	etag string // ETag of the object the file was downloaded from
+}
+
+// s3Fetcher is a struct representing the cache of databases downloaded from S3.
+type s3Fetcher struct {
+	mu       sync.Mutex // guards copies and urlLocks, never held during a download
+	client   *s3.Client
+	cacheDir string
+	copies   map[string]localCopy   // keyed by s3:// URL
+	urlLocks map[string]*sync.Mutex // keyed by s3:// URL, only one download of a URL at a time so a database requested twice is not downloaded twice
+}
+
+// copyName matches the names of the files fetch and download create in the cache directory, nothing else in it is touched.
+var copyName = regexp.MustCompile(`^([0-9a-f]{16}\.db|download-[0-9]+)$`)
+
+// removeCopies removes the files an earlier run left in cacheDir, they are useless since their ETags are unknown. Other files are left alone, cacheDir may be shared.
+func removeCopies(cacheDir string) error {
+	entries, err := os.ReadDir(cacheDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && copyName.MatchString(entry.Name()) {
+			err = os.Remove(filepath.Join(cacheDir, entry.Name()))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newClient returns an S3 client for the region and endpoint of cfg.
+func newClient(cfg config.S3Config) (*s3.Client, error) {
+	var loadOptions []func(*awsconfig.LoadOptions) error
+	if cfg.Region != "" {
+		loadOptions = append(loadOptions, awsconfig.WithRegion(cfg.Region))
+	}
+	awsConfig, err := awsconfig.LoadDefaultConfig(context.Background(), loadOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load AWS config: %v\n", err)
+	}
+	return s3.NewFromConfig(awsConfig, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+		o.UsePathStyle = cfg.UsePathStyle
+	}), nil
+}
+
+// EnableS3 makes db paths like "s3://bucket/app.db" usable everywhere a db path is accepted. Every time such a database is opened its ETag is checked and it is only downloaded again if the object changed.
+func EnableS3(cfg config.S3Config) error {
+	client, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	cacheDir := cfg.CacheDir
+	if cacheDir == "" {
+		cacheDir = filepath.Join(os.TempDir(), "bbolt-apiEndpoint-s3")
+	}
+	err = removeCopies(cacheDir)
+	if err == nil {
+		err = os.MkdirAll(cacheDir, 0700)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to prepare S3 cache directory: %v\n", err)
+	}
+
+	fetcher := &s3Fetcher{
+		client:   client,
+		cacheDir: cacheDir,
+		copies:   make(map[string]localCopy),
+		urlLocks: make(map[string]*sync.Mutex),
+	}
+	bboltdump.RegisterFetcher("s3", fetcher.fetch)
+	return nil
+}
+
+// fetch returns the path of the local copy of the database at dbUrl, downloading it if there is none yet or the object changed since.
+func (f *s3Fetcher) fetch(dbUrl string) (string, error) {
+	parsedUrl, err := url.Parse(dbUrl)
+	if err != nil || parsedUrl.Host == "" || strings.Trim(parsedUrl.Path, "/") == "" {
+		return "", fmt.Errorf("Invalid S3 URL %v, it must look like s3://bucket/key\n", dbUrl)
+	}
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(parsedUrl.Host),
+		Key:    aws.String(strings.TrimPrefix(parsedUrl.Path, "/")),
+	}
+
+	// other URLs are downloaded meanwhile
+	f.mu.Lock()
+	urlLock, found := f.urlLocks[dbUrl]
+	if !found {
+		urlLock = &sync.Mutex{}
+		f.urlLocks[dbUrl] = urlLock
+	}
+	f.mu.Unlock()
+	urlLock.Lock()
+	defer urlLock.Unlock()
+
+	f.mu.Lock()
+	cached, found := f.copies[dbUrl]
+	f.mu.Unlock()
+	if found {
+		input.IfNoneMatch = aws.String(cached.etag)
+	}
+	output, err := f.client.GetObject(context.Background(), input)
+	var responseError *awshttp.ResponseError
+	if found && errors.As(err, &responseError) && responseError.HTTPStatusCode() == http.StatusNotModified {
+		return cached.path, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("Failed to download %v: %v\n", dbUrl, err)
+	}
+	defer output.Body.Close()
+
+	// every version gets a file of its own, so handles that are still open on the old version keep reading a consistent file
+	etag := aws.ToString(output.ETag)
+	nameHash := sha256.Sum256([]byte(dbUrl + "\n" + etag))
+	copyPath := filepath.Join(f.cacheDir, hex.EncodeToString(nameHash[:8])+".db")
+	err = download(output.Body, copyPath)
+	if err != nil {
+		return "", fmt.Errorf("Failed to download %v: %v\n", dbUrl, err)
+	}
+
+	if found && cached.path != copyPath {
+		os.Remove(cached.path) // open handles keep working on the removed file
+	}
+	f.mu.Lock()
+	f.copies[dbUrl] = localCopy{path: copyPath, etag: etag}
+	f.mu.Unlock()
+	return copyPath, nil
+}
+
+// download writes body to a temporary file that is renamed to copyPath once complete, so an interrupted download never leaves a truncated database behind.
+func download(body io.Reader, copyPath string) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(copyPath), "download-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmpFile, body)
+	closeErr := tmpFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), copyPath)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+	}
+	return err
+}
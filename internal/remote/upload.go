@@ -0,0 +1,214 @@
+package remote
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+)
+
+const DefaultPartSize = 16 << 20     // files larger than this are uploaded in parts of this size if the config does not say otherwise
+const checksumMetadataKey = "sha256" // metadata key of the hex encoded SHA-256 of the object
+
+// Uploader is a struct representing the S3 prefix backups are uploaded to and how.
+type Uploader struct {
+	client   *s3.Client
+	bucket   string
+	prefix   string // key prefix of the uploaded objects, ends in "/" unless empty
+	gzip     bool
+	checksum bool
+	partSize int64
+}
+
+// NewUploader returns an Uploader for the upload settings uploadCfg, connecting to S3 with the region and endpoint of cfg.
+func NewUploader(cfg config.S3Config, uploadCfg config.BackupS3Config) (*Uploader, error) {
+	parsedUrl, err := url.Parse(uploadCfg.Url)
+	if err != nil || parsedUrl.Scheme != "s3" || parsedUrl.Host == "" {
+		return nil, fmt.Errorf("Invalid S3 URL %v, it must look like s3://bucket/prefix/\n", uploadCfg.Url)
+	}
+	client, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	uploader := &Uploader{
+		client:   client,
+		bucket:   parsedUrl.Host,
+		prefix:   strings.Trim(parsedUrl.Path, "/"),
+		gzip:     uploadCfg.Gzip,
+		checksum: uploadCfg.Checksum,
+		partSize: uploadCfg.PartSize,
+	}
+	if uploader.prefix != "" {
+		uploader.prefix += "/"
+	}
+	if uploader.partSize == 0 {
+		uploader.partSize = DefaultPartSize
+	}
+	return uploader, nil
+}
+
+// Upload uploads the file at filePath as the object name below the prefix, or name + ".gz" if the uploader compresses, and returns the s3:// URL of the object.
+func (u *Uploader) Upload(filePath string, name string) (string, error) {
+	key := u.prefix + name
+	contentType := "application/octet-stream"
+	if u.gzip {
+		key += ".gz"
+		contentType = "application/gzip"
+		gzipPath, err := gzipFile(filePath)
+		if err != nil {
+			return "", fmt.Errorf("Failed to compress %v: %v\n", filePath, err)
+		}
+		defer os.Remove(gzipPath)
+		filePath = gzipPath
+	}
+	objectUrl := "s3://" + u.bucket + "/" + key
+
+	uploadFile, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("Failed to open %v: %v\n", filePath, err)
+	}
+	defer uploadFile.Close()
+	fileInfo, err := uploadFile.Stat()
+	if err != nil {
+		return "", fmt.Errorf("Failed to read file info of %v: %v\n", filePath, err)
+	}
+
+	// the digest is metadata, so it has to be known before the upload starts
+	metadata := map[string]string{}
+	var checksumAlgorithm types.ChecksumAlgorithm
+	if u.checksum {
+		digest := sha256.New()
+		_, err = io.Copy(digest, uploadFile)
+		if err == nil {
+			_, err = uploadFile.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			return "", fmt.Errorf("Failed to checksum %v: %v\n", filePath, err)
+		}
+		metadata[checksumMetadataKey] = hex.EncodeToString(digest.Sum(nil))
+		checksumAlgorithm = types.ChecksumAlgorithmSha256 // S3 verifies every part on arrival
+	}
+
+	if fileInfo.Size() <= u.partSize {
+		_, err = u.client.PutObject(context.Background(), &s3.PutObjectInput{
+			Bucket:            aws.String(u.bucket),
+			Key:               aws.String(key),
+			Body:              uploadFile,
+			ContentLength:     aws.Int64(fileInfo.Size()),
+			ContentType:       aws.String(contentType),
+			Metadata:          metadata,
+			ChecksumAlgorithm: checksumAlgorithm,
+		})
+	} else {
+		err = u.uploadParts(uploadFile, key, contentType, metadata, checksumAlgorithm)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Failed to upload %v: %v\n", objectUrl, err)
+	}
+	return objectUrl, nil
+}
+
+// uploadParts uploads the content of file as the object key with a multipart upload of partSize parts, one part in memory at a time. A failed upload is aborted so S3 does not keep its parts.
+func (u *Uploader) uploadParts(file io.Reader, key string, contentType string, metadata map[string]string, checksumAlgorithm types.ChecksumAlgorithm) error {
+	ctx := context.Background()
+	createOutput, err := u.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:            aws.String(u.bucket),
+		Key:               aws.String(key),
+		ContentType:       aws.String(contentType),
+		Metadata:          metadata,
+		ChecksumAlgorithm: checksumAlgorithm,
+	})
+	if err != nil {
+		return err
+	}
+	abort := func(err error) error {
+		u.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(u.bucket),
+			Key:      aws.String(key),
+			UploadId: createOutput.UploadId,
+		})
+		return err
+	}
+
+	var completedParts []types.CompletedPart
+	buffer := make([]byte, u.partSize)
+	for partNumber := int32(1); ; partNumber++ {
+		n, err := io.ReadFull(file, buffer)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return abort(err)
+		}
+		partOutput, err := u.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:            aws.String(u.bucket),
+			Key:               aws.String(key),
+			UploadId:          createOutput.UploadId,
+			PartNumber:        aws.Int32(partNumber),
+			Body:              bytes.NewReader(buffer[:n]),
+			ContentLength:     aws.Int64(int64(n)),
+			ChecksumAlgorithm: checksumAlgorithm,
+		})
+		if err != nil {
+			return abort(err)
+		}
+		completedParts = append(completedParts, types.CompletedPart{
+			ETag:           partOutput.ETag,
+			PartNumber:     aws.Int32(partNumber),
+			ChecksumSHA256: partOutput.ChecksumSHA256,
+		})
+		if n < len(buffer) {
+			break
+		}
+	}
+
+	_, err = u.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(u.bucket),
+		Key:             aws.String(key),
+		UploadId:        createOutput.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completedParts},
+	})
+	if err != nil {
+		return abort(err)
+	}
+	return nil
+}
+
+// gzipFile writes the file at filePath gzipped to a temporary file next to it and returns its path, the caller is responsible for removing it.
+func gzipFile(filePath string) (string, error) {
+	srcFile, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer srcFile.Close()
+	gzipPath := filePath + ".gz.tmp"
+	gzipFile, err := os.OpenFile(gzipPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer gzipFile.Close()
+
+	gzipWriter := gzip.NewWriter(gzipFile)
+	_, err = io.Copy(gzipWriter, srcFile)
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+	if err != nil {
+		os.Remove(gzipPath)
+		return "", err
+	}
+	return gzipPath, nil
+}
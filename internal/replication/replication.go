@@ -0,0 +1,171 @@
+// Package replication ships consistent copies of registered databases to the restore endpoint of a standby instance whenever they changed, so the standby is a warm copy.
+package replication
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+	bolt "go.etcd.io/bbolt"
+)
+
+// replica is a struct representing a database that is pushed to a peer and what the peer has of it.
+type replica struct {
+	peer         config.ReplicationPeer
+	registeredDb config.RegisteredDb
+	pushedTxId   uint64 // last transaction the peer has, 0 until the first push succeeded
+}
+
+// copyDb writes a consistent copy of the registered database to a temporary file from within a read transaction and returns its path and the id of the transaction it shows.
+func (rp *replica) copyDb() (string, uint64, error) {
+	dbInstance, closeDb, err := bboltdump.OpenLiveDb(rp.registeredDb.Path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer closeDb()
+
+	tempFile, err := os.CreateTemp("", "bbolt-replica-*.db")
+	if err != nil {
+		return "", 0, fmt.Errorf("Failed to create temporary file: %v\n", err)
+	}
+	defer tempFile.Close()
+
+	var txId uint64
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		txId = uint64(tx.ID())
+		_, err := tx.WriteTo(tempFile)
+		return err
+	})
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return "", 0, fmt.Errorf("Failed to copy database %v: %v\n", rp.registeredDb.Name, err)
+	}
+	if !rp.peer.Internal {
+		err = stripCopy(tempFile.Name())
+		if err != nil {
+			os.Remove(tempFile.Name())
+			return "", 0, fmt.Errorf("Failed to copy database %v: %v\n", rp.registeredDb.Name, err)
+		}
+	}
+	return tempFile.Name(), txId, nil
+}
+
+// stripCopy deletes the journal and the stored responses of idempotent writes from the copy at copyPath. Expiries, indexes and views stay, the reads of the standby need them.
+func stripCopy(copyPath string) error {
+	copyDb, err := bolt.Open(copyPath, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	defer copyDb.Close()
+	return copyDb.Update(func(tx *bolt.Tx) error {
+		for _, bucketName := range []string{bboltdump.JournalBucket, bboltdump.IdempotencyBucket} {
+			err := tx.DeleteBucket([]byte(bucketName))
+			if err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// push sends the copy at copyPath to the restore endpoint of the peer as the "backup" file of a multipart form. The form is streamed, so the copy is never held in memory.
+func (rp *replica) push(copyPath string) (bboltdump.RestoreResult, error) {
+	var restoreResult bboltdump.RestoreResult
+	copyFile, err := os.Open(copyPath)
+	if err != nil {
+		return restoreResult, fmt.Errorf("Failed to open copy of database %v: %v\n", rp.registeredDb.Name, err)
+	}
+	defer copyFile.Close()
+
+	bodyReader, bodyWriter := io.Pipe()
+	form := multipart.NewWriter(bodyWriter)
+	go func() {
+		err := form.WriteField("db", rp.peer.PeerDb)
+		if err == nil {
+			var part io.Writer
+			part, err = form.CreateFormFile("backup", rp.registeredDb.Name+".db")
+			if err == nil {
+				_, err = io.Copy(part, copyFile)
+			}
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		bodyWriter.CloseWithError(err)
+	}()
+
+	restoreUrl := strings.TrimSuffix(rp.peer.Url, "/") + "/admin/restore"
+	request, err := http.NewRequest(http.MethodPost, restoreUrl+"?raw=true", bodyReader)
+	if err != nil {
+		bodyReader.Close()
+		return restoreResult, fmt.Errorf("Failed to create request to %v: %v\n", restoreUrl, err)
+	}
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	if rp.peer.ApiKey != "" {
+		request.Header.Set("X-Api-Key", rp.peer.ApiKey)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return restoreResult, fmt.Errorf("Failed to push database %v to %v: %v\n", rp.registeredDb.Name, restoreUrl, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return restoreResult, fmt.Errorf("Peer %v responded to the push of database %v with %v\n", restoreUrl, rp.registeredDb.Name, response.Status)
+	}
+
+	err = json.NewDecoder(response.Body).Decode(&restoreResult)
+	if err != nil {
+		return restoreResult, fmt.Errorf("Failed to read response of peer %v: %v\n", restoreUrl, err)
+	}
+	return restoreResult, nil
+}
+
+// sync pushes the database to the peer if it changed since the last push.
+func (rp *replica) sync() {
+	// reading the meta pages is cheap and never waits for the file lock, so unchanged databases are not even opened
+	txId, err := bboltdump.ReadTxId(rp.registeredDb.Path)
+	if err == nil && txId == rp.pushedTxId {
+		return
+	}
+
+	copyPath, txId, err := rp.copyDb()
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+	defer os.Remove(copyPath)
+
+	restoreResult, err := rp.push(copyPath)
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+	rp.pushedTxId = txId
+	fmt.Printf("Pushed database %v at transaction %v to %v, %v bytes\n", rp.registeredDb.Name, txId, rp.peer.Url, restoreResult.Size)
+}
+
+// Run pushes every replicated database to its peer right away and then whenever it changed, checking once per configured interval. It never returns.
+func Run(cfg config.Config) {
+	replicas := make([]*replica, 0, len(cfg.Replication.Peers))
+	for _, peer := range cfg.Replication.Peers {
+		registeredDb, _ := cfg.LookupDb(peer.Db)
+		replicas = append(replicas, &replica{peer: peer, registeredDb: registeredDb})
+	}
+
+	ticker := time.NewTicker(cfg.Replication.Interval.Duration)
+	defer ticker.Stop()
+	for {
+		for _, rp := range replicas {
+			rp.sync()
+		}
+		<-ticker.C
+	}
+}
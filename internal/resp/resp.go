@@ -0,0 +1,610 @@
+// Package resp serves a registered database over a subset of the Redis protocol (RESP).
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/acl"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/audit"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	respMaxCommandLength = 8 << 20  // most bytes a single command may take, which also bounds the largest key or value a client may send
+	respMaxLineLength    = 64 << 10 // longest line a client may send, an inline command or the header of an array or bulk string, like the inline limit of Redis
+	respMaxArrayLength   = 1 << 20  // most arguments a single command may have
+	respMaxGlobNesting   = 1000     // deepest recursion of matchGlob, like Redis limits it
+	respDefaultCount     = 10       // amount of keys SCAN looks at if COUNT is not given
+)
+
+var (
+	errRespProtocol = errors.New("Protocol error")                       // returned when a client sends something that is not valid RESP
+	errRespTooBig   = fmt.Errorf("%w: too big request", errRespProtocol) // returned when a line or command of a client exceeds respMaxLineLength or respMaxCommandLength
+)
+
+// respConn is a struct representing a client connected to the RESP listener.
+type respConn struct {
+	reader *bufio.Reader
+	writer *bufio.Writer
+	dbPath string // path to the db file all commands operate on
+	bucket []byte // bucket chosen with SELECT, nil while keys carry their bucket as "bucket:key" prefix
+
+	auditLog   *audit.Log // records the commands that access data, nil if auditing is disabled
+	acl        *acl.Acl   // decides which buckets the client may access, nil if access control is disabled
+	dbName     string     // name of the registered database, for the audit log
+	remoteAddr string
+}
+
+// readLine reads one line terminated by \r\n and returns it without the terminator. Lines longer than respMaxLineLength are rejected before they are buffered completely.
+func (c *respConn) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := c.reader.ReadSlice('\n')
+		if len(line)+len(chunk) > respMaxLineLength {
+			return nil, errRespTooBig
+		}
+		line = append(line, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return bytes.TrimRight(line, "\r\n"), nil
+	}
+}
+
+// readCommand reads the next command, either as an array of bulk strings or as an inline command. Commands longer than respMaxCommandLength are rejected before their arguments are buffered.
+func (c *respConn) readCommand() ([][]byte, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	// inline commands like "GET foo" as typed into telnet
+	if len(line) == 0 || line[0] != '*' {
+		return bytes.Fields(line), nil
+	}
+
+	argCount, err := strconv.Atoi(string(line[1:]))
+	if err != nil || argCount < 0 || argCount > respMaxArrayLength {
+		return nil, errRespProtocol
+	}
+	args := make([][]byte, 0, min(argCount, 1024)) // grows with the arguments actually sent
+	commandLength := len(line)
+	for i := 0; i < argCount; i++ {
+		line, err = c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errRespProtocol
+		}
+		bulkLength, err := strconv.Atoi(string(line[1:]))
+		if err != nil || bulkLength < 0 {
+			return nil, errRespProtocol
+		}
+		commandLength += len(line)
+		if bulkLength > respMaxCommandLength-commandLength {
+			return nil, errRespTooBig
+		}
+		commandLength += bulkLength
+		// bulk string plus its \r\n terminator
+		bulk := make([]byte, bulkLength+2)
+		_, err = io.ReadFull(c.reader, bulk)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, bulk[:bulkLength])
+	}
+	return args, nil
+}
+
+// writeSimple writes a simple string reply like +OK.
+func (c *respConn) writeSimple(s string) {
+	c.writer.WriteString("+" + s + "\r\n")
+}
+
+// writeError writes an error reply.
+func (c *respConn) writeError(s string) {
+	c.writer.WriteString("-ERR " + s + "\r\n")
+}
+
+// writeInteger writes an integer reply.
+func (c *respConn) writeInteger(n int) {
+	c.writer.WriteString(":" + strconv.Itoa(n) + "\r\n")
+}
+
+// writeBulk writes a bulk string reply, nil is written as the null bulk string.
+func (c *respConn) writeBulk(b []byte) {
+	if b == nil {
+		c.writer.WriteString("$-1\r\n")
+		return
+	}
+	c.writer.WriteString("$" + strconv.Itoa(len(b)) + "\r\n")
+	c.writer.Write(b)
+	c.writer.WriteString("\r\n")
+}
+
+// writeArray writes an array reply of bulk strings.
+func (c *respConn) writeArray(items [][]byte) {
+	c.writer.WriteString("*" + strconv.Itoa(len(items)) + "\r\n")
+	for _, item := range items {
+		c.writeBulk(item)
+	}
+}
+
+// resolveKey splits a command argument into bucket and key. Without a selected bucket the argument must look like "bucket:key".
+func (c *respConn) resolveKey(arg []byte) ([]byte, []byte, error) {
+	if c.bucket != nil {
+		return c.bucket, arg, nil
+	}
+	bucketName, keyBytes, found := bytes.Cut(arg, []byte(":"))
+	if !found || len(bucketName) == 0 || len(keyBytes) == 0 {
+		return nil, nil, fmt.Errorf("no bucket selected, use SELECT <bucket> or prefix keys with <bucket>:")
+	}
+	return bucketName, keyBytes, nil
+}
+
+// get handles GET key.
+func (c *respConn) get(args [][]byte) error {
+	if len(args) != 1 {
+		return fmt.Errorf("wrong number of arguments for 'get' command")
+	}
+	bucketName, keyBytes, err := c.resolveKey(args[0])
+	if err != nil {
+		return err
+	}
+
+	dbInstance, closeDb, err := bboltdump.OpenDb(c.dbPath)
+	if err != nil {
+		return err
+	}
+	defer closeDb()
+
+	var valueBytes []byte
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		b := bboltdump.ResolveBucket(tx, string(bucketName))
+		if b == nil {
+			return nil
+		}
+		// copy, the value is only valid while the transaction is open
+		if v := b.Get(keyBytes); v != nil && !bboltdump.IsExpired(tx, string(bucketName), keyBytes) {
+			valueBytes = append([]byte{}, v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.writeBulk(valueBytes)
+	return nil
+}
+
+// set handles SET key value [EX seconds | PX milliseconds], the bucket is created if it does not exist. Without EX or PX an existing expiry of the key is removed like in Redis.
+func (c *respConn) set(args [][]byte) error {
+	if len(args) != 2 && len(args) != 4 {
+		return fmt.Errorf("wrong number of arguments for 'set' command")
+	}
+	bucketName, keyBytes, err := c.resolveKey(args[0])
+	if err != nil {
+		return err
+	}
+	var expiresAt time.Time
+	if len(args) == 4 {
+		amount, err := strconv.Atoi(string(args[3]))
+		if err != nil || amount <= 0 {
+			return fmt.Errorf("invalid expire time in 'set' command")
+		}
+		switch strings.ToUpper(string(args[2])) {
+		case "EX":
+			expiresAt = time.Now().Add(time.Duration(amount) * time.Second)
+		case "PX":
+			expiresAt = time.Now().Add(time.Duration(amount) * time.Millisecond)
+		default:
+			return fmt.Errorf("syntax error")
+		}
+	}
+
+	dbInstance, closeDb, err := bboltdump.OpenDbForWriting(c.dbPath)
+	if err != nil {
+		return err
+	}
+	defer closeDb()
+
+	err = dbInstance.Update(func(tx *bolt.Tx) error {
+		b, err := bboltdump.CreateBucketPath(tx, string(bucketName))
+		if err != nil {
+			return err
+		}
+		err = bboltdump.Journal(tx, audit.Anonymous, bboltdump.ChangePut, string(bucketName), keyBytes, b.Get(keyBytes), args[1])
+		if err == nil {
+			err = b.Put(keyBytes, args[1])
+		}
+		if err != nil {
+			return err
+		}
+		bboltdump.RecordChanges(tx, bboltdump.NewChange(bboltdump.ChangePut, string(bucketName), keyBytes))
+		return bboltdump.SetExpiry(tx, string(bucketName), keyBytes, expiresAt)
+	})
+	if err != nil {
+		return err
+	}
+	c.writeSimple("OK")
+	return nil
+}
+
+// del handles DEL key [key ...] and replies with the amount of keys that existed.
+func (c *respConn) del(args [][]byte) error {
+	if len(args) == 0 {
+		return fmt.Errorf("wrong number of arguments for 'del' command")
+	}
+
+	dbInstance, closeDb, err := bboltdump.OpenDbForWriting(c.dbPath)
+	if err != nil {
+		return err
+	}
+	defer closeDb()
+
+	deleted := 0
+	err = dbInstance.Update(func(tx *bolt.Tx) error {
+		changes := []bboltdump.Change{}
+		for _, arg := range args {
+			bucketName, keyBytes, err := c.resolveKey(arg)
+			if err != nil {
+				return err
+			}
+			b := bboltdump.ResolveBucket(tx, string(bucketName))
+			if b == nil || b.Get(keyBytes) == nil {
+				continue
+			}
+			expired := bboltdump.IsExpired(tx, string(bucketName), keyBytes)
+			err = bboltdump.Journal(tx, audit.Anonymous, bboltdump.ChangeDelete, string(bucketName), keyBytes, b.Get(keyBytes), nil)
+			if err == nil {
+				err = b.Delete(keyBytes)
+			}
+			if err == nil {
+				err = bboltdump.SetExpiry(tx, string(bucketName), keyBytes, time.Time{})
+			}
+			if err != nil {
+				return err
+			}
+			if !expired {
+				deleted++
+			}
+			changes = append(changes, bboltdump.NewChange(bboltdump.ChangeDelete, string(bucketName), keyBytes))
+		}
+		bboltdump.RecordChanges(tx, changes...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.writeInteger(deleted)
+	return nil
+}
+
+// matchGlob reports whether key matches the glob pattern the way Redis matches keys in SCAN MATCH and KEYS, byte by byte: * matches any bytes including none, ? any single byte,
+// [abc], [a-z] and [^a-z] a byte of or not of a set, and \ escapes the byte after it. Unlike path.Match, * and ? also match '/', and there are no invalid patterns, an unterminated [ ends at the end of the pattern.
+func matchGlob(pattern []byte, key []byte) bool {
+	skipLonger := false
+	return matchGlobFrom(pattern, key, &skipLonger, 0)
+}
+
+// matchGlobFrom matches like matchGlob at the given recursion depth. skipLonger is set once a * could not match the rest of the key, trying the key from later on cannot match either then.
+func matchGlobFrom(pattern []byte, key []byte, skipLonger *bool, nesting int) bool {
+	if nesting > respMaxGlobNesting {
+		return false
+	}
+	for len(pattern) > 0 && len(key) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for len(key) > 0 {
+				if matchGlobFrom(pattern[1:], key, skipLonger, nesting+1) {
+					return true
+				}
+				if *skipLonger {
+					return false
+				}
+				key = key[1:]
+			}
+			*skipLonger = true
+			return false
+		case '?':
+			key = key[1:]
+		case '[':
+			pattern = pattern[1:]
+			negated := len(pattern) > 0 && pattern[0] == '^'
+			if negated {
+				pattern = pattern[1:]
+			}
+			matched := false
+			for len(pattern) > 0 && pattern[0] != ']' {
+				if pattern[0] == '\\' && len(pattern) >= 2 {
+					pattern = pattern[1:]
+					matched = matched || pattern[0] == key[0]
+				} else if len(pattern) >= 3 && pattern[1] == '-' {
+					start, end := min(pattern[0], pattern[2]), max(pattern[0], pattern[2])
+					matched = matched || (key[0] >= start && key[0] <= end)
+					pattern = pattern[2:]
+				} else {
+					matched = matched || pattern[0] == key[0]
+				}
+				pattern = pattern[1:]
+			}
+			if matched == negated {
+				return false
+			}
+			key = key[1:]
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if pattern[0] != key[0] {
+				return false
+			}
+			key = key[1:]
+		}
+		if len(pattern) > 0 {
+			pattern = pattern[1:] // past the byte, the ] of a set or the byte escaped
+		}
+	}
+	for len(key) == 0 && len(pattern) > 0 && pattern[0] == '*' {
+		pattern = pattern[1:]
+	}
+	return len(pattern) == 0 && len(key) == 0
+}
+
+// scan handles SCAN cursor [MATCH pattern] [COUNT count].
+// The cursor is the amount of keys already looked at, because redis-cli and most client libraries expect a numeric cursor. Without a selected bucket all buckets are scanned and keys are returned as "bucket:key".
+func (c *respConn) scan(args [][]byte) error {
+	if len(args) == 0 || len(args)%2 != 1 {
+		return fmt.Errorf("wrong number of arguments for 'scan' command")
+	}
+	offset, err := strconv.Atoi(string(args[0]))
+	if err != nil || offset < 0 {
+		return fmt.Errorf("invalid cursor")
+	}
+	var pattern []byte // nil without MATCH
+	count := respDefaultCount
+	for i := 1; i < len(args); i += 2 {
+		switch strings.ToUpper(string(args[i])) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			count, err = strconv.Atoi(string(args[i+1]))
+			if err != nil || count <= 0 {
+				return fmt.Errorf("value is not an integer or out of range")
+			}
+		default:
+			return fmt.Errorf("syntax error")
+		}
+	}
+
+	dbInstance, closeDb, err := bboltdump.OpenDb(c.dbPath)
+	if err != nil {
+		return err
+	}
+	defer closeDb()
+
+	keys := [][]byte{}
+	position := 0
+	exhausted := true
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		// visits the keys of one bucket, returns false once count keys were looked at
+		visitBucket := func(b *bolt.Bucket, bucketName []byte, prefix []byte) bool {
+			cursor := b.Cursor()
+			for keyBytes, valueBytes := cursor.First(); keyBytes != nil; keyBytes, valueBytes = cursor.Next() {
+				if valueBytes == nil {
+					continue // nested bucket
+				}
+				position++
+				if position <= offset {
+					continue
+				}
+				if position > offset+count {
+					exhausted = false
+					return false
+				}
+				if bboltdump.IsExpired(tx, string(bucketName), keyBytes) {
+					continue // counted as looked at so the cursor stays stable, but not returned
+				}
+				fullKey := append(append([]byte(nil), prefix...), keyBytes...)
+				if pattern == nil || matchGlob(pattern, fullKey) {
+					keys = append(keys, fullKey)
+				}
+			}
+			return true
+		}
+
+		if c.bucket != nil {
+			if b := bboltdump.ResolveBucket(tx, string(c.bucket)); b != nil {
+				visitBucket(b, c.bucket, nil)
+			}
+			return nil
+		}
+		cursor := tx.Cursor()
+		for bucketName, _ := cursor.First(); bucketName != nil; bucketName, _ = cursor.Next() {
+			if bboltdump.IsMetaBucket(string(bucketName)) {
+				continue // internal buckets are not keys of the database
+			}
+			if !visitBucket(tx.Bucket(bucketName), bucketName, append(append([]byte(nil), bucketName...), ':')) {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	nextCursor := "0"
+	if !exhausted {
+		nextCursor = strconv.Itoa(offset + count)
+	}
+	c.writer.WriteString("*2\r\n")
+	c.writeBulk([]byte(nextCursor))
+	c.writeArray(keys)
+	return nil
+}
+
+// allowed reports whether the ACL lets the client, which is anonymous like all RESP clients, run command on the keys in args.
+func (c *respConn) allowed(command string, args [][]byte) bool {
+	operation := acl.Read
+	keyArgs := args
+	switch command {
+	case "GET":
+	case "SET", "DEL":
+		operation = acl.Write
+	case "SCAN":
+		return c.acl.Allows(audit.Anonymous, c.dbName, string(c.bucket), operation)
+	default:
+		return true
+	}
+	if command != "DEL" && len(keyArgs) > 1 {
+		keyArgs = keyArgs[:1] // the rest are values and options
+	}
+
+	for _, arg := range keyArgs {
+		bucketName, _, err := c.resolveKey(arg)
+		if err != nil {
+			continue // the command reports the invalid key
+		}
+		if !c.acl.Allows(audit.Anonymous, c.dbName, string(bucketName), operation) {
+			return false
+		}
+	}
+	return true
+}
+
+// handle executes a single command and writes its reply. It returns false if the connection should be closed.
+func (c *respConn) handle(args [][]byte) bool {
+	if len(args) == 0 {
+		return true
+	}
+
+	var err error
+	command := strings.ToUpper(string(args[0]))
+	if !c.allowed(command, args[1:]) {
+		c.writer.WriteString("-NOPERM no permissions to run the '" + strings.ToLower(command) + "' command on these keys\r\n")
+		return true
+	}
+	switch command {
+	case "PING":
+		c.writeSimple("PONG")
+	case "QUIT":
+		c.writeSimple("OK")
+		return false
+	case "COMMAND":
+		// redis-cli asks for command docs on connect, an empty reply is fine
+		c.writeArray(nil)
+	case "SELECT":
+		if len(args) != 2 {
+			err = fmt.Errorf("wrong number of arguments for 'select' command")
+			break
+		}
+		c.bucket = append([]byte(nil), args[1]...)
+		c.writeSimple("OK")
+	case "GET":
+		err = c.get(args[1:])
+	case "SET":
+		err = c.set(args[1:])
+	case "DEL":
+		err = c.del(args[1:])
+	case "SCAN":
+		err = c.scan(args[1:])
+	default:
+		err = fmt.Errorf("unknown command '%s'", args[0])
+	}
+	if err != nil {
+		// RESP errors have to fit on a single line
+		c.writeError(strings.ReplaceAll(strings.TrimSpace(err.Error()), "\n", " "))
+	}
+
+	switch command {
+	case "GET", "SET", "DEL", "SCAN":
+		event := audit.Event{
+			Time:       time.Now().UTC(),
+			Who:        audit.Anonymous, // RESP clients do not authenticate
+			RemoteAddr: c.remoteAddr,
+			Operation:  "RESP " + command,
+			Db:         c.dbName,
+			Bucket:     string(c.bucket),
+		}
+		if command != "SCAN" && len(args) > 1 {
+			if bucketName, _, err := c.resolveKey(args[1]); err == nil {
+				event.Bucket = string(bucketName)
+			}
+		}
+		c.auditLog.Record(event)
+	}
+	return true
+}
+
+// serveRespConn reads and executes commands from conn until the client disconnects.
+func serveRespConn(conn net.Conn, registeredDb config.RegisteredDb, auditLog *audit.Log, accessControl *acl.Acl) {
+	defer conn.Close()
+
+	c := &respConn{
+		reader:     bufio.NewReader(conn),
+		writer:     bufio.NewWriter(conn),
+		dbPath:     registeredDb.Path,
+		auditLog:   auditLog,
+		acl:        accessControl,
+		dbName:     registeredDb.Name,
+		remoteAddr: conn.RemoteAddr().String(),
+	}
+	for {
+		args, err := c.readCommand()
+		if err != nil {
+			if errors.Is(err, errRespProtocol) {
+				c.writeError(err.Error())
+				c.writer.Flush()
+			}
+			return
+		}
+		keepOpen := c.handle(args)
+		c.writer.Flush()
+		if !keepOpen {
+			return
+		}
+	}
+}
+
+// Run accepts Redis protocol clients on the configured address and serves GET, SET, DEL, SCAN and SELECT against the configured registered database. Commands that access data are recorded in auditLog and checked against the ACL rules for anonymous clients. It only returns if the listener fails.
+func Run(cfg config.Config, auditLog *audit.Log) error {
+	registeredDb, _ := cfg.LookupDb(cfg.Resp.Db)
+	accessControl := acl.New(cfg, "")
+
+	listener, err := net.Listen("tcp", cfg.Resp.Addr)
+	if err != nil {
+		return fmt.Errorf("Failed to start RESP listener: %v\n", err)
+	}
+	defer listener.Close()
+	fmt.Println("RESP listener on", cfg.Resp.Addr, "serving database", registeredDb.Name)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return fmt.Errorf("RESP listener stopped: %v\n", err)
+		}
+		go serveRespConn(conn, registeredDb, auditLog, accessControl)
+	}
+}
@@ -0,0 +1,139 @@
+package sdkgen
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/server"
+)
+
+// openApiSchema returns the JSON schema of t, structs refer to their schema in the components.
+func openApiSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return openApiSchema(t.Elem())
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": openApiSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": openApiSchema(t.Elem())}
+	case reflect.Struct:
+		if isString(t) {
+			return map[string]any{"type": "string"}
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + typeName(t)}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	}
+	return map[string]any{"type": "integer"}
+}
+
+// OpenApi returns the OpenAPI 3 document of routes served below "/bbolt", like OpenApiFor.
+func OpenApi(routes []server.Route) ([]byte, error) {
+	return OpenApiFor(routes, "/bbolt")
+}
+
+// OpenApiFor returns an OpenAPI 3 document that describes every route with its payload and result, for routes served below apiEndpoint. The docs at /docs are built from it.
+func OpenApiFor(routes []server.Route, apiEndpoint string) ([]byte, error) {
+	m, err := newModel(routes)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := make(map[string]any)
+	for _, st := range m.types {
+		properties := make(map[string]any)
+		required := []string{}
+		for _, f := range st.fields {
+			properties[f.name] = openApiSchema(f.typ)
+			if !f.optional {
+				required = append(required, f.name)
+			}
+		}
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		schemas[st.name] = schema
+	}
+
+	paths := make(map[string]any)
+	for _, route := range routes {
+		operation := map[string]any{
+			"operationId": route.Name,
+			"summary":     route.Summary,
+		}
+		parameters := []any{}
+		for _, name := range pathParameters(route.Path) {
+			parameters = append(parameters, map[string]any{"name": name, "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+		}
+		if route.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": openApiSchema(reflect.TypeOf(route.Request))}},
+			}
+		}
+
+		responses := map[string]any{
+			"default": map[string]any{"description": "The request was rejected, the body is a plain text message.", "content": map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}}},
+		}
+		if route.Result == nil {
+			// the endpoint sends a file, or only a status like the exists check
+			responses["200"] = map[string]any{"description": "The requested file, if the endpoint sends one.", "content": map[string]any{"application/octet-stream": map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}}}
+		} else {
+			parameters = append(parameters, map[string]any{
+				"name":        "raw",
+				"in":          "query",
+				"description": "Send the result itself instead of wrapping it as a JSON string in {\"result\": ...}.",
+				"schema":      map[string]any{"type": "boolean"},
+				"example":     true,
+			})
+			responses["200"] = map[string]any{"description": "The result, as it is sent with raw=true.", "content": map[string]any{"application/json": map[string]any{"schema": openApiSchema(reflect.TypeOf(route.Result))}}}
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		operation["responses"] = responses
+
+		routePath := route.Path
+		pathItem := map[string]any{"post": operation}
+		if !route.Absolute && routePath == "" {
+			routePath = apiEndpoint // the full dump is served at the API endpoint itself, not below it
+		}
+		if route.Absolute || routePath == apiEndpoint {
+			pathItem["servers"] = []any{map[string]any{"url": "/"}}
+		}
+		paths[routePath] = pathItem
+	}
+
+	document := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "bbolt-apiEndpoint",
+			"description": "Every endpoint only accepts POST requests with a JSON payload.",
+			"version":     "1",
+		},
+		"servers": []any{map[string]any{"url": apiEndpoint}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-Api-Key"},
+			},
+		},
+		"security": []any{map[string]any{"apiKey": []any{}}, map[string]any{}}, // an API key is only needed with access control enabled
+	}
+	documentBytes, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(documentBytes, '\n'), nil
+}
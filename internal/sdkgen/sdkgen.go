@@ -0,0 +1,186 @@
+// Package sdkgen generates typed Swift and TypeScript clients and the OpenAPI document of the HTTP API from server.Routes.
+package sdkgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/importer"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/server"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/stats"
+)
+
+// Languages maps each language an SDK can be generated for to its generator, "openapi" generates the OpenAPI document instead.
+var Languages = map[string]func(routes []server.Route) ([]byte, error){
+	"swift":      Swift,
+	"typescript": TypeScript,
+	"openapi":    OpenApi,
+}
+
+// typeNames renames Go types whose name would be ambiguous in the SDKs, all other types keep their Go name.
+var typeNames = map[reflect.Type]string{
+	reflect.TypeOf(importer.Result{}): "ImportResult",
+	reflect.TypeOf(stats.Latency{}):   "RequestLatency",
+}
+
+// emptyResultName is the name of the result of the endpoints that only report success.
+const emptyResultName = "EmptyResult"
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// pathParameter matches the parameters of a route path like "{db}".
+var pathParameter = regexp.MustCompile(`\{(\w+)\}`)
+
+// field is a struct representing a JSON field of a payload or result.
+type field struct {
+	name     string
+	typ      reflect.Type
+	optional bool // the field may be missing or null, or callers may leave it out
+}
+
+// structType is a struct representing a payload or result type as JSON sees it, with embedded structs flattened.
+type structType struct {
+	name   string
+	fields []field
+}
+
+// model is a struct representing all types the routes use, in the order they were first used.
+type model struct {
+	types   []*structType
+	byName  map[string]reflect.Type
+	visited map[reflect.Type]bool
+}
+
+// isString reports whether t is written as a JSON string by a MarshalJSON method, like time.Time or config.Duration.
+func isString(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && (t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType))
+}
+
+// typeName returns the name of the struct t in the SDKs.
+func typeName(t reflect.Type) string {
+	if name, found := typeNames[t]; found {
+		return name
+	}
+	if t.Name() == "" && t.NumField() == 0 {
+		return emptyResultName
+	}
+	return t.Name()
+}
+
+// newModel collects the types of the requests and results of routes.
+func newModel(routes []server.Route) (*model, error) {
+	m := &model{byName: make(map[string]reflect.Type), visited: make(map[reflect.Type]bool)}
+	for _, route := range routes {
+		if route.Request != nil {
+			err := m.add(reflect.TypeOf(route.Request), true)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if route.Result != nil {
+			err := m.add(reflect.TypeOf(route.Result), false)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+// add adds t and all types it refers to. All fields of request types are optional, the server treats missing fields as their zero value.
+func (m *model) add(t reflect.Type, request bool) error {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		if t.Kind() == reflect.Map && t.Key().Kind() != reflect.String {
+			return fmt.Errorf("Unsupported map key of %v\n", t)
+		}
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return nil // []byte is a base64 string
+		}
+		return m.add(t.Elem(), request)
+	case reflect.Struct:
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return nil
+	default:
+		return fmt.Errorf("Unsupported type %v\n", t)
+	}
+	if isString(t) || m.visited[t] {
+		return nil
+	}
+	m.visited[t] = true
+
+	name := typeName(t)
+	if other, found := m.byName[name]; found {
+		return fmt.Errorf("Types %v and %v are both named %v, add one of them to typeNames\n", other, t, name)
+	}
+	m.byName[name] = t
+
+	st := &structType{name: name}
+	m.types = append(m.types, st)
+	var addFields func(t reflect.Type, optional bool) error
+	addFields = func(t reflect.Type, optional bool) error {
+		for i := 0; i < t.NumField(); i++ {
+			structField := t.Field(i)
+			jsonName, options, _ := strings.Cut(structField.Tag.Get("json"), ",")
+			if jsonName == "-" {
+				continue
+			}
+
+			// embedded structs without a JSON name are flattened, the fields of a nil pointer are left out
+			if structField.Anonymous && jsonName == "" {
+				embedded := structField.Type
+				embeddedOptional := optional
+				if embedded.Kind() == reflect.Pointer {
+					embedded = embedded.Elem()
+					embeddedOptional = true
+				}
+				if embedded.Kind() == reflect.Struct && !isString(embedded) {
+					err := addFields(embedded, embeddedOptional)
+					if err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			if !structField.IsExported() {
+				continue
+			}
+			if jsonName == "" {
+				jsonName = structField.Name
+			}
+
+			kind := structField.Type.Kind()
+			isBytes := kind == reflect.Slice && structField.Type.Elem().Kind() == reflect.Uint8
+			st.fields = append(st.fields, field{
+				name: jsonName,
+				typ:  structField.Type,
+				// nil slices, maps and pointers are written as null
+				optional: request || optional || strings.Contains(options, "omitempty") || kind == reflect.Pointer || (kind == reflect.Slice && !isBytes) || kind == reflect.Map,
+			})
+			err := m.add(structField.Type, request)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return addFields(t, false)
+}
+
+// pathParameters returns the names of the parameters of routePath in order.
+func pathParameters(routePath string) []string {
+	var names []string
+	for _, match := range pathParameter.FindAllStringSubmatch(routePath, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// header is the first line of every generated file, which tools recognize as generated code.
+func header(language string) string {
+	return fmt.Sprintf("// Code generated by \"bbolt-apiEndpoint sdk --lang %v\"; DO NOT EDIT.\n", language)
+}
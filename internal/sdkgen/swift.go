@@ -0,0 +1,181 @@
+package sdkgen
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/server"
+)
+
+// swiftKeywords are the keywords that have to be escaped with backticks when used as property names.
+var swiftKeywords = map[string]bool{"default": true, "protocol": true, "class": true, "struct": true, "func": true, "var": true, "let": true, "in": true, "self": true, "import": true, "return": true}
+
+// swiftName returns name escaped for use as a Swift identifier.
+func swiftName(name string) string {
+	if swiftKeywords[name] {
+		return "`" + name + "`"
+	}
+	return name
+}
+
+// swiftType returns the Swift type of t.
+func swiftType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return swiftType(t.Elem())
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "String"
+		}
+		return "[" + swiftType(t.Elem()) + "]"
+	case reflect.Map:
+		return "[String: " + swiftType(t.Elem()) + "]"
+	case reflect.Struct:
+		if isString(t) {
+			return "String"
+		}
+		return typeName(t)
+	case reflect.String:
+		return "String"
+	case reflect.Bool:
+		return "Bool"
+	case reflect.Float32, reflect.Float64:
+		return "Double"
+	}
+	return "Int"
+}
+
+// Swift returns a Swift file with a Codable struct for every payload and result and a BboltClient with an async method for every route.
+func Swift(routes []server.Route) ([]byte, error) {
+	m, err := newModel(routes)
+	if err != nil {
+		return nil, err
+	}
+
+	var out strings.Builder
+	out.WriteString(header("swift"))
+	out.WriteString(`
+import Foundation
+#if canImport(FoundationNetworking)
+import FoundationNetworking
+#endif
+
+/// Error thrown when the server answers with a status other than 200, message is the body of the response.
+public struct BboltError: Error {
+    public let status: Int
+    public let message: String
+}
+`)
+
+	for _, st := range m.types {
+		fmt.Fprintf(&out, "\npublic struct %v: Codable {\n", st.name)
+		var parameters, assignments []string
+		for _, f := range st.fields {
+			fieldType := swiftType(f.typ)
+			if f.optional {
+				fieldType += "?"
+			}
+			fmt.Fprintf(&out, "    public var %v: %v\n", swiftName(f.name), fieldType)
+			parameter := f.name + ": " + fieldType
+			if f.optional {
+				parameter += " = nil"
+			}
+			parameters = append(parameters, parameter)
+			assignments = append(assignments, fmt.Sprintf("        self.%v = %v\n", f.name, swiftName(f.name)))
+		}
+		// the memberwise initializer of a struct is internal, clients of the package need a public one
+		fmt.Fprintf(&out, "\n    public init(%v) {\n%v    }\n}\n", strings.Join(parameters, ", "), strings.Join(assignments, ""))
+	}
+
+	out.WriteString(`
+/// Client of a bbolt-apiEndpoint server. Every method sends one POST request and throws a BboltError if the server rejects it.
+public final class BboltClient {
+    public let baseURL: URL
+    public let apiEndpoint: String
+    public var headers: [String: String]
+    private let session: URLSession
+
+    /// baseURL is the address of the server like "http://localhost:8085", apiEndpoint the path the API is served below.
+    public init(baseURL: URL, apiEndpoint: String = "/bbolt", headers: [String: String] = [:], session: URLSession = .shared) {
+        self.baseURL = baseURL
+        self.apiEndpoint = apiEndpoint
+        self.headers = headers
+        self.session = session
+    }
+
+    /// Sends body to path and returns the body of the response, for requests the typed methods do not cover like dumps whose values are not strings.
+    public func post(_ path: String, body: Data?) async throws -> Data {
+        var base = baseURL.absoluteString
+        if base.hasSuffix("/") {
+            base.removeLast()
+        }
+        guard let url = URL(string: base + path) else {
+            throw URLError(.badURL)
+        }
+        var urlRequest = URLRequest(url: url)
+        urlRequest.httpMethod = "POST"
+        urlRequest.httpBody = body
+        urlRequest.setValue("application/json", forHTTPHeaderField: "Content-Type")
+        for (name, value) in headers {
+            urlRequest.setValue(value, forHTTPHeaderField: name)
+        }
+        let (data, response) = try await session.data(for: urlRequest)
+        let status = (response as? HTTPURLResponse)?.statusCode ?? 0
+        guard status == 200 else {
+            throw BboltError(status: status, message: String(decoding: data, as: UTF8.self).trimmingCharacters(in: .whitespacesAndNewlines))
+        }
+        return data
+    }
+
+    /// Results are requested in raw mode, so the body of the response is the result itself.
+    private func call<Request: Encodable, Result: Decodable>(_ path: String, _ request: Request) async throws -> Result {
+        return try JSONDecoder().decode(Result.self, from: try await post(path + "?raw=true", body: try JSONEncoder().encode(request)))
+    }
+
+    private func call<Result: Decodable>(_ path: String) async throws -> Result {
+        return try JSONDecoder().decode(Result.self, from: try await post(path + "?raw=true", body: nil))
+    }
+
+    /// Escapes a path parameter.
+    private func escape(_ parameter: String) -> String {
+        var allowed = CharacterSet.urlPathAllowed
+        allowed.remove("/")
+        return parameter.addingPercentEncoding(withAllowedCharacters: allowed) ?? parameter
+    }
+`)
+
+	for _, route := range routes {
+		var parameters []string
+		swiftPath := route.Path
+		for _, name := range pathParameters(route.Path) {
+			parameters = append(parameters, name+": String")
+			swiftPath = strings.ReplaceAll(swiftPath, "{"+name+"}", `\(escape(`+name+`))`)
+		}
+		pathExpression := `"` + swiftPath + `"`
+		if !route.Absolute && swiftPath == "" {
+			pathExpression = "apiEndpoint"
+		} else if !route.Absolute {
+			pathExpression = `apiEndpoint + "` + swiftPath + `"`
+		}
+		callArguments := pathExpression
+		if route.Request != nil {
+			parameters = append(parameters, "_ request: "+typeName(reflect.TypeOf(route.Request)))
+			callArguments += ", request"
+		}
+
+		fmt.Fprintf(&out, "\n    /// %v\n", route.Summary)
+		if route.Result == nil {
+			// the endpoint sends a file
+			body := "nil"
+			if route.Request != nil {
+				body = "try JSONEncoder().encode(request)"
+			}
+			fmt.Fprintf(&out, "    public func %v(%v) async throws -> Data {\n        return try await post(%v, body: %v)\n    }\n", route.Name, strings.Join(parameters, ", "), pathExpression, body)
+			continue
+		}
+		fmt.Fprintf(&out, "    public func %v(%v) async throws -> %v {\n        return try await call(%v)\n    }\n", route.Name, strings.Join(parameters, ", "), swiftType(reflect.TypeOf(route.Result)), callArguments)
+	}
+	out.WriteString("}\n")
+	return []byte(out.String()), nil
+}
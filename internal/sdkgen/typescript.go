@@ -0,0 +1,150 @@
+package sdkgen
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/server"
+)
+
+// typeScriptType returns the TypeScript type of t.
+func typeScriptType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeScriptType(t.Elem())
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		return typeScriptType(t.Elem()) + "[]"
+	case reflect.Map:
+		return "Record<string, " + typeScriptType(t.Elem()) + ">"
+	case reflect.Struct:
+		if isString(t) {
+			return "string"
+		}
+		return typeName(t)
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	}
+	return "number"
+}
+
+// TypeScript returns a TypeScript module with an interface for every payload and result and a BboltClient with a method for every route. It only needs fetch, so it runs in browsers, Node.js and Deno.
+func TypeScript(routes []server.Route) ([]byte, error) {
+	m, err := newModel(routes)
+	if err != nil {
+		return nil, err
+	}
+
+	var out strings.Builder
+	out.WriteString(header("typescript"))
+	out.WriteString(`
+/** Error thrown when the server answers with a status other than 200, the message is the body of the response. */
+export class BboltError extends Error {
+  constructor(readonly status: number, message: string) {
+    super(message);
+    this.name = "BboltError";
+  }
+}
+`)
+
+	for _, st := range m.types {
+		if len(st.fields) == 0 {
+			fmt.Fprintf(&out, "\nexport type %v = Record<string, never>;\n", st.name)
+			continue
+		}
+		fmt.Fprintf(&out, "\nexport interface %v {\n", st.name)
+		for _, f := range st.fields {
+			// nil slices, maps and pointers are written as null
+			fieldType := typeScriptType(f.typ)
+			kind := f.typ.Kind()
+			if (kind == reflect.Pointer || kind == reflect.Slice || kind == reflect.Map) && fieldType != "string" {
+				fieldType += " | null"
+			}
+			if f.optional {
+				fmt.Fprintf(&out, "  %v?: %v;\n", f.name, fieldType)
+			} else {
+				fmt.Fprintf(&out, "  %v: %v;\n", f.name, fieldType)
+			}
+		}
+		out.WriteString("}\n")
+	}
+
+	out.WriteString(`
+export interface BboltClientOptions {
+  /** Path the API is served below, "/bbolt" by default. */
+  apiEndpoint?: string;
+  /** Headers sent with every request, like an API key. */
+  headers?: Record<string, string>;
+  /** fetch implementation to use instead of the global one. */
+  fetch?: typeof fetch;
+}
+
+/** Client of a bbolt-apiEndpoint server. Every method sends one POST request and rejects with a BboltError if the server rejects it. */
+export class BboltClient {
+  readonly baseUrl: string;
+  readonly apiEndpoint: string;
+  headers: Record<string, string>;
+  private readonly fetchFn: typeof fetch;
+
+  /** baseUrl is the address of the server like "http://localhost:8085". */
+  constructor(baseUrl: string, options: BboltClientOptions = {}) {
+    this.baseUrl = baseUrl.replace(/\/$/, "");
+    this.apiEndpoint = options.apiEndpoint ?? "/bbolt";
+    this.headers = options.headers ?? {};
+    this.fetchFn = options.fetch ?? fetch.bind(globalThis);
+  }
+
+  /** Sends body to path and returns the response, for requests the typed methods do not cover like dumps whose values are not strings. */
+  async post(path: string, body?: unknown): Promise<Response> {
+    const response = await this.fetchFn(this.baseUrl + path, {
+      method: "POST",
+      headers: { "Content-Type": "application/json", ...this.headers },
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    if (response.status !== 200) {
+      throw new BboltError(response.status, (await response.text()).trim());
+    }
+    return response;
+  }
+
+  /** Results are requested in raw mode, so the body of the response is the result itself. */
+  private async call<T>(path: string, body?: unknown): Promise<T> {
+    return (await (await this.post(path + "?raw=true", body)).json()) as T;
+  }
+`)
+
+	for _, route := range routes {
+		var parameters []string
+		typeScriptPath := route.Path
+		for _, name := range pathParameters(route.Path) {
+			parameters = append(parameters, name+": string")
+			typeScriptPath = strings.ReplaceAll(typeScriptPath, "{"+name+"}", "${encodeURIComponent("+name+")}")
+		}
+		pathExpression := "`" + typeScriptPath + "`"
+		if !route.Absolute && typeScriptPath == "" {
+			pathExpression = "this.apiEndpoint"
+		} else if !route.Absolute {
+			pathExpression = "this.apiEndpoint + `" + typeScriptPath + "`"
+		}
+		callArguments := pathExpression
+		if route.Request != nil {
+			parameters = append(parameters, "request: "+typeName(reflect.TypeOf(route.Request)))
+			callArguments += ", request"
+		}
+
+		fmt.Fprintf(&out, "\n  /** %v */\n", route.Summary)
+		if route.Result == nil {
+			// the endpoint sends a file
+			fmt.Fprintf(&out, "  async %v(%v): Promise<ArrayBuffer> {\n    return (await this.post(%v)).arrayBuffer();\n  }\n", route.Name, strings.Join(parameters, ", "), callArguments)
+			continue
+		}
+		fmt.Fprintf(&out, "  %v(%v): Promise<%v> {\n    return this.call(%v);\n  }\n", route.Name, strings.Join(parameters, ", "), typeScriptType(reflect.TypeOf(route.Result)), callArguments)
+	}
+	out.WriteString("}\n")
+	return []byte(out.String()), nil
+}
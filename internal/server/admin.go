@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// handleHandleListRequest handles requests that list the database handles the server holds open
+func handleHandleListRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resultBytes, err := json.Marshal(bboltdump.CachedHandles())
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+
+	sendResult(w, r, resultBytes)
+}
+
+// handleHandleCloseRequest handles requests to close the cached handle of a database, so the file can be replaced or deleted
+func handleHandleCloseRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload RequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Input == "" {
+		sendBadRequest(w, err)
+		return
+	}
+
+	// do actual work
+	handleInfo, found, err := bboltdump.CloseCachedHandle(requestPayload.Input)
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Not Found: database is not open", http.StatusNotFound)
+		return
+	}
+	resultBytes, err := json.Marshal(handleInfo)
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+
+	sendResult(w, r, resultBytes)
+}
+
+// handleOpenDatabasesRequest handles requests that report which databases are open, who holds them and for how long
+func handleOpenDatabasesRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resultBytes, err := json.Marshal(bboltdump.OpenDatabases())
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+
+	sendResult(w, r, resultBytes)
+}
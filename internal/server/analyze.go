@@ -0,0 +1,47 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// AnalyzeRequestPayload is a struct representing the expected request payload of the analyze endpoint
+type AnalyzeRequestPayload struct {
+	Input      string `json:"input"`      // path to db file
+	Bucket     string `json:"bucket"`     // bucket to analyze along with its nested buckets, all buckets if empty
+	SampleSize int    `json:"sampleSize"` // entries per bucket to infer the formats from, defaults to bboltdump.DefaultAnalyzeSample
+}
+
+// handleAnalyzeRequest handles requests for a report of the key patterns, value formats and sizes of the buckets of a database
+func handleAnalyzeRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload AnalyzeRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.SampleSize < 0 || requestPayload.SampleSize > bboltdump.MaxPageLimit {
+		sendBadRequest(w, err)
+		return
+	}
+	if requestPayload.SampleSize == 0 {
+		requestPayload.SampleSize = bboltdump.DefaultAnalyzeSample
+	}
+
+	// do actual work
+	result, err := bboltdump.Analyze(requestPayload.Input, requestPayload.Bucket, requestPayload.SampleSize)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	sendValue(w, r, result)
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/backup"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+)
+
+// BackupRequestPayload is a struct representing the expected request payload of the backup endpoints
+type BackupRequestPayload struct {
+	Db     string `json:"db"`     // name of a registered database
+	Backup string `json:"backup"` // id of a backup, only used by the download endpoint
+}
+
+// decodeBackupRequest decodes the request payload and looks up the registered database it refers to. On failure an error response has already been sent.
+func (s *Server) decodeBackupRequest(w http.ResponseWriter, r *http.Request) (BackupRequestPayload, config.RegisteredDb, bool) {
+	var requestPayload BackupRequestPayload
+
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return requestPayload, config.RegisteredDb{}, false
+	}
+	if s.config().Backups.Dir == "" {
+		http.Error(w, "Backups are not enabled", http.StatusNotFound)
+		return requestPayload, config.RegisteredDb{}, false
+	}
+
+	// decode request
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil {
+		sendBadRequest(w, err)
+		return requestPayload, config.RegisteredDb{}, false
+	}
+	registeredDb, found := s.config().LookupDb(requestPayload.Db)
+	if !found {
+		http.Error(w, "Unknown database", http.StatusNotFound)
+		return requestPayload, config.RegisteredDb{}, false
+	}
+	return requestPayload, registeredDb, true
+}
+
+// handleBackupListRequest handles requests that list the stored backups of a registered database
+func (s *Server) handleBackupListRequest(w http.ResponseWriter, r *http.Request) {
+	_, registeredDb, ok := s.decodeBackupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// do actual work
+	backups, err := backup.List(s.config().Backups.Dir, registeredDb.Name)
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+	resultBytes, err := json.Marshal(backups)
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+
+	sendResult(w, r, resultBytes)
+}
+
+// handleBackupDownloadRequest handles requests that download a stored backup of a registered database as a bolt file
+func (s *Server) handleBackupDownloadRequest(w http.ResponseWriter, r *http.Request) {
+	requestPayload, registeredDb, ok := s.decodeBackupRequest(w, r)
+	if !ok {
+		return
+	}
+	// the id ends up in a file path, so only accept well formed ids
+	_, err := time.Parse(backup.IdFormat, requestPayload.Backup)
+	if err != nil {
+		http.Error(w, "Bad Request: invalid backup id", http.StatusBadRequest)
+		return
+	}
+
+	// the file is opened before the retention policy may delete it, an open file can still be read to the end
+	backupFile, err := os.Open(backup.Path(s.config().Backups.Dir, registeredDb.Name, requestPayload.Backup))
+	if err != nil {
+		http.Error(w, "Unknown backup", http.StatusNotFound)
+		return
+	}
+	defer backupFile.Close()
+	fileInfo, err := backupFile.Stat()
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+
+	// send file
+	downloadName := backup.FileName(registeredDb.Name, requestPayload.Backup)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", downloadName))
+	http.ServeContent(s.streamWriter(w, r), r, downloadName, fileInfo.ModTime(), backupFile)
+	fmt.Println("Successfully sent backup.")
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// KeyRefPayload is a struct representing a key to read in the request payload of the batch get endpoint
+type KeyRefPayload struct {
+	Bucket string `json:"bucket"` // bucket to read from
+	Key    string `json:"key"`    // hex encoded key
+}
+
+// BatchGetRequestPayload is a struct representing the expected request payload of the batch get endpoint
+type BatchGetRequestPayload struct {
+	Input   string          `json:"input"`   // path to db file
+	Lookups []KeyRefPayload `json:"lookups"` // keys to read, at most bboltdump.MaxBatchKeys together with the ones of Buckets
+	Buckets []string        `json:"buckets"` // buckets to read Key from, for entities spread across several buckets under the same key
+	Key     string          `json:"key"`     // hex encoded key read from every bucket of Buckets
+	Values  string          `json:"values"`  // "raw" (default), "type" or "sha256" to get the size and content type or digest of each value instead
+	Keys    string          `json:"keys"`    // "text" (default) to get readable keys as text, "hex" to get every key hex encoded only or "time" to also get timestamp keys as RFC 3339 times
+}
+
+// handleBatchGetRequest handles requests that read several keys in one read transaction
+func handleBatchGetRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload BatchGetRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil {
+		sendBadRequest(w, err)
+		return
+	}
+	if len(requestPayload.Buckets) > 0 && requestPayload.Key == "" {
+		http.Error(w, "Bad Request: buckets need a key", http.StatusBadRequest)
+		return
+	}
+	for _, bucketName := range requestPayload.Buckets {
+		requestPayload.Lookups = append(requestPayload.Lookups, KeyRefPayload{Bucket: bucketName, Key: requestPayload.Key})
+	}
+	if len(requestPayload.Lookups) == 0 || len(requestPayload.Lookups) > bboltdump.MaxBatchKeys {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	keyRefs := make([]bboltdump.KeyRef, 0, len(requestPayload.Lookups))
+	for _, lookup := range requestPayload.Lookups {
+		keyBytes, err := hex.DecodeString(lookup.Key)
+		if err != nil || lookup.Bucket == "" || len(keyBytes) == 0 {
+			http.Error(w, "Bad Request: every lookup needs a bucket and a hex encoded key", http.StatusBadRequest)
+			return
+		}
+		keyRefs = append(keyRefs, bboltdump.KeyRef{Bucket: lookup.Bucket, Key: keyBytes})
+	}
+	if !bboltdump.IsValidValueMode(requestPayload.Values) {
+		http.Error(w, "Bad Request: values must be raw, type or sha256", http.StatusBadRequest)
+		return
+	}
+	if !bboltdump.IsValidKeyMode(requestPayload.Keys) {
+		http.Error(w, "Bad Request: keys must be hex, time or text", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	result, err := bboltdump.GetEntries(requestPayload.Input, keyRefs, requestPayload.Values, requestPayload.Keys)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	// polling clients send the ETag of their last result and only get the entries again once a value changed
+	sendValueUnlessNotModified(w, r, result)
+}
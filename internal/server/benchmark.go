@@ -0,0 +1,66 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/benchmark"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+)
+
+// BenchmarkRequestPayload is a struct representing the expected request payload of the benchmark endpoint
+type BenchmarkRequestPayload struct {
+	Input        string          `json:"input"`        // path to db file
+	Workload     string          `json:"workload"`     // "get", "scan" or "dump"
+	Bucket       string          `json:"bucket"`       // bucket gets and scans read from, a dump only dumps this bucket if it is set
+	Duration     config.Duration `json:"duration"`     // how long the benchmark runs like "30s", defaults to benchmark.DefaultDuration
+	Concurrency  int             `json:"concurrency"`  // amount of operations running at the same time, defaults to benchmark.DefaultConcurrency
+	PrefixLength int             `json:"prefixLength"` // leading bytes of a random key a scan reads the keys of, defaults to benchmark.DefaultPrefixLength
+	Limit        int             `json:"limit"`        // most entries one scan reads, defaults to bboltdump.DefaultPageLimit
+}
+
+// handleBenchmarkRequest handles requests that run a synthetic read workload against a database and report its throughput and latencies
+func handleBenchmarkRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload BenchmarkRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Input == "" || requestPayload.Duration.Duration < 0 || requestPayload.Duration.Duration > benchmark.MaxDuration ||
+		requestPayload.Concurrency < 0 || requestPayload.Concurrency > benchmark.MaxConcurrency || requestPayload.PrefixLength < 0 || requestPayload.Limit < 0 {
+		sendBadRequest(w, err)
+		return
+	}
+	if !benchmark.IsValidWorkload(requestPayload.Workload) {
+		http.Error(w, "Bad Request: workload must be get, scan or dump", http.StatusBadRequest)
+		return
+	}
+	if requestPayload.Workload != benchmark.WorkloadDump && requestPayload.Bucket == "" {
+		http.Error(w, "Bad Request: get and scan need a bucket", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work, stopping early if the client goes away
+	result, err := benchmark.Run(r.Context(), requestPayload.Input, benchmark.Options{
+		Workload:     requestPayload.Workload,
+		Bucket:       requestPayload.Bucket,
+		Duration:     requestPayload.Duration.Duration,
+		Concurrency:  requestPayload.Concurrency,
+		PrefixLength: requestPayload.PrefixLength,
+		ScanLimit:    requestPayload.Limit,
+	})
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	// the benchmark may have run for longer than the write timeout
+	sendValue(deadlineWriter{w, r}, r, result)
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+const cborContentType = "application/cbor"
+
+// acceptsCbor reports whether the client of r asked for CBOR with an Accept header like "application/cbor".
+func acceptsCbor(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err == nil && mediaType == cborContentType && params["q"] != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+// cborEntry is a struct representing a bboltdump.Entry in CBOR, where keys and values are byte strings instead of hex and text.
+type cborEntry struct {
+	Key     []byte `cbor:"key"`
+	KeyTime string `cbor:"keyTime,omitempty"`
+	Value   []byte `cbor:"value,omitempty"` // left out if the ValueInfo is sent instead or the value is empty
+	*bboltdump.ValueInfo
+}
+
+// cborBucketPage is a struct representing a bboltdump.BucketPage in CBOR.
+type cborBucketPage struct {
+	Bucket     string      `cbor:"bucket"`
+	Entries    []cborEntry `cbor:"entries"`
+	NextCursor string      `cbor:"nextCursor,omitempty"`
+}
+
+// cborBucketSample is a struct representing a bboltdump.BucketSample in CBOR.
+type cborBucketSample struct {
+	Bucket  string      `cbor:"bucket"`
+	Entries []cborEntry `cbor:"entries"`
+	Total   int         `cbor:"total"`
+}
+
+// cborBatchEntry is a struct representing a bboltdump.BatchEntry in CBOR.
+type cborBatchEntry struct {
+	Bucket string `cbor:"bucket"`
+	Found  bool   `cbor:"found"`
+	cborEntry
+}
+
+// cborScanEntry is a struct representing a bboltdump.ScanEntry in CBOR.
+type cborScanEntry struct {
+	Bucket string `cbor:"bucket"`
+	cborEntry
+}
+
+// cborScanResult is a struct representing a bboltdump.ScanResult in CBOR.
+type cborScanResult struct {
+	Entries   []cborScanEntry `cbor:"entries"`
+	Scanned   int             `cbor:"scanned"`
+	Truncated bool            `cbor:"truncated"`
+}
+
+// newCborEntry returns entry with its key and value as the bytes they were in the database.
+func newCborEntry(entry bboltdump.Entry) cborEntry {
+	hexKey := entry.KeyHex
+	if hexKey == "" {
+		hexKey = entry.Key // the key was returned hex encoded only
+	}
+	keyBytes, _ := hex.DecodeString(hexKey)
+	cborEntry := cborEntry{Key: keyBytes, KeyTime: entry.KeyTime, ValueInfo: entry.ValueInfo}
+	if entry.ValueInfo == nil {
+		cborEntry.Value = []byte(entry.Value)
+	}
+	return cborEntry
+}
+
+// newCborEntries returns entries as cborEntry values.
+func newCborEntries(entries []bboltdump.Entry) []cborEntry {
+	cborEntries := make([]cborEntry, 0, len(entries))
+	for _, entry := range entries {
+		cborEntries = append(cborEntries, newCborEntry(entry))
+	}
+	return cborEntries
+}
+
+// toCbor returns result encoded as CBOR. Entries are sent with binary keys and values, all other results like their JSON.
+func toCbor(result any) ([]byte, error) {
+	switch result := result.(type) {
+	case bboltdump.Entry:
+		return cbor.Marshal(newCborEntry(result))
+	case bboltdump.BucketPage:
+		return cbor.Marshal(cborBucketPage{Bucket: result.Bucket, Entries: newCborEntries(result.Entries), NextCursor: result.NextCursor})
+	case bboltdump.BucketSample:
+		return cbor.Marshal(cborBucketSample{Bucket: result.Bucket, Entries: newCborEntries(result.Entries), Total: result.Total})
+	case []bboltdump.BatchEntry:
+		cborBatchEntries := make([]cborBatchEntry, 0, len(result))
+		for _, batchEntry := range result {
+			cborBatchEntries = append(cborBatchEntries, cborBatchEntry{Bucket: batchEntry.Bucket, Found: batchEntry.Found, cborEntry: newCborEntry(batchEntry.Entry)})
+		}
+		return cbor.Marshal(cborBatchEntries)
+	case bboltdump.ScanResult:
+		cborScanEntries := make([]cborScanEntry, 0, len(result.Entries))
+		for _, scanEntry := range result.Entries {
+			cborScanEntries = append(cborScanEntries, cborScanEntry{Bucket: scanEntry.Bucket, cborEntry: newCborEntry(scanEntry.Entry)})
+		}
+		return cbor.Marshal(cborScanResult{Entries: cborScanEntries, Scanned: result.Scanned, Truncated: result.Truncated})
+	}
+	return cbor.Marshal(result)
+}
+
+// jsonToCbor returns the JSON document resultBytes encoded as CBOR, integers stay integers.
+func jsonToCbor(resultBytes []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(resultBytes))
+	decoder.UseNumber()
+	var result any
+	err := decoder.Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode result: %v\n", err)
+	}
+	return cbor.Marshal(withCborNumbers(result))
+}
+
+// withCborNumbers replaces the json.Number values of a decoded JSON document by int64 or float64, CBOR would send them as strings otherwise.
+func withCborNumbers(value any) any {
+	switch value := value.(type) {
+	case json.Number:
+		if number, err := value.Int64(); err == nil {
+			return number
+		}
+		number, _ := value.Float64()
+		return number
+	case []any:
+		for i := range value {
+			value[i] = withCborNumbers(value[i])
+		}
+	case map[string]any:
+		for key := range value {
+			value[key] = withCborNumbers(value[key])
+		}
+	}
+	return value
+}
+
+// sendCbor sends resultBytes as a CBOR response.
+func sendCbor(w http.ResponseWriter, resultBytes []byte) {
+	w.Header().Set("Content-Type", cborContentType)
+	_, err := w.Write(resultBytes)
+	if err != nil {
+		fmt.Println("ERROR: Failed to send response:", err)
+		return
+	}
+	fmt.Println("Successfully sent response.")
+}
+
+// sendValue sends result to the client of r, as CBOR if the client accepts it and like sendResult otherwise.
+func sendValue(w http.ResponseWriter, r *http.Request, result any) {
+	if acceptsCbor(r) {
+		resultBytes, err := toCbor(result)
+		if err != nil {
+			fmt.Println("ERROR: Failed to serialize object to cbor:", err)
+			return
+		}
+		sendCbor(w, resultBytes)
+		return
+	}
+
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		fmt.Println("ERROR: Failed to serialize object to json:", err)
+		return
+	}
+	sendResult(w, r, resultBytes)
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/compaction"
+)
+
+// handleCompactionsRequest handles requests that list the compaction schedules of the registered databases and how their last compaction went
+func (s *Server) handleCompactionsRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resultBytes, err := json.Marshal(compaction.Statuses(s.config()))
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+
+	sendResult(w, r, resultBytes)
+}
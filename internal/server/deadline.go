@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+)
+
+// NewHttpServer returns the http.Server that serves handler on addr with the timeouts of cfg, timeouts that are not set get their default.
+// Besides HTTP/1.1 it speaks HTTP/2 without TLS (h2c), so clients can multiplex many requests over one connection.
+func NewHttpServer(addr string, handler http.Handler, cfg config.ServerConfig) *http.Server {
+	cfg = cfg.WithDefaults() // Load filled them in already unless the server was started without a config file
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		Protocols:         &protocols,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout.Duration,
+		ReadTimeout:       cfg.ReadTimeout.Duration,
+		WriteTimeout:      cfg.WriteTimeout.Duration,
+		IdleTimeout:       cfg.IdleTimeout.Duration,
+	}
+}
+
+// withTimeout returns r with a context that is done once the timeout the client gave with ?timeout=5s ran out, cut down to maxTimeout, or once maxTimeout ran out if the client gave none.
+// Endpoints that read whole buckets stop reading then and send what they read so far. Requests to endpoints that cannot stop early, which have takesTimeout unset, keep their context and fail if they were given a timeout.
+func withTimeout(r *http.Request, takesTimeout bool, maxTimeout time.Duration) (*http.Request, context.CancelFunc, error) {
+	timeoutString := r.URL.Query().Get("timeout")
+	if !takesTimeout {
+		if timeoutString != "" {
+			return nil, nil, fmt.Errorf("%v cannot stop before it is done and does not take a timeout\n", r.URL.Path)
+		}
+		return r, func() {}, nil
+	}
+	timeout := maxTimeout
+	if timeoutString != "" {
+		clientTimeout, err := time.ParseDuration(timeoutString)
+		if err != nil || clientTimeout <= 0 {
+			return nil, nil, fmt.Errorf("timeout must be a positive duration like 5s\n")
+		}
+		timeout = min(clientTimeout, maxTimeout)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	return r.WithContext(ctx), cancel, nil
+}
+
+// deadlineWriter is a http.ResponseWriter that gives the response another WriteTimeout of its server before every write.
+// Streamed responses like dumps and exports of large databases take longer than the timeout, with deadlineWriter they only end once the client stopped reading for that long.
+type deadlineWriter struct {
+	http.ResponseWriter
+	r *http.Request
+}
+
+// Write moves the write deadline forward and writes p.
+func (dw deadlineWriter) Write(p []byte) (int, error) {
+	httpServer, ok := dw.r.Context().Value(http.ServerContextKey).(*http.Server)
+	if ok && httpServer.WriteTimeout > 0 {
+		http.NewResponseController(dw.ResponseWriter).SetWriteDeadline(time.Now().Add(httpServer.WriteTimeout))
+	}
+	return dw.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, so http.ResponseController can flush it.
+func (dw deadlineWriter) Unwrap() http.ResponseWriter {
+	return dw.ResponseWriter
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/audit"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// DeleteRequestPayload is a struct representing the expected request payload of the bulk delete endpoint
+type DeleteRequestPayload struct {
+	Input  string `json:"input"`  // path to db file
+	Bucket string `json:"bucket"` // bucket to delete from
+	Prefix string `json:"prefix"` // hex encoded prefix the keys must start with
+	Start  string `json:"start"`  // hex encoded first key of the range, inclusive
+	End    string `json:"end"`    // hex encoded end of the range, exclusive
+	DryRun bool   `json:"dryRun"` // roll the delete back and only report what would change
+}
+
+// handleDeleteRequest handles requests to delete all keys of a bucket matching a prefix or range
+func handleDeleteRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload DeleteRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Input == "" || requestPayload.Bucket == "" {
+		sendBadRequest(w, err)
+		return
+	}
+	// refuse to empty a whole bucket by accident
+	if requestPayload.Prefix == "" && requestPayload.Start == "" && requestPayload.End == "" {
+		http.Error(w, "Bad Request: prefix, start or end is required", http.StatusBadRequest)
+		return
+	}
+	prefix, prefixErr := hex.DecodeString(requestPayload.Prefix)
+	start, startErr := hex.DecodeString(requestPayload.Start)
+	end, endErr := hex.DecodeString(requestPayload.End)
+	if prefixErr != nil || startErr != nil || endErr != nil {
+		http.Error(w, "Bad Request: prefix, start and end must be hex encoded", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	deleteResult, err := bboltdump.DeleteKeys(requestPayload.Input, requestPayload.Bucket, prefix, start, end, requestPayload.DryRun, audit.Who(r))
+	if errors.Is(err, bboltdump.ErrBucketNotFound) {
+		http.Error(w, "Unknown bucket", http.StatusNotFound)
+		return
+	}
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		http.Error(w, "Failed to delete keys", http.StatusInternalServerError)
+		return
+	}
+	resultBytes, err := json.Marshal(deleteResult)
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+
+	sendResult(w, r, resultBytes)
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+const maxUploadMemory = 32 << 20 // uploaded backups larger than this are buffered on disk while parsing the form
+
+// DiffRequestPayload is a struct representing the expected request payload of the diff endpoint
+type DiffRequestPayload struct {
+	Input string `json:"input"` // path to the base db file
+	Other string `json:"other"` // path to the db file to compare against
+	Keys  string `json:"keys"`  // "text" (default) to get readable keys as text, "hex" to get every key hex encoded only or "time" to also get timestamp keys as RFC 3339 times
+}
+
+// saveUpload writes the uploaded file of the given form field to a temporary file and returns its path, the caller is responsible for removing it.
+func saveUpload(r *http.Request, field string) (string, error) {
+	uploadedFile, _, err := r.FormFile(field)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read uploaded file %v: %v\n", field, err)
+	}
+	defer uploadedFile.Close()
+
+	tempFile, err := os.CreateTemp("", "bbolt-upload-*.db")
+	if err != nil {
+		return "", fmt.Errorf("Failed to create temporary file: %v\n", err)
+	}
+	defer tempFile.Close()
+
+	_, err = io.Copy(tempFile, uploadedFile)
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return "", fmt.Errorf("Failed to store uploaded file: %v\n", err)
+	}
+	return tempFile.Name(), nil
+}
+
+// removeUpload deletes a file stored by saveUpload, closing its cached handle first so the handle cache does not keep the deleted file open.
+func removeUpload(uploadPath string) {
+	bboltdump.CloseCachedHandle(uploadPath)
+	os.Remove(uploadPath)
+}
+
+// handleDiffRequest handles requests to compare two databases.
+// The other database is either given as a path in a JSON payload or uploaded as the "backup" file of a multipart form that also carries the "input" path.
+func handleDiffRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload DiffRequestPayload
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err := r.ParseMultipartForm(maxUploadMemory)
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		requestPayload.Input = r.FormValue("input")
+		requestPayload.Keys = r.FormValue("keys")
+		requestPayload.Other, err = saveUpload(r, "backup")
+		if err != nil {
+			http.Error(w, "Bad Request: missing backup file", http.StatusBadRequest)
+			return
+		}
+		defer removeUpload(requestPayload.Other)
+	} else {
+		err := decodePayload(w, r, &requestPayload)
+		if err != nil || requestPayload.Other == "" {
+			sendBadRequest(w, err)
+			return
+		}
+	}
+	if !bboltdump.IsValidKeyMode(requestPayload.Keys) {
+		http.Error(w, "Bad Request: keys must be hex, time or text", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	resultBytes, err := bboltdump.DiffDbsAsJson(requestPayload.Input, requestPayload.Other, requestPayload.Keys)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	sendResult(w, r, resultBytes)
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed docs
+var docsFiles embed.FS
+
+// RegisterDocs serves a Swagger UI at /docs on mux that explores and tries the endpoints described by openApi, the document sdkgen.OpenApiFor generates from Routes.
+// Swagger UI is embedded along with the page, so the browser does not need to reach anything but the server.
+func RegisterDocs(mux *http.ServeMux, openApi []byte) {
+	docsRoot, err := fs.Sub(docsFiles, "docs")
+	if err != nil {
+		panic(err) // the directory is embedded at compile time, so this cannot happen
+	}
+	mux.Handle("/docs/", http.StripPrefix("/docs/", http.FileServer(http.FS(docsRoot))))
+	mux.HandleFunc("/docs/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openApi)
+	})
+	mux.Handle("/docs", http.RedirectHandler("/docs/", http.StatusMovedPermanently))
+}
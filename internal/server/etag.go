@@ -0,0 +1,43 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// entityTag returns a weak ETag for result, which changes whenever a value in it does. It is weak since the same result is sent as JSON or CBOR, wrapped or raw.
+func entityTag(result any) (string, error) {
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(resultBytes)
+	return `W/"` + hex.EncodeToString(hash[:16]) + `"`, nil
+}
+
+// matchesEtag reports whether the If-None-Match header of r names etag or is "*". Tags are compared weakly, W/ is ignored.
+func matchesEtag(r *http.Request, etag string) bool {
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sendValueUnlessNotModified sends result like sendValue along with its ETag, or only 304 Not Modified if the client of r already has it.
+func sendValueUnlessNotModified(w http.ResponseWriter, r *http.Request, result any) {
+	etag, err := entityTag(result)
+	if err == nil {
+		w.Header().Set("ETag", etag)
+		if matchesEtag(r, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	sendValue(w, r, result)
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// ExistsRequestPayload is a struct representing the expected request payload of the exists endpoint, HEAD requests pass the same fields as query parameters
+type ExistsRequestPayload struct {
+	Input  string `json:"input"`  // path to db file
+	Bucket string `json:"bucket"` // bucket to look in
+	Key    string `json:"key"`    // hex encoded key
+}
+
+// handleExistsRequest handles requests that check whether a key exists, answering with the status and the size and type of the value in headers but without a body
+func handleExistsRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST and HEAD requests
+	if r.Method != http.MethodPost && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed. Please use POST or HEAD.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request, a HEAD request has no body so it names the key in the query
+	var requestPayload ExistsRequestPayload
+	var err error
+	if r.Method == http.MethodHead {
+		query := r.URL.Query()
+		requestPayload = ExistsRequestPayload{Input: query.Get("input"), Bucket: query.Get("bucket"), Key: query.Get("key")}
+	} else {
+		err = decodePayload(w, r, &requestPayload)
+	}
+	keyBytes, keyErr := hex.DecodeString(requestPayload.Key)
+	if err != nil || keyErr != nil || requestPayload.Input == "" || requestPayload.Bucket == "" || len(keyBytes) == 0 {
+		sendBadRequest(w, err)
+		return
+	}
+
+	// do actual work
+	batchEntries, err := bboltdump.GetEntries(requestPayload.Input, []bboltdump.KeyRef{{Bucket: requestPayload.Bucket, Key: keyBytes}}, bboltdump.ValuesType, bboltdump.KeysHex)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		http.Error(w, "Failed to read key", http.StatusInternalServerError)
+		return
+	}
+
+	batchEntry := batchEntries[0]
+	if !batchEntry.Found {
+		http.Error(w, "Unknown key", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("X-Bbolt-Value-Size", strconv.Itoa(batchEntry.ValueInfo.Size))
+	w.Header().Set("X-Bbolt-Value-Type", batchEntry.ValueInfo.ContentType)
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,313 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/export"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// ExportRequestPayload is a struct representing the expected request payload of the SQLite and Parquet export endpoints
+type ExportRequestPayload struct {
+	Input    string `json:"input"`    // path to db file
+	Where    string `json:"where"`    // filter expression like `json.status = "failed"` the exported entries have to pass, see bboltdump.Filter. All entries are exported if empty
+	Internal bool   `json:"internal"` // also export the buckets the server keeps beside the data like expiries or the journal, see bboltdump.IsMetaBucket
+}
+
+// parseExportFilter parses the filter expression where of an export and rejects the request if it is invalid, a nil filter exports everything.
+func parseExportFilter(w http.ResponseWriter, where string) (*bboltdump.Filter, bool) {
+	if where == "" {
+		return nil, true
+	}
+	filter, err := bboltdump.ParseFilter(where)
+	if err != nil {
+		http.Error(w, "Bad Request: "+strings.TrimSpace(err.Error()), http.StatusBadRequest)
+		return nil, false
+	}
+	return filter, true
+}
+
+// handleSqliteExportRequest handles requests that download a database converted to SQLite
+func (s *Server) handleSqliteExportRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload ExportRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil {
+		sendBadRequest(w, err)
+		return
+	}
+	filter, ok := parseExportFilter(w, requestPayload.Where)
+	if !ok {
+		return
+	}
+
+	// do actual work, SQLite needs a real file so the export is written to disk before it is streamed
+	tempDir, err := os.MkdirTemp("", "bbolt-sqlite-*")
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	sqlitePath := filepath.Join(tempDir, "export.sqlite")
+
+	err = export.ToSqlite(requestPayload.Input, sqlitePath, filter, requestPayload.Internal)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	sqliteFile, err := os.Open(sqlitePath)
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+	defer sqliteFile.Close()
+	fileInfo, err := sqliteFile.Stat()
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+
+	// send file
+	downloadName := strings.TrimSuffix(filepath.Base(requestPayload.Input), filepath.Ext(requestPayload.Input)) + ".sqlite"
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", downloadName))
+	http.ServeContent(s.streamWriter(w, r), r, downloadName, fileInfo.ModTime(), sqliteFile)
+	fmt.Println("Successfully sent SQLite export.")
+}
+
+// handleParquetExportRequest handles requests that download a database converted to Parquet
+func (s *Server) handleParquetExportRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload ExportRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil {
+		sendBadRequest(w, err)
+		return
+	}
+	filter, ok := parseExportFilter(w, requestPayload.Where)
+	if !ok {
+		return
+	}
+
+	// do actual work, unlike SQLite the Parquet file is streamed to the client while it is written
+	downloadName := strings.TrimSuffix(filepath.Base(requestPayload.Input), filepath.Ext(requestPayload.Input)) + ".parquet"
+	w.Header().Set("Content-Type", "application/vnd.apache.parquet")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", downloadName))
+	err = export.ToParquet(requestPayload.Input, s.streamWriter(w, r), filter, requestPayload.Internal)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // the client gets a truncated file, which Parquet readers reject since the footer is missing
+	}
+	fmt.Println("Successfully sent Parquet export.")
+}
+
+// NdjsonExportRequestPayload is a struct representing the expected request payload of the resumable NDJSON export endpoint
+type NdjsonExportRequestPayload struct {
+	Input  string `json:"input" payload:"optional"` // path to db file, only used to start an export
+	Bucket string `json:"bucket"`                   // only export this bucket, all top level buckets if empty
+	Where  string `json:"where"`                    // filter expression the exported entries have to pass, see bboltdump.Filter. Only used to start an export, all entries are exported if empty
+	Export string `json:"export"`                   // id of an export started before to resume, a new export is started if empty
+	Keys   string `json:"keys"`                     // "text" (default) to export readable keys as text or "hex" to hex encode every key. Only used to start an export
+}
+
+// parseByteRange returns the start and the end (exclusive) of the single byte range the Range header rangeHeader asks for, within content of the given size.
+func parseByteRange(rangeHeader string, size int64) (int64, int64, bool) {
+	spec, found := strings.CutPrefix(rangeHeader, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+	first, last, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, 0, false
+	}
+	if first == "" {
+		// a suffix like "-500" asks for the last 500 bytes
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix <= 0 || size == 0 {
+			return 0, 0, false
+		}
+		return max(size-suffix, 0), size, true
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+	if last == "" {
+		return start, size, true
+	}
+	end, err := strconv.ParseInt(last, 10, 64)
+	if err != nil || end < start {
+		return 0, 0, false
+	}
+	return start, min(end+1, size), true
+}
+
+// handleNdjsonExportRequest handles requests that download a copy of a database as NDJSON which can be resumed with a Range header
+func (s *Server) handleNdjsonExportRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload NdjsonExportRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || (requestPayload.Input == "" && requestPayload.Export == "") {
+		sendBadRequest(w, err)
+		return
+	}
+	if requestPayload.Keys != "" && requestPayload.Keys != bboltdump.KeysText && requestPayload.Keys != bboltdump.KeysHex {
+		http.Error(w, "Bad Request: keys must be hex or text", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work, a new export copies the database first so it can be resumed from the same data
+	exportConfig := s.config().Exports.WithDefaults() // Load filled them in already unless the server was started without a config file
+	var resumable export.Resumable
+	if requestPayload.Export == "" {
+		if _, ok := parseExportFilter(w, requestPayload.Where); !ok {
+			return
+		}
+		resumable, err = export.StartResumable(exportConfig.Dir, exportConfig.Retention.Duration, requestPayload.Input, requestPayload.Bucket, requestPayload.Where, requestPayload.Keys)
+	} else {
+		resumable, err = export.LoadResumable(exportConfig.Dir, requestPayload.Export)
+	}
+	if errors.Is(err, export.ErrUnknownExport) {
+		http.Error(w, "Unknown export", http.StatusNotFound)
+		return
+	}
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	// send the requested part of the export
+	start, end := int64(0), resumable.Size
+	status := http.StatusOK
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+		var ok bool
+		start, end, ok = parseByteRange(rangeHeader, resumable.Size)
+		if !ok {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", resumable.Size))
+			http.Error(w, "Requested range not satisfiable", http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, resumable.Size))
+		status = http.StatusPartialContent
+	}
+	downloadName := strings.TrimSuffix(filepath.Base(resumable.Input), filepath.Ext(resumable.Input)) + ".ndjson"
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", downloadName))
+	w.Header().Set("Content-Length", strconv.FormatInt(end-start, 10))
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("X-Bbolt-Export", resumable.Id)
+	w.WriteHeader(status)
+
+	// lines are small, so they are collected into larger writes
+	writer := bufio.NewWriterSize(s.streamWriter(w, r), 64<<10)
+	err = resumable.WriteRange(writer, exportConfig.Dir, start, end)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // the client got less than Content-Length and can resume from where it stopped
+	}
+	fmt.Println("Successfully sent NDJSON export.")
+}
+
+// ShardExportRequestPayload is a struct representing the expected request payload of the sharded export endpoint
+type ShardExportRequestPayload struct {
+	Input     string `json:"input"`     // path to db file
+	Shards    int    `json:"shards"`    // amount of bbolt files to split the database into
+	Partition string `json:"partition"` // "bucket" to put every top level bucket into one shard, "key" to spread the keys of every bucket by their hash
+	Where     string `json:"where"`     // filter expression the exported entries have to pass, see bboltdump.Filter. All entries are exported if empty
+}
+
+// handleShardExportRequest handles requests that download a database split into several bbolt files as a tar archive
+func (s *Server) handleShardExportRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload ShardExportRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil {
+		sendBadRequest(w, err)
+		return
+	}
+	if requestPayload.Shards < 1 || requestPayload.Shards > export.MaxShards {
+		http.Error(w, fmt.Sprintf("Bad Request: shards must be between 1 and %v", export.MaxShards), http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(export.ShardPartitions, requestPayload.Partition) {
+		http.Error(w, fmt.Sprintf("Bad Request: partition must be one of %v", export.ShardPartitions), http.StatusBadRequest)
+		return
+	}
+	filter, ok := parseExportFilter(w, requestPayload.Where)
+	if !ok {
+		return
+	}
+
+	// do actual work, bolt needs real files so the shards are written to disk before they are streamed
+	tempDir, err := os.MkdirTemp("", "bbolt-shards-*")
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+
+	manifest, err := export.ToShards(requestPayload.Input, tempDir, requestPayload.Shards, requestPayload.Partition, filter)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	// send archive
+	downloadName := strings.TrimSuffix(filepath.Base(requestPayload.Input), filepath.Ext(requestPayload.Input)) + "-shards.tar"
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", downloadName))
+	err = export.WriteShardsTar(s.streamWriter(w, r), tempDir, manifest)
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // the client gets a truncated archive, which tar readers reject since the end marker is missing
+	}
+	fmt.Println("Successfully sent sharded export.")
+}
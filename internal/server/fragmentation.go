@@ -0,0 +1,37 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// handleFragmentationRequest handles requests for the free pages of a database and how much compacting it would reclaim
+func handleFragmentationRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload RequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil {
+		sendBadRequest(w, err)
+		return
+	}
+
+	// do actual work
+	report, err := bboltdump.GetFragmentationReport(requestPayload.Input)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	sendValue(w, r, report)
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// HistogramRequestPayload is a struct representing the expected request payload of the histogram endpoint
+type HistogramRequestPayload struct {
+	Input  string `json:"input"`  // path to db file
+	Bucket string `json:"bucket"` // bucket to count along with its nested buckets, all buckets if empty
+}
+
+// handleHistogramRequest handles requests for the key length and value size histograms of the buckets of a database
+func handleHistogramRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload HistogramRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil {
+		sendBadRequest(w, err)
+		return
+	}
+
+	// do actual work
+	result, err := bboltdump.GetHistograms(requestPayload.Input, requestPayload.Bucket)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	sendValue(w, r, result)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/audit"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/importer"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// ImportRequestPayload is a struct representing the expected request payload of the import endpoint
+type ImportRequestPayload struct {
+	Input       string  `json:"input"`       // path to the bbolt db file to import into, created if it does not exist
+	Bucket      string  `json:"bucket"`      // bucket to import into, created if it does not exist
+	Format      string  `json:"format"`      // format of the source database, "leveldb" or "badger"
+	Source      string  `json:"source"`      // path to the directory of the source database
+	FillPercent float64 `json:"fillPercent"` // Bucket.FillPercent of the writes, 0 keeps bolt's default
+	DryRun      bool    `json:"dryRun"`      // roll every batch back and only report what would change
+}
+
+// handleImportRequest handles requests that load a LevelDB or Badger database into a bbolt database
+func handleImportRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload ImportRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Input == "" || requestPayload.Bucket == "" || requestPayload.Source == "" {
+		sendBadRequest(w, err)
+		return
+	}
+	if !bboltdump.IsValidFillPercent(requestPayload.FillPercent) {
+		http.Error(w, "Bad Request: fillPercent must be between 0.1 and 1.0", http.StatusBadRequest)
+		return
+	}
+	if !importer.IsSupported(requestPayload.Format) {
+		http.Error(w, fmt.Sprintf("Bad Request: format must be one of %v", importer.SupportedFormats()), http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	importResult, err := importer.Import(requestPayload.Input, requestPayload.Bucket, requestPayload.Format, requestPayload.Source, requestPayload.FillPercent, requestPayload.DryRun, audit.Who(r))
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+	resultBytes, err := json.Marshal(importResult)
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+
+	sendResult(w, r, resultBytes)
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bytes"
+)
+
+// indenter indents JSON that arrives in pieces the way json.Indent indents a whole document, so streamed results can be pretty printed too.
+type indenter struct {
+	depth    int
+	inString bool
+	escaped  bool // the previous byte of the string was a backslash
+	opened   bool // an object or array was just opened, its newline waits until it is known not to be empty
+}
+
+// indent returns the indented form of the next piece p of the document.
+func (in *indenter) indent(p []byte) []byte {
+	var out bytes.Buffer
+	for _, c := range p {
+		if in.inString {
+			out.WriteByte(c)
+			if in.escaped {
+				in.escaped = false
+			} else if c == '\\' {
+				in.escaped = true
+			} else if c == '"' {
+				in.inString = false
+			}
+			continue
+		}
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			continue
+		}
+
+		if in.opened {
+			in.opened = false
+			if c == '}' || c == ']' {
+				in.depth--
+				out.WriteByte(c) // empty objects and arrays stay on one line
+				continue
+			}
+			in.newline(&out)
+		}
+		switch c {
+		case '{', '[':
+			out.WriteByte(c)
+			in.depth++
+			in.opened = true
+		case '}', ']':
+			in.depth--
+			in.newline(&out)
+			out.WriteByte(c)
+		case ',':
+			out.WriteByte(c)
+			in.newline(&out)
+		case ':':
+			out.WriteString(": ")
+		case '"':
+			out.WriteByte(c)
+			in.inString = true
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
+
+// newline starts a new line at the current depth.
+func (in *indenter) newline(out *bytes.Buffer) {
+	out.WriteByte('\n')
+	for i := 0; i < in.depth; i++ {
+		out.WriteString("  ")
+	}
+}
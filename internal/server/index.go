@@ -0,0 +1,172 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// IndexRequestPayload is a struct representing the expected request payload of the endpoints that build and drop indexes
+type IndexRequestPayload struct {
+	Input  string `json:"input"`  // path to db file
+	Index  string `json:"index"`  // name of the index
+	Bucket string `json:"bucket"` // bucket whose entries are indexed, only needed to build
+	Field  string `json:"field"`  // path of the JSON field of the values to index like "email", the whole value if empty
+}
+
+// handleIndexListRequest handles requests that list the indexes of a database
+func handleIndexListRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload RequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Input == "" {
+		sendBadRequest(w, err)
+		return
+	}
+
+	// do actual work
+	result, err := bboltdump.ListIndexes(requestPayload.Input)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	sendValue(w, r, result)
+}
+
+// handleIndexBuildRequest handles requests that build an index of a bucket, or build it again
+func handleIndexBuildRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload IndexRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Input == "" || requestPayload.Index == "" || requestPayload.Bucket == "" {
+		sendBadRequest(w, err)
+		return
+	}
+	if _, err = bboltdump.ParseRefPath(requestPayload.Field); err != nil {
+		http.Error(w, "Bad Request: invalid field", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	result, err := bboltdump.BuildIndex(requestPayload.Input, bboltdump.IndexDefinition{Name: requestPayload.Index, Bucket: requestPayload.Bucket, Field: requestPayload.Field})
+	if errors.Is(err, bboltdump.ErrBucketNotFound) {
+		http.Error(w, "Unknown bucket", http.StatusNotFound)
+		return
+	}
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		http.Error(w, "Failed to build index", http.StatusInternalServerError)
+		return
+	}
+
+	sendValue(w, r, result)
+}
+
+// handleIndexDropRequest handles requests that remove an index
+func handleIndexDropRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload IndexRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Input == "" || requestPayload.Index == "" {
+		sendBadRequest(w, err)
+		return
+	}
+
+	// do actual work
+	err = bboltdump.DropIndex(requestPayload.Input, requestPayload.Index)
+	if errors.Is(err, bboltdump.ErrIndexNotFound) {
+		http.Error(w, "Unknown index", http.StatusNotFound)
+		return
+	}
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		http.Error(w, "Failed to drop index", http.StatusInternalServerError)
+		return
+	}
+
+	sendResult(w, r, []byte("{}"))
+}
+
+// LookupRequestPayload is a struct representing the expected request payload of the lookup endpoint
+type LookupRequestPayload struct {
+	Input  string `json:"input"`  // path to db file
+	Index  string `json:"index"`  // name of the index to look the value up in
+	Value  string `json:"value"`  // value of the indexed field, numbers are written like in JSON
+	Limit  int    `json:"limit"`  // max amount of entries to return, defaults to bboltdump.DefaultPageLimit
+	Values string `json:"values"` // "raw" (default), "type" or "sha256" to get the size and content type or digest of each value instead
+	Keys   string `json:"keys"`   // "text" (default) to get readable keys as text, "hex" to get every key hex encoded only or "time" to also get timestamp keys as RFC 3339 times
+}
+
+// handleLookupRequest handles requests for the entries whose indexed field has a value
+func handleLookupRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload LookupRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Index == "" || requestPayload.Limit < 0 || requestPayload.Limit > bboltdump.MaxPageLimit {
+		sendBadRequest(w, err)
+		return
+	}
+	if requestPayload.Limit == 0 {
+		requestPayload.Limit = bboltdump.DefaultPageLimit
+	}
+	if !bboltdump.IsValidValueMode(requestPayload.Values) {
+		http.Error(w, "Bad Request: values must be raw, type or sha256", http.StatusBadRequest)
+		return
+	}
+	if !bboltdump.IsValidKeyMode(requestPayload.Keys) {
+		http.Error(w, "Bad Request: keys must be hex, time or text", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	result, err := bboltdump.LookupIndex(requestPayload.Input, requestPayload.Index, []byte(requestPayload.Value), requestPayload.Limit, requestPayload.Values, requestPayload.Keys)
+	if errors.Is(err, bboltdump.ErrIndexNotFound) {
+		http.Error(w, "Unknown index", http.StatusNotFound)
+		return
+	}
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	sendValue(w, r, result)
+}
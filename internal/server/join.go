@@ -0,0 +1,68 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// JoinRequestPayload is a struct representing the expected request payload of the join endpoint
+type JoinRequestPayload struct {
+	Input      string `json:"input"`      // path to db file
+	Bucket     string `json:"bucket"`     // bucket whose entries refer to keys of joinBucket
+	JoinBucket string `json:"joinBucket"` // bucket the referenced keys are looked up in
+	Ref        string `json:"ref"`        // path of the JSON field of each value that holds the referenced key like "user.id", the whole value is the key if empty
+	Limit      int    `json:"limit"`      // max amount of entries of bucket to return, defaults to bboltdump.DefaultPageLimit
+	Cursor     string `json:"cursor"`     // nextCursor of the previous page, empty for the first page
+	Values     string `json:"values"`     // "raw" (default), "type" or "sha256" to get the size and content type or digest of each value instead
+	Keys       string `json:"keys"`       // "text" (default) to get readable keys as text, "hex" to get every key hex encoded only or "time" to also get timestamp keys as RFC 3339 times
+}
+
+// handleJoinRequest handles requests for a page of a bucket joined with the entries its values refer to in another bucket
+func handleJoinRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload JoinRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Bucket == "" || requestPayload.JoinBucket == "" || requestPayload.Limit < 0 || requestPayload.Limit > bboltdump.MaxPageLimit {
+		sendBadRequest(w, err)
+		return
+	}
+	if requestPayload.Limit == 0 {
+		requestPayload.Limit = bboltdump.DefaultPageLimit
+	}
+	if _, err = bboltdump.ParseRefPath(requestPayload.Ref); err != nil {
+		http.Error(w, "Bad Request: invalid ref", http.StatusBadRequest)
+		return
+	}
+	if _, err = bboltdump.DecodeCursor(requestPayload.Cursor); err != nil {
+		http.Error(w, "Bad Request: invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if !bboltdump.IsValidValueMode(requestPayload.Values) {
+		http.Error(w, "Bad Request: values must be raw, type or sha256", http.StatusBadRequest)
+		return
+	}
+	if !bboltdump.IsValidKeyMode(requestPayload.Keys) {
+		http.Error(w, "Bad Request: keys must be hex, time or text", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	result, err := bboltdump.JoinBuckets(requestPayload.Input, requestPayload.Bucket, requestPayload.JoinBucket, requestPayload.Ref, requestPayload.Limit, requestPayload.Cursor, requestPayload.Values, requestPayload.Keys)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	sendValue(w, r, result)
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// JournalRequestPayload is a struct representing the expected request payload of the journal endpoint, GET requests pass the same fields as query parameters
+type JournalRequestPayload struct {
+	Input string `json:"input"` // path to db file
+	After uint64 `json:"after"` // seq of the last entry already read, 0 starts at the first entry
+	Limit int    `json:"limit"` // most entries returned, defaults to bboltdump.DefaultPageLimit
+}
+
+// handleJournalRequest handles requests for a range of entries of the journal of a database
+func handleJournalRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST and GET requests
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed. Please use POST or GET.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request, a GET request follows a Link header and names the range in the query
+	var requestPayload JournalRequestPayload
+	var err error
+	if r.Method == http.MethodGet {
+		requestPayload, err = journalRequestFromQuery(r.URL.Query())
+	} else {
+		err = decodePayload(w, r, &requestPayload)
+	}
+	if err != nil || requestPayload.Input == "" || requestPayload.Limit < 0 || requestPayload.Limit > bboltdump.MaxPageLimit {
+		sendBadRequest(w, err)
+		return
+	}
+
+	// do actual work
+	journalPage, err := bboltdump.ReadJournal(requestPayload.Input, requestPayload.After, requestPayload.Limit)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	setJournalLinks(w, r, requestPayload, journalPage)
+	sendValue(w, r, journalPage)
+}
+
+// handleJournalVerifyRequest handles requests to check the hash chain of the journal of a database
+func handleJournalVerifyRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload RequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Input == "" {
+		sendBadRequest(w, err)
+		return
+	}
+
+	// do actual work
+	verification, err := bboltdump.VerifyJournal(requestPayload.Input)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	sendValue(w, r, verification)
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// KeyspaceRequestPayload is a struct representing the expected request payload of the keyspace endpoint
+type KeyspaceRequestPayload struct {
+	Input       string `json:"input"`       // path to db file
+	Bucket      string `json:"bucket"`      // bucket to aggregate along with its nested buckets, all buckets if empty
+	Separator   string `json:"separator"`   // splits keys into segments, defaults to bboltdump.DefaultKeyspaceSeparator
+	Depth       int    `json:"depth"`       // levels of key segments below each bucket, defaults to bboltdump.DefaultKeyspaceDepth
+	MaxChildren int    `json:"maxChildren"` // children per node, defaults to bboltdump.DefaultKeyspaceMaxChildren
+	Keys        string `json:"keys"`        // "text" (default) to get readable key segments as text or "hex" to get every segment hex encoded
+}
+
+// handleKeyspaceRequest handles requests for the keyspace of a database aggregated into a tree of buckets and key prefixes, without any values
+func handleKeyspaceRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload KeyspaceRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Depth < 0 || requestPayload.Depth > bboltdump.MaxKeyspaceDepth || requestPayload.MaxChildren < 0 || requestPayload.MaxChildren > bboltdump.MaxKeyspaceMaxChildren {
+		sendBadRequest(w, err)
+		return
+	}
+	if requestPayload.Keys != "" && requestPayload.Keys != bboltdump.KeysText && requestPayload.Keys != bboltdump.KeysHex {
+		http.Error(w, "Bad Request: keys must be hex or text", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	result, err := bboltdump.GetKeyspaceTree(r.Context(), requestPayload.Input, requestPayload.Bucket, bboltdump.KeyspaceOptions{
+		Separator:   requestPayload.Separator,
+		Depth:       requestPayload.Depth,
+		MaxChildren: requestPayload.MaxChildren,
+		Keys:        requestPayload.Keys,
+	})
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	sendValue(w, r, result)
+}
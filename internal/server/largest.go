@@ -0,0 +1,52 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// LargestRequestPayload is a struct representing the expected request payload of the largest values endpoint
+type LargestRequestPayload struct {
+	Input  string `json:"input"`  // path to db file
+	Bucket string `json:"bucket"` // bucket to search along with its nested buckets, all buckets if empty
+	Count  int    `json:"count"`  // amount of values to return, defaults to bboltdump.DefaultPageLimit
+	Keys   string `json:"keys"`   // "text" (default) to get readable keys as text, "hex" to get every key hex encoded only or "time" to also get timestamp keys as RFC 3339 times
+}
+
+// handleLargestRequest handles requests for the largest values of a database
+func handleLargestRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload LargestRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Count < 0 || requestPayload.Count > bboltdump.MaxPageLimit {
+		sendBadRequest(w, err)
+		return
+	}
+	if requestPayload.Count == 0 {
+		requestPayload.Count = bboltdump.DefaultPageLimit
+	}
+	if !bboltdump.IsValidKeyMode(requestPayload.Keys) {
+		http.Error(w, "Bad Request: keys must be hex, time or text", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	result, err := bboltdump.GetLargestValues(requestPayload.Input, requestPayload.Bucket, requestPayload.Count, requestPayload.Keys)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	sendValue(w, r, result)
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/tenancy"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// clientPath returns dbPath as the client of r sent it, the tenancy moved the paths of tenants below their root.
+func clientPath(r *http.Request, dbPath string) string {
+	if tenant := tenancy.Of(r); tenant != nil {
+		return tenancy.ClientPath(tenant, dbPath)
+	}
+	return dbPath
+}
+
+// link returns the Link header value of the page at path with query as rel. The raw mode and the counting of r carry over, so a client following the links gets every page in the same shape.
+func link(r *http.Request, path string, query url.Values, rel string) string {
+	for _, name := range []string{"raw", "count"} {
+		if r.URL.Query().Has(name) {
+			query.Set(name, r.URL.Query().Get(name))
+		}
+	}
+	return "<" + path + "?" + query.Encode() + ">; rel=\"" + rel + "\""
+}
+
+// pageQuery returns the query parameters of a GET request to the page endpoint that asks for the page of requestPayload following cursor.
+func pageQuery(r *http.Request, requestPayload PageRequestPayload, cursor string) url.Values {
+	query := url.Values{}
+	query.Set("input", clientPath(r, requestPayload.Input))
+	query.Set("bucket", requestPayload.Bucket)
+	query.Set("limit", strconv.Itoa(requestPayload.Limit))
+	for name, value := range map[string]string{"cursor": cursor, "order": requestPayload.Order, "values": requestPayload.Values, "keys": requestPayload.Keys} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	return query
+}
+
+// pageRequestFromQuery returns the payload of a GET request to the page endpoint, which passes the fields of PageRequestPayload as query parameters.
+func pageRequestFromQuery(query url.Values) (PageRequestPayload, error) {
+	requestPayload := PageRequestPayload{
+		Input:  query.Get("input"),
+		Bucket: query.Get("bucket"),
+		Cursor: query.Get("cursor"),
+		Order:  query.Get("order"),
+		Values: query.Get("values"),
+		Keys:   query.Get("keys"),
+	}
+	var err error
+	if query.Has("limit") {
+		requestPayload.Limit, err = strconv.Atoi(query.Get("limit"))
+	}
+	return requestPayload, err
+}
+
+// countRequested reports whether the client asked for the X-Total-Count header with ?count=true, counting walks over every key of the bucket.
+func countRequested(r *http.Request) bool {
+	return r.URL.Query().Get("count") == "true"
+}
+
+// setPageLinks sets the Link header with the first, previous and next page for a page of entries of the page endpoint at pagePath, requested with requestPayload, and the X-Total-Count header if pageLinks has a total.
+func setPageLinks(w http.ResponseWriter, r *http.Request, pagePath string, requestPayload PageRequestPayload, nextCursor string, pageLinks bboltdump.PageLinks) {
+	links := []string{link(r, pagePath, pageQuery(r, requestPayload, ""), "first")}
+	if pageLinks.HasPrev {
+		links = append(links, link(r, pagePath, pageQuery(r, requestPayload, pageLinks.PrevCursor), "prev"))
+	}
+	if nextCursor != "" {
+		links = append(links, link(r, pagePath, pageQuery(r, requestPayload, nextCursor), "next"))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+	if pageLinks.Total >= 0 {
+		w.Header().Set("X-Total-Count", strconv.Itoa(pageLinks.Total))
+	}
+}
+
+// pagePath returns the path of the page endpoint next to the endpoint r was sent to, like "/bbolt/page" for "/bbolt/seek".
+func pagePath(r *http.Request) string {
+	return r.URL.Path[:strings.LastIndex(r.URL.Path, "/")] + "/page"
+}
+
+// journalQuery returns the query parameters of a GET request to the journal endpoint that asks for limit entries after the entry with seq after.
+func journalQuery(r *http.Request, input string, after uint64, limit int) url.Values {
+	query := url.Values{}
+	query.Set("input", clientPath(r, input))
+	query.Set("after", strconv.FormatUint(after, 10))
+	query.Set("limit", strconv.Itoa(limit))
+	return query
+}
+
+// journalRequestFromQuery returns the payload of a GET request to the journal endpoint, which passes the fields of JournalRequestPayload as query parameters.
+func journalRequestFromQuery(query url.Values) (JournalRequestPayload, error) {
+	requestPayload := JournalRequestPayload{Input: query.Get("input")}
+	var err error
+	if query.Has("after") {
+		requestPayload.After, err = strconv.ParseUint(query.Get("after"), 10, 64)
+	}
+	if err == nil && query.Has("limit") {
+		requestPayload.Limit, err = strconv.Atoi(query.Get("limit"))
+	}
+	return requestPayload, err
+}
+
+// setJournalLinks sets the Link header with the first, previous and next range and the X-Total-Count header for journalPage, requested with requestPayload.
+func setJournalLinks(w http.ResponseWriter, r *http.Request, requestPayload JournalRequestPayload, journalPage bboltdump.JournalPage) {
+	limit := requestPayload.Limit
+	if limit == 0 {
+		limit = bboltdump.DefaultPageLimit
+	}
+	links := []string{link(r, r.URL.Path, journalQuery(r, requestPayload.Input, 0, limit), "first")}
+	if requestPayload.After > 0 {
+		links = append(links, link(r, r.URL.Path, journalQuery(r, requestPayload.Input, requestPayload.After-min(requestPayload.After, uint64(limit)), limit), "prev"))
+	}
+	if journalPage.NextAfter != 0 {
+		links = append(links, link(r, r.URL.Path, journalQuery(r, requestPayload.Input, journalPage.NextAfter, limit), "next"))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+	w.Header().Set("X-Total-Count", strconv.FormatUint(journalPage.Total, 10))
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/pin"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// sendLocked answers with 423 Locked and who likely holds the lock if err is a bboltdump.LockedError, and reports whether it did.
+// Reads of a pin that expired are answered with 410 Gone, writes to read-only databases with 403 Forbidden and dumps beyond the memory limit with 503 Service Unavailable the same way, every handler passes the error of opening its database here.
+func sendLocked(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, bboltdump.ErrReadOnlyDb) {
+		http.Error(w, "Forbidden: the database is read-only", http.StatusForbidden)
+		return true
+	}
+	if errors.Is(err, pin.ErrUnknownPin) {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "Gone: the pin does not exist or expired", http.StatusGone)
+		return true
+	}
+	if errors.Is(err, bboltdump.ErrMemoryLimit) {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "Service Unavailable: the dump needs more memory than the server allows, try again later or dump without keySeparator", http.StatusServiceUnavailable)
+		return true
+	}
+	var lockedError *bboltdump.LockedError
+	if !errors.As(err, &lockedError) {
+		return false
+	}
+	fmt.Println("ERROR:", err)
+	w.Header().Del("Content-Disposition") // set by the exports before they open the database
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedError.Waited.Seconds()))))
+	http.Error(w, strings.TrimSpace(lockedError.Error()), http.StatusLocked)
+	return true
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/audit"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// MergeRequestPayload is a struct representing the expected request payload of the merge endpoint
+type MergeRequestPayload struct {
+	Input          string            `json:"input"`          // path to the db file to merge into, created if it does not exist
+	Other          string            `json:"other"`          // path to the db file to merge from
+	Buckets        []string          `json:"buckets"`        // buckets of other to merge along with their nested buckets, all buckets if empty
+	Policy         string            `json:"policy"`         // what to do with keys that have different values in both databases, "skip" (default), "overwrite" or "fail"
+	BucketPolicies map[string]string `json:"bucketPolicies"` // policy by bucket path, for the bucket and the buckets nested in it
+	DryRun         bool              `json:"dryRun"`         // roll every batch back and only report what would change
+}
+
+// handleMergeRequest handles requests that merge the buckets of one database into another, like per-device databases into one
+func handleMergeRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload MergeRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil {
+		sendBadRequest(w, err)
+		return
+	}
+	if requestPayload.Input == requestPayload.Other {
+		http.Error(w, "Bad Request: input and other must be different databases", http.StatusBadRequest)
+		return
+	}
+	policies := []string{requestPayload.Policy}
+	for _, policy := range requestPayload.BucketPolicies {
+		policies = append(policies, policy)
+	}
+	for _, policy := range policies {
+		if policy != "" && !slices.Contains(bboltdump.MergePolicies, policy) {
+			http.Error(w, fmt.Sprintf("Bad Request: policies must be one of %v", bboltdump.MergePolicies), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// do actual work
+	mergeResult, err := bboltdump.Merge(requestPayload.Input, requestPayload.Other, bboltdump.MergeOptions{
+		Buckets:        requestPayload.Buckets,
+		Policy:         requestPayload.Policy,
+		BucketPolicies: requestPayload.BucketPolicies,
+		DryRun:         requestPayload.DryRun,
+		Actor:          audit.Who(r),
+	})
+	if conflictError, conflict := err.(*bboltdump.MergeConflictError); conflict {
+		http.Error(w, fmt.Sprintf("Key %x of bucket %v has different values in both databases, merge it with another policy", conflictError.Key, conflictError.Bucket), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, bboltdump.ErrBucketNotFound) {
+		http.Error(w, "Unknown bucket", http.StatusNotFound)
+		return
+	}
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		http.Error(w, "Failed to merge databases", http.StatusInternalServerError)
+		return
+	}
+
+	sendValue(w, r, mergeResult)
+}
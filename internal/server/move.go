@@ -0,0 +1,136 @@
+package server
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/audit"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// MoveRequestPayload is a struct representing the expected request payload of the move endpoint
+type MoveRequestPayload struct {
+	Input     string `json:"input"`     // path to db file
+	Bucket    string `json:"bucket"`    // bucket the key is in
+	Key       string `json:"key"`       // hex encoded key to move
+	ToBucket  string `json:"toBucket"`  // bucket to move the key to, defaults to bucket
+	ToKey     string `json:"toKey"`     // hex encoded new key, defaults to key
+	Overwrite bool   `json:"overwrite"` // replace the target key if it exists
+	DryRun    bool   `json:"dryRun"`    // roll the move back and only report what would change
+}
+
+// PreviewResult is a struct representing the response of the move and rename endpoints, which is empty unless they were dry runs
+type PreviewResult struct {
+	Preview *bboltdump.Preview `json:"preview,omitempty"` // what the dry run would have changed
+}
+
+// handleMoveRequest handles requests to rename a key or move it to another bucket
+func handleMoveRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload MoveRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Input == "" || requestPayload.Bucket == "" {
+		sendBadRequest(w, err)
+		return
+	}
+	if requestPayload.ToBucket == "" {
+		requestPayload.ToBucket = requestPayload.Bucket
+	}
+	if requestPayload.ToKey == "" {
+		requestPayload.ToKey = requestPayload.Key
+	}
+	keyBytes, err := hex.DecodeString(requestPayload.Key)
+	if err != nil || len(keyBytes) == 0 {
+		http.Error(w, "Bad Request: key must be hex encoded", http.StatusBadRequest)
+		return
+	}
+	toKeyBytes, err := hex.DecodeString(requestPayload.ToKey)
+	if err != nil {
+		http.Error(w, "Bad Request: toKey must be hex encoded", http.StatusBadRequest)
+		return
+	}
+	if requestPayload.ToBucket == requestPayload.Bucket && requestPayload.ToKey == requestPayload.Key {
+		http.Error(w, "Bad Request: toBucket or toKey must differ from bucket and key", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	preview, err := bboltdump.MoveKey(requestPayload.Input, requestPayload.Bucket, keyBytes, requestPayload.ToBucket, toKeyBytes, requestPayload.Overwrite, requestPayload.DryRun, audit.Who(r))
+	if errors.Is(err, bboltdump.ErrKeyNotFound) {
+		http.Error(w, "Unknown key", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, bboltdump.ErrKeyExists) {
+		http.Error(w, "Target key already exists, set overwrite to replace it", http.StatusConflict)
+		return
+	}
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		http.Error(w, "Failed to move key", http.StatusInternalServerError)
+		return
+	}
+
+	sendValue(w, r, PreviewResult{Preview: preview})
+}
+
+// RenameBucketRequestPayload is a struct representing the expected request payload of the bucket rename endpoint
+type RenameBucketRequestPayload struct {
+	Input    string `json:"input"`    // path to db file
+	Bucket   string `json:"bucket"`   // bucket to rename
+	ToBucket string `json:"toBucket"` // new name or path of the bucket
+	DryRun   bool   `json:"dryRun"`   // roll the rename back and only report what would change
+}
+
+// handleRenameBucketRequest handles requests to rename a bucket or move it below another bucket
+func handleRenameBucketRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload RenameBucketRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Input == "" || requestPayload.Bucket == "" || requestPayload.ToBucket == "" {
+		sendBadRequest(w, err)
+		return
+	}
+	if requestPayload.ToBucket == requestPayload.Bucket || strings.HasPrefix(requestPayload.ToBucket, requestPayload.Bucket+"/") {
+		http.Error(w, "Bad Request: a bucket cannot be renamed to itself or into one of its nested buckets", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	preview, err := bboltdump.RenameBucket(requestPayload.Input, requestPayload.Bucket, requestPayload.ToBucket, requestPayload.DryRun, audit.Who(r))
+	if errors.Is(err, bboltdump.ErrBucketNotFound) {
+		http.Error(w, "Unknown bucket", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, bboltdump.ErrBucketExists) {
+		http.Error(w, "Target bucket already exists", http.StatusConflict)
+		return
+	}
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		http.Error(w, "Failed to rename bucket", http.StatusInternalServerError)
+		return
+	}
+
+	sendValue(w, r, PreviewResult{Preview: preview})
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// PageRequestPayload is a struct representing the expected request payload of the page endpoint, GET requests pass the same fields as query parameters
+type PageRequestPayload struct {
+	Input  string `json:"input"`  // path to db file
+	Bucket string `json:"bucket"` // bucket to read from
+	Limit  int    `json:"limit"`  // max amount of entries to return, defaults to bboltdump.DefaultPageLimit
+	Cursor string `json:"cursor"` // nextCursor of the previous page, empty for the first page
+	Order  string `json:"order"`  // "asc" (default) or "desc"
+	Values string `json:"values"` // "raw" (default), "type" or "sha256" to get the size and content type or digest of each value instead
+	Keys   string `json:"keys"`   // "text" (default) to get readable keys as text, "hex" to get every key hex encoded only or "time" to also get timestamp keys as RFC 3339 times
+}
+
+// handlePageRequest handles requests for a single page of a bucket
+func handlePageRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST and GET requests
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed. Please use POST or GET.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request, a GET request follows a Link header and names the page in the query
+	var requestPayload PageRequestPayload
+	var err error
+	if r.Method == http.MethodGet {
+		requestPayload, err = pageRequestFromQuery(r.URL.Query())
+	} else {
+		err = decodePayload(w, r, &requestPayload)
+	}
+	if err != nil || requestPayload.Bucket == "" || requestPayload.Limit < 0 || requestPayload.Limit > bboltdump.MaxPageLimit {
+		sendBadRequest(w, err)
+		return
+	}
+	if requestPayload.Limit == 0 {
+		requestPayload.Limit = bboltdump.DefaultPageLimit
+	}
+	if !bboltdump.IsValidOrder(requestPayload.Order) {
+		http.Error(w, "Bad Request: order must be asc or desc", http.StatusBadRequest)
+		return
+	}
+	if _, err = bboltdump.DecodeCursor(requestPayload.Cursor); err != nil {
+		http.Error(w, "Bad Request: invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	if !bboltdump.IsValidValueMode(requestPayload.Values) {
+		http.Error(w, "Bad Request: values must be raw, type or sha256", http.StatusBadRequest)
+		return
+	}
+	if !bboltdump.IsValidKeyMode(requestPayload.Keys) {
+		http.Error(w, "Bad Request: keys must be hex, time or text", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	result, pageLinks, err := bboltdump.GetBucketPageWithLinks(r.Context(), requestPayload.Input, requestPayload.Bucket, requestPayload.Limit, requestPayload.Cursor, requestPayload.Order, requestPayload.Values, requestPayload.Keys, countRequested(r))
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	setPageLinks(w, r, r.URL.Path, requestPayload, result.NextCursor, pageLinks)
+	sendValue(w, r, result)
+}
+
+// SeekRequestPayload is a struct representing the expected request payload of the seek endpoint
+type SeekRequestPayload struct {
+	Input  string `json:"input"`  // path to db file
+	Bucket string `json:"bucket"` // bucket to seek in
+	Key    string `json:"key"`    // hex encoded key to jump to
+	Count  int    `json:"count"`  // amount of entries to return after the one found at key, defaults to bboltdump.DefaultPageLimit
+	Values string `json:"values"` // "raw" (default), "type" or "sha256" to get the size and content type or digest of each value instead
+	Keys   string `json:"keys"`   // "text" (default) to get readable keys as text, "hex" to get every key hex encoded only or "time" to also get timestamp keys as RFC 3339 times
+}
+
+// handleSeekRequest handles requests that jump to a key of a bucket
+func handleSeekRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload SeekRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Bucket == "" || requestPayload.Count < 0 || requestPayload.Count > bboltdump.MaxPageLimit {
+		sendBadRequest(w, err)
+		return
+	}
+	if requestPayload.Count == 0 {
+		requestPayload.Count = bboltdump.DefaultPageLimit
+	}
+	seekKey, err := hex.DecodeString(requestPayload.Key)
+	if err != nil {
+		http.Error(w, "Bad Request: key must be hex encoded", http.StatusBadRequest)
+		return
+	}
+
+	if !bboltdump.IsValidValueMode(requestPayload.Values) {
+		http.Error(w, "Bad Request: values must be raw, type or sha256", http.StatusBadRequest)
+		return
+	}
+	if !bboltdump.IsValidKeyMode(requestPayload.Keys) {
+		http.Error(w, "Bad Request: keys must be hex, time or text", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	result, pageLinks, err := bboltdump.SeekBucketWithLinks(r.Context(), requestPayload.Input, requestPayload.Bucket, seekKey, requestPayload.Count, requestPayload.Values, requestPayload.Keys, countRequested(r))
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	// the entries after the ones found continue on the page endpoint
+	setPageLinks(w, r, pagePath(r), PageRequestPayload{Input: requestPayload.Input, Bucket: requestPayload.Bucket, Limit: requestPayload.Count, Values: requestPayload.Values, Keys: requestPayload.Keys}, result.NextCursor, pageLinks)
+	sendValue(w, r, result)
+}
+
+// TailRequestPayload is a struct representing the expected request payload of the tail endpoint
+type TailRequestPayload struct {
+	Input  string `json:"input"`  // path to db file
+	Bucket string `json:"bucket"` // bucket to read from
+	Count  int    `json:"count"`  // amount of entries to return, defaults to bboltdump.DefaultPageLimit
+	Values string `json:"values"` // "raw" (default), "type" or "sha256" to get the size and content type or digest of each value instead
+	Keys   string `json:"keys"`   // "text" (default) to get readable keys as text, "hex" to get every key hex encoded only or "time" to also get timestamp keys as RFC 3339 times
+}
+
+// handleTailRequest handles requests for the last entries of a bucket
+func handleTailRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload TailRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Bucket == "" || requestPayload.Count < 0 || requestPayload.Count > bboltdump.MaxPageLimit {
+		sendBadRequest(w, err)
+		return
+	}
+	if requestPayload.Count == 0 {
+		requestPayload.Count = bboltdump.DefaultPageLimit
+	}
+
+	if !bboltdump.IsValidValueMode(requestPayload.Values) {
+		http.Error(w, "Bad Request: values must be raw, type or sha256", http.StatusBadRequest)
+		return
+	}
+	if !bboltdump.IsValidKeyMode(requestPayload.Keys) {
+		http.Error(w, "Bad Request: keys must be hex, time or text", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	result, pageLinks, err := bboltdump.GetBucketPageWithLinks(r.Context(), requestPayload.Input, requestPayload.Bucket, requestPayload.Count, "", bboltdump.OrderDesc, requestPayload.Values, requestPayload.Keys, countRequested(r))
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	// the tail is the first page of the bucket walked backwards
+	setPageLinks(w, r, pagePath(r), PageRequestPayload{Input: requestPayload.Input, Bucket: requestPayload.Bucket, Limit: requestPayload.Count, Order: bboltdump.OrderDesc, Values: requestPayload.Values, Keys: requestPayload.Keys}, result.NextCursor, pageLinks)
+	sendValue(w, r, result)
+}
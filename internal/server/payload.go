@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	bolt "go.etcd.io/bbolt"
+)
+
+// maxPathLength is the longest db file path a payload may name.
+const maxPathLength = 4096
+
+// limits of the payload fields, by their JSON name
+var pathFields = []string{"input", "other", "source"}                               // paths to db files, they must not be empty unless tagged payload:"optional"
+var bucketFields = []string{"bucket", "toBucket", "joinBucket", "buckets", "index"} // names or paths of buckets, indexes are buckets too
+var keyFields = []string{"key", "toKey", "prefix", "start", "end"}                  // encoded keys, hex takes two characters per byte
+
+// payloadError is returned by decodePayload when a payload is malformed, its message says which field is wrong and is sent to the client.
+type payloadError struct {
+	message string
+}
+
+func (e *payloadError) Error() string {
+	return e.message
+}
+
+type payloadLimitKey struct{}
+
+// withPayloadLimit returns r with the largest payload decodePayload reads from it.
+func withPayloadLimit(r *http.Request, maxPayloadBytes int64) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), payloadLimitKey{}, maxPayloadBytes))
+}
+
+// decodePayload decodes the JSON payload of r into payload, which must be a pointer to a struct, and validates its fields.
+// Payloads larger than the limit of the server, with fields payload does not have, with more than one JSON value or with fields beyond their limits are refused, send the error with sendBadRequest.
+func decodePayload(w http.ResponseWriter, r *http.Request, payload any) error {
+	maxPayloadBytes, found := r.Context().Value(payloadLimitKey{}).(int64)
+	if !found {
+		maxPayloadBytes = config.DefaultMaxPayloadBytes
+	}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPayloadBytes))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(payload)
+	if err == nil && decoder.Decode(&json.RawMessage{}) != io.EOF {
+		return &payloadError{"payload must be a single JSON object"}
+	}
+	var maxBytesErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case err == nil:
+		return validatePayload(reflect.ValueOf(payload).Elem(), "")
+	case errors.As(err, &maxBytesErr):
+		return err
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return &payloadError{fmt.Sprintf("%v must be of type %v", typeErr.Field, typeErr.Type)}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return &payloadError{"payload is not valid JSON"}
+	case err == io.EOF:
+		return &payloadError{"payload must not be empty"}
+	}
+	// unknown fields are only reported by their message
+	return &payloadError{strings.TrimPrefix(err.Error(), "json: ")}
+}
+
+// validatePayload checks the fields of the struct v and of the structs it holds against their limits, prefix is the JSON path of v like "lookups[2].".
+func validatePayload(v reflect.Value, prefix string) error {
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		value := v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && value.Kind() == reflect.Struct {
+			err := validatePayload(value, prefix)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		err := validateField(value, prefix+name, name, field.Tag.Get("payload") == "optional")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateField checks value of the field with the JSON name name at the JSON path fieldPath against the limits for that name.
+func validateField(value reflect.Value, fieldPath string, name string, optional bool) error {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return nil
+		}
+		return validateField(value.Elem(), fieldPath, name, optional)
+	case reflect.Struct:
+		return validatePayload(value, fieldPath+".")
+	case reflect.Slice:
+		for i := range value.Len() {
+			err := validateField(value.Index(i), fmt.Sprintf("%v[%v]", fieldPath, i), name, false)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.String:
+	default:
+		return nil
+	}
+
+	s := value.String()
+	switch {
+	case slices.Contains(pathFields, name) && s == "" && !optional:
+		return &payloadError{fieldPath + " must not be empty"}
+	case slices.Contains(pathFields, name) && len(s) > maxPathLength:
+		return &payloadError{fmt.Sprintf("%v must be at most %v bytes long", fieldPath, maxPathLength)}
+	case slices.Contains(pathFields, name) && strings.ContainsRune(s, 0):
+		return &payloadError{fieldPath + " must not contain NUL bytes"}
+	case slices.Contains(bucketFields, name) && len(s) > bolt.MaxKeySize:
+		return &payloadError{fmt.Sprintf("%v must be at most %v bytes long", fieldPath, bolt.MaxKeySize)}
+	case slices.Contains(keyFields, name) && len(s) > 2*bolt.MaxKeySize:
+		return &payloadError{fmt.Sprintf("%v must be at most %v characters long, keys are at most %v bytes", fieldPath, 2*bolt.MaxKeySize, bolt.MaxKeySize)}
+	}
+	return nil
+}
+
+// sendBadRequest rejects a request whose payload could not be decoded, with what is wrong with it if decodePayload said so. Payloads over the limit get 413.
+func sendBadRequest(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	var payloadErr *payloadError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		http.Error(w, fmt.Sprintf("Request Entity Too Large: payloads are limited to %v bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+	case errors.As(err, &payloadErr):
+		http.Error(w, "Bad Request: "+payloadErr.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/pin"
+)
+
+// handlePinRequest handles requests that pin a database as it is now, so the following reads of the pin see the same data
+func handlePinRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload RequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Input == "" {
+		sendBadRequest(w, err)
+		return
+	}
+
+	// do actual work
+	result, err := pin.Create(requestPayload.Input)
+	if errors.Is(err, pin.ErrTooManyPins) {
+		http.Error(w, "Too many pins, release one first", http.StatusTooManyRequests)
+		return
+	}
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		http.Error(w, "Failed to pin database", http.StatusInternalServerError)
+		return
+	}
+
+	sendValue(w, r, result)
+}
+
+// handlePinReleaseRequest handles requests that release a pin before it expires
+func handlePinReleaseRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request, the pin is named by its path so access control checks it like the database it was taken of
+	var requestPayload RequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Input == "" {
+		sendBadRequest(w, err)
+		return
+	}
+	id, isPin := strings.CutPrefix(requestPayload.Input, pin.Scheme+"://")
+	if !isPin {
+		http.Error(w, "Bad Request: input must be the input of a pin", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	err = pin.Release(id)
+	if errors.Is(err, pin.ErrUnknownPin) {
+		http.Error(w, "Unknown pin", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		http.Error(w, "Failed to release pin", http.StatusInternalServerError)
+		return
+	}
+
+	sendResult(w, r, []byte("{}"))
+}
+
+// handlePinListRequest handles requests that list the pins the server holds
+func handlePinListRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sendValue(w, r, pin.List())
+}
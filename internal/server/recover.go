@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// startedRecorder is an http.ResponseWriter that remembers whether the response was started.
+type startedRecorder struct {
+	http.ResponseWriter
+	started bool
+}
+
+// WriteHeader remembers that the response was started and sends status.
+func (s *startedRecorder) WriteHeader(status int) {
+	s.started = true
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Write remembers that the response was started and sends p.
+func (s *startedRecorder) Write(p []byte) (int, error) {
+	s.started = true
+	return s.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the Flush of the wrapped writer, which streamed dumps rely on.
+func (s *startedRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Recover returns a handler that turns a panic of next into a 500 response and logs it with its stack, so a bug in one endpoint does not reset the connection without an answer.
+// If the response was already started the client cannot be told anymore, then the connection is aborted so the client sees that the response is incomplete.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &startedRecorder{ResponseWriter: w}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered) // the handler aborted the response on purpose
+			}
+			fmt.Printf("ERROR: Request to %v panicked: %v\n%s", r.URL.Path, recovered, debug.Stack())
+			if recorder.started {
+				panic(http.ErrAbortHandler)
+			}
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// Reloadable is an http.Handler whose handler can be replaced while it serves, like the middlewares built from a reloaded config.
+// Requests already being served finish with the handler they started with.
+type Reloadable struct {
+	handler atomic.Pointer[http.Handler]
+}
+
+// NewReloadable returns a Reloadable that passes requests to handler until Swap replaces it.
+func NewReloadable(handler http.Handler) *Reloadable {
+	reloadable := &Reloadable{}
+	reloadable.Swap(handler)
+	return reloadable
+}
+
+// Swap makes the following requests go to handler.
+func (h *Reloadable) Swap(handler http.Handler) {
+	h.handler.Store(&handler)
+}
+
+func (h *Reloadable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*h.handler.Load()).ServeHTTP(w, r)
+}
+
+// handleConfigReloadRequest handles requests that load the config file again without restarting the server
+func (s *Server) handleConfigReloadRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.reload == nil {
+		http.Error(w, "The server was started without a config file", http.StatusNotFound)
+		return
+	}
+
+	// do actual work, an invalid config file leaves the current config in place
+	err := s.reload()
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		http.Error(w, "Failed to reload config: "+strings.TrimSpace(err.Error()), http.StatusUnprocessableEntity)
+		return
+	}
+
+	sendResult(w, r, []byte("{}"))
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// RestoreRequestPayload is a struct representing the expected request payload of the restore endpoint
+type RestoreRequestPayload struct {
+	Db     string `json:"db"`     // name of the registered database to replace
+	Source string `json:"source"` // path to the db file to restore from
+}
+
+// handleRestoreRequest handles requests that replace a registered database by a copy, which is how another instance pushes its databases to this one.
+// The copy is either given as a path in a JSON payload or uploaded as the "backup" file of a multipart form that also carries the "db" name.
+func (s *Server) handleRestoreRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload RestoreRequestPayload
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err := r.ParseMultipartForm(maxUploadMemory)
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		requestPayload.Db = r.FormValue("db")
+		requestPayload.Source, err = saveUpload(r, "backup")
+		if err != nil {
+			http.Error(w, "Bad Request: missing backup file", http.StatusBadRequest)
+			return
+		}
+		defer removeUpload(requestPayload.Source)
+	} else {
+		err := decodePayload(w, r, &requestPayload)
+		if err != nil || requestPayload.Source == "" {
+			sendBadRequest(w, err)
+			return
+		}
+	}
+	registeredDb, found := s.config().LookupDb(requestPayload.Db)
+	if !found {
+		http.Error(w, "Unknown database", http.StatusNotFound)
+		return
+	}
+
+	// do actual work
+	restoreResult, err := bboltdump.Restore(registeredDb.Path, requestPayload.Source)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+	fmt.Println("Restored database", registeredDb.Name)
+
+	sendValue(w, r, restoreResult)
+}
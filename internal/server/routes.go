@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/backup"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/benchmark"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/compaction"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/importer"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/pin"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/snapshot"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/stats"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// Route is a struct representing an API endpoint. RegisterRoutes serves Routes and the client SDKs are generated from them, so both always agree.
+type Route struct {
+	Path     string // path below the API endpoint like "/page", or the whole path if Absolute. Path parameters are written like "{db}"
+	Absolute bool   // Path is not below the API endpoint
+	Name     string // name of the SDK method calling the endpoint
+	Summary  string // what the endpoint does, for the doc comment of the SDK method
+	Request  any    // zero value of the request payload, nil if the endpoint takes none
+	Result   any    // zero value of the result the ResponsePayload carries, nil if the endpoint sends a file instead
+	Writes   bool   // the endpoint modifies databases, it is refused while the package is read-only
+	Timeout  bool   // the endpoint stops reading once the context of the request is done, so it runs for at most maxRequestTimeout and takes ?timeout=. Others reject ?timeout=
+
+	handler func(s *Server, w http.ResponseWriter, r *http.Request)
+}
+
+// withoutServer turns a handler that needs no Server into a Route handler.
+func withoutServer(handler http.HandlerFunc) func(s *Server, w http.ResponseWriter, r *http.Request) {
+	return func(_ *Server, w http.ResponseWriter, r *http.Request) {
+		handler(w, r)
+	}
+}
+
+// Routes are all API endpoints of the server, every one of them only accepts POST requests.
+var Routes = []Route{
+	{Path: "", Name: "dump", Summary: "Dumps all buckets of a database.", Request: DumpRequestPayload{}, Result: bboltdump.BboltDb{}, Timeout: true, handler: (*Server).handleRequest},
+	{Path: "/page", Name: "page", Summary: "Returns one page of the entries of a bucket, with Link headers to the first, previous and next page and, with ?count=true, the amount of entries in X-Total-Count. Also answers GET requests that pass the payload as query parameters, which the links point to.", Request: PageRequestPayload{}, Result: bboltdump.BucketPage{}, Timeout: true, handler: withoutServer(handlePageRequest)},
+	{Path: "/seek", Name: "seek", Summary: "Returns the entries of a bucket starting at a key.", Request: SeekRequestPayload{}, Result: bboltdump.BucketPage{}, Timeout: true, handler: withoutServer(handleSeekRequest)},
+	{Path: "/tail", Name: "tail", Summary: "Returns the last entries of a bucket.", Request: TailRequestPayload{}, Result: bboltdump.BucketPage{}, Timeout: true, handler: withoutServer(handleTailRequest)},
+	{Path: "/sample", Name: "sample", Summary: "Returns a random sample of the entries of a bucket.", Request: SampleRequestPayload{}, Result: bboltdump.BucketSample{}, handler: withoutServer(handleSampleRequest)},
+	{Path: "/get", Name: "getKeys", Summary: "Returns the values of several keys from one read transaction.", Request: BatchGetRequestPayload{}, Result: []bboltdump.BatchEntry{}, handler: withoutServer(handleBatchGetRequest)},
+	{Path: "/exists", Name: "keyExists", Summary: "Tells whether a key exists with the status and the size and type of its value in the X-Bbolt-Value-Size and X-Bbolt-Value-Type headers, without a body. Also answers HEAD requests that pass the payload as query parameters.", Request: ExistsRequestPayload{}, handler: withoutServer(handleExistsRequest)},
+	{Path: "/scan", Name: "scan", Summary: "Returns the entries of a bucket or database that match a filter expression.", Request: ScanRequestPayload{}, Result: bboltdump.ScanResult{}, Timeout: true, handler: withoutServer(handleScanRequest)},
+	{Path: "/join", Name: "join", Summary: "Returns one page of the entries of a bucket, each with the entry of another bucket its value refers to by key.", Request: JoinRequestPayload{}, Result: bboltdump.JoinPage{}, handler: withoutServer(handleJoinRequest)},
+	{Path: "/lookup", Name: "lookup", Summary: "Returns the entries whose field indexed by a secondary index has a value.", Request: LookupRequestPayload{}, Result: bboltdump.IndexLookup{}, handler: withoutServer(handleLookupRequest)},
+	{Path: "/analyze", Name: "analyze", Summary: "Reports the key patterns, value formats and sizes of the buckets of a database.", Request: AnalyzeRequestPayload{}, Result: bboltdump.DbAnalysis{}, handler: withoutServer(handleAnalyzeRequest)},
+	{Path: "/keyspace", Name: "keyspaceTree", Summary: "Aggregates the keyspace of a database into a tree of its buckets and key prefixes with their key counts and byte totals, without transferring any values.", Request: KeyspaceRequestPayload{}, Result: bboltdump.KeyspaceTree{}, Timeout: true, handler: withoutServer(handleKeyspaceRequest)},
+	{Path: "/histogram", Name: "histogram", Summary: "Returns histograms of the key lengths and value sizes of the buckets of a database.", Request: HistogramRequestPayload{}, Result: []bboltdump.BucketHistogram{}, handler: withoutServer(handleHistogramRequest)},
+	{Path: "/largest", Name: "largestValues", Summary: "Returns the bucket, key and size of the largest values of a database.", Request: LargestRequestPayload{}, Result: []bboltdump.LargeValue{}, handler: withoutServer(handleLargestRequest)},
+	{Path: "/diff", Name: "diff", Summary: "Compares two databases.", Request: DiffRequestPayload{}, Result: bboltdump.DbDiff{}, handler: withoutServer(handleDiffRequest)},
+	{Path: "/export/sqlite", Name: "exportSqlite", Summary: "Returns a database converted to a SQLite file.", Request: ExportRequestPayload{}, handler: (*Server).handleSqliteExportRequest},
+	{Path: "/export/parquet", Name: "exportParquet", Summary: "Returns a database converted to a Parquet file with the columns bucket, key, value and value_size.", Request: ExportRequestPayload{}, handler: (*Server).handleParquetExportRequest},
+	{Path: "/export/ndjson", Name: "exportNdjson", Summary: "Returns a copy of a database as NDJSON, a Range header resumes an export that was started before.", Request: NdjsonExportRequestPayload{}, handler: (*Server).handleNdjsonExportRequest},
+	{Path: "/export/shards", Name: "exportShards", Summary: "Returns a database split into several bbolt files by bucket or by key hash, as a tar archive with a manifest.", Request: ShardExportRequestPayload{}, handler: (*Server).handleShardExportRequest},
+	{Path: "/import", Name: "importDatabase", Summary: "Loads a LevelDB or Badger database into a bucket.", Request: ImportRequestPayload{}, Result: importer.Result{}, Writes: true, handler: withoutServer(handleImportRequest)},
+	{Path: "/merge", Name: "mergeDatabases", Summary: "Merges the buckets of one database into another, resolving keys with different values in both by a conflict policy per bucket.", Request: MergeRequestPayload{}, Result: bboltdump.MergeResult{}, Writes: true, handler: withoutServer(handleMergeRequest)},
+	{Path: "/move", Name: "moveKey", Summary: "Renames a key or moves it to another bucket.", Request: MoveRequestPayload{}, Result: PreviewResult{}, Writes: true, handler: withoutServer(handleMoveRequest)},
+	{Path: "/delete", Name: "deleteKeys", Summary: "Deletes the keys of a bucket matching a prefix or range.", Request: DeleteRequestPayload{}, Result: bboltdump.DeleteResult{}, Writes: true, handler: withoutServer(handleDeleteRequest)},
+	{Path: "/snapshots", Name: "listSnapshots", Summary: "Lists the snapshots of a registered database.", Request: SnapshotRequestPayload{}, Result: []snapshot.Snapshot{}, handler: (*Server).handleSnapshotListRequest},
+	{Path: "/snapshots/diff", Name: "diffSnapshot", Summary: "Compares a snapshot with the current state of its database.", Request: SnapshotRequestPayload{}, Result: bboltdump.DbDiff{}, handler: (*Server).handleSnapshotDiffRequest},
+	{Path: "/pins", Name: "pinDatabase", Summary: "Copies a database as it is now, reads of the input of the pin see that copy until it is released or expires.", Request: RequestPayload{}, Result: pin.Pin{}, handler: withoutServer(handlePinRequest)},
+	{Path: "/pins/release", Name: "releasePin", Summary: "Deletes a pin before it expires.", Request: RequestPayload{}, Result: struct{}{}, handler: withoutServer(handlePinReleaseRequest)},
+	{Path: "/backups", Name: "listBackups", Summary: "Lists the stored backups of a registered database.", Request: BackupRequestPayload{}, Result: []backup.Backup{}, handler: (*Server).handleBackupListRequest},
+	{Path: "/backups/download", Name: "downloadBackup", Summary: "Returns a stored backup of a registered database as a bolt file.", Request: BackupRequestPayload{}, handler: (*Server).handleBackupDownloadRequest},
+	{Path: "/journal", Name: "readJournal", Summary: "Returns a range of the journaled writes of a database, with Link headers to the first, previous and next range and the amount of entries in X-Total-Count. Also answers GET requests that pass the payload as query parameters, which the links point to.", Request: JournalRequestPayload{}, Result: bboltdump.JournalPage{}, handler: withoutServer(handleJournalRequest)},
+	{Path: "/journal/verify", Name: "verifyJournal", Summary: "Checks the hash chain of the journal of a database.", Request: RequestPayload{}, Result: bboltdump.JournalVerification{}, handler: withoutServer(handleJournalVerifyRequest)},
+	{Path: "/databases", Name: "listDatabases", Summary: "Lists the registered databases.", Result: []config.RegisteredDb{}, handler: (*Server).handleDatabasesRequest},
+	{Path: "/buckets", Name: "listBuckets", Summary: "Lists the top level buckets of a database.", Request: RequestPayload{}, Result: []bboltdump.BucketInfo{}, handler: withoutServer(handleBucketsRequest)},
+	{Path: "/info", Name: "dbInfo", Summary: "Returns the file size, modification time, meta pages and freelist size of a database without dumping it.", Request: RequestPayload{}, Result: bboltdump.DbFileInfo{}, handler: withoutServer(handleInfoRequest)},
+	{Path: "/fragmentation", Name: "fragmentation", Summary: "Reports the free pages of a database and how much compacting it would likely reclaim.", Request: RequestPayload{}, Result: bboltdump.FragmentationReport{}, handler: withoutServer(handleFragmentationRequest)},
+	{Path: "/buckets/rename", Name: "renameBucket", Summary: "Renames a bucket or moves it below another bucket.", Request: RenameBucketRequestPayload{}, Result: PreviewResult{}, Writes: true, handler: withoutServer(handleRenameBucketRequest)},
+	{Path: "/admin/handles", Name: "listHandles", Summary: "Lists the handles of the handle cache.", Result: []bboltdump.HandleInfo{}, handler: withoutServer(handleHandleListRequest)},
+	{Path: "/admin/handles/close", Name: "closeHandle", Summary: "Closes the cached handle of a database.", Request: RequestPayload{}, Result: bboltdump.HandleInfo{}, handler: withoutServer(handleHandleCloseRequest)},
+	{Path: "/admin/open", Name: "listOpenDatabases", Summary: "Lists the databases that are open and who holds them.", Result: []bboltdump.OpenDbInfo{}, handler: withoutServer(handleOpenDatabasesRequest)},
+	{Path: "/admin/compactions", Name: "listCompactions", Summary: "Lists the compaction schedules of the registered databases and how their last compaction went.", Result: []compaction.Status{}, handler: (*Server).handleCompactionsRequest},
+	{Path: "/admin/stats", Name: "dbStats", Summary: "Lists the request counts, error rates and latency percentiles of every database since the server started, the most requested first.", Result: []stats.DbStats{}, handler: withoutServer(handleStatsRequest)},
+	{Path: "/admin/benchmark", Name: "benchmark", Summary: "Runs random gets, prefix scans or full dumps against a database for a while and reports the throughput and latency percentiles.", Request: BenchmarkRequestPayload{}, Result: benchmark.Result{}, Timeout: true, handler: withoutServer(handleBenchmarkRequest)},
+	{Path: "/admin/indexes", Name: "listIndexes", Summary: "Lists the secondary indexes of a database.", Request: RequestPayload{}, Result: []bboltdump.IndexInfo{}, handler: withoutServer(handleIndexListRequest)},
+	{Path: "/admin/indexes/build", Name: "buildIndex", Summary: "Builds a secondary index of a JSON field of the values of a bucket, which writes through the API keep up to date.", Request: IndexRequestPayload{}, Result: bboltdump.IndexInfo{}, Writes: true, handler: withoutServer(handleIndexBuildRequest)},
+	{Path: "/admin/indexes/drop", Name: "dropIndex", Summary: "Removes a secondary index.", Request: IndexRequestPayload{}, Result: struct{}{}, Writes: true, handler: withoutServer(handleIndexDropRequest)},
+	{Path: "/admin/views", Name: "listViews", Summary: "Lists the materialized views of the registered databases.", Result: []config.ViewConfig{}, handler: (*Server).handleViewListRequest},
+	{Path: "/admin/views/refresh", Name: "refreshViews", Summary: "Computes the materialized views of a registered database from scratch.", Request: ViewRefreshRequestPayload{}, Result: []bboltdump.ViewInfo{}, Writes: true, handler: (*Server).handleViewRefreshRequest},
+	{Path: "/admin/pins", Name: "listPins", Summary: "Lists the pins the server holds.", Result: []pin.Pin{}, handler: withoutServer(handlePinListRequest)},
+	{Path: "/admin/config/reload", Name: "reloadConfig", Summary: "Loads the config file again, registered databases, access control rules, tenants and limits apply to the following requests.", Result: struct{}{}, handler: (*Server).handleConfigReloadRequest},
+	{Path: "/admin/restore", Name: "restoreDatabase", Summary: "Replaces a registered database by a copy uploaded as the backup file of a multipart form.", Request: RestoreRequestPayload{}, Result: bboltdump.RestoreResult{}, Writes: true, handler: (*Server).handleRestoreRequest},
+
+	// transactions of registered databases that span several requests
+	{Path: "/v1/dbs/{db}/tx", Absolute: true, Name: "beginTx", Summary: "Begins a transaction.", Request: TxBeginRequestPayload{}, Result: TxInfo{}, handler: (*Server).handleTxBeginRequest},
+	{Path: "/v1/dbs/{db}/tx/{token}/get", Absolute: true, Name: "txGet", Summary: "Reads a key within a transaction.", Request: TxKeyRequestPayload{}, Result: bboltdump.Entry{}, handler: (*Server).handleTxRequest},
+	{Path: "/v1/dbs/{db}/tx/{token}/put", Absolute: true, Name: "txPut", Summary: "Writes a key within a transaction.", Request: TxKeyRequestPayload{}, Result: struct{}{}, Writes: true, handler: (*Server).handleTxRequest},
+	{Path: "/v1/dbs/{db}/tx/{token}/delete", Absolute: true, Name: "txDelete", Summary: "Deletes a key within a transaction.", Request: TxKeyRequestPayload{}, Result: struct{}{}, Writes: true, handler: (*Server).handleTxRequest},
+	{Path: "/v1/dbs/{db}/tx/{token}/commit", Absolute: true, Name: "txCommit", Summary: "Commits a transaction.", Result: struct{}{}, handler: (*Server).handleTxRequest},
+	{Path: "/v1/dbs/{db}/tx/{token}/rollback", Absolute: true, Name: "txRollback", Summary: "Rolls back a transaction.", Result: struct{}{}, handler: (*Server).handleTxRequest},
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// SampleRequestPayload is a struct representing the expected request payload of the sample endpoint
+type SampleRequestPayload struct {
+	Input  string `json:"input"`  // path to db file
+	Bucket string `json:"bucket"` // bucket to sample
+	Size   int    `json:"size"`   // amount of entries to sample, defaults to bboltdump.DefaultPageLimit
+	Keys   string `json:"keys"`   // "text" (default) to get readable keys as text, "hex" to get every key hex encoded only or "time" to also get timestamp keys as RFC 3339 times
+}
+
+// handleSampleRequest handles requests for a random sample of a bucket
+func handleSampleRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload SampleRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Bucket == "" || requestPayload.Size < 0 || requestPayload.Size > bboltdump.MaxPageLimit {
+		sendBadRequest(w, err)
+		return
+	}
+	if requestPayload.Size == 0 {
+		requestPayload.Size = bboltdump.DefaultPageLimit
+	}
+	if !bboltdump.IsValidKeyMode(requestPayload.Keys) {
+		http.Error(w, "Bad Request: keys must be hex, time or text", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	result, err := bboltdump.SampleBucket(requestPayload.Input, requestPayload.Bucket, requestPayload.Size, requestPayload.Keys)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	sendValue(w, r, result)
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// ScanRequestPayload is a struct representing the expected request payload of the scan endpoint
+type ScanRequestPayload struct {
+	Input  string `json:"input"`  // path to db file
+	Bucket string `json:"bucket"` // bucket to scan, all top level buckets if empty
+	Match  string `json:"match"`  // glob pattern like "user:*:settings" the keys have to match, see bboltdump.KeyGlob. Every key matches if empty
+	Where  string `json:"where"`  // filter expression like `key startsWith "a:" AND valueSize > 1024`, see bboltdump.Filter. Every entry matches if empty
+	Limit  int    `json:"limit"`  // max amount of entries to return, defaults to bboltdump.DefaultPageLimit
+	Values string `json:"values"` // "raw" (default), "type" or "sha256" to get the size and content type or digest of each value instead
+	Keys   string `json:"keys"`   // "text" (default) to get readable keys as text, "hex" to get every key hex encoded only or "time" to also get timestamp keys as RFC 3339 times
+}
+
+// handleScanRequest handles requests that scan buckets for the entries matching a filter
+func handleScanRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload ScanRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Limit < 0 || requestPayload.Limit > bboltdump.MaxPageLimit {
+		sendBadRequest(w, err)
+		return
+	}
+	if requestPayload.Limit == 0 {
+		requestPayload.Limit = bboltdump.DefaultPageLimit
+	}
+	var match *bboltdump.KeyGlob
+	if requestPayload.Match != "" {
+		match, err = bboltdump.ParseKeyGlob(requestPayload.Match)
+		if err != nil {
+			http.Error(w, "Bad Request: "+strings.TrimSpace(err.Error()), http.StatusBadRequest)
+			return
+		}
+	}
+	var filter *bboltdump.Filter
+	if requestPayload.Where != "" {
+		filter, err = bboltdump.ParseFilter(requestPayload.Where)
+		if err != nil {
+			http.Error(w, "Bad Request: "+strings.TrimSpace(err.Error()), http.StatusBadRequest)
+			return
+		}
+	}
+	if !bboltdump.IsValidValueMode(requestPayload.Values) {
+		http.Error(w, "Bad Request: values must be raw, type or sha256", http.StatusBadRequest)
+		return
+	}
+	if !bboltdump.IsValidKeyMode(requestPayload.Keys) {
+		http.Error(w, "Bad Request: keys must be hex, time or text", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	result, err := bboltdump.Scan(r.Context(), requestPayload.Input, requestPayload.Bucket, match, filter, requestPayload.Limit, requestPayload.Values, requestPayload.Keys)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	sendValue(w, r, result)
+}
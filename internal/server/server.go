@@ -0,0 +1,303 @@
+// Package server implements the HTTP API endpoints.
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/audit"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/metrics"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// Server is a struct representing the HTTP API together with the config it serves.
+type Server struct {
+	mu         sync.RWMutex // guards cfg and bandwidth, which Reload replaces while requests are served
+	cfg        config.Config
+	bandwidth  *limiter     // limit all streamed responses share, nil if there is none
+	txSessions *txSessions  // transactions spanning several requests
+	reload     func() error // loads the config file again, nil if the server was started without one
+}
+
+// New returns a Server that serves the databases registered in cfg.
+func New(cfg config.Config) *Server {
+	return &Server{
+		cfg:        cfg,
+		txSessions: newTxSessions(cfg.Transactions.WithDefaults()),
+		bandwidth:  newLimiter(cfg.Bandwidth.Global),
+	}
+}
+
+// config returns the config the server currently serves.
+func (s *Server) config() config.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// Reload makes the server serve cfg from the next request on, requests already being served finish with the config they started with.
+// The bandwidth limit all streamed responses share is only replaced if it changed, transactions keep the idle timeout they were begun with.
+func (s *Server) Reload(cfg config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cfg.Bandwidth.Global != s.cfg.Bandwidth.Global {
+		s.bandwidth = newLimiter(cfg.Bandwidth.Global)
+	}
+	s.cfg = cfg
+}
+
+// OnReload makes the config reload endpoint call reload, which loads the config file again and passes it to Reload and to everything else that depends on it.
+func (s *Server) OnReload(reload func() error) {
+	s.reload = reload
+}
+
+// RegisterRoutes registers all API endpoints on mux below apiEndpoint, e.g. "/bbolt".
+func (s *Server) RegisterRoutes(mux *http.ServeMux, apiEndpoint string) {
+	for _, route := range Routes {
+		routePath := apiEndpoint + route.Path
+		if route.Absolute {
+			routePath = route.Path
+		}
+		handler := route.handler
+		if route.Writes && bboltdump.IsReadOnly() {
+			mux.HandleFunc(routePath, func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "Forbidden: the server is read-only", http.StatusForbidden)
+			})
+			continue
+		}
+		mux.HandleFunc(routePath, func(w http.ResponseWriter, r *http.Request) {
+			serverConfig := s.config().Server.WithDefaults() // Load filled it in already unless the server was started without a config file
+			r, cancel, err := withTimeout(r, route.Timeout, serverConfig.MaxRequestTimeout.Duration)
+			if err != nil {
+				http.Error(w, "Bad Request: "+strings.TrimSpace(err.Error()), http.StatusBadRequest)
+				return
+			}
+			defer cancel()
+			defer s.trackRequest(r)()
+			handler(s, w, withPayloadLimit(r, serverConfig.MaxPayloadBytes))
+		})
+	}
+
+	// the UI talks to the endpoints above, config.js tells it where they live
+	mux.Handle("/ui/", http.StripPrefix("/ui/", uiHandler()))
+	mux.HandleFunc("/ui/config.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript")
+		fmt.Fprintf(w, "const API_ENDPOINT = %q;\n", apiEndpoint)
+	})
+	mux.Handle("/ui", http.RedirectHandler("/ui/", http.StatusMovedPermanently))
+
+	// scraped by Prometheus with GET, so it is not one of the Routes
+	mux.Handle("/metrics", metrics.Handler())
+}
+
+// trackRequest records the databases r names with bboltdump.TrackRequest, so the open databases report which requests hold them, and returns the function that ends the tracking.
+func (s *Server) trackRequest(r *http.Request) func() {
+	target := audit.ReadTarget(r)
+	request := bboltdump.RequestInfo{Method: r.Method, Path: r.URL.Path, Who: audit.Who(r)}
+	untrack := []func(){}
+	for _, db := range []string{target.Db, target.Other} {
+		if db == "" {
+			continue
+		}
+		// registered databases are named in transactions and by the db field
+		if registeredDb, found := s.config().LookupDb(db); found {
+			db = registeredDb.Path
+		}
+		untrack = append(untrack, bboltdump.TrackRequest(db, request))
+	}
+	return func() {
+		for _, done := range untrack {
+			done()
+		}
+	}
+}
+
+// RequestPayload is a struct representing the expected request payload
+type RequestPayload struct {
+	Input string `json:"input"`
+}
+
+// DumpRequestPayload is a struct representing the expected request payload of the full dump endpoint
+type DumpRequestPayload struct {
+	Input         string `json:"input"`         // path to db file
+	DecryptionKey string `json:"decryptionKey"` // base64 encoded AES key to decrypt AES-GCM encrypted values with
+	KeyId         string `json:"keyId"`         // id of a key of the keyring in the config to decrypt values with instead of decryptionKey
+	bboltdump.DumpOptions
+}
+
+// ResponsePayload is a struct representing the response payload
+type ResponsePayload struct {
+	Result string `json:"result"`
+}
+
+// handleRequest handles API endpoint requests
+func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload DumpRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil {
+		sendBadRequest(w, err)
+		return
+	}
+	err = requestPayload.DumpOptions.Validate()
+	if err != nil {
+		http.Error(w, "Bad Request: "+strings.TrimSpace(err.Error()), http.StatusBadRequest)
+		return
+	}
+	var key []byte
+	if requestPayload.KeyId != "" {
+		key, err = s.config().LookupKey(requestPayload.KeyId)
+	} else if requestPayload.DecryptionKey != "" {
+		key, err = base64.StdEncoding.DecodeString(requestPayload.DecryptionKey)
+	}
+	if err == nil && key != nil {
+		requestPayload.DumpOptions.Decrypter, err = bboltdump.NewDecrypter(key)
+	}
+	if err != nil {
+		http.Error(w, "Bad Request: decryptionKey must be a base64 encoded AES key and keyId a key of the keyring", http.StatusBadRequest)
+		return
+	}
+
+	requestPayload.DumpOptions.Context = r.Context()
+
+	// do actual work, the dump is streamed to the client bucket by bucket
+	resultWriter := newResultWriter(s.streamWriter(w, r), r)
+	err = bboltdump.WriteDbContentAsJson(resultWriter, requestPayload.Input, requestPayload.DumpOptions)
+	if !resultWriter.started && sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		if resultWriter.started {
+			panic(http.ErrAbortHandler) // part of the dump was sent already, aborting the response tells the client it is incomplete
+		}
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	resultWriter.Close()
+}
+
+// resultWriter is an io.Writer that sends what is written to it as the result of a ResponsePayload like sendResult, without holding the whole result in memory.
+// The response is only started by the first write, so errors that occur before can still be reported to the client.
+// With ?raw=true the result is sent as the response body itself, so clients do not have to decode it twice, and ?pretty=true indents it.
+type resultWriter struct {
+	w        http.ResponseWriter
+	raw      bool      // send the result itself instead of a ResponsePayload
+	indenter *indenter // indents the result, nil unless the client asked for pretty output
+	started  bool
+	pending  []byte // start of a UTF-8 character that was split between two writes
+}
+
+// newResultWriter returns a resultWriter that sends the result of r to w in the mode the query of r asks for.
+func newResultWriter(w http.ResponseWriter, r *http.Request) *resultWriter {
+	query := r.URL.Query()
+	rw := &resultWriter{w: w}
+	rw.raw, _ = strconv.ParseBool(query.Get("raw"))
+	if pretty, _ := strconv.ParseBool(query.Get("pretty")); pretty {
+		rw.indenter = &indenter{}
+	}
+	return rw
+}
+
+// Write escapes p as part of the result string and sends it.
+func (rw *resultWriter) Write(p []byte) (int, error) {
+	if !rw.started {
+		rw.started = true
+		rw.w.Header().Set("Content-Type", "application/json")
+		if !rw.raw {
+			_, err := io.WriteString(rw.w, `{"result":"`)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	written := len(p)
+	if rw.indenter != nil {
+		p = rw.indenter.indent(p)
+	}
+	if rw.raw {
+		_, err := rw.w.Write(p)
+		if err != nil {
+			return 0, err
+		}
+		return written, nil
+	}
+
+	// an incomplete character is kept back, escaping it on its own would replace it
+	data := append(rw.pending, p...)
+	complete := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				complete = i
+			}
+			break
+		}
+	}
+	rw.pending = append([]byte{}, data[complete:]...)
+
+	escaped, err := json.Marshal(string(data[:complete]))
+	if err != nil {
+		return 0, err
+	}
+	_, err = rw.w.Write(escaped[1 : len(escaped)-1]) // without the quotes
+	if err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+// Flush sends everything written so far to the client.
+func (rw *resultWriter) Flush() {
+	http.NewResponseController(rw.w).Flush()
+}
+
+// Close ends the result and the response payload.
+func (rw *resultWriter) Close() {
+	_, err := rw.Write(nil) // starts the response if nothing was written
+	if err == nil && rw.raw {
+		_, err = io.WriteString(rw.w, "\n")
+	} else if err == nil {
+		_, err = io.WriteString(rw.w, "\"}\n")
+	}
+	if err != nil {
+		fmt.Println("ERROR: Failed to send response:", err)
+		return
+	}
+	fmt.Println("Successfully sent response.")
+}
+
+// sendResult wraps resultBytes in a ResponsePayload, or sends them as they are in raw mode, to the client of r. Clients that accept CBOR get the result itself as CBOR.
+func sendResult(w http.ResponseWriter, r *http.Request, resultBytes []byte) {
+	if acceptsCbor(r) {
+		cborBytes, err := jsonToCbor(resultBytes)
+		if err != nil {
+			fmt.Println("ERROR:", err)
+			return
+		}
+		sendCbor(w, cborBytes)
+		return
+	}
+
+	resultWriter := newResultWriter(w, r)
+	_, err := resultWriter.Write(resultBytes)
+	if err != nil {
+		fmt.Println("ERROR: Failed to send response:", err)
+		return
+	}
+	resultWriter.Close()
+}
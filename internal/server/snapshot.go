@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/snapshot"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// SnapshotRequestPayload is a struct representing the expected request payload of the snapshot endpoints
+type SnapshotRequestPayload struct {
+	Db       string `json:"db"`       // name of a registered database
+	Snapshot string `json:"snapshot"` // id of a snapshot, only used by the diff endpoint
+	Keys     string `json:"keys"`     // "text" (default) to get readable keys as text, "hex" to get every key hex encoded only or "time" to also get timestamp keys as RFC 3339 times, only used by the diff endpoint
+}
+
+// decodeSnapshotRequest decodes the request payload and looks up the registered database it refers to. On failure an error response has already been sent.
+func (s *Server) decodeSnapshotRequest(w http.ResponseWriter, r *http.Request) (SnapshotRequestPayload, config.RegisteredDb, bool) {
+	var requestPayload SnapshotRequestPayload
+
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return requestPayload, config.RegisteredDb{}, false
+	}
+	if s.config().Snapshots.Dir == "" {
+		http.Error(w, "Snapshots are not enabled", http.StatusNotFound)
+		return requestPayload, config.RegisteredDb{}, false
+	}
+
+	// decode request
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil {
+		sendBadRequest(w, err)
+		return requestPayload, config.RegisteredDb{}, false
+	}
+	registeredDb, found := s.config().LookupDb(requestPayload.Db)
+	if !found {
+		http.Error(w, "Unknown database", http.StatusNotFound)
+		return requestPayload, config.RegisteredDb{}, false
+	}
+	return requestPayload, registeredDb, true
+}
+
+// handleSnapshotListRequest handles requests that list the stored snapshots of a registered database
+func (s *Server) handleSnapshotListRequest(w http.ResponseWriter, r *http.Request) {
+	_, registeredDb, ok := s.decodeSnapshotRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// do actual work
+	snapshots, err := snapshot.List(s.config().Snapshots.Dir, registeredDb.Name)
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+	resultBytes, err := json.Marshal(snapshots)
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+
+	sendResult(w, r, resultBytes)
+}
+
+// handleSnapshotDiffRequest handles requests that compare a stored snapshot with the current state of a registered database.
+// Keys reported as added, removed or changed were modified after the snapshot was taken.
+func (s *Server) handleSnapshotDiffRequest(w http.ResponseWriter, r *http.Request) {
+	requestPayload, registeredDb, ok := s.decodeSnapshotRequest(w, r)
+	if !ok {
+		return
+	}
+	// the id ends up in a file path, so only accept well formed ids
+	_, err := time.Parse(snapshot.IdFormat, requestPayload.Snapshot)
+	if err != nil {
+		http.Error(w, "Bad Request: invalid snapshot id", http.StatusBadRequest)
+		return
+	}
+	if !bboltdump.IsValidKeyMode(requestPayload.Keys) {
+		http.Error(w, "Bad Request: keys must be hex, time or text", http.StatusBadRequest)
+		return
+	}
+	snapshotFile := snapshot.Path(s.config().Snapshots.Dir, registeredDb.Name, requestPayload.Snapshot)
+	if _, err = os.Stat(snapshotFile); err != nil {
+		http.Error(w, "Unknown snapshot", http.StatusNotFound)
+		return
+	}
+
+	// do actual work
+	resultBytes, err := bboltdump.DiffDbsAsJson(snapshotFile, registeredDb.Path, requestPayload.Keys)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	sendResult(w, r, resultBytes)
+}
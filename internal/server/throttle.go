@@ -0,0 +1,96 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// throttleChunkSize is the most bytes a throttledWriter writes at once, so a large write does not go out in a burst.
+const throttleChunkSize = 16 << 10
+
+// limiter is a struct representing a bandwidth limit that paces the bytes of all writers sharing it.
+type limiter struct {
+	mu             sync.Mutex
+	bytesPerSecond int64
+	next           time.Time // when the bytes admitted so far will have been sent at the limit
+}
+
+// newLimiter returns a limiter for bytesPerSecond, or nil if bytesPerSecond is 0 and there is no limit.
+func newLimiter(bytesPerSecond int64) *limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &limiter{bytesPerSecond: bytesPerSecond}
+}
+
+// reserve admits n bytes and returns how long the caller has to wait before sending them.
+func (l *limiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now // the limit was not used up, unused bandwidth cannot be saved for later
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.bytesPerSecond))
+	return delay
+}
+
+// throttledWriter is a http.ResponseWriter that sends the response no faster than all its limiters allow.
+type throttledWriter struct {
+	http.ResponseWriter
+	r        *http.Request
+	limiters []*limiter
+}
+
+// Write sends p in chunks of throttleChunkSize, waiting before each chunk until every limiter admits it. It stops early if the client went away.
+func (tw throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		chunk := p[written:min(written+throttleChunkSize, len(p))]
+		var delay time.Duration
+		for _, limiter := range tw.limiters {
+			delay = max(delay, limiter.reserve(len(chunk)))
+		}
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-tw.r.Context().Done():
+				timer.Stop()
+				return written, tw.r.Context().Err()
+			}
+		}
+		n, err := tw.ResponseWriter.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, so http.ResponseController can flush it.
+func (tw throttledWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// streamWriter returns the writer streamed responses to r are sent through: it moves the write deadline forward like deadlineWriter and keeps to the bandwidth limits of the config.
+func (s *Server) streamWriter(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	var streamWriter http.ResponseWriter = deadlineWriter{w, r}
+	limiters := []*limiter{}
+	if perRequest := newLimiter(s.config().Bandwidth.PerRequest); perRequest != nil {
+		limiters = append(limiters, perRequest)
+	}
+	s.mu.RLock()
+	bandwidth := s.bandwidth
+	s.mu.RUnlock()
+	if bandwidth != nil {
+		limiters = append(limiters, bandwidth)
+	}
+	if len(limiters) > 0 {
+		streamWriter = throttledWriter{ResponseWriter: streamWriter, r: r, limiters: limiters}
+	}
+	return streamWriter
+}
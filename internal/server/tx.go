@@ -0,0 +1,428 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/audit"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/tenancy"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// TxBeginRequestPayload is a struct representing the expected request payload of the endpoint that begins a transaction
+type TxBeginRequestPayload struct {
+	Writable    bool    `json:"writable"`    // begin a read-write transaction instead of a read-only one
+	FillPercent float64 `json:"fillPercent"` // Bucket.FillPercent of all puts of the transaction, 0 keeps bolt's default
+}
+
+// TxInfo is a struct representing a transaction session as returned to the client
+type TxInfo struct {
+	Token    string `json:"token"`    // token that identifies the session in the following requests
+	Db       string `json:"db"`       // name of the registered database
+	Writable bool   `json:"writable"` // true for a read-write transaction
+}
+
+// TxKeyRequestPayload is a struct representing the expected request payload of the get, put and delete endpoints of a transaction
+type TxKeyRequestPayload struct {
+	Bucket   string `json:"bucket"`   // bucket to read from or write to
+	Key      string `json:"key"`      // hex encoded key
+	Value    string `json:"value"`    // value to store, only used by put
+	Encoding string `json:"encoding"` // how value is turned into the stored bytes, one of bboltdump.Encodings, "raw" if empty. Only used by put
+	Ttl      string `json:"ttl"`      // duration like "10m" after which the key expires, only used by put
+	Keys     string `json:"keys"`     // "text" (default) to get a readable key as text, "hex" to get it hex encoded only or "time" to also get a timestamp key as RFC 3339 time. Only used by get
+}
+
+// txSession is a struct representing a bolt transaction that spans several requests.
+type txSession struct {
+	mu          sync.Mutex // a bolt transaction must not be used by two requests at once
+	tx          *bolt.Tx
+	closeDb     func()
+	untrack     func() // ends the bboltdump.TrackRequest of the session
+	db          string
+	actor       string  // identity of the client that began the transaction, its writes are journaled as theirs
+	fillPercent float64 // Bucket.FillPercent of all puts, 0 keeps bolt's default
+	began       time.Time
+	lastUsed    time.Time
+	done        bool               // set once the transaction was committed or rolled back
+	changes     []bboltdump.Change // writes of the transaction, reported once it is committed
+}
+
+// record adds a write of keyBytes in the bucket bucketName to the changes of the transaction, consecutive writes of the same kind to the same bucket end up in one Change. The caller must hold s.mu.
+func (s *txSession) record(operation string, bucketName string, keyBytes []byte) {
+	last := len(s.changes) - 1
+	if last < 0 || s.changes[last].Operation != operation || s.changes[last].Bucket != bucketName {
+		s.changes = append(s.changes, bboltdump.NewChange(operation, bucketName, keyBytes))
+		return
+	}
+	s.changes[last].KeyCount++
+	if len(s.changes[last].Keys) < bboltdump.MaxChangeKeys {
+		s.changes[last].Keys = append(s.changes[last].Keys, hex.EncodeToString(keyBytes))
+	}
+}
+
+// finish commits or rolls back the transaction and gives back the database. The caller must hold s.mu.
+func (s *txSession) finish(commit bool) error {
+	s.done = true
+	defer s.untrack()
+	defer s.closeDb()
+	if commit {
+		bboltdump.RecordChanges(s.tx, s.changes...)
+		return s.tx.Commit()
+	}
+	return s.tx.Rollback()
+}
+
+// errTooManyTxSessions is returned when a transaction is begun while the maximum amount of sessions is open.
+var errTooManyTxSessions = errors.New("too many transactions")
+
+// abort rolls back the transaction after the write that failed with err, a half written change must not be committed. The caller must hold s.mu.
+func (s *txSession) abort(err error) {
+	fmt.Println("ERROR:", err)
+	err = s.finish(false)
+	if err != nil {
+		fmt.Println("ERROR: Failed to roll back transaction:", err)
+	}
+}
+
+// txSessions is a struct representing the open transaction sessions of the server.
+type txSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*txSession
+	cfg      config.TransactionConfig
+}
+
+// newTxSessions returns an empty set of sessions and starts rolling back the ones that were idle or open for too long, cfg must have its defaults filled in.
+func newTxSessions(cfg config.TransactionConfig) *txSessions {
+	sessions := &txSessions{
+		sessions: make(map[string]*txSession),
+		cfg:      cfg,
+	}
+	go sessions.rollbackIdleSessions()
+	return sessions
+}
+
+// begin opens the database and starts a transaction on it for actor, returning the token of the new session.
+func (ts *txSessions) begin(registeredDb config.RegisteredDb, actor string, writable bool, fillPercent float64) (string, error) {
+	tokenBytes := make([]byte, 16)
+	_, err := rand.Read(tokenBytes)
+	if err != nil {
+		return "", fmt.Errorf("Failed to generate transaction token: %v\n", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	// the count is checked again once the session is added, this check only spares opening the database
+	ts.mu.Lock()
+	full := len(ts.sessions) >= ts.cfg.MaxSessions
+	ts.mu.Unlock()
+	if full {
+		return "", errTooManyTxSessions
+	}
+
+	openDb := bboltdump.OpenDb
+	if writable {
+		openDb = bboltdump.OpenDbForWriting
+	}
+	dbInstance, closeDb, err := openDb(registeredDb.Path)
+	if err != nil {
+		return "", err
+	}
+	tx, err := dbInstance.Begin(writable)
+	if err != nil {
+		closeDb()
+		return "", fmt.Errorf("Failed to begin transaction: %v\n", err)
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if len(ts.sessions) >= ts.cfg.MaxSessions {
+		tx.Rollback()
+		closeDb()
+		return "", errTooManyTxSessions
+	}
+	now := time.Now()
+	ts.sessions[token] = &txSession{
+		tx:          tx,
+		closeDb:     closeDb,
+		untrack:     bboltdump.TrackRequest(registeredDb.Path, bboltdump.RequestInfo{Who: actor, Transaction: true, Writable: writable}),
+		db:          registeredDb.Name,
+		actor:       actor,
+		fillPercent: fillPercent,
+		began:       now,
+		lastUsed:    now,
+	}
+	return token, nil
+}
+
+// acquire returns the locked session of token if it belongs to the database dbName, the caller must call release when done.
+func (ts *txSessions) acquire(token string, dbName string) (*txSession, bool) {
+	ts.mu.Lock()
+	session, found := ts.sessions[token]
+	ts.mu.Unlock()
+	if !found || session.db != dbName {
+		return nil, false
+	}
+
+	session.mu.Lock()
+	if session.done {
+		// finished while we were waiting for the lock
+		session.mu.Unlock()
+		return nil, false
+	}
+	return session, true
+}
+
+// release unlocks session and removes it if it was finished.
+func (ts *txSessions) release(token string, session *txSession) {
+	session.lastUsed = time.Now()
+	if session.done {
+		ts.mu.Lock()
+		delete(ts.sessions, token)
+		ts.mu.Unlock()
+	}
+	session.mu.Unlock()
+}
+
+// rollbackIdleSessions periodically rolls back the sessions that were not used for the idle timeout or that were begun longer than the maximum duration ago. It never returns.
+func (ts *txSessions) rollbackIdleSessions() {
+	ticker := time.NewTicker(min(ts.cfg.IdleTimeout.Duration, ts.cfg.MaxDuration.Duration) / 2)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ts.mu.Lock()
+		idleSessions := make(map[string]*txSession)
+		for token, session := range ts.sessions {
+			idleSessions[token] = session
+		}
+		ts.mu.Unlock()
+
+		for token, session := range idleSessions {
+			if !session.mu.TryLock() {
+				continue // a request is using it right now
+			}
+			if !session.done && (time.Since(session.lastUsed) >= ts.cfg.IdleTimeout.Duration || time.Since(session.began) >= ts.cfg.MaxDuration.Duration) {
+				err := session.finish(false)
+				if err != nil {
+					fmt.Println("ERROR: Failed to roll back expired transaction:", err)
+				}
+				ts.mu.Lock()
+				delete(ts.sessions, token)
+				ts.mu.Unlock()
+			}
+			session.mu.Unlock()
+		}
+	}
+}
+
+// handleTxBeginRequest handles requests that begin a transaction on a registered database
+func (s *Server) handleTxBeginRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+	registeredDb, found := s.config().LookupDb(r.PathValue("db"))
+	if !found {
+		http.Error(w, "Unknown database", http.StatusNotFound)
+		return
+	}
+
+	// decode request, an empty body begins a read-only transaction
+	var requestPayload TxBeginRequestPayload
+	if r.ContentLength != 0 {
+		err := decodePayload(w, r, &requestPayload)
+		if err != nil {
+			sendBadRequest(w, err)
+			return
+		}
+	}
+	if !bboltdump.IsValidFillPercent(requestPayload.FillPercent) {
+		http.Error(w, "Bad Request: fillPercent must be between 0.1 and 1.0", http.StatusBadRequest)
+		return
+	}
+
+	// do actual work
+	token, err := s.txSessions.begin(registeredDb, audit.Who(r), requestPayload.Writable, requestPayload.FillPercent)
+	if errors.Is(err, errTooManyTxSessions) {
+		http.Error(w, "Too many transactions, commit or roll back one first", http.StatusTooManyRequests)
+		return
+	}
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	dbName := registeredDb.Name
+	if tenant := tenancy.Of(r); tenant != nil {
+		dbName, _ = tenancy.Name(tenant, dbName) // the name the tenant knows it by
+	}
+	resultBytes, err := json.Marshal(TxInfo{
+		Token:    token,
+		Db:       dbName,
+		Writable: requestPayload.Writable,
+	})
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+
+	sendResult(w, r, resultBytes)
+}
+
+// handleTxRequest handles the get, put, delete, commit and rollback requests of a transaction
+func (s *Server) handleTxRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+	token := r.PathValue("token")
+	session, found := s.txSessions.acquire(token, r.PathValue("db"))
+	if !found {
+		http.Error(w, "Unknown transaction", http.StatusNotFound)
+		return
+	}
+	defer s.txSessions.release(token, session)
+
+	operation := path.Base(r.URL.Path) // every operation is a route of its own
+	switch operation {
+	case "commit", "rollback":
+		err := session.finish(operation == "commit")
+		if err != nil {
+			fmt.Println("ERROR:", err)
+			http.Error(w, "Failed to "+operation+" transaction", http.StatusInternalServerError)
+			return
+		}
+		sendResult(w, r, []byte("{}"))
+		return
+
+	case "get", "put", "delete":
+	default:
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	// decode request
+	var requestPayload TxKeyRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Bucket == "" {
+		sendBadRequest(w, err)
+		return
+	}
+	keyBytes, err := hex.DecodeString(requestPayload.Key)
+	if err != nil || len(keyBytes) == 0 {
+		http.Error(w, "Bad Request: key must be hex encoded", http.StatusBadRequest)
+		return
+	}
+	if len(keyBytes) > bolt.MaxKeySize {
+		http.Error(w, fmt.Sprintf("Bad Request: key must be at most %v bytes", bolt.MaxKeySize), http.StatusBadRequest)
+		return
+	}
+	if !bboltdump.IsValidKeyMode(requestPayload.Keys) {
+		http.Error(w, "Bad Request: keys must be hex, time or text", http.StatusBadRequest)
+		return
+	}
+	var ttl time.Duration
+	if requestPayload.Ttl != "" {
+		ttl, err = time.ParseDuration(requestPayload.Ttl)
+		if err != nil || ttl <= 0 {
+			http.Error(w, "Bad Request: ttl must be a positive duration like \"10m\"", http.StatusBadRequest)
+			return
+		}
+	}
+	if operation != "get" && !session.tx.Writable() {
+		http.Error(w, "Transaction is read-only", http.StatusConflict)
+		return
+	}
+
+	// do actual work
+	switch operation {
+	case "get":
+		b := bboltdump.ResolveBucket(session.tx, requestPayload.Bucket)
+		if b == nil {
+			http.Error(w, "Unknown bucket", http.StatusNotFound)
+			return
+		}
+		valueBytes := b.Get(keyBytes)
+		if valueBytes == nil || bboltdump.IsExpired(session.tx, requestPayload.Bucket, keyBytes) {
+			http.Error(w, "Unknown key", http.StatusNotFound)
+			return
+		}
+		sendValueUnlessNotModified(w, r, bboltdump.NewEntry(keyBytes, valueBytes, bboltdump.ValuesRaw, requestPayload.Keys))
+
+	case "put":
+		// the value is encoded before anything is written, so a value that does not fit its encoding leaves the transaction as it was
+		valueBytes, err := bboltdump.EncodeValue(requestPayload.Bucket, requestPayload.Value, requestPayload.Encoding)
+		if err != nil {
+			http.Error(w, "Bad Request: "+strings.TrimSpace(err.Error()), http.StatusBadRequest)
+			return
+		}
+		if len(valueBytes) > bolt.MaxValueSize {
+			http.Error(w, fmt.Sprintf("Bad Request: value must be at most %v bytes", bolt.MaxValueSize), http.StatusBadRequest)
+			return
+		}
+		// without a ttl an earlier expiry of the key is removed
+		var expiresAt time.Time
+		if ttl > 0 {
+			expiresAt = time.Now().Add(ttl)
+		}
+		// a key that names a nested bucket cannot be overwritten, it is rejected before the journal is written
+		if b := bboltdump.ResolveBucket(session.tx, requestPayload.Bucket); b != nil && b.Bucket(keyBytes) != nil {
+			http.Error(w, "Key is a nested bucket", http.StatusConflict)
+			return
+		}
+		b, err := bboltdump.CreateBucketPath(session.tx, requestPayload.Bucket)
+		if err == nil {
+			if session.fillPercent != 0 {
+				b.FillPercent = session.fillPercent
+			}
+			err = bboltdump.Journal(session.tx, session.actor, bboltdump.ChangePut, requestPayload.Bucket, keyBytes, b.Get(keyBytes), valueBytes)
+		}
+		if err == nil {
+			err = b.Put(keyBytes, valueBytes)
+		}
+		if err == nil {
+			err = bboltdump.SetExpiry(session.tx, requestPayload.Bucket, keyBytes, expiresAt)
+		}
+		if err != nil {
+			// the journal or the index may already hold the write, so the transaction must not be committed anymore
+			session.abort(err)
+			http.Error(w, "Failed to write key, the transaction was rolled back", http.StatusInternalServerError)
+			return
+		}
+		session.record(bboltdump.ChangePut, requestPayload.Bucket, keyBytes)
+		sendResult(w, r, []byte("{}"))
+
+	case "delete":
+		b := bboltdump.ResolveBucket(session.tx, requestPayload.Bucket)
+		if b != nil && b.Get(keyBytes) != nil {
+			err = bboltdump.Journal(session.tx, session.actor, bboltdump.ChangeDelete, requestPayload.Bucket, keyBytes, b.Get(keyBytes), nil)
+			if err == nil {
+				err = b.Delete(keyBytes)
+			}
+		}
+		if err == nil {
+			err = bboltdump.SetExpiry(session.tx, requestPayload.Bucket, keyBytes, time.Time{})
+		}
+		if err != nil {
+			session.abort(err)
+			http.Error(w, "Failed to delete key, the transaction was rolled back", http.StatusInternalServerError)
+			return
+		}
+		session.record(bboltdump.ChangeDelete, requestPayload.Bucket, keyBytes)
+		sendResult(w, r, []byte("{}"))
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/tenancy"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+//go:embed ui
+var uiFiles embed.FS
+
+// uiHandler serves the embedded web UI, index.html is served for the root.
+func uiHandler() http.Handler {
+	uiRoot, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err) // the directory is embedded at compile time, so this cannot happen
+	}
+	return http.FileServer(http.FS(uiRoot))
+}
+
+// handleBucketsRequest handles requests that list the buckets of a database
+func handleBucketsRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload RequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil {
+		sendBadRequest(w, err)
+		return
+	}
+
+	// do actual work
+	resultBytes, err := bboltdump.ListBucketsAsJson(requestPayload.Input)
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return // if the request is valid but the response invalid, then do not respond
+	}
+
+	sendResult(w, r, resultBytes)
+}
+
+// handleDatabasesRequest handles requests that list the registered databases
+func (s *Server) handleDatabasesRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	registeredDbs := s.config().Databases
+	if tenant := tenancy.Of(r); tenant != nil {
+		registeredDbs = tenancy.Databases(tenant, registeredDbs) // a tenant only learns about its own databases
+	}
+	if registeredDbs == nil {
+		registeredDbs = []config.RegisteredDb{}
+	}
+	resultBytes, err := json.Marshal(registeredDbs)
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+
+	sendResult(w, r, resultBytes)
+}
@@ -0,0 +1,172 @@
+// Small browser for the bbolt API, talks to the same endpoints as any other client.
+"use strict";
+
+const PAGE_LIMIT = 50;
+const PREVIEW_LENGTH = 80;
+
+const state = {
+	dbPath: "",
+	bucket: "",
+	cursors: [""], // cursor of every page visited so far, the last one is the current page
+	nextCursor: "",
+};
+
+// post sends payload to an endpoint and returns the parsed result, the API wraps every result in a JSON string.
+async function post(endpoint, payload) {
+	const response = await fetch(API_ENDPOINT + endpoint, {
+		method: "POST",
+		headers: {"Content-Type": "application/json"},
+		body: JSON.stringify(payload),
+	});
+	if (!response.ok) {
+		throw new Error(endpoint + ": " + (await response.text()));
+	}
+	const text = await response.text();
+	if (text === "") {
+		throw new Error(endpoint + ": the server did not respond, check its log");
+	}
+	return JSON.parse(JSON.parse(text).result);
+}
+
+function showError(err) {
+	document.getElementById("error").textContent = err ? err.message : "";
+}
+
+function select(list, item) {
+	for (const child of list.children) {
+		child.classList.toggle("selected", child === item);
+	}
+}
+
+async function loadDatabases() {
+	const list = document.getElementById("database-list");
+	const databases = await post("/databases", {});
+	list.replaceChildren();
+	for (const db of databases) {
+		const item = document.createElement("li");
+		item.textContent = db.name;
+		item.title = db.path;
+		item.onclick = () => {
+			select(list, item);
+			openDatabase(db.path);
+		};
+		list.append(item);
+	}
+}
+
+// formatBytes renders a byte count with a binary unit.
+function formatBytes(bytes) {
+	const units = ["B", "KiB", "MiB", "GiB", "TiB"];
+	let unit = 0;
+	while (bytes >= 1024 && unit < units.length - 1) {
+		bytes /= 1024;
+		unit++;
+	}
+	return (unit === 0 ? bytes : bytes.toFixed(1)) + " " + units[unit];
+}
+
+// renderKeyspace lists node and the nodes below it, each with a bar as wide as its share of total.
+function renderKeyspace(list, node, depth, total) {
+	const size = node.keyBytes + node.valueBytes;
+	const item = document.createElement("li");
+	const bar = document.createElement("span");
+	bar.className = "bar";
+	bar.style.width = (total > 0 ? 20 * size / total : 0) + "em";
+	item.style.paddingLeft = depth + "em";
+	item.append(bar, (node.kind === "prefix" ? node.name + "…" : node.name || "/") + " " + formatBytes(size) + " (" + node.keys + " keys)");
+	item.title = node.kind;
+	list.append(item);
+	for (const child of node.children || []) {
+		renderKeyspace(list, child, depth + 1, total);
+	}
+}
+
+async function loadKeyspace(dbPath) {
+	const list = document.getElementById("keyspace-tree");
+	list.replaceChildren();
+	const tree = await post("/keyspace", {input: dbPath, depth: 2, maxChildren: 10});
+	if (tree.root) {
+		renderKeyspace(list, tree.root, 0, tree.root.keyBytes + tree.root.valueBytes);
+	}
+}
+
+async function openDatabase(dbPath) {
+	showError(null);
+	state.dbPath = dbPath;
+	const list = document.getElementById("bucket-list");
+	list.replaceChildren();
+	document.getElementById("key-rows").replaceChildren();
+	try {
+		const buckets = await post("/buckets", {input: dbPath});
+		for (const bucket of buckets) {
+			const item = document.createElement("li");
+			item.textContent = bucket.name + " (" + bucket.keys + ")";
+			item.onclick = () => {
+				select(list, item);
+				openBucket(bucket.name);
+			};
+			list.append(item);
+		}
+		await loadKeyspace(dbPath);
+	} catch (err) {
+		showError(err);
+	}
+}
+
+function openBucket(bucket) {
+	state.bucket = bucket;
+	state.cursors = [""];
+	document.getElementById("keys-title").textContent = "Keys of " + bucket;
+	loadPage();
+}
+
+async function loadPage() {
+	showError(null);
+	const rows = document.getElementById("key-rows");
+	try {
+		const page = await post("/page", {
+			input: state.dbPath,
+			bucket: state.bucket,
+			limit: PAGE_LIMIT,
+			cursor: state.cursors[state.cursors.length - 1],
+		});
+		rows.replaceChildren();
+		for (const entry of page.entries) {
+			const row = document.createElement("tr");
+			const keyCell = document.createElement("td");
+			const valueCell = document.createElement("td");
+			keyCell.textContent = entry.key;
+			keyCell.title = entry.keyHex;
+			valueCell.textContent = entry.value.slice(0, PREVIEW_LENGTH);
+			row.append(keyCell, valueCell);
+			row.onclick = () => {
+				select(rows, row);
+				document.getElementById("value-preview").textContent = entry.value;
+			};
+			rows.append(row);
+		}
+		state.nextCursor = page.nextCursor || "";
+		document.getElementById("next-page").disabled = state.nextCursor === "";
+		document.getElementById("prev-page").disabled = state.cursors.length === 1;
+		document.getElementById("page-number").textContent = "Page " + state.cursors.length;
+	} catch (err) {
+		showError(err);
+	}
+}
+
+document.getElementById("next-page").onclick = () => {
+	state.cursors.push(state.nextCursor);
+	loadPage();
+};
+
+document.getElementById("prev-page").onclick = () => {
+	state.cursors.pop();
+	loadPage();
+};
+
+document.getElementById("path-form").onsubmit = (event) => {
+	event.preventDefault();
+	openDatabase(document.getElementById("path-input").value);
+};
+
+loadDatabases().catch(showError);
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/views"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// ViewRefreshRequestPayload is a struct representing the expected request payload of the view refresh endpoint
+type ViewRefreshRequestPayload struct {
+	Db   string `json:"db"`   // name of the registered database
+	View string `json:"view"` // name of the view to refresh, all views of the database if empty
+}
+
+// handleViewListRequest handles requests that list the materialized views of the registered databases
+func (s *Server) handleViewListRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resultBytes, err := json.Marshal(s.config().Views)
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+
+	sendResult(w, r, resultBytes)
+}
+
+// handleViewRefreshRequest handles requests that compute materialized views from scratch
+func (s *Server) handleViewRefreshRequest(w http.ResponseWriter, r *http.Request) {
+	// only allow POST request
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// decode request
+	var requestPayload ViewRefreshRequestPayload
+	err := decodePayload(w, r, &requestPayload)
+	if err != nil || requestPayload.Db == "" {
+		sendBadRequest(w, err)
+		return
+	}
+
+	// do actual work
+	result, err := views.Refresh(s.config(), requestPayload.Db, requestPayload.View)
+	if errors.Is(err, views.ErrViewNotFound) {
+		http.Error(w, "Unknown view", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, bboltdump.ErrBucketNotFound) {
+		http.Error(w, "Unknown bucket", http.StatusNotFound)
+		return
+	}
+	if sendLocked(w, err) {
+		return
+	}
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		http.Error(w, "Failed to refresh view", http.StatusInternalServerError)
+		return
+	}
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		fmt.Println("ERROR:", err)
+		return
+	}
+
+	sendResult(w, r, resultBytes)
+}
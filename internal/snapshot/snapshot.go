@@ -0,0 +1,143 @@
+// Package snapshot periodically copies registered databases into a snapshot directory and keeps a limited amount of copies per database.
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+	bolt "go.etcd.io/bbolt"
+)
+
+const IdFormat = "20060102T150405Z" // snapshot files are named after the UTC time they were taken at
+
+// Snapshot is a struct representing a stored copy of a registered database.
+type Snapshot struct {
+	Id   string    `json:"id"`   // identifies the snapshot, pass it to the snapshot diff endpoint
+	Time time.Time `json:"time"` // time the snapshot was taken at
+	Size int64     `json:"size"` // size of the snapshot file in bytes
+}
+
+// Path returns the path of the snapshot file with the given id of the database dbName below the snapshot directory dir.
+func Path(dir string, dbName string, id string) string {
+	return filepath.Join(dir, dbName, id+".db")
+}
+
+// Take copies the registered database into its directory below the snapshot directory dir from within a read transaction, so the copy is consistent even while the database is in use.
+func Take(dir string, registeredDb config.RegisteredDb) (Snapshot, error) {
+	snapshotTime := time.Now().UTC()
+	snapshot := Snapshot{
+		Id:   snapshotTime.Format(IdFormat),
+		Time: snapshotTime,
+	}
+
+	err := os.MkdirAll(filepath.Join(dir, registeredDb.Name), 0700)
+	if err != nil {
+		return snapshot, fmt.Errorf("Failed to create snapshot directory: %v\n", err)
+	}
+
+	// open database
+	dbInstance, closeDb, err := bboltdump.OpenLiveDb(registeredDb.Path)
+	if err != nil {
+		return snapshot, err
+	}
+	defer closeDb()
+
+	// write to a temporary file first so a half written snapshot is never listed
+	finalPath := Path(dir, registeredDb.Name, snapshot.Id)
+	tempPath := finalPath + ".tmp"
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		snapshot.Size = tx.Size()
+		return tx.CopyFile(tempPath, 0600)
+	})
+	if err != nil {
+		os.Remove(tempPath)
+		return snapshot, fmt.Errorf("Failed to snapshot database %v: %v\n", registeredDb.Name, err)
+	}
+	err = os.Rename(tempPath, finalPath)
+	if err != nil {
+		os.Remove(tempPath)
+		return snapshot, fmt.Errorf("Failed to store snapshot of database %v: %v\n", registeredDb.Name, err)
+	}
+
+	return snapshot, nil
+}
+
+// List returns the snapshots of the database dbName stored below the snapshot directory dir, newest first.
+func List(dir string, dbName string) ([]Snapshot, error) {
+	snapshots := []Snapshot{}
+
+	dirEntries, err := os.ReadDir(filepath.Join(dir, dbName))
+	if os.IsNotExist(err) {
+		return snapshots, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list snapshots of database %v: %v\n", dbName, err)
+	}
+
+	for _, dirEntry := range dirEntries {
+		id, isDb := strings.CutSuffix(dirEntry.Name(), ".db")
+		snapshotTime, err := time.Parse(IdFormat, id)
+		if !isDb || err != nil {
+			continue // not a snapshot, e.g. a leftover temporary file
+		}
+		fileInfo, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{
+			Id:   id,
+			Time: snapshotTime,
+			Size: fileInfo.Size(),
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.After(snapshots[j].Time)
+	})
+	return snapshots, nil
+}
+
+// prune deletes all but the newest retention snapshots of the database dbName.
+func prune(dir string, dbName string, retention int) error {
+	snapshots, err := List(dir, dbName)
+	if err != nil {
+		return err
+	}
+	for i := retention; i < len(snapshots); i++ {
+		bboltdump.CloseCachedHandle(Path(dir, dbName, snapshots[i].Id)) // a snapshot diff may still hold it open
+		err = os.Remove(Path(dir, dbName, snapshots[i].Id))
+		if err != nil {
+			return fmt.Errorf("Failed to delete old snapshot %v of database %v: %v\n", snapshots[i].Id, dbName, err)
+		}
+	}
+	return nil
+}
+
+// Run snapshots every registered database right away and then once per configured interval, pruning old snapshots as it goes. It never returns.
+func Run(cfg config.Config) {
+	ticker := time.NewTicker(cfg.Snapshots.Interval.Duration)
+	defer ticker.Stop()
+
+	for {
+		for _, registeredDb := range cfg.Databases {
+			snapshot, err := Take(cfg.Snapshots.Dir, registeredDb)
+			if err != nil {
+				fmt.Println("ERROR:", err)
+				continue
+			}
+			fmt.Println("Took snapshot", snapshot.Id, "of database", registeredDb.Name)
+
+			err = prune(cfg.Snapshots.Dir, registeredDb.Name, cfg.Snapshots.Retention)
+			if err != nil {
+				fmt.Println("ERROR:", err)
+			}
+		}
+		<-ticker.C
+	}
+}
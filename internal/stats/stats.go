@@ -0,0 +1,254 @@
+// Package stats counts the requests of every database with their errors and latencies in memory, to see which databases are hot and which requests are slow.
+package stats
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/audit"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/metrics"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/pin"
+)
+
+const (
+	windowSize = 1024 // amount of the latest latencies the percentiles are taken of, per database and per operation
+	maxDbs     = 1000 // most databases counted, requests for further databases are not, so clients cannot fill the memory with made up paths
+)
+
+// Latency is a struct representing how long the latest requests took, in milliseconds.
+type Latency struct {
+	P50 float64 `json:"p50Ms"`
+	P90 float64 `json:"p90Ms"`
+	P99 float64 `json:"p99Ms"`
+	Max float64 `json:"maxMs"`
+}
+
+// Usage is a struct representing the requests counted since the server started.
+type Usage struct {
+	Requests     int64   `json:"requests"`
+	ClientErrors int64   `json:"clientErrors"` // responses with a 4xx status, like rejected payloads or missing keys
+	Errors       int64   `json:"errors"`       // responses with a 5xx status
+	ErrorRate    float64 `json:"errorRate"`    // share of the requests answered with a 5xx status
+	Latency      Latency `json:"latency"`      // of the latest requests, until the response was sent completely
+}
+
+// OperationStats is a struct representing the requests of one operation on a database.
+type OperationStats struct {
+	Operation string `json:"operation"` // method and path of the requests like "POST /bbolt/page"
+	Usage
+}
+
+// DbStats is a struct representing the requests of a database.
+type DbStats struct {
+	Db          string    `json:"db"` // path or registered name of the database as the requests named it
+	LastRequest time.Time `json:"lastRequest"`
+	Usage
+	Operations []OperationStats `json:"operations"` // most requested first
+}
+
+// counter is a struct representing the counts and latest latencies of Usage.
+type counter struct {
+	requests     int64
+	clientErrors int64
+	errors       int64
+	latencies    []time.Duration // ring of the latest latencies
+	next         int             // index of latencies the next latency is written to
+}
+
+// add counts a request answered with status after latency.
+func (c *counter) add(status int, latency time.Duration) {
+	c.requests++
+	if status >= 500 {
+		c.errors++
+	} else if status >= 400 {
+		c.clientErrors++
+	}
+	if len(c.latencies) < windowSize {
+		c.latencies = append(c.latencies, latency)
+		return
+	}
+	c.latencies[c.next] = latency
+	c.next = (c.next + 1) % windowSize
+}
+
+// usage returns the Usage of the counts.
+func (c *counter) usage() Usage {
+	usage := Usage{Requests: c.requests, ClientErrors: c.clientErrors, Errors: c.errors}
+	if c.requests > 0 {
+		usage.ErrorRate = float64(c.errors) / float64(c.requests)
+	}
+	if len(c.latencies) == 0 {
+		return usage
+	}
+	latencies := append([]time.Duration{}, c.latencies...)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	milliseconds := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	percentile := func(p int) float64 { return milliseconds(latencies[(len(latencies)-1)*p/100]) }
+	usage.Latency = Latency{
+		P50: percentile(50),
+		P90: percentile(90),
+		P99: percentile(99),
+		Max: milliseconds(latencies[len(latencies)-1]),
+	}
+	return usage
+}
+
+// dbCounter is a struct representing the counts of a database and of each of its operations.
+type dbCounter struct {
+	counter
+	lastRequest time.Time
+	operations  map[string]*counter
+}
+
+// counted holds the dbCounter of every database by the name the requests used.
+var counted = struct {
+	mu   sync.Mutex
+	byDb map[string]*dbCounter
+}{byDb: make(map[string]*dbCounter)}
+
+// Enable serves the counts at /metrics too.
+func Enable() {
+	metrics.Register(writeMetrics)
+}
+
+// record counts a request for operation on db.
+func record(db string, operation string, status int, latency time.Duration) {
+	counted.mu.Lock()
+	defer counted.mu.Unlock()
+	dbCount, found := counted.byDb[db]
+	if !found {
+		if len(counted.byDb) >= maxDbs {
+			return
+		}
+		dbCount = &dbCounter{operations: make(map[string]*counter)}
+		counted.byDb[db] = dbCount
+	}
+	dbCount.lastRequest = time.Now().UTC()
+	dbCount.add(status, latency)
+	operationCount, found := dbCount.operations[operation]
+	if !found {
+		operationCount = &counter{}
+		dbCount.operations[operation] = operationCount
+	}
+	operationCount.add(status, latency)
+}
+
+// List returns the stats of every database that was requested since the server started, most requested first.
+func List() []DbStats {
+	counted.mu.Lock()
+	defer counted.mu.Unlock()
+	result := make([]DbStats, 0, len(counted.byDb))
+	for db, dbCount := range counted.byDb {
+		dbStats := DbStats{Db: db, LastRequest: dbCount.lastRequest, Usage: dbCount.usage(), Operations: []OperationStats{}}
+		for operation, operationCount := range dbCount.operations {
+			dbStats.Operations = append(dbStats.Operations, OperationStats{Operation: operation, Usage: operationCount.usage()})
+		}
+		sort.Slice(dbStats.Operations, func(i, j int) bool {
+			if dbStats.Operations[i].Requests != dbStats.Operations[j].Requests {
+				return dbStats.Operations[i].Requests > dbStats.Operations[j].Requests
+			}
+			return dbStats.Operations[i].Operation < dbStats.Operations[j].Operation
+		})
+		result = append(result, dbStats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Requests != result[j].Requests {
+			return result[i].Requests > result[j].Requests
+		}
+		return result[i].Db < result[j].Db
+	})
+	return result
+}
+
+// statusRecorder is an http.ResponseWriter that remembers the status code of the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader remembers status and sends it.
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Write sends p, which implies status 200 if no status was sent yet.
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the Flush of the wrapped writer, which streamed dumps rely on.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Handler counts every request next handles that names a database. Reads of a pin are counted for the database it was taken of.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/ui") || strings.HasPrefix(r.URL.Path, "/docs") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		target := audit.ReadTarget(r)
+		if target.Db == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		start := time.Now()
+		defer func() {
+			// a panic aborting a streamed response counts as the error it is
+			if recovered := recover(); recovered != nil {
+				record(pin.Source(target.Db), target.Operation, http.StatusInternalServerError, time.Since(start))
+				panic(recovered)
+			}
+			status := recorder.status
+			if status == 0 {
+				status = http.StatusOK // the handler sent an empty response
+			}
+			record(pin.Source(target.Db), target.Operation, status, time.Since(start))
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// writeMetrics writes the request counts and latencies of every database.
+func writeMetrics(w *bytes.Buffer) {
+	dbStatsList := List()
+	sort.Slice(dbStatsList, func(i, j int) bool { return dbStatsList[i].Db < dbStatsList[j].Db })
+
+	for _, metric := range []struct {
+		name, metricType, help string
+		value                  func(usage Usage) float64
+	}{
+		{"bbolt_db_requests_total", "counter", "Requests that named the database.", func(usage Usage) float64 { return float64(usage.Requests) }},
+		{"bbolt_db_client_errors_total", "counter", "Requests of the database answered with a 4xx status.", func(usage Usage) float64 { return float64(usage.ClientErrors) }},
+		{"bbolt_db_errors_total", "counter", "Requests of the database answered with a 5xx status.", func(usage Usage) float64 { return float64(usage.Errors) }},
+	} {
+		metrics.WriteHelp(w, metric.name, metric.metricType, metric.help)
+		for _, dbStats := range dbStatsList {
+			metrics.WriteSample(w, metric.name, dbStats.Db, metric.value(dbStats.Usage))
+		}
+	}
+
+	metrics.WriteHelp(w, "bbolt_db_request_duration_seconds", "gauge", fmt.Sprintf("Quantiles of the latency of the latest %v requests of the database.", windowSize))
+	for _, dbStats := range dbStatsList {
+		for _, quantile := range []struct {
+			quantile     string
+			milliseconds float64
+		}{{"0.5", dbStats.Latency.P50}, {"0.9", dbStats.Latency.P90}, {"0.99", dbStats.Latency.P99}} {
+			metrics.WriteQuantile(w, "bbolt_db_request_duration_seconds", dbStats.Db, quantile.quantile, quantile.milliseconds/1000)
+		}
+	}
+}
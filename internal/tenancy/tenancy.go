@@ -0,0 +1,178 @@
+// Package tenancy isolates the customers sharing one server from each other: the clients of a tenant only reach the files below its root directory and the databases registered for it.
+package tenancy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/audit"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+)
+
+// pathFields and nameFields are the payload fields that name a db file and a registered database. JSON field names match case-insensitively like the handlers decode them, so no spelling slips past.
+var pathFields = []string{"input", "other", "source"}
+var nameFields = []string{"db"}
+
+// globalEndpoints are the paths below the API endpoint that report on or reconfigure every database of the server or hand out db paths outside of any root like pins, tenants are refused them.
+var globalEndpoints = []string{"/admin/handles", "/admin/open", "/admin/compactions", "/admin/stats", "/admin/views", "/admin/config/reload", "/pins", "/pins/release", "/admin/pins"}
+
+// Tenancy is a struct representing the tenants of the server by their clients.
+type Tenancy struct {
+	tenants     map[string]config.TenantConfig // by identity
+	apiEndpoint string
+}
+
+// New returns the Tenancy of cfg, or nil if cfg has no tenants. apiEndpoint is the path the endpoints are registered below, e.g. "/bbolt".
+func New(cfg config.Config, apiEndpoint string) *Tenancy {
+	if len(cfg.Tenants) == 0 {
+		return nil
+	}
+	t := &Tenancy{tenants: make(map[string]config.TenantConfig), apiEndpoint: apiEndpoint}
+	for _, tenant := range cfg.Tenants {
+		for _, identity := range tenant.Identities {
+			t.tenants[identity] = tenant
+		}
+	}
+	return t
+}
+
+type tenantKey struct{}
+
+// Of returns the tenant r was sent by as Handler found it, or nil if the client belongs to no tenant.
+func Of(r *http.Request) *config.TenantConfig {
+	tenant, _ := r.Context().Value(tenantKey{}).(*config.TenantConfig)
+	return tenant
+}
+
+// Name returns the name the database registered as name is known by to its tenant, and whether it belongs to tenant at all.
+func Name(tenant *config.TenantConfig, name string) (string, bool) {
+	return strings.CutPrefix(name, tenant.Name+".")
+}
+
+// ClientPath returns path as the clients of tenant see it, the inverse of config.TenantConfig.Path. Paths outside of its root are returned unchanged.
+func ClientPath(tenant *config.TenantConfig, path string) string {
+	rest, found := strings.CutPrefix(path, tenant.Root)
+	if !found || (rest != "" && !strings.HasPrefix(rest, string(filepath.Separator))) {
+		return path
+	}
+	return "/" + filepath.ToSlash(strings.TrimPrefix(rest, string(filepath.Separator)))
+}
+
+// Databases returns the databases of registeredDbs that belong to tenant, with their names and paths as its clients see them.
+func Databases(tenant *config.TenantConfig, registeredDbs []config.RegisteredDb) []config.RegisteredDb {
+	tenantDbs := []config.RegisteredDb{}
+	for _, registeredDb := range registeredDbs {
+		name, found := Name(tenant, registeredDb.Name)
+		if !found {
+			continue
+		}
+		registeredDb.Name = name
+		registeredDb.Path = ClientPath(tenant, registeredDb.Path)
+		tenantDbs = append(tenantDbs, registeredDb)
+	}
+	return tenantDbs
+}
+
+// Handler moves the requests of the clients of a tenant into its namespace before they reach next: paths are resolved below its root and names of registered databases get its prefix, in the payload, the query and the path of transactions.
+// Requests of clients that belong to no tenant pass unchanged, like the static files of the UI and the API docs.
+func (t *Tenancy) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t == nil || strings.HasPrefix(r.URL.Path, "/ui") || strings.HasPrefix(r.URL.Path, "/docs") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tenant, found := t.tenants[audit.Who(r)]
+		if !found {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// uploads name their database in a form, which is not rewritten
+		if t.isGlobal(r.URL.Path) || strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if rest, found := strings.CutPrefix(r.URL.Path, "/v1/dbs/"); found {
+			r.URL.Path = "/v1/dbs/" + tenant.Name + "." + rest
+			r.URL.RawPath = ""
+		}
+		query := r.URL.Query()
+		if query.Get("input") != "" {
+			query.Set("input", tenant.Path(query.Get("input")))
+			r.URL.RawQuery = query.Encode()
+		}
+		if r.Body != nil {
+			payloadBytes, err := audit.Payload(r)
+			if err == nil {
+				payloadBytes, err = rewritePayload(payloadBytes, tenant)
+			}
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, fmt.Sprintf("Request Entity Too Large: payloads are limited to %v bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+			r = audit.WithPayload(r, payloadBytes)
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey{}, &tenant)))
+	})
+}
+
+// isGlobal reports whether urlPath is one of the globalEndpoints or the metrics.
+func (t *Tenancy) isGlobal(urlPath string) bool {
+	if urlPath == "/metrics" {
+		return true
+	}
+	for _, globalEndpoint := range globalEndpoints {
+		if urlPath == t.apiEndpoint+globalEndpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// rewritePayload returns the JSON payload payloadBytes with its paths resolved below the root of tenant and its database names prefixed.
+// It reads the first JSON value of the payload like the handlers do, a payload it cannot read is rejected rather than passed on unchanged.
+func rewritePayload(payloadBytes []byte, tenant config.TenantConfig) ([]byte, error) {
+	if len(bytes.TrimSpace(payloadBytes)) == 0 {
+		return payloadBytes, nil // nothing to rewrite, requests without payload are left to the handler
+	}
+	var payload map[string]json.RawMessage
+	err := json.NewDecoder(bytes.NewReader(payloadBytes)).Decode(&payload)
+	if err != nil {
+		return nil, err
+	}
+	for field, rawValue := range payload {
+		var value string
+		if json.Unmarshal(rawValue, &value) != nil || value == "" {
+			continue // not a string or empty, the handler rejects it or does without
+		}
+		rewritten := value
+		for _, pathField := range pathFields {
+			if strings.EqualFold(field, pathField) {
+				rewritten = tenant.Path(value)
+			}
+		}
+		for _, nameField := range nameFields {
+			if strings.EqualFold(field, nameField) {
+				rewritten = tenant.Name + "." + value
+			}
+		}
+		if rewritten != value {
+			payload[field], err = json.Marshal(rewritten)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return json.Marshal(payload)
+}
@@ -0,0 +1,125 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// gobBasicTypes maps the names of the basic types a type hint may use to their Go type.
+var gobBasicTypes = map[string]reflect.Type{
+	"string": reflect.TypeOf(""),
+	"bool":   reflect.TypeOf(false),
+	"int":    reflect.TypeOf(int64(0)),
+	"uint":   reflect.TypeOf(uint64(0)),
+	"float":  reflect.TypeOf(float64(0)),
+	"bytes":  reflect.TypeOf([]byte(nil)),
+	"time":   reflect.TypeOf(time.Time{}),
+}
+
+// GobType returns the Go type described by typeHint, the JSON of a type hint of the config.
+// A type hint is the name of a basic type like "string", "int" (any signed integer), "uint", "float", "bool", "bytes" or "time", prefixed with "[]" or "map[string]" for slices and maps of it.
+// An object describes a struct by its field names, which have to match the names of the fields in the Go app, and an array with one element a slice of the element. Fields the Go app has but the hint lacks are skipped.
+func GobType(typeHint json.RawMessage) (reflect.Type, error) {
+	typeHint = bytes.TrimSpace(typeHint)
+	if len(typeHint) == 0 {
+		return nil, fmt.Errorf("Empty gob type hint\n")
+	}
+	switch typeHint[0] {
+	case '"':
+		var name string
+		err := json.Unmarshal(typeHint, &name)
+		if err != nil {
+			return nil, err
+		}
+		return gobNamedType(name)
+
+	case '[':
+		var elementHints []json.RawMessage
+		err := json.Unmarshal(typeHint, &elementHints)
+		if err != nil {
+			return nil, err
+		}
+		if len(elementHints) != 1 {
+			return nil, fmt.Errorf("Gob type hint of a slice needs exactly one element type\n")
+		}
+		elementType, err := GobType(elementHints[0])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(elementType), nil
+
+	case '{':
+		var fieldHints map[string]json.RawMessage
+		err := json.Unmarshal(typeHint, &fieldHints)
+		if err != nil {
+			return nil, err
+		}
+		fieldNames := make([]string, 0, len(fieldHints))
+		for fieldName := range fieldHints {
+			fieldNames = append(fieldNames, fieldName)
+		}
+		sort.Strings(fieldNames)
+		fields := make([]reflect.StructField, 0, len(fieldNames))
+		for _, fieldName := range fieldNames {
+			if fieldName == "" || !unicode.IsUpper([]rune(fieldName)[0]) {
+				return nil, fmt.Errorf("Gob type hint field %q must be exported like in the Go app\n", fieldName)
+			}
+			fieldType, err := GobType(fieldHints[fieldName])
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, reflect.StructField{Name: fieldName, Type: fieldType})
+		}
+		return reflect.StructOf(fields), nil
+	}
+	return nil, fmt.Errorf("Gob type hint must be a string, an array or an object, not %s\n", typeHint)
+}
+
+// gobNamedType returns the Go type of a type hint string like "int" or "[]string".
+func gobNamedType(name string) (reflect.Type, error) {
+	if elementName, found := strings.CutPrefix(name, "[]"); found && elementName != "" {
+		elementType, err := gobNamedType(elementName)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(elementType), nil
+	}
+	if elementName, found := strings.CutPrefix(name, "map[string]"); found {
+		elementType, err := gobNamedType(elementName)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(gobBasicTypes["string"], elementType), nil
+	}
+	basicType, found := gobBasicTypes[name]
+	if !found {
+		return nil, fmt.Errorf("Unknown gob type %q, use string, bool, int, uint, float, bytes or time\n", name)
+	}
+	return basicType, nil
+}
+
+// LoadGobTypes registers the type of every gob type of the config for its buckets, so dumps decode their values and writes can encode them.
+func LoadGobTypes(gobTypes []config.GobTypeConfig) error {
+	for _, gobTypeConfig := range gobTypes {
+		valueType, err := GobType(gobTypeConfig.Type)
+		if err != nil {
+			return fmt.Errorf("Invalid gob type for %v: %v", gobTypeConfig.Buckets, err)
+		}
+		for _, bucketPattern := range gobTypeConfig.Buckets {
+			err = bboltdump.RegisterGobType(bucketPattern, reflect.Zero(valueType).Interface())
+			if err != nil {
+				return fmt.Errorf("Invalid gob type bucket pattern %q: %v\n", bucketPattern, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+// Package transform loads the Go plugins and gob type hints of the config that turn values stored in app specific serializations into JSON for full dumps.
+package transform
+
+import (
+	"fmt"
+	"plugin"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// Symbol is the name of the function every transformer plugin has to export.
+const Symbol = "Transform"
+
+// Load opens the plugins of transformers and registers their Transform function for their buckets.
+// A plugin has to be built with -buildmode=plugin by the same Go version and with the same versions of shared packages as the server.
+func Load(transformers []config.TransformerConfig) error {
+	for _, transformerConfig := range transformers {
+		loadedPlugin, err := plugin.Open(transformerConfig.Plugin)
+		if err != nil {
+			return fmt.Errorf("Failed to load transformer plugin: %v\n", err)
+		}
+		symbol, err := loadedPlugin.Lookup(Symbol)
+		if err != nil {
+			return fmt.Errorf("Failed to load transformer plugin: %v\n", err)
+		}
+		transformFunc, ok := symbol.(func([]byte) ([]byte, error))
+		if !ok {
+			return fmt.Errorf("Transformer plugin %v must export func %v(value []byte) ([]byte, error), not %T\n", transformerConfig.Plugin, Symbol, symbol)
+		}
+		for _, bucketPattern := range transformerConfig.Buckets {
+			err = bboltdump.RegisterTransformer(bucketPattern, transformFunc)
+			if err != nil {
+				return fmt.Errorf("Invalid transformer bucket pattern %q: %v\n", bucketPattern, err)
+			}
+		}
+		fmt.Println("Loaded transformer", transformerConfig.Plugin, "for", transformerConfig.Buckets)
+	}
+	return nil
+}
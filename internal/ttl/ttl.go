@@ -0,0 +1,29 @@
+// Package ttl periodically deletes the expired keys of the registered databases.
+package ttl
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// Run sweeps every registered database once per configured interval. It never returns.
+func Run(cfg config.Config) {
+	ticker := time.NewTicker(cfg.Ttl.SweepInterval.Duration)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, registeredDb := range cfg.Databases {
+			deleted, err := bboltdump.SweepExpired(registeredDb.Path)
+			if err != nil {
+				fmt.Println("ERROR:", err)
+				continue
+			}
+			if deleted > 0 {
+				fmt.Println("Deleted", deleted, "expired keys of database", registeredDb.Name)
+			}
+		}
+	}
+}
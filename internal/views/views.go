@@ -0,0 +1,65 @@
+// Package views computes the materialized views of the registered databases and keeps those refreshed on write up to date.
+package views
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+)
+
+// ErrViewNotFound is returned when a view that is not in the config is refreshed.
+var ErrViewNotFound = errors.New("view does not exist")
+
+// Definition returns the bboltdump.ViewDefinition of view.
+func Definition(view config.ViewConfig) bboltdump.ViewDefinition {
+	return bboltdump.ViewDefinition{Name: view.Name, Bucket: view.Bucket, Kind: view.Kind, Separator: view.Separator}
+}
+
+// Maintain makes every write through the API update the views of cfg that are refreshed on write.
+func Maintain(cfg config.Config) {
+	for _, view := range cfg.Views {
+		registeredDb, found := cfg.LookupDb(view.Db)
+		if found && view.Refresh == "write" {
+			bboltdump.MaintainView(registeredDb.Path, Definition(view))
+		}
+	}
+}
+
+// RefreshAll computes the views of cfg that are refreshed on write once, since writes made while the server was not running are not in them yet.
+func RefreshAll(cfg config.Config) {
+	for _, view := range cfg.Views {
+		if view.Refresh != "write" {
+			continue
+		}
+		_, err := Refresh(cfg, view.Db, view.Name)
+		if err != nil {
+			fmt.Println("ERROR: Failed to refresh view", view.Name, "of database", view.Db+":", err)
+		}
+	}
+}
+
+// Refresh computes the view name of the registered database db from scratch, or all its views if name is empty, and returns their ViewInfo.
+// It fails with ErrViewNotFound if db has no such view.
+func Refresh(cfg config.Config, db string, name string) ([]bboltdump.ViewInfo, error) {
+	registeredDb, found := cfg.LookupDb(db)
+	if !found {
+		return nil, ErrViewNotFound
+	}
+	viewInfos := []bboltdump.ViewInfo{}
+	for _, view := range cfg.Views {
+		if view.Db != db || name != "" && view.Name != name {
+			continue
+		}
+		viewInfo, err := bboltdump.RefreshView(registeredDb.Path, Definition(view))
+		if err != nil {
+			return nil, err
+		}
+		viewInfos = append(viewInfos, viewInfo)
+	}
+	if name != "" && len(viewInfos) == 0 {
+		return nil, ErrViewNotFound
+	}
+	return viewInfos, nil
+}
@@ -0,0 +1,108 @@
+package watch
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const queueLength = 1000              // notifications waiting for a subscriber at most, newer ones are dropped while it is full
+const firstRetryDelay = time.Second   // wait before the first retry of a failed delivery, it doubles with every retry
+const maxRetryDelay = 5 * time.Minute // longest wait between two retries
+const signatureHeader = "X-Bbolt-Signature-256"
+const deliveryHeader = "X-Bbolt-Delivery"
+
+// subscriber is a struct representing a webhook URL and the notifications waiting to be delivered to it, which are delivered one at a time in the order they happened.
+type subscriber struct {
+	url     string
+	secret  string // key of the HMAC signature of every notification, unsigned if empty
+	retries int
+	queue   chan []byte
+}
+
+// newSubscriber returns a subscriber for url and starts delivering its notifications.
+func newSubscriber(url string, secret string, retries int) *subscriber {
+	sub := &subscriber{
+		url:     url,
+		secret:  secret,
+		retries: retries,
+		queue:   make(chan []byte, queueLength),
+	}
+	go sub.run()
+	return sub
+}
+
+// notify queues payload for delivery as JSON without waiting for it.
+func (sub *subscriber) notify(payload any) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		fmt.Println("ERROR: Failed to serialize webhook payload:", err)
+		return
+	}
+	select {
+	case sub.queue <- payloadBytes:
+	default:
+		fmt.Printf("ERROR: Dropped webhook notification for %v, %v notifications are waiting for it already\n", sub.url, queueLength)
+	}
+}
+
+// run delivers the queued notifications. It never returns.
+func (sub *subscriber) run() {
+	for payloadBytes := range sub.queue {
+		sub.deliver(payloadBytes)
+	}
+}
+
+// deliver POSTs payloadBytes to the subscriber and retries with growing delays if it fails, every attempt carries the same delivery id so the subscriber can tell retries apart from new notifications.
+func (sub *subscriber) deliver(payloadBytes []byte) {
+	idBytes := make([]byte, 16)
+	rand.Read(idBytes)
+	deliveryId := hex.EncodeToString(idBytes)
+
+	delay := firstRetryDelay
+	for attempt := 0; ; attempt++ {
+		retryable, err := sub.send(deliveryId, payloadBytes)
+		if err == nil {
+			return
+		}
+		if !retryable || attempt == sub.retries {
+			fmt.Printf("ERROR: Gave up delivering webhook %v after %v attempts: %v", deliveryId, attempt+1, err)
+			return
+		}
+		time.Sleep(delay)
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// send POSTs payloadBytes to the subscriber once and reports whether a failure is worth retrying, which it is unless the subscriber rejected the notification.
+func (sub *subscriber) send(deliveryId string, payloadBytes []byte) (bool, error) {
+	request, err := http.NewRequest(http.MethodPost, sub.url, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return false, fmt.Errorf("Failed to create webhook request for %v: %v\n", sub.url, err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(deliveryHeader, deliveryId)
+	if sub.secret != "" {
+		mac := hmac.New(sha256.New, []byte(sub.secret))
+		mac.Write(payloadBytes)
+		request.Header.Set(signatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := http.Client{Timeout: webhookTimeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return true, fmt.Errorf("Failed to deliver webhook to %v: %v\n", sub.url, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		retryable := response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusRequestTimeout
+		return retryable, fmt.Errorf("Webhook subscriber %v responded with %v\n", sub.url, response.Status)
+	}
+	return false, nil
+}
@@ -0,0 +1,204 @@
+// Package watch notifies webhook subscribers about changes of registered databases, either by polling the files or right after the server committed a write.
+package watch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+	bolt "go.etcd.io/bbolt"
+)
+
+const webhookTimeout = 10 * time.Second // how long a subscriber may take to accept a notification
+
+// BucketChangeCounts is a struct representing how many keys of a bucket changed.
+type BucketChangeCounts struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Changed int `json:"changed"`
+}
+
+// DiffSummary is a struct representing a condensed DbDiff that only holds counts instead of keys.
+type DiffSummary struct {
+	AddedBuckets   []string                      `json:"addedBuckets"`
+	RemovedBuckets []string                      `json:"removedBuckets"`
+	Buckets        map[string]BucketChangeCounts `json:"buckets"`
+}
+
+// ChangeNotification is a struct representing the payload POSTed to webhook subscribers when a watched database changes.
+type ChangeNotification struct {
+	Db      string       `json:"db"`             // name of the registered database
+	Path    string       `json:"path"`           // path to db file
+	Size    int64        `json:"size"`           // size of the db file in bytes
+	ModTime time.Time    `json:"mtime"`          // modification time of the db file
+	Diff    *DiffSummary `json:"diff,omitempty"` // only set if the webhook asked for a diff summary
+}
+
+// summarizeDiff turns a DbDiff into a DiffSummary.
+func summarizeDiff(dbDiff *bboltdump.DbDiff) *DiffSummary {
+	diffSummary := &DiffSummary{
+		AddedBuckets:   dbDiff.AddedBuckets,
+		RemovedBuckets: dbDiff.RemovedBuckets,
+		Buckets:        make(map[string]BucketChangeCounts),
+	}
+	for bucketName, bucketDiff := range dbDiff.Buckets {
+		diffSummary.Buckets[bucketName] = BucketChangeCounts{
+			Added:   len(bucketDiff.Added),
+			Removed: len(bucketDiff.Removed),
+			Changed: len(bucketDiff.Changed),
+		}
+	}
+	return diffSummary
+}
+
+// WriteNotification is a struct representing the payload POSTed to webhook subscribers of writes once the server committed a write to a registered database.
+type WriteNotification struct {
+	Db      string             `json:"db"`      // name of the registered database
+	Path    string             `json:"path"`    // path to db file
+	Time    time.Time          `json:"time"`    // time of the commit
+	Changes []bboltdump.Change `json:"changes"` // what the commit changed per bucket
+}
+
+// fileWatcher is a struct representing the state of a single watched database.
+type fileWatcher struct {
+	webhook      config.WebhookConfig
+	subscribers  []*subscriber
+	registeredDb config.RegisteredDb
+	lastSize     int64
+	lastModTime  time.Time
+	baselinePath string // copy of the database as of the last notification, only used for diff summaries
+}
+
+// copyToBaseline replaces the baseline copy of the watched database with its current content.
+func (fw *fileWatcher) copyToBaseline() error {
+	if fw.baselinePath == "" {
+		tempFile, err := os.CreateTemp("", "bbolt-watch-*.db")
+		if err != nil {
+			return fmt.Errorf("Failed to create baseline file: %v\n", err)
+		}
+		tempFile.Close()
+		fw.baselinePath = tempFile.Name()
+	}
+
+	// the last diff summary may have left the old baseline open in the handle cache, it must not be read while it is overwritten
+	_, _, err := bboltdump.CloseCachedHandle(fw.baselinePath)
+	if err != nil {
+		return err
+	}
+
+	dbInstance, closeDb, err := bboltdump.OpenLiveDb(fw.registeredDb.Path)
+	if err != nil {
+		return err
+	}
+	defer closeDb()
+
+	return dbInstance.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(fw.baselinePath, 0600)
+	})
+}
+
+// check compares the file with what was seen last time and notifies the subscribers if it changed.
+func (fw *fileWatcher) check() {
+	fileInfo, err := os.Stat(fw.registeredDb.Path)
+	if err != nil {
+		fmt.Println("ERROR: Failed to stat watched database:", err)
+		return
+	}
+	if fileInfo.Size() == fw.lastSize && fileInfo.ModTime().Equal(fw.lastModTime) {
+		return
+	}
+	fw.lastSize = fileInfo.Size()
+	fw.lastModTime = fileInfo.ModTime()
+
+	changeNotification := ChangeNotification{
+		Db:      fw.registeredDb.Name,
+		Path:    fw.registeredDb.Path,
+		Size:    fileInfo.Size(),
+		ModTime: fileInfo.ModTime(),
+	}
+	if fw.webhook.DiffSummary {
+		dbDiff, err := bboltdump.DiffDbs(fw.baselinePath, fw.registeredDb.Path, bboltdump.KeysHex)
+		if err != nil {
+			fmt.Println("ERROR:", err)
+		} else {
+			changeNotification.Diff = summarizeDiff(dbDiff)
+		}
+		err = fw.copyToBaseline()
+		if err != nil {
+			fmt.Println("ERROR:", err)
+		}
+	}
+
+	for _, sub := range fw.subscribers {
+		sub.notify(changeNotification)
+	}
+}
+
+// Run polls the size and modification time of every database that has webhooks configured and notifies the subscribers whenever one of them changes. It never returns.
+// Subscribers of writes are notified after every commit of a write made by the server instead.
+func Run(cfg config.Config) {
+	fileWatchers := []*fileWatcher{}
+	writeSubscribers := make(map[string][]*subscriber) // by absolute path of the db file
+	dbNames := make(map[string]string)                 // by absolute path of the db file
+	for _, webhook := range cfg.Watch.Webhooks {
+		registeredDb, _ := cfg.LookupDb(webhook.Db)
+		subscribers := []*subscriber{}
+		for _, url := range webhook.Urls {
+			subscribers = append(subscribers, newSubscriber(url, webhook.Secret, cfg.Watch.Retries))
+		}
+		if webhook.Writes {
+			absolutePath, err := filepath.Abs(registeredDb.Path)
+			if err != nil {
+				absolutePath = registeredDb.Path
+			}
+			writeSubscribers[absolutePath] = append(writeSubscribers[absolutePath], subscribers...)
+			dbNames[absolutePath] = registeredDb.Name
+			continue
+		}
+
+		fw := &fileWatcher{
+			webhook:      webhook,
+			subscribers:  subscribers,
+			registeredDb: registeredDb,
+		}
+
+		// remember the current state so the first check does not fire
+		fileInfo, err := os.Stat(registeredDb.Path)
+		if err == nil {
+			fw.lastSize = fileInfo.Size()
+			fw.lastModTime = fileInfo.ModTime()
+		}
+		if webhook.DiffSummary {
+			err = fw.copyToBaseline()
+			if err != nil {
+				fmt.Println("ERROR:", err)
+			}
+		}
+		fileWatchers = append(fileWatchers, fw)
+	}
+
+	if len(writeSubscribers) > 0 {
+		bboltdump.OnChange(func(dbPath string, changes []bboltdump.Change) {
+			writeNotification := WriteNotification{
+				Db:      dbNames[dbPath],
+				Path:    dbPath,
+				Time:    time.Now().UTC(),
+				Changes: changes,
+			}
+			for _, sub := range writeSubscribers[dbPath] {
+				sub.notify(writeNotification)
+			}
+		})
+	}
+
+	ticker := time.NewTicker(cfg.Watch.Interval.Duration)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, fw := range fileWatchers {
+			fw.check()
+		}
+	}
+}
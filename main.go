@@ -1,154 +1,306 @@
 package main
 
 import (
-	"encoding/hex"
-	"encoding/json"
+	"flag"
 	"fmt"
+	"maps"
 	"net/http" 		// API endpoints
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
 
-	bolt "go.etcd.io/bbolt"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/acl"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/audit"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/backup"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/cli"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/compaction"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/config"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/idempotency"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/pin"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/remote"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/replication"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/resp"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/sdkgen"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/server"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/snapshot"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/stats"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/tenancy"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/transform"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/ttl"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/views"
+	"github.com/downIoads/go-bbolt-apiEndpoint/internal/watch"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/fixture"
+	"github.com/quic-go/quic-go/http3"
 )
 
+// the client SDKs in sdk/ are generated from server.Routes, rerun "go generate" after changing an endpoint
+//go:generate go run . sdk --lang swift --out sdk/swift/Sources/BboltClient/BboltClient.swift
+//go:generate go run . sdk --lang typescript --out sdk/typescript/bbolt-client.ts
+//go:generate go run . sdk --lang openapi --out sdk/openapi.json
 
-// ---- Bbolt related code ----
-
-// BboltDb is a struct representing a bbolt database.
-type BboltDb struct {
-	Path string 							`json:"path"`		// path to db file (this data is received from Swift program) 
-	Buckets map[string]map[string]string 	`json:"buckets"`	// map each Bucket to the key-value pairs it contains
-}
-
-// GetDbContentAsJson takes the path to a bbolt database, reads all its content and returns it as a serialized JSON object of BboltDb along with an error.
-func GetDbContentAsJson(dbPath string) ([]byte, error) {
-	var bboltDbObject BboltDb
+func main() {
+	API_ENDPOINT := "/bbolt"
+	PORT := 8085
 
-	// intialize the Buckets map
-	bboltDbObject.Buckets = make(map[string]map[string]string)
+	// run CLI subcommands like "dump" without starting the server
+	if cli.Run(os.Args[1:]) {
+		return
+	}
 
-	// open database
-	dbInstance, err := bolt.Open(dbPath, 0400, nil) // 0400 == read only
-	if err != nil {
-		return nil, fmt.Errorf("Failed to open database: %v\n", err)
+	// load registered databases
+	var serverConfig config.Config
+	configPath := flag.String("config", "", "path to JSON config file with registered databases")
+	readOnly := flag.Bool("read-only", false, "refuse all writes and open every database with bolt's ReadOnly option")
+	flag.Parse()
+	if *readOnly {
+		// before the first database is opened, fixtures are still created since they are new files
+		bboltdump.SetReadOnly()
+		fmt.Println("Serving all databases read-only")
+	}
+	if *configPath != "" {
+		loadedConfig, err := config.Load(*configPath)
+		if err != nil {
+			fmt.Println("ERROR:", err)
+			os.Exit(1)
+		}
+		serverConfig = loadedConfig
 	}
-	defer dbInstance.Close()
 
-	// get existing buckets
-	err = dbInstance.View(func(tx *bolt.Tx) error {
-		return tx.ForEach(func(bucketName []byte, _ *bolt.Bucket) error {
-			// create new empty bucket that represents the bucket we just found
-			bboltDbObject.Buckets[string(bucketName)] = make(map[string]string)
-			return nil
+	// databases registered with a fixture are created from it and only live as long as the server does
+	fixturePaths := []string{}
+	for i, registeredDb := range serverConfig.Databases {
+		if registeredDb.Fixture == "" {
+			continue
+		}
+		loadedFixture, err := fixture.Load(registeredDb.Fixture)
+		if err == nil && registeredDb.Path == "" {
+			serverConfig.Databases[i].Path, err = fixture.CreateTemp("", "bbolt-fixture-*.db", loadedFixture)
+		} else if err == nil {
+			err = fixture.Create(registeredDb.Path, loadedFixture)
+		}
+		if err != nil {
+			fmt.Println("ERROR:", err)
+			for _, fixturePath := range fixturePaths {
+				os.Remove(fixturePath)
+			}
+			os.Exit(1)
+		}
+		fixturePaths = append(fixturePaths, serverConfig.Databases[i].Path)
+		fmt.Println("Created database " + registeredDb.Name + " from fixture " + registeredDb.Fixture + " at " + serverConfig.Databases[i].Path)
+	}
+	if len(fixturePaths) > 0 {
+		go func() {
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+			<-signals
+			for _, fixturePath := range fixturePaths {
+				os.Remove(fixturePath)
+			}
+			os.Exit(0)
+		}()
+	}
+	if serverConfig.Lock.Timeout.Duration > 0 {
+		bboltdump.SetLockPolicy(bboltdump.LockPolicy{
+			Timeout: serverConfig.Lock.Timeout.Duration,
+			Retries: serverConfig.Lock.Retries,
+			Backoff: serverConfig.Lock.Backoff.Duration,
 		})
-	})
-	if err != nil {
-		return nil, fmt.Errorf("Failed to get buckets of database due to error: %v\n", err)
 	}
-
-	// iterate over each bucket
-	for bucketNameString := range bboltDbObject.Buckets {
-		// populate bboltDbObject with data
-		err = dbInstance.View(func(tx *bolt.Tx) error {
-			// access current bucket
-	        b := tx.Bucket([]byte(bucketNameString))
-	        if b == nil {
-	            return fmt.Errorf("Failed to access bucket %v even though it should exist!\n", bucketNameString)
-	        }
-	        // iterate over each key in current bucket
-	        cursor := b.Cursor()
-	        for keyBytes, _ := cursor.First(); keyBytes != nil; keyBytes, _ = cursor.Next() {
-
-				// cast key to string
-	        	keyString := hex.EncodeToString(keyBytes)
-
-	        	// get value that corresponds to this key
-	        	v := b.Get(keyBytes)
-			    if v == nil {
-			    	return fmt.Errorf("In bucket %v tried to access value of key %v but failed due to error: %v\n", bucketNameString, keyString, err)
-			    }
-
-	        	// add key-value pair to bboltDbObject in the correct bucket
-	            bboltDbObject.Buckets[bucketNameString][keyString] = string(v)
-	        }
-
-	        return nil
-	    })
-	    if err != nil {
-	        panic(err)
-	    }
-
+	applyDatabaseSettings(serverConfig)
+	if serverConfig.HandleCache.Enabled {
+		bboltdump.EnableHandleCache(serverConfig.HandleCache.IdleTimeout.Duration)
 	}
-
-	// serialize bboltDbObject to json
-	bboltDbObjectJson, err := json.Marshal(bboltDbObject)
+	if serverConfig.S3.Enabled {
+		err := remote.EnableS3(serverConfig.S3)
+		if err != nil {
+			fmt.Println("ERROR:", err)
+			os.Exit(1)
+		}
+	}
+	err := pin.Enable(serverConfig.Pins)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to serialize object to json: %v\n", err)
+		fmt.Println("ERROR:", err)
+		os.Exit(1)
 	}
-
-	return bboltDbObjectJson, nil
-}
-
-
-// ---- API endpoints related code ----
-
-// RequestPayload is a struct representing the expected request payload
-type RequestPayload struct {
-	Input string `json:"input"`
-}
-
-// ResponsePayload is a struct representing the response payload
-type ResponsePayload struct {
-	Result string `json:"result"`
-}
-
-// handleRequest handles API endpoint requests
-func handleRequest(w http.ResponseWriter, r *http.Request) {
-	// only allow POST request
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed. Please use POST.", http.StatusMethodNotAllowed)
-		return
+	err = transform.Load(serverConfig.Transformers)
+	if err == nil {
+		err = transform.LoadGobTypes(serverConfig.GobTypes)
 	}
-
-	// decode request
-	var requestPayload RequestPayload
-	err := json.NewDecoder(r.Body).Decode(&requestPayload)
 	if err != nil {
-		http.Error(w, "Bad Request", http.StatusBadRequest)
-		return
+		fmt.Println("ERROR:", err)
+		os.Exit(1)
 	}
-
-	// do actual work
-	resultBytes, err := GetDbContentAsJson(requestPayload.Input)
+	auditLog, err := audit.Open(serverConfig.Audit)
 	if err != nil {
 		fmt.Println("ERROR:", err)
-		return // if the request is valid but the response invalid, then do not respond
+		os.Exit(1)
 	}
-	result := string(resultBytes)
-
-	// create response payload
-	responsePayload := ResponsePayload {
-		Result: result,
+	if serverConfig.Snapshots.Dir != "" {
+		go snapshot.Run(serverConfig)
+	}
+	stats.Enable()
+	if len(serverConfig.Compaction.Schedules) > 0 && !*readOnly {
+		go compaction.Run(serverConfig)
+	}
+	if len(serverConfig.Backups.Schedules) > 0 {
+		go backup.Run(serverConfig)
+	}
+	if len(serverConfig.Replication.Peers) > 0 {
+		go replication.Run(serverConfig)
+	}
+	if serverConfig.Ttl.SweepInterval.Duration > 0 && !*readOnly {
+		go ttl.Run(serverConfig)
+	}
+	if len(serverConfig.Views) > 0 && !*readOnly {
+		views.Maintain(serverConfig)
+		go views.RefreshAll(serverConfig)
+	}
+	if len(serverConfig.Watch.Webhooks) > 0 {
+		go watch.Run(serverConfig)
+	}
+	if serverConfig.Resp.Addr != "" {
+		go func() {
+			err := resp.Run(serverConfig, auditLog)
+			fmt.Println("ERROR:", err)
+		}()
 	}
 
-	// encode response payload and send it
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(responsePayload)
+	apiServer := server.New(serverConfig)
+	apiServer.RegisterRoutes(http.DefaultServeMux, API_ENDPOINT)
+	openApi, err := sdkgen.OpenApiFor(server.Routes, API_ENDPOINT)
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
+		fmt.Println("ERROR:", err)
+		os.Exit(1)
 	}
-	fmt.Println("Successfully sent response.")
-}
-
-func main() {
-	API_ENDPOINT := "/bbolt"
-	PORT := 8085
-
-	http.HandleFunc(API_ENDPOINT, handleRequest)
+	server.RegisterDocs(http.DefaultServeMux, openApi)
 	fmt.Println("Server listening on localhost:" + fmt.Sprint(PORT) + API_ENDPOINT)
-	http.ListenAndServe(":" + fmt.Sprint(PORT), nil)
+	// denied requests and requests that panicked are audited too, replayed writes only once they are allowed. Payloads are read and limited once, before any middleware looks at them
+	handlerFor := func(cfg config.Config) http.Handler {
+		return audit.LimitPayload(auditLog.Handler(tenancy.New(cfg, API_ENDPOINT).Handler(stats.Handler(acl.New(cfg, API_ENDPOINT).Handler(idempotency.New(cfg, API_ENDPOINT).Handler(server.Recover(http.DefaultServeMux)))))), cfg.Server.WithDefaults().MaxPayloadBytes)
+	}
+	handler := server.NewReloadable(handlerFor(serverConfig))
+	if *configPath != "" {
+		// registered databases, access control rules, tenants and limits are replaced on SIGHUP or by the reload endpoint, listeners and schedules need a restart
+		var reloadMu sync.Mutex
+		runningConfig := serverConfig
+		reload := func() error {
+			reloadMu.Lock()
+			defer reloadMu.Unlock()
+			reloadedConfig, err := config.Load(*configPath)
+			if err != nil {
+				return err
+			}
+			// databases created from fixtures keep the file they were created in at startup
+			for i, registeredDb := range reloadedConfig.Databases {
+				if registeredDb.Fixture == "" {
+					continue
+				}
+				runningDb, found := runningConfig.LookupDb(registeredDb.Name)
+				if !found || runningDb.Fixture != registeredDb.Fixture {
+					return fmt.Errorf("Database %v is created from a fixture, which needs a restart\n", registeredDb.Name)
+				}
+				reloadedConfig.Databases[i].Path = runningDb.Path
+			}
+			err = checkStartupSettings(runningConfig, reloadedConfig)
+			if err != nil {
+				return err
+			}
+			applyDatabaseSettings(reloadedConfig)
+			apiServer.Reload(reloadedConfig)
+			handler.Swap(handlerFor(reloadedConfig))
+			runningConfig = reloadedConfig
+			fmt.Println("Reloaded config from " + *configPath)
+			return nil
+		}
+		apiServer.OnReload(reload)
+		go func() {
+			hangups := make(chan os.Signal, 1)
+			signal.Notify(hangups, syscall.SIGHUP)
+			for range hangups {
+				err := reload()
+				if err != nil {
+					fmt.Println("ERROR:", err)
+				}
+			}
+		}()
+	}
+	httpServer := server.NewHttpServer(":" + fmt.Sprint(PORT), handler, serverConfig.Server)
+	if serverConfig.Http3.Addr != "" {
+		// the same endpoints over QUIC, which keeps large dumps going on lossy mobile networks
+		go func() {
+			fmt.Println("HTTP/3 listening on " + serverConfig.Http3.Addr + API_ENDPOINT)
+			http3Server := &http3.Server{Addr: serverConfig.Http3.Addr, Handler: handler, IdleTimeout: serverConfig.Server.IdleTimeout.Duration}
+			err := http3Server.ListenAndServeTLS(serverConfig.Http3.CertFile, serverConfig.Http3.KeyFile)
+			fmt.Println("ERROR:", err)
+		}()
+	}
+	httpServer.ListenAndServe()
 
 	// SEND EXAMPLE REQUEST:
 	// 		curl -X POST -H "Content-Type: application/json" -d '{"input":"./myBboltDb.db"}' localhost:8085/bbolt
 
 	// if you put path to non-existing database, response will be: {"result":"{\"path\":\"\",\"buckets\":{}}"}
 }
+
+// applyDatabaseSettings makes the package open the registered databases of cfg with their options, journal their writes and keep to the memory limit of cfg.
+func applyDatabaseSettings(cfg config.Config) {
+	bboltdump.SetMemoryLimit(cfg.Server.WithDefaults().MemoryLimit)
+	for _, registeredDb := range cfg.Databases {
+		if options := registeredDb.Options; options != nil {
+			bboltdump.SetOpenOptions(registeredDb.Path, bboltdump.OpenOptions{
+				PageSize:        options.PageSize,
+				NoFreelistSync:  options.NoFreelistSync,
+				FreelistType:    options.FreelistType,
+				Mlock:           options.Mlock,
+				InitialMmapSize: options.InitialMmapSize,
+				PreLoadFreelist: options.PreLoadFreelist,
+			})
+		}
+		if registeredDb.Journal {
+			bboltdump.EnableJournal(registeredDb.Path)
+		}
+	}
+}
+
+// databaseSettings are the settings of a registered database that applyDatabaseSettings registers with the package, which keeps them until the server stops.
+type databaseSettings struct {
+	journal bool
+	options config.BoltOptions
+}
+
+// databaseSettingsOf returns the databaseSettings of the registered databases of cfg that have any, by path.
+func databaseSettingsOf(cfg config.Config) map[string]databaseSettings {
+	settings := make(map[string]databaseSettings)
+	for _, registeredDb := range cfg.Databases {
+		if registeredDb.Options == nil && !registeredDb.Journal {
+			continue
+		}
+		dbSettings := databaseSettings{journal: registeredDb.Journal}
+		if registeredDb.Options != nil {
+			dbSettings.options = *registeredDb.Options
+		}
+		settings[registeredDb.Path] = dbSettings
+	}
+	return settings
+}
+
+// checkStartupSettings returns an error if reloadedConfig changes the settings the package only takes when the server starts: the journal and the options of the registered databases and the views along with the paths of their databases.
+func checkStartupSettings(runningConfig config.Config, reloadedConfig config.Config) error {
+	if !maps.Equal(databaseSettingsOf(runningConfig), databaseSettingsOf(reloadedConfig)) {
+		return fmt.Errorf("Changing the journal or the options of a registered database needs a restart\n")
+	}
+	if !slices.Equal(runningConfig.Views, reloadedConfig.Views) {
+		return fmt.Errorf("Changing the views needs a restart\n")
+	}
+	for _, view := range runningConfig.Views {
+		runningDb, _ := runningConfig.LookupDb(view.Db)
+		reloadedDb, _ := reloadedConfig.LookupDb(view.Db)
+		if runningDb.Path != reloadedDb.Path {
+			return fmt.Errorf("Database %v has views, changing its path needs a restart\n", view.Db)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package bboltdump
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultAnalyzeSample is the amount of entries per bucket Analyze looks at closely if the client does not specify it.
+const DefaultAnalyzeSample = 1000
+
+// maxKeyPatterns is how many of the most common key patterns are reported per bucket, the rest are counted as "other".
+const maxKeyPatterns = 10
+
+// value formats reported by Analyze, well known binary formats are reported by their sniffed content type like "image/png"
+const (
+	FormatEmpty    = "empty"
+	FormatJson     = "json"     // JSON object or array
+	FormatText     = "text"     // UTF-8 text without control characters
+	FormatProtobuf = "protobuf" // binary data that parses as protobuf wire format
+	FormatBinary   = "binary"
+)
+
+// DbAnalysis is a struct representing what Analyze found out about the content of a database.
+type DbAnalysis struct {
+	Buckets []BucketAnalysis `json:"buckets"` // every bucket holding values, nested ones by their path, in key order
+}
+
+// BucketAnalysis is a struct representing the inferred key patterns, value formats and sizes of a bucket.
+type BucketAnalysis struct {
+	Bucket       string         `json:"bucket"`               // name or path of the bucket
+	Keys         int            `json:"keys"`                 // amount of values of the bucket, without its nested buckets
+	Sampled      int            `json:"sampled"`              // amount of entries the formats, patterns and percentiles were inferred from, picked at random
+	ValueFormats map[string]int `json:"valueFormats"`         // sampled values by format, like "json": 950, "text": 50
+	JsonFields   map[string]int `json:"jsonFields,omitempty"` // sampled JSON objects by the top level fields they have, like "id": 950, "email": 700
+	KeyPatterns  map[string]int `json:"keyPatterns"`          // sampled keys by their shape, like "user:{n}", "{uuid}", "{time}" or "{bin:16}"
+	KeySizes     SizeStats      `json:"keySizes"`             // distribution of the key lengths
+	ValueSizes   SizeStats      `json:"valueSizes"`           // distribution of the value sizes
+}
+
+// SizeStats is a struct representing a distribution of sizes in bytes. Min, Max, Mean and Total are taken over all entries, the percentiles over the sampled ones.
+type SizeStats struct {
+	Min   int     `json:"min"`
+	Max   int     `json:"max"`
+	Mean  float64 `json:"mean"`
+	P50   int     `json:"p50"`
+	P90   int     `json:"p90"`
+	P99   int     `json:"p99"`
+	Total int64   `json:"total"` // sum of all sizes
+}
+
+// Analyze takes the path to a bbolt database and returns a DbAnalysis of the bucket bucketName and the buckets nested in it, or of all buckets if bucketName is empty, along with an error.
+// Every bucket is read once. Sizes are counted for all entries, while formats, key patterns and percentiles are inferred from sampleSize entries per bucket picked with reservoir sampling, so a quick look at an unknown database does not have to hold it in memory.
+func Analyze(dbPath string, bucketName string, sampleSize int) (DbAnalysis, error) {
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return DbAnalysis{}, err
+	}
+	defer closeDb()
+
+	dbAnalysis := DbAnalysis{Buckets: []BucketAnalysis{}}
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		checker := newExpiryChecker(tx)
+		return forEachBucketPath(tx, bucketName, func(b *bolt.Bucket, path string) []string {
+			bucketAnalysis, nestedBuckets := analyzeBucket(b, path, sampleSize, checker)
+			if bucketAnalysis.Keys > 0 {
+				dbAnalysis.Buckets = append(dbAnalysis.Buckets, bucketAnalysis)
+			}
+			return nestedBuckets
+		})
+	})
+	if err != nil {
+		return DbAnalysis{}, fmt.Errorf("Failed to analyze database due to error: %v\n", err)
+	}
+	return dbAnalysis, nil
+}
+
+// analyzeBucket reads the values of b and returns their BucketAnalysis along with the names of the buckets nested in b.
+func analyzeBucket(b *bolt.Bucket, path string, sampleSize int, checker *expiryChecker) (BucketAnalysis, []string) {
+	bucketAnalysis := BucketAnalysis{
+		Bucket:       path,
+		ValueFormats: make(map[string]int),
+		KeyPatterns:  make(map[string]int),
+	}
+	nestedBuckets := []string{}
+	type sampledEntry struct{ keyBytes, valueBytes []byte }
+	sample := make([]sampledEntry, 0, sampleSize)
+
+	cursor := b.Cursor()
+	for keyBytes, valueBytes := cursor.First(); keyBytes != nil; keyBytes, valueBytes = cursor.Next() {
+		if valueBytes == nil {
+			nestedBuckets = append(nestedBuckets, string(keyBytes))
+			continue
+		}
+		if checker.isExpired([]byte(path), keyBytes) {
+			continue
+		}
+		bucketAnalysis.Keys++
+		bucketAnalysis.KeySizes.add(len(keyBytes), bucketAnalysis.Keys)
+		bucketAnalysis.ValueSizes.add(len(valueBytes), bucketAnalysis.Keys)
+
+		// fill the reservoir first, afterwards replace a random slot with probability sampleSize/Keys
+		slot := len(sample)
+		if slot == sampleSize {
+			slot = rand.Intn(bucketAnalysis.Keys)
+			if slot >= sampleSize {
+				continue
+			}
+		}
+		entry := sampledEntry{append([]byte{}, keyBytes...), append([]byte{}, valueBytes...)} // the bytes are only valid while the cursor is on them
+		if slot == len(sample) {
+			sample = append(sample, entry)
+		} else {
+			sample[slot] = entry
+		}
+	}
+
+	keySizes := make([]int, 0, len(sample))
+	valueSizes := make([]int, 0, len(sample))
+	for _, entry := range sample {
+		keySizes = append(keySizes, len(entry.keyBytes))
+		valueSizes = append(valueSizes, len(entry.valueBytes))
+		bucketAnalysis.KeyPatterns[keyPattern(entry.keyBytes)]++
+		format := valueFormat(entry.valueBytes)
+		bucketAnalysis.ValueFormats[format]++
+		if format == FormatJson {
+			var object map[string]json.RawMessage
+			if json.Unmarshal(entry.valueBytes, &object) == nil {
+				if bucketAnalysis.JsonFields == nil {
+					bucketAnalysis.JsonFields = make(map[string]int)
+				}
+				for field := range object {
+					bucketAnalysis.JsonFields[field]++
+				}
+			}
+		}
+	}
+	bucketAnalysis.Sampled = len(sample)
+	bucketAnalysis.KeySizes.setPercentiles(keySizes)
+	bucketAnalysis.ValueSizes.setPercentiles(valueSizes)
+	bucketAnalysis.KeyPatterns = mostCommon(bucketAnalysis.KeyPatterns, maxKeyPatterns)
+	return bucketAnalysis, nestedBuckets
+}
+
+// add counts size as the n-th size of the distribution.
+func (s *SizeStats) add(size int, n int) {
+	if n == 1 || size < s.Min {
+		s.Min = size
+	}
+	if size > s.Max {
+		s.Max = size
+	}
+	s.Total += int64(size)
+	s.Mean = float64(s.Total) / float64(n)
+}
+
+// setPercentiles sets the percentiles of s to the ones of sizes.
+func (s *SizeStats) setPercentiles(sizes []int) {
+	if len(sizes) == 0 {
+		return
+	}
+	sort.Ints(sizes)
+	percentile := func(p int) int {
+		return sizes[(len(sizes)-1)*p/100]
+	}
+	s.P50, s.P90, s.P99 = percentile(50), percentile(90), percentile(99)
+}
+
+// mostCommon returns the limit entries of counts with the highest counts, the others are summed up under "other".
+func mostCommon(counts map[string]int, limit int) map[string]int {
+	if len(counts) <= limit {
+		return counts
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	common := make(map[string]int, limit+1)
+	for i, name := range names {
+		if i < limit {
+			common[name] = counts[name]
+		} else {
+			common["other"] += counts[name]
+		}
+	}
+	return common
+}
+
+// patterns that keyPattern replaces in printable keys, most specific first
+var keyPatternParts = []struct {
+	pattern     *regexp.Regexp
+	placeholder string
+}{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "{uuid}"},
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}([T ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:?\d{2})?)?`), "{date}"},
+	{regexp.MustCompile(`[0-9a-fA-F]{16,}`), "{hex}"},
+	{regexp.MustCompile(`\d+`), "{n}"},
+}
+
+// keyPattern returns the shape of keyBytes: printable keys with their ids, dates and numbers replaced by placeholders like "user:{n}", timestamp keys as "{time}" and other binary keys as "{bin:<length>}".
+func keyPattern(keyBytes []byte) string {
+	if !isText(keyBytes) {
+		if KeyTime(keyBytes) != "" {
+			return "{time}"
+		}
+		return fmt.Sprintf("{bin:%d}", len(keyBytes))
+	}
+	pattern := string(keyBytes)
+	for _, part := range keyPatternParts {
+		pattern = part.pattern.ReplaceAllString(pattern, part.placeholder)
+	}
+	return pattern
+}
+
+// valueFormat returns the format of valueBytes, one of the Format constants or the sniffed content type of a well known binary format.
+func valueFormat(valueBytes []byte) string {
+	if len(valueBytes) == 0 {
+		return FormatEmpty
+	}
+	trimmed := strings.TrimSpace(string(valueBytes[:min(len(valueBytes), 64)]))
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid(valueBytes) {
+		return FormatJson
+	}
+	if isText(valueBytes) {
+		return FormatText
+	}
+	contentType := http.DetectContentType(valueBytes)
+	if contentType != "application/octet-stream" && !strings.HasPrefix(contentType, "text/") {
+		return contentType
+	}
+	if looksLikeProtobuf(valueBytes) {
+		return FormatProtobuf
+	}
+	return FormatBinary
+}
+
+// isText reports whether data is valid UTF-8 without control characters other than tabs and line breaks.
+func isText(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if r < 0x20 && !slices.Contains([]rune{'\t', '\n', '\r'}, r) || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// looksLikeProtobuf reports whether data parses as a sequence of protobuf fields that uses up all bytes. Text can parse too, so this is only asked for binary data.
+func looksLikeProtobuf(data []byte) bool {
+	for len(data) > 0 {
+		tag, n := readVarint(data)
+		if n == 0 || tag>>3 == 0 || tag>>3 > 1<<29-1 {
+			return false
+		}
+		data = data[n:]
+		switch tag & 7 {
+		case 0: // varint
+			_, n = readVarint(data)
+			if n == 0 {
+				return false
+			}
+		case 1: // 64 bit
+			n = 8
+		case 2: // length delimited
+			length, lengthSize := readVarint(data)
+			if lengthSize == 0 || length > uint64(len(data)-lengthSize) {
+				return false
+			}
+			n = lengthSize + int(length)
+		case 5: // 32 bit
+			n = 4
+		default: // groups are deprecated, 6 and 7 do not exist
+			return false
+		}
+		if n > len(data) {
+			return false
+		}
+		data = data[n:]
+	}
+	return true
+}
+
+// readVarint returns the protobuf varint at the start of data and its length, which is 0 if data does not start with a complete varint.
+func readVarint(data []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		value |= uint64(data[i]&0x7f) << (7 * i)
+		if data[i] < 0x80 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
@@ -0,0 +1,63 @@
+package bboltdump
+
+import (
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// MaxBatchKeys is the upper bound for the amount of keys a client may read with one GetEntries.
+const MaxBatchKeys = MaxPageLimit
+
+// KeyRef is a struct representing a key of a bucket to be read.
+type KeyRef struct {
+	Bucket string // name or path of the bucket
+	Key    []byte // the key itself
+}
+
+// BatchEntry is a struct representing the result of reading one KeyRef.
+type BatchEntry struct {
+	Bucket string `json:"bucket"` // name of the bucket the key was looked up in
+	Found  bool   `json:"found"`  // the bucket exists and holds an unexpired value under the key, Value is empty otherwise
+	Entry
+}
+
+// GetEntries takes the path to a bbolt database and the keys to read and returns their entries in the same order along with an error.
+// All keys are read within one read transaction, so the entries are consistent with each other. Missing buckets and keys, nested buckets and expired keys are returned with Found unset instead of failing the whole batch.
+// values is one of the Values modes and tells what to return for each value, keys one of the Keys modes.
+func GetEntries(dbPath string, keyRefs []KeyRef, values string, keys string) ([]BatchEntry, error) {
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeDb()
+
+	batchEntries := make([]BatchEntry, 0, len(keyRefs))
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		checker := newExpiryChecker(tx)
+		buckets := make(map[string]*bolt.Bucket) // resolving a nested bucket path walks all its parents
+		for _, keyRef := range keyRefs {
+			b, resolved := buckets[keyRef.Bucket]
+			if !resolved {
+				b = ResolveBucket(tx, keyRef.Bucket)
+				buckets[keyRef.Bucket] = b
+			}
+
+			batchEntry := BatchEntry{Bucket: keyRef.Bucket, Entry: NewEntry(keyRef.Key, nil, ValuesRaw, keys)}
+			if b != nil {
+				valueBytes := b.Get(keyRef.Key)
+				if valueBytes != nil && !checker.isExpired([]byte(keyRef.Bucket), keyRef.Key) {
+					batchEntry.Found = true
+					batchEntry.Entry = NewEntry(keyRef.Key, valueBytes, values, keys)
+				}
+			}
+			batchEntries = append(batchEntries, batchEntry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read keys of database due to error: %v\n", err)
+	}
+	return batchEntries, nil
+}
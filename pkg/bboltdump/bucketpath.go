@@ -0,0 +1,75 @@
+package bboltdump
+
+import (
+	"fmt"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ResolveBucket returns the bucket bucketPath refers to, or nil if it does not exist.
+// bucketPath is either the name of a top level bucket or the slash separated path of a nested bucket like "config/devices/ios". A top level bucket whose name contains a slash wins over a nested bucket with the same path, so databases that already use such names keep working.
+func ResolveBucket(tx *bolt.Tx, bucketPath string) *bolt.Bucket {
+	if b := tx.Bucket([]byte(bucketPath)); b != nil || !strings.Contains(bucketPath, "/") {
+		return b
+	}
+
+	names := strings.Split(bucketPath, "/")
+	b := tx.Bucket([]byte(names[0]))
+	for _, name := range names[1:] {
+		if b == nil {
+			return nil
+		}
+		b = b.Bucket([]byte(name))
+	}
+	return b
+}
+
+// CreateBucketPath returns the bucket bucketPath refers to like ResolveBucket, creating it and all intermediate nested buckets that do not exist yet.
+func CreateBucketPath(tx *bolt.Tx, bucketPath string) (*bolt.Bucket, error) {
+	if b := ResolveBucket(tx, bucketPath); b != nil {
+		return b, nil
+	}
+
+	names := strings.Split(bucketPath, "/")
+	for _, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("Bucket path %q contains an empty bucket name\n", bucketPath)
+		}
+	}
+	b, err := tx.CreateBucketIfNotExists([]byte(names[0]))
+	for _, name := range names[1:] {
+		if err != nil {
+			break
+		}
+		b, err = b.CreateBucketIfNotExists([]byte(name))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create bucket %v: %v\n", bucketPath, err)
+	}
+	return b, nil
+}
+
+// forEachBucketPath calls visit for the bucket bucketPath and all buckets nested in it, or for all buckets if bucketPath is empty, parents before their children.
+// visit reads the bucket anyway, so it returns the names of the buckets nested in it to save a second pass over its keys.
+func forEachBucketPath(tx *bolt.Tx, bucketPath string, visit func(b *bolt.Bucket, path string) []string) error {
+	var descend func(b *bolt.Bucket, path string)
+	descend = func(b *bolt.Bucket, path string) {
+		for _, nestedBucket := range visit(b, path) {
+			descend(b.Bucket([]byte(nestedBucket)), path+"/"+nestedBucket)
+		}
+	}
+
+	if bucketPath != "" {
+		b := ResolveBucket(tx, bucketPath)
+		if b == nil {
+			return fmt.Errorf("Bucket %v does not exist\n", bucketPath)
+		}
+		descend(b, bucketPath)
+		return nil
+	}
+	return tx.ForEach(func(bucketName []byte, b *bolt.Bucket) error {
+		descend(b, string(bucketName))
+		return nil
+	})
+}
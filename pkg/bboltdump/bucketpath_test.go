@@ -0,0 +1,98 @@
+package bboltdump
+
+import (
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestResolveBucket(t *testing.T) {
+	dbPath := createTestDb(t, map[string]map[string]string{
+		"config":         {"a": "1"},
+		"config/devices": {"ios": "on"},
+		"a/b":            {},
+	})
+	// a top level bucket whose name contains a slash, next to the nested bucket a/b
+	updateTestDb(t, dbPath, func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("x/y"))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte("top"), []byte("level"))
+	})
+
+	tests := []struct {
+		name       string
+		bucketPath string
+		wantKey    string // a key the resolved bucket has, empty if it must not resolve
+	}{
+		{"top level bucket", "config", "a"},
+		{"nested bucket", "config/devices", "ios"},
+		{"top level name with slash", "x/y", "top"},
+		{"missing top level bucket", "missing", ""},
+		{"missing nested bucket", "config/missing", ""},
+		{"missing parent", "missing/devices", ""},
+	}
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDb()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbInstance.View(func(tx *bolt.Tx) error {
+				b := ResolveBucket(tx, test.bucketPath)
+				if test.wantKey == "" {
+					if b != nil {
+						t.Errorf("ResolveBucket(%q) = bucket, want nil", test.bucketPath)
+					}
+					return nil
+				}
+				if b == nil {
+					t.Fatalf("ResolveBucket(%q) = nil, want bucket", test.bucketPath)
+				}
+				if b.Get([]byte(test.wantKey)) == nil {
+					t.Errorf("ResolveBucket(%q) has no key %q", test.bucketPath, test.wantKey)
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestCreateBucketPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		bucketPath string
+		wantErr    bool
+	}{
+		{"top level bucket", "users", false},
+		{"nested bucket", "config/devices/ios", false},
+		{"existing bucket", "existing", false},
+		{"empty name in the middle", "config//ios", true},
+		{"trailing slash", "config/", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbPath := createTestDb(t, map[string]map[string]string{"existing": {"k": "v"}})
+			dbInstance, closeDb, err := OpenDbForWriting(dbPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closeDb()
+			err = dbInstance.Update(func(tx *bolt.Tx) error {
+				b, err := CreateBucketPath(tx, test.bucketPath)
+				if err != nil {
+					return err
+				}
+				if ResolveBucket(tx, test.bucketPath) != b {
+					t.Errorf("CreateBucketPath(%q) returned another bucket than ResolveBucket", test.bucketPath)
+				}
+				return nil
+			})
+			if (err != nil) != test.wantErr {
+				t.Errorf("CreateBucketPath(%q) error = %v, want error %v", test.bucketPath, err, test.wantErr)
+			}
+		})
+	}
+}
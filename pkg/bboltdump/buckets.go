@@ -0,0 +1,46 @@
+package bboltdump
+
+import (
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BucketInfo is a struct representing a top level bucket without its content.
+type BucketInfo struct {
+	Name string `json:"name"` // name of the bucket
+	Keys int    `json:"keys"` // amount of keys, including the keys of nested buckets
+}
+
+// ListBucketsAsJson takes the path to a bbolt database and returns its top level buckets in key order as a serialized JSON array of BucketInfo along with an error.
+func ListBucketsAsJson(dbPath string) ([]byte, error) {
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeDb()
+
+	bucketInfos := []BucketInfo{}
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(bucketName []byte, b *bolt.Bucket) error {
+			bucketInfos = append(bucketInfos, BucketInfo{
+				Name: string(bucketName),
+				Keys: b.Stats().KeyN,
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list buckets of database due to error: %v\n", err)
+	}
+
+	// serialize bucketInfos to json
+	bucketInfosJson, err := json.Marshal(bucketInfos)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to serialize object to json: %v\n", err)
+	}
+
+	return bucketInfosJson, nil
+}
@@ -0,0 +1,67 @@
+package bboltdump
+
+import (
+	"encoding/hex"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// MaxChangeKeys is how many keys a Change lists at most, KeyCount tells how many there were.
+const MaxChangeKeys = 1000
+
+// operations of a Change
+const (
+	ChangePut          = "put"          // keys were written
+	ChangeDelete       = "delete"       // keys were deleted, explicitly or because they expired
+	ChangeRenameBucket = "renameBucket" // a bucket was renamed or moved, along with everything in it
+)
+
+// Change is a struct representing what a commit changed in one bucket.
+type Change struct {
+	Operation string   `json:"operation"`          // ChangePut, ChangeDelete or ChangeRenameBucket
+	Bucket    string   `json:"bucket"`             // path of the bucket
+	Keys      []string `json:"keys,omitempty"`     // the first MaxChangeKeys keys that were written or deleted, always hex encoded so subscribers can send them back as they are
+	KeyCount  int      `json:"keyCount,omitempty"` // amount of keys that were written or deleted
+	ToBucket  string   `json:"toBucket,omitempty"` // new path of a renamed bucket
+}
+
+// NewChange returns the Change of operation on keysBytes in the bucket bucketName.
+func NewChange(operation string, bucketName string, keysBytes ...[]byte) Change {
+	change := Change{Operation: operation, Bucket: bucketName, KeyCount: len(keysBytes)}
+	for i := 0; i < len(keysBytes) && i < MaxChangeKeys; i++ {
+		change.Keys = append(change.Keys, hex.EncodeToString(keysBytes[i]))
+	}
+	return change
+}
+
+var changeHooksMu sync.RWMutex
+var changeHooks []func(dbPath string, changes []Change)
+
+// OnChange makes hook learn about every commit a write of the package or RecordChanges reports, with the absolute path of the database and what the commit changed.
+// Hooks run in the committing goroutine while the database is still held open, so they must not block.
+func OnChange(hook func(dbPath string, changes []Change)) {
+	changeHooksMu.Lock()
+	defer changeHooksMu.Unlock()
+	changeHooks = append(changeHooks, hook)
+}
+
+// RecordChanges passes changes to the hooks of OnChange once tx is committed, nothing happens if it is rolled back. Writers of their own transactions call it once per transaction.
+func RecordChanges(tx *bolt.Tx, changes ...Change) {
+	if len(changes) == 0 {
+		return
+	}
+	changeHooksMu.RLock()
+	hooks := changeHooks
+	changeHooksMu.RUnlock()
+	if len(hooks) == 0 {
+		return
+	}
+
+	dbPath := absolutePathOf(tx.DB().Path())
+	tx.OnCommit(func() {
+		for _, hook := range hooks {
+			hook(dbPath, changes)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package bboltdump
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultCompactTxMaxSize is how many bytes of keys and values Compact copies per write transaction of the compacted copy, which bounds its memory use.
+const DefaultCompactTxMaxSize = 64 << 20
+
+// CompactResult is a struct representing a finished compaction.
+type CompactResult struct {
+	SizeBefore int64  `json:"sizeBefore"` // size of the file in bytes before it was compacted
+	SizeAfter  int64  `json:"sizeAfter"`  // size of the file in bytes after it was compacted
+	Duration   string `json:"duration"`   // time the compaction took, like "1.5s"
+}
+
+// Compact rewrites the database at dbPath without its free pages and shrinks the file, copying txMaxSize bytes per transaction (DefaultCompactTxMaxSize if 0).
+// The database is held open for writing the whole time, so nobody else can write it meanwhile: a cached handle is closed first and a LockedError is returned if another process holds the file.
+// The compacted copy is written next to the file and then copied over it in place, so processes waiting for the lock open the compacted file and not a deleted one. If copying it back fails, the compacted copy is kept as dbPath + ".compact".
+func Compact(dbPath string, txMaxSize int64) (CompactResult, error) {
+	start := time.Now()
+	if txMaxSize == 0 {
+		txMaxSize = DefaultCompactTxMaxSize
+	}
+	dbPath, err := localPath(dbPath, true)
+	if err != nil {
+		return CompactResult{}, err
+	}
+	_, _, err = CloseCachedHandle(dbPath) // the cache would hold the file lock otherwise
+	if err != nil {
+		return CompactResult{}, err
+	}
+
+	// open database, privately since nobody may use it while it is replaced
+	holderId := registerHolder(dbPath, true, "bboltdump.Compact")
+	defer unregisterHolder(holderId)
+	srcDb, err := openDbForWriting(dbPath)
+	if err != nil {
+		if lockedError, locked := err.(*LockedError); locked {
+			lockedError.Holders = lockHolders(dbPath)
+		}
+		return CompactResult{}, err
+	}
+	holderOpened(holderId, srcDb)
+	defer srcDb.Close()
+
+	fileInfo, err := os.Stat(dbPath)
+	if err != nil {
+		return CompactResult{}, fmt.Errorf("Failed to read database file info: %v\n", err)
+	}
+	compactResult := CompactResult{SizeBefore: fileInfo.Size()}
+
+	// write the compacted copy with the page size and options of the database
+	compactPath := dbPath + ".compact"
+	os.Remove(compactPath) // left over by an earlier compaction that failed
+	options := boltOptionsFor(dbPath)
+	options.PageSize = srcDb.Info().PageSize
+	options.Timeout = 0
+	dstDb, err := bolt.Open(compactPath, fileInfo.Mode().Perm(), &options)
+	if err != nil {
+		return CompactResult{}, fmt.Errorf("Failed to create compacted copy: %v\n", err)
+	}
+	err = bolt.Compact(dstDb, srcDb, txMaxSize)
+	if closeErr := dstDb.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(compactPath)
+		return CompactResult{}, fmt.Errorf("Failed to compact database %v: %v\n", dbPath, err)
+	}
+
+	compactResult.SizeAfter, err = copyInPlace(compactPath, dbPath)
+	if err != nil {
+		return CompactResult{}, fmt.Errorf("Failed to replace database %v by its compacted copy %v, restore it from there: %v\n", dbPath, compactPath, err)
+	}
+	os.Remove(compactPath)
+	compactResult.Duration = time.Since(start).Round(time.Millisecond).String()
+	return compactResult, nil
+}
+
+// copyInPlace overwrites the file at dstPath with the content of the file at srcPath, truncates it to that size and returns the size.
+func copyInPlace(srcPath string, dstPath string) (int64, error) {
+	srcFile, err := os.Open(srcPath)
+	if err != nil {
+		return 0, err
+	}
+	defer srcFile.Close()
+	dstFile, err := os.OpenFile(dstPath, os.O_WRONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer dstFile.Close()
+
+	size, err := io.Copy(dstFile, srcFile)
+	if err != nil {
+		return 0, err
+	}
+	err = dstFile.Truncate(size)
+	if err != nil {
+		return 0, err
+	}
+	return size, dstFile.Sync()
+}
@@ -0,0 +1,560 @@
+// Package bboltdump reads bbolt databases and turns their content into JSON. It holds the dump, paging, sampling and diff logic of the API server so other Go programs can embed it without running the server.
+package bboltdump
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BboltDb is a struct representing a bbolt database.
+type BboltDb struct {
+	Path      string                       `json:"path"`                // path to db file (this data is received from Swift program)
+	Buckets   map[string]map[string]string `json:"buckets"`             // map each Bucket to the key-value pairs it contains by key as RenderKey renders it for DumpOptions.Keys. Nested buckets are listed by their path like "config/devices"
+	Truncated []string                     `json:"truncated,omitempty"` // buckets that had more keys than DumpOptions.MaxKeysPerBucket allowed
+	TimedOut  []string                     `json:"timedOut,omitempty"`  // buckets that were not read completely since DumpOptions.Context was done, Buckets holds what was read before
+
+	DecryptionFailed map[string][]string `json:"decryptionFailed,omitempty"` // keys per bucket like in Buckets whose values DumpOptions.Decrypter could not decrypt, they are left out of Buckets
+	TransformFailed  map[string][]string `json:"transformFailed,omitempty"`  // keys per bucket like in Buckets whose values the Transformer of the bucket rejected, they are left out of Buckets
+
+	KeyTimes map[string]map[string]string `json:"keyTimes,omitempty"` // RFC 3339 time per timestamp key like in Buckets per bucket, only set if DumpOptions.Keys is KeysTime
+
+	BucketErrors map[string]string `json:"bucketErrors,omitempty"` // error per bucket that failed to be read, only set if DumpOptions.Partial is set. Buckets holds what was read before the error
+
+	Writers *WriterActivity `json:"writers,omitempty"` // whether someone else appears to be writing the database, nil for databases that are not local files
+}
+
+// BboltDbInfo is like BboltDb but holds the ValueInfo of each value instead of the value, it is what a dump with DumpOptions.Values set to ValuesType or ValuesSha256 returns.
+type BboltDbInfo struct {
+	Path      string                          `json:"path"`
+	Buckets   map[string]map[string]ValueInfo `json:"buckets"`
+	Truncated []string                        `json:"truncated,omitempty"`
+	TimedOut  []string                        `json:"timedOut,omitempty"`
+
+	DecryptionFailed map[string][]string `json:"decryptionFailed,omitempty"`
+	TransformFailed  map[string][]string `json:"transformFailed,omitempty"`
+
+	KeyTimes map[string]map[string]string `json:"keyTimes,omitempty"`
+
+	BucketErrors map[string]string `json:"bucketErrors,omitempty"`
+
+	Writers *WriterActivity `json:"writers,omitempty"`
+}
+
+// OpenDb opens the bbolt database at dbPath and returns its handle along with the function that closes it, the caller is responsible for calling it.
+// If the handle cache is enabled the cached handle is returned instead and the function gives it back to the cache. If a read replica is set for dbPath, the replica is opened instead.
+func OpenDb(dbPath string) (*bolt.DB, func(), error) {
+	return open(readReplicaOf(dbPath), false, callerName())
+}
+
+// OpenDbForWriting opens the bbolt database at dbPath for modifications, the file is created if it does not exist yet. Like OpenDb it returns the function that closes the handle, the caller is responsible for calling it.
+func OpenDbForWriting(dbPath string) (*bolt.DB, func(), error) {
+	return open(dbPath, true, callerName())
+}
+
+var readOnly bool
+
+// SetReadOnly makes the package open every database with bolt's ReadOnly option, which only takes a shared file lock so the app owning the database can keep it open, and refuse all writes with ErrReadOnlyDb. It must be called before the first database is opened.
+func SetReadOnly() {
+	readOnly = true
+}
+
+// IsReadOnly reports whether SetReadOnly was called.
+func IsReadOnly() bool {
+	return readOnly
+}
+
+// open opens the database at dbPath for holderName, either through the handle cache or directly, and keeps track of it until the returned function is called.
+func open(dbPath string, writable bool, holderName string) (*bolt.DB, func(), error) {
+	dbPath, err := localPath(dbPath, writable)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opener := openDb
+	if writable {
+		opener = openDbForWriting
+	}
+
+	// the holder is registered before opening, so callers waiting for the file lock show up too
+	holderId := registerHolder(dbPath, writable, holderName)
+
+	var dbInstance *bolt.DB
+	var closeDb func()
+	if cache != nil {
+		dbInstance, closeDb, err = cache.acquire(dbPath, opener)
+	} else {
+		dbInstance, err = opener(dbPath)
+		closeDb = func() { dbInstance.Close() }
+	}
+	if err != nil {
+		unregisterHolder(holderId)
+		// looked up once waiting gave up, so it names who held the lock all along
+		if lockedError, locked := err.(*LockedError); locked {
+			lockedError.Holders = lockHolders(dbPath)
+		}
+		return nil, nil, err
+	}
+	holderOpened(holderId, dbInstance)
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			unregisterHolder(holderId)
+			closeDb()
+		})
+	}
+	return dbInstance, release, nil
+}
+
+// openDb opens the bbolt database at dbPath without going through the handle cache.
+func openDb(dbPath string) (*bolt.DB, error) {
+	dbInstance, err := openWithLockPolicy(dbPath, 0400) // 0400 == read only
+	if _, locked := err.(*LockedError); locked {
+		return nil, err // as it is, so callers can tell that the database is busy
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open database: %v\n", err)
+	}
+	return dbInstance, nil
+}
+
+// openDbForWriting opens the bbolt database at dbPath for modifications without going through the handle cache.
+func openDbForWriting(dbPath string) (*bolt.DB, error) {
+	dbInstance, err := openWithLockPolicy(dbPath, 0600)
+	if _, locked := err.(*LockedError); locked {
+		return nil, err // as it is, so callers can tell that the database is busy
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open database for writing: %v\n", err)
+	}
+	return dbInstance, nil
+}
+
+// IsValidFillPercent reports whether fillPercent can be used as the Bucket.FillPercent of a write. Zero means bolt's default of bolt.DefaultFillPercent.
+// Higher values pack pages fuller, 1.0 gives the smallest file for keys that are inserted in sorted order like by an import.
+func IsValidFillPercent(fillPercent float64) bool {
+	return fillPercent == 0 || (fillPercent >= 0.1 && fillPercent <= 1.0) // bolt silently clamps values outside of this range
+}
+
+// GetDbContentAsJson takes the path to a bbolt database, reads all its content and returns it as a serialized JSON object of BboltDb along with an error.
+func GetDbContentAsJson(dbPath string) ([]byte, error) {
+	return GetDbContentWithOptionsAsJson(dbPath, DumpOptions{})
+}
+
+// GetDbContentWithOptionsAsJson is like GetDbContentAsJson but only dumps what dumpOptions ask for, e.g. leaves out huge buckets.
+// The dump is built in memory, so it fails with ErrMemoryLimit if it would not fit under the memory limit. WriteDbContentAsJson streams it instead.
+func GetDbContentWithOptionsAsJson(dbPath string, dumpOptions DumpOptions) ([]byte, error) {
+	account := &memoryAccount{}
+	defer account.close()
+	bboltDbObjectJson := &accountedBuffer{account: account}
+	err := WriteDbContentAsJson(bboltDbObjectJson, dbPath, dumpOptions)
+	if err != nil {
+		return nil, err
+	}
+	return bboltDbObjectJson.Bytes(), nil
+}
+
+// WriteDbContentAsJson writes the same JSON object GetDbContentWithOptionsAsJson returns to out, but encodes it key by key instead of building it in memory first, so databases larger than the RAM can be dumped.
+// If out has a Flush method, like an http.ResponseWriter, it is called after every top level bucket. Nothing is written if the options are invalid or the database cannot be opened, so the caller can still report the error.
+func WriteDbContentAsJson(out io.Writer, dbPath string, dumpOptions DumpOptions) error {
+	err := dumpOptions.Validate()
+	if err != nil {
+		return err
+	}
+
+	// look for other writers before the lock of the open keeps them out
+	writers := probeWriters(dbPath)
+
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return err
+	}
+	defer closeDb()
+
+	// get existing buckets
+	topLevelBucketNames := []string{}
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		if writers != nil {
+			writers.observe(uint64(tx.ID()))
+		}
+		return tx.ForEach(func(bucketName []byte, _ *bolt.Bucket) error {
+			if dumpOptions.includesBucket(string(bucketName)) {
+				topLevelBucketNames = append(topLevelBucketNames, string(bucketName))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to get buckets of database due to error: %v\n", err)
+	}
+
+	// what the dump holds in memory besides what it streams counts against the memory limit until it is done
+	account := &memoryAccount{}
+	defer account.close()
+
+	// the object is written in the shape of BboltDb, or BboltDbInfo if the ValueInfo of each value is dumped instead of the value
+	writer := bufio.NewWriter(out)
+	writer.WriteString(`{"path":"","buckets":{`)
+	var report dumpReport
+
+	// hand a finished top level bucket to the client
+	flushBucket := func(bucketReport dumpReport) error {
+		report.add(bucketReport)
+		err := writer.Flush()
+		if err != nil {
+			return fmt.Errorf("Failed to write dump: %v\n", err)
+		}
+		if flusher, ok := out.(interface{ Flush() }); ok {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	if dumpOptions.Workers > 1 {
+		err = writeBucketsInParallel(dbInstance, topLevelBucketNames, dumpOptions, account, func(bucketIndex int, bucketJson []byte, bucketReport dumpReport) error {
+			if bucketIndex > 0 {
+				writer.WriteByte(',')
+			}
+			writer.Write(bucketJson)
+			return flushBucket(bucketReport)
+		}, func(bucketIndex int, tx *bolt.Tx) error {
+			if bucketIndex > 0 {
+				writer.WriteByte(',')
+			}
+			bucketReport, err := writeBucket(writer, tx, topLevelBucketNames[bucketIndex], dumpOptions, account)
+			if err != nil {
+				return err
+			}
+			return flushBucket(bucketReport)
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		// iterate over each bucket, each in a transaction of its own so a long dump does not hold one transaction open all the time
+		for bucketIndex, bucketNameString := range topLevelBucketNames {
+			if bucketIndex > 0 {
+				writer.WriteByte(',')
+			}
+			var bucketReport dumpReport
+			err = dbInstance.View(func(tx *bolt.Tx) error {
+				bucketReport, err = writeBucket(writer, tx, bucketNameString, dumpOptions, account)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			err = flushBucket(bucketReport)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.WriteByte('}')
+
+	// buckets are read in transactions of their own, writes through a shared handle can slip in between
+	if writers != nil {
+		dbInstance.View(func(tx *bolt.Tx) error {
+			writers.observe(uint64(tx.ID()))
+			return nil
+		})
+	}
+
+	if len(report.truncated) > 0 {
+		writer.WriteString(`,"truncated":`)
+		writeJson(writer, report.truncated)
+	}
+	if len(report.timedOut) > 0 {
+		writer.WriteString(`,"timedOut":`)
+		writeJson(writer, report.timedOut)
+	}
+	if len(report.decryptionFailed) > 0 {
+		writer.WriteString(`,"decryptionFailed":`)
+		writeJson(writer, report.decryptionFailed)
+	}
+	if len(report.transformFailed) > 0 {
+		writer.WriteString(`,"transformFailed":`)
+		writeJson(writer, report.transformFailed)
+	}
+	if len(report.keyTimes) > 0 {
+		writer.WriteString(`,"keyTimes":`)
+		writeJson(writer, report.keyTimes)
+	}
+	if len(report.bucketErrors) > 0 {
+		writer.WriteString(`,"bucketErrors":`)
+		writeJson(writer, report.bucketErrors)
+	}
+	if writers != nil {
+		writer.WriteString(`,"writers":`)
+		writeJson(writer, writers)
+	}
+	writer.WriteByte('}')
+	err = writer.Flush()
+	if err != nil {
+		return fmt.Errorf("Failed to write dump: %v\n", err)
+	}
+	return nil
+}
+
+// dumpReport is a struct representing what a dump reports about its buckets besides their content.
+type dumpReport struct {
+	truncated        []string            // paths of the buckets that were truncated
+	timedOut         []string            // paths of the buckets that were not read completely since the dump timed out
+	decryptionFailed map[string][]string // keys per bucket path whose values could not be decrypted, rendered like the dumped keys
+	transformFailed  map[string][]string // keys per bucket path whose values could not be transformed, rendered like the dumped keys
+
+	keyTimes map[string]map[string]string // RFC 3339 time per timestamp key per bucket path, rendered like the dumped keys
+
+	bucketErrors map[string]string // error per bucket path that failed to be read in partial mode
+}
+
+// add adds the findings of other to r.
+func (r *dumpReport) add(other dumpReport) {
+	r.truncated = append(r.truncated, other.truncated...)
+	r.timedOut = append(r.timedOut, other.timedOut...)
+	for bucketPath, keys := range other.decryptionFailed {
+		r.decryptionFailed = addKeys(r.decryptionFailed, bucketPath, keys...)
+	}
+	for bucketPath, keys := range other.transformFailed {
+		r.transformFailed = addKeys(r.transformFailed, bucketPath, keys...)
+	}
+	for bucketPath, keyTimes := range other.keyTimes {
+		for keyString, keyTime := range keyTimes {
+			r.addKeyTime(bucketPath, keyString, keyTime)
+		}
+	}
+	for bucketPath, bucketError := range other.bucketErrors {
+		r.addBucketError(bucketPath, bucketError)
+	}
+}
+
+// addBucketError reports that reading the bucket bucketPath failed with bucketError.
+func (r *dumpReport) addBucketError(bucketPath string, bucketError string) {
+	if r.bucketErrors == nil {
+		r.bucketErrors = make(map[string]string)
+	}
+	r.bucketErrors[bucketPath] = bucketError
+}
+
+// addKeyTime reports that the key keyString of the bucket bucketPath stands for keyTime.
+func (r *dumpReport) addKeyTime(bucketPath string, keyString string, keyTime string) {
+	if r.keyTimes == nil {
+		r.keyTimes = make(map[string]map[string]string)
+	}
+	if r.keyTimes[bucketPath] == nil {
+		r.keyTimes[bucketPath] = make(map[string]string)
+	}
+	r.keyTimes[bucketPath][keyString] = keyTime
+}
+
+// addKeys adds keyStrings to the keys of the bucket bucketPath in keysPerBucket, which is created if nil.
+func addKeys(keysPerBucket map[string][]string, bucketPath string, keyStrings ...string) map[string][]string {
+	if keysPerBucket == nil {
+		keysPerBucket = make(map[string][]string)
+	}
+	keysPerBucket[bucketPath] = append(keysPerBucket[bucketPath], keyStrings...)
+	return keysPerBucket
+}
+
+// writeBucket writes the top level bucket bucketName and all buckets nested in it as members of the "buckets" object of a dump and reports which of them were truncated or could not be decrypted.
+// Panics while reading, like those of bolt on a corrupted page, are returned as errors. In partial mode the error of a bucket is reported instead and the dump goes on with the next bucket, unless it is ErrMemoryLimit.
+// The keys of a bucket whose keys are split are held in memory until the bucket is complete, they are reserved in account.
+func writeBucket(writer *bufio.Writer, tx *bolt.Tx, bucketName string, dumpOptions DumpOptions, account *memoryAccount) (dumpReport, error) {
+	writtenBuckets := 0
+	var report dumpReport
+	var checker *expiryChecker
+
+	// dumpBucket writes the keys of b as the bucket bucketPath followed by its nested buckets, depth is 1 for top level buckets
+	var dumpBucket func(b *bolt.Bucket, bucketPath string, depth int) error
+	dumpBucket = func(b *bolt.Bucket, bucketPath string, depth int) (err error) {
+		if writtenBuckets > 0 {
+			writer.WriteByte(',')
+		}
+		writeJson(writer, bucketPath)
+		writer.WriteString(":{")
+		writtenBuckets++
+
+		// errors only happen between two key-value pairs, so closing the bucket keeps the dump valid JSON
+		open := true
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = fmt.Errorf("Reading bucket %v failed: %v\n", bucketPath, recovered)
+			}
+			if err != nil && open {
+				writer.WriteByte('}')
+			}
+			if err != nil && dumpOptions.Partial && !errors.Is(err, ErrMemoryLimit) {
+				report.addBucketError(bucketPath, strings.TrimSpace(err.Error()))
+				err = nil
+			}
+		}()
+		if b == nil {
+			return fmt.Errorf("Failed to access bucket %v even though it should exist!\n", bucketPath)
+		}
+		if checker == nil {
+			checker = newExpiryChecker(tx)
+		}
+
+		// iterate over each key in current bucket, nested buckets are written once the bucket itself is complete
+		transformer := dumpOptions.transformerFor(bucketPath)
+		dumpedKeys := 0
+		var nestedKeys keyTree // values by the parts of their keys, nil unless keys are split
+		var nestedBytes int64  // reserved for nestedKeys
+		defer func() { account.release(nestedBytes) }()
+		if dumpOptions.KeySeparator != "" {
+			nestedKeys = keyTree{}
+		}
+		nestedBucketNames := [][]byte{}
+		cursor := b.Cursor()
+		for keyBytes, _ := cursor.First(); keyBytes != nil; keyBytes, _ = cursor.Next() {
+			// keys whose ttl ran out are gone as far as readers are concerned
+			if checker.isExpired([]byte(bucketPath), keyBytes) {
+				continue
+			}
+			// stop reading once the client does not wait any longer, the buckets that follow are reported the same way
+			if dumpOptions.timedOut() {
+				report.timedOut = append(report.timedOut, bucketPath)
+				break
+			}
+			// stop reading the bucket once it has enough keys for a preview
+			if dumpOptions.MaxKeysPerBucket != 0 && dumpedKeys == dumpOptions.MaxKeysPerBucket {
+				report.truncated = append(report.truncated, bucketPath)
+				break
+			}
+
+			// cast key to string
+			keyString := RenderKey(keyBytes, dumpOptions.Keys)
+
+			// get value that corresponds to this key
+			v := b.Get(keyBytes)
+			if v == nil {
+				if b.Bucket(keyBytes) == nil {
+					return fmt.Errorf("In bucket %v tried to access value of key %v but failed\n", bucketPath, keyString)
+				}
+				// nested buckets are dumped as buckets of their own named by their path, as deep as dumpOptions allow
+				if dumpOptions.MaxDepth == 0 || depth < dumpOptions.MaxDepth {
+					nestedBucketNames = append(nestedBucketNames, append([]byte{}, keyBytes...))
+				}
+				continue
+			}
+
+			// a value that cannot be decrypted is reported instead of failing the whole dump
+			v, err := dumpOptions.Decrypter.decrypt(v)
+			if err != nil {
+				report.decryptionFailed = addKeys(report.decryptionFailed, bucketPath, keyString)
+				continue
+			}
+			var transformedJson []byte
+			if transformer != nil {
+				transformedJson, err = transformer(v)
+				if err != nil || !json.Valid(transformedJson) {
+					report.transformFailed = addKeys(report.transformFailed, bucketPath, keyString)
+					continue
+				}
+			}
+
+			if keyTime := describeKey(keyBytes, dumpOptions.Keys); keyTime != "" {
+				report.addKeyTime(bucketPath, keyString, keyTime)
+			}
+
+			// add key-value pair to the current bucket, split keys are written once all of them are known
+			var valueJson []byte
+			if transformedJson != nil {
+				valueJson = transformedJson
+			} else if valueInfo := describeValue(v, dumpOptions.Values); valueInfo != nil {
+				valueJson, _ = json.Marshal(valueInfo)
+			} else {
+				valueJson, _ = json.Marshal(string(v))
+			}
+			if nestedKeys != nil {
+				err = account.reserve(int64(len(keyBytes) + len(valueJson)))
+				if err != nil {
+					return err
+				}
+				nestedBytes += int64(len(keyBytes) + len(valueJson))
+				nestedKeys.nestKey(keyBytes, []byte(dumpOptions.KeySeparator), valueJson)
+			} else {
+				if dumpedKeys > 0 {
+					writer.WriteByte(',')
+				}
+				writeJson(writer, keyString)
+				writer.WriteByte(':')
+				_, err = writer.Write(valueJson) // fails once the buffer of a parallel dump is full, see accountedBuffer
+				if err != nil {
+					return err
+				}
+			}
+			dumpedKeys++
+		}
+		if len(nestedKeys) > 0 {
+			nestedJson, err := json.Marshal(nestedKeys)
+			if err != nil {
+				return err
+			}
+			writer.Write(nestedJson[1 : len(nestedJson)-1]) // the braces of the bucket are written already
+		}
+		writer.WriteByte('}')
+		open = false
+
+		for _, nestedBucketName := range nestedBucketNames {
+			err := dumpBucket(b.Bucket(nestedBucketName), bucketPath+"/"+string(nestedBucketName), depth+1)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := dumpBucket(tx.Bucket([]byte(bucketName)), bucketName, 1)
+	return report, err
+}
+
+// keyTree is the content of a bucket whose keys are split into parts, a part maps to the value of the key that ends with it or to a keyTree of the keys that go on after it.
+type keyTree map[string]any
+
+// nestKey adds valueJson to t under the parts of keyBytes split on separator, so "user:123:settings" ends up at t["user"]["123"]["settings"].
+// Parts are written as KeyText renders them, binary parts hex encoded with "0x" in front. The value of a key that other keys go on after, like "user" next to "user:123", is kept under "" in their keyTree.
+// Empty parts are written as "0x", the hex encoding of no bytes, so "" only ever holds such a value and "user:" ends up at t["user"]["0x"] instead of taking the place of "user".
+func (t keyTree) nestKey(keyBytes []byte, separator []byte, valueJson []byte) {
+	parts := bytes.Split(keyBytes, separator)
+	node := t
+	for i, part := range parts {
+		partString := KeyText(part)
+		if len(part) == 0 {
+			partString = keyTextHexPrefix
+		}
+		if i == len(parts)-1 {
+			if child, isTree := node[partString].(keyTree); isTree {
+				child[""] = json.RawMessage(valueJson)
+			} else {
+				node[partString] = json.RawMessage(valueJson)
+			}
+			return
+		}
+		child, isTree := node[partString].(keyTree)
+		if !isTree {
+			child = keyTree{}
+			if leafJson, isLeaf := node[partString].(json.RawMessage); isLeaf {
+				child[""] = leafJson
+			}
+			node[partString] = child
+		}
+		node = child
+	}
+}
+
+// writeJson writes v encoded as JSON to writer, write errors are kept by writer and returned by its next Flush.
+func writeJson(writer *bufio.Writer, v any) {
+	jsonBytes, _ := json.Marshal(v) // only strings, string slices, ValueInfo and WriterActivity are written, which always encode
+	writer.Write(jsonBytes)
+}
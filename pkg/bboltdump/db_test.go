@@ -0,0 +1,182 @@
+package bboltdump
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGetDbContentWithOptionsAsJson(t *testing.T) {
+	dbPath := createTestDb(t, map[string]map[string]string{
+		"users":          {"alice": "1", "bob": "2", "carol": "3"},
+		"userdata":       {"x": "y"},
+		"config":         {"a": "1"},
+		"config/devices": {"ios": "on"},
+	})
+	expireTestKey(t, dbPath, "users", "carol", time.Now().Add(-time.Second))
+
+	tests := []struct {
+		name          string
+		options       DumpOptions
+		want          map[string]map[string]string
+		wantTruncated []string
+	}{
+		{
+			name:    "everything",
+			options: DumpOptions{Keys: KeysHex},
+			want: map[string]map[string]string{
+				"users":          {"616c696365": "1", "626f62": "2"},
+				"userdata":       {"78": "y"},
+				"config":         {"61": "1"},
+				"config/devices": {"696f73": "on"},
+			},
+		},
+		{
+			name:    "include glob",
+			options: DumpOptions{Keys: KeysHex, Include: []string{"user*"}},
+			want: map[string]map[string]string{
+				"users":    {"616c696365": "1", "626f62": "2"},
+				"userdata": {"78": "y"},
+			},
+		},
+		{
+			name:    "exclude wins",
+			options: DumpOptions{Keys: KeysHex, Include: []string{"user*"}, Exclude: []string{"userdata"}},
+			want: map[string]map[string]string{
+				"users": {"616c696365": "1", "626f62": "2"},
+			},
+		},
+		{
+			name:    "top level only",
+			options: DumpOptions{Keys: KeysHex, Include: []string{"config"}, MaxDepth: 1},
+			want: map[string]map[string]string{
+				"config": {"61": "1"},
+			},
+		},
+		{
+			name:          "truncated",
+			options:       DumpOptions{Keys: KeysHex, Include: []string{"users"}, MaxKeysPerBucket: 1},
+			want:          map[string]map[string]string{"users": {"616c696365": "1"}},
+			wantTruncated: []string{"users"},
+		},
+		{
+			name:    "text keys",
+			options: DumpOptions{Keys: KeysText, Include: []string{"users"}},
+			want:    map[string]map[string]string{"users": {"alice": "1", "bob": "2"}},
+		},
+		{
+			name:    "parallel",
+			options: DumpOptions{Keys: KeysHex, Include: []string{"users", "config"}, Workers: 2},
+			want: map[string]map[string]string{
+				"users":          {"616c696365": "1", "626f62": "2"},
+				"config":         {"61": "1"},
+				"config/devices": {"696f73": "on"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dumpJson, err := GetDbContentWithOptionsAsJson(dbPath, test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var bboltDb BboltDb
+			err = json.Unmarshal(dumpJson, &bboltDb)
+			if err != nil {
+				t.Fatalf("dump is not valid JSON: %v\n%s", err, dumpJson)
+			}
+			if !maps.EqualFunc(bboltDb.Buckets, test.want, maps.Equal) {
+				t.Errorf("Buckets = %v, want %v", bboltDb.Buckets, test.want)
+			}
+			if !slices.Equal(bboltDb.Truncated, test.wantTruncated) {
+				t.Errorf("Truncated = %v, want %v", bboltDb.Truncated, test.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestGetDbContentWithOptionsAsJsonInternalBuckets(t *testing.T) {
+	dbPath := createTestDb(t, map[string]map[string]string{"users": {"alice": "1"}})
+	expireTestKey(t, dbPath, "users", "alice", time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name        string
+		options     DumpOptions
+		wantBuckets []string
+	}{
+		{"left out", DumpOptions{}, []string{"users"}},
+		{"left out by glob", DumpOptions{Include: []string{"*"}}, []string{"users"}},
+		{"named", DumpOptions{Include: []string{"users", TtlBucket}}, []string{TtlBucket, "users"}},
+		{"internal", DumpOptions{Internal: true}, []string{TtlBucket, "users"}},
+		{"internal excluded", DumpOptions{Internal: true, Exclude: []string{"__*"}}, []string{"users"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dumpJson, err := GetDbContentWithOptionsAsJson(dbPath, test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var bboltDb BboltDb
+			err = json.Unmarshal(dumpJson, &bboltDb)
+			if err != nil {
+				t.Fatalf("dump is not valid JSON: %v\n%s", err, dumpJson)
+			}
+			if got := slices.Sorted(maps.Keys(bboltDb.Buckets)); !slices.Equal(got, test.wantBuckets) {
+				t.Errorf("buckets = %v, want %v", got, test.wantBuckets)
+			}
+		})
+	}
+}
+
+func TestDumpOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options DumpOptions
+		wantErr bool
+	}{
+		{"zero value", DumpOptions{}, false},
+		{"negative depth", DumpOptions{MaxDepth: -1}, true},
+		{"negative workers", DumpOptions{Workers: -1}, true},
+		{"unknown values mode", DumpOptions{Values: "base32"}, true},
+		{"unknown keys mode", DumpOptions{Keys: "base32"}, true},
+		{"invalid glob", DumpOptions{Include: []string{"["}}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.options.Validate()
+			if (err != nil) != test.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyTreeNestKey(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string // in bolt's order
+		want string
+	}{
+		{"split keys", []string{"user:1", "user:2"}, `{"user":{"1":"v","2":"v"}}`},
+		{"key others go on after", []string{"user", "user:1"}, `{"user":{"":"v","1":"v"}}`},
+		{"empty last part", []string{"user", "user:", "user:1"}, `{"user":{"":"v","0x":"v","1":"v"}}`},
+		{"empty middle part", []string{"user::1", "user:1"}, `{"user":{"0x":{"1":"v"},"1":"v"}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := keyTree{}
+			for _, key := range test.keys {
+				tree.nestKey([]byte(key), []byte(":"), []byte(`"v"`))
+			}
+			got, err := json.Marshal(tree)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("keyTree = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package bboltdump
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+)
+
+// Decrypter is a struct representing an AES-GCM key that values encrypted at rest are decrypted with before they are dumped.
+// Values are expected to be the nonce followed by the ciphertext and the authentication tag, which is what cipher.AEAD.Seal returns when called with the nonce as dst.
+type Decrypter struct {
+	aead cipher.AEAD
+}
+
+// NewDecrypter returns a Decrypter for key, which has to be 16, 24 or 32 bytes long for AES-128, AES-192 or AES-256.
+func NewDecrypter(key []byte) (*Decrypter, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid decryption key: %v\n", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid decryption key: %v\n", err)
+	}
+	return &Decrypter{aead: aead}, nil
+}
+
+// decrypt returns the plaintext of valueBytes, a nil *Decrypter returns valueBytes unchanged.
+func (d *Decrypter) decrypt(valueBytes []byte) ([]byte, error) {
+	if d == nil {
+		return valueBytes, nil
+	}
+	nonceSize := d.aead.NonceSize()
+	if len(valueBytes) < nonceSize+d.aead.Overhead() {
+		return nil, fmt.Errorf("value is too short to be encrypted")
+	}
+	return d.aead.Open(nil, valueBytes[:nonceSize], valueBytes[nonceSize:], nil)
+}
@@ -0,0 +1,82 @@
+package bboltdump
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DeleteResult is a struct representing the outcome of a bulk delete.
+type DeleteResult struct {
+	Bucket  string   `json:"bucket"`            // bucket the keys were deleted from
+	Deleted int      `json:"deleted"`           // amount of keys deleted, or that would be deleted in a dry run
+	DryRun  bool     `json:"dryRun"`            // true if nothing was actually deleted
+	Preview *Preview `json:"preview,omitempty"` // what the dry run would have changed, only set with DryRun
+}
+
+// DeleteKeys deletes all keys of the bucket bucketName that start with prefix and lie in the range [start, end) within one transaction. Empty bounds are unlimited. Nested buckets are left alone.
+// With dryRun set the keys are deleted in a transaction that is rolled back, the result reports how many keys would be deleted and its Preview how many bytes. If the journal is enabled for the database every deleted key is journaled by actor.
+func DeleteKeys(dbPath string, bucketName string, prefix []byte, start []byte, end []byte, dryRun bool, actor string) (DeleteResult, error) {
+	deleteResult := DeleteResult{
+		Bucket: bucketName,
+		DryRun: dryRun,
+	}
+
+	// the prefix narrows down the range, iteration starts at whichever bound is larger
+	if bytes.Compare(prefix, start) > 0 {
+		start = prefix
+	}
+
+	// visits the matching keys of b in order
+	forEachMatch := func(b *bolt.Bucket, fn func(keyBytes []byte)) {
+		cursor := b.Cursor()
+		keyBytes, valueBytes := cursor.Seek(start)
+		for ; keyBytes != nil; keyBytes, valueBytes = cursor.Next() {
+			if !bytes.HasPrefix(keyBytes, prefix) || (len(end) > 0 && bytes.Compare(keyBytes, end) >= 0) {
+				return
+			}
+			if valueBytes != nil {
+				fn(keyBytes)
+			}
+		}
+	}
+
+	dbInstance, closeDb, err := OpenDbForWriting(dbPath)
+	if err != nil {
+		return deleteResult, err
+	}
+	defer closeDb()
+
+	deleteResult.Preview, err = UpdateOrDryRun(dbInstance, dryRun, func(tx *bolt.Tx) error {
+		b := ResolveBucket(tx, bucketName)
+		if b == nil {
+			return ErrBucketNotFound
+		}
+
+		// collect first, deleting while a cursor moves over the bucket can skip keys
+		matchingKeys := [][]byte{}
+		forEachMatch(b, func(keyBytes []byte) {
+			matchingKeys = append(matchingKeys, append([]byte{}, keyBytes...))
+		})
+		for _, keyBytes := range matchingKeys {
+			err := Journal(tx, actor, ChangeDelete, bucketName, keyBytes, b.Get(keyBytes), nil)
+			if err == nil {
+				err = b.Delete(keyBytes)
+			}
+			if err == nil {
+				err = SetExpiry(tx, bucketName, keyBytes, time.Time{})
+			}
+			if err != nil {
+				return fmt.Errorf("Failed to delete key %x due to error: %v\n", keyBytes, err)
+			}
+		}
+		deleteResult.Deleted = len(matchingKeys)
+		if len(matchingKeys) > 0 {
+			RecordChanges(tx, NewChange(ChangeDelete, bucketName, matchingKeys...))
+		}
+		return nil
+	})
+	return deleteResult, err
+}
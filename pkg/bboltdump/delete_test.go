@@ -0,0 +1,74 @@
+package bboltdump
+
+import (
+	"errors"
+	"maps"
+	"testing"
+)
+
+func TestDeleteKeys(t *testing.T) {
+	tests := []struct {
+		name        string
+		bucket      string
+		prefix      string
+		start       string
+		end         string
+		dryRun      bool
+		wantDeleted int
+		wantErr     error
+		wantLeft    map[string]string // keys of the bucket log after the delete
+	}{
+		{
+			name: "prefix", bucket: "log", prefix: "2024-01",
+			wantDeleted: 2, wantLeft: map[string]string{"2023-12-31": "a", "2024-02-01": "d", "2024-02-02": "e"},
+		},
+		{
+			name: "range", bucket: "log", start: "2024-01-02", end: "2024-02-02",
+			wantDeleted: 2, wantLeft: map[string]string{"2023-12-31": "a", "2024-01-01": "b", "2024-02-02": "e"},
+		},
+		{
+			name: "prefix and range", bucket: "log", prefix: "2024", end: "2024-02",
+			wantDeleted: 2, wantLeft: map[string]string{"2023-12-31": "a", "2024-02-01": "d", "2024-02-02": "e"},
+		},
+		{
+			name: "everything", bucket: "log",
+			wantDeleted: 5, wantLeft: map[string]string{},
+		},
+		{
+			name: "dry run", bucket: "log", prefix: "2024", dryRun: true,
+			wantDeleted: 4, wantLeft: map[string]string{"2023-12-31": "a", "2024-01-01": "b", "2024-01-02": "c", "2024-02-01": "d", "2024-02-02": "e"},
+		},
+		{name: "missing bucket", bucket: "missing", wantErr: ErrBucketNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbPath := createTestDb(t, map[string]map[string]string{
+				"log":        {"2023-12-31": "a", "2024-01-01": "b", "2024-01-02": "c", "2024-02-01": "d", "2024-02-02": "e"},
+				"log/nested": {"2024-01-01": "kept"},
+			})
+			deleteResult, err := DeleteKeys(dbPath, test.bucket, []byte(test.prefix), []byte(test.start), []byte(test.end), test.dryRun, "test")
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("DeleteKeys error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deleteResult.Deleted != test.wantDeleted {
+				t.Errorf("Deleted = %v, want %v", deleteResult.Deleted, test.wantDeleted)
+			}
+			if test.dryRun && deleteResult.Preview == nil {
+				t.Error("dry run has no Preview")
+			}
+			got := readTestDb(t, dbPath)
+			if !maps.Equal(got["log"], test.wantLeft) {
+				t.Errorf("keys left = %v, want %v", got["log"], test.wantLeft)
+			}
+			if got["log/nested"]["2024-01-01"] != "kept" {
+				t.Error("nested bucket was changed")
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package bboltdump
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BucketDiff is a struct representing the differences of a single bucket between two databases.
+type BucketDiff struct {
+	Added   []string `json:"added"`   // keys that only exist in the other database, rendered by RenderKey
+	Removed []string `json:"removed"` // keys that only exist in the base database
+	Changed []string `json:"changed"` // keys that exist in both databases but with different values
+}
+
+// DbDiff is a struct representing the differences between a base database and another database.
+type DbDiff struct {
+	Path           string                 `json:"path"`           // path to the base db file
+	Other          string                 `json:"other"`          // path to the db file it was compared against
+	AddedBuckets   []string               `json:"addedBuckets"`   // buckets that only exist in the other database
+	RemovedBuckets []string               `json:"removedBuckets"` // buckets that only exist in the base database
+	Buckets        map[string]*BucketDiff `json:"buckets"`        // map each bucket that differs to its differences
+}
+
+// bucketNames returns the names of all top level buckets of tx.
+func bucketNames(tx *bolt.Tx) map[string]bool {
+	names := make(map[string]bool)
+	tx.ForEach(func(bucketName []byte, _ *bolt.Bucket) error {
+		names[string(bucketName)] = true
+		return nil
+	})
+	return names
+}
+
+// valueCursor is a cursor over the key-value pairs of a bucket that skips nested buckets.
+type valueCursor struct {
+	cursor *bolt.Cursor
+	key    []byte // current key, nil once the cursor is exhausted
+	value  []byte // value of the current key
+}
+
+// newValueCursor returns a valueCursor positioned on the first key-value pair of b, b may be nil in which case the cursor is exhausted right away.
+func newValueCursor(b *bolt.Bucket) *valueCursor {
+	vc := &valueCursor{}
+	if b != nil {
+		vc.cursor = b.Cursor()
+		vc.key, vc.value = vc.cursor.First()
+		vc.skipNested()
+	}
+	return vc
+}
+
+// next moves the cursor to the next key-value pair.
+func (vc *valueCursor) next() {
+	vc.key, vc.value = vc.cursor.Next()
+	vc.skipNested()
+}
+
+// skipNested moves the cursor forward until it is positioned on a key that is not a nested bucket.
+func (vc *valueCursor) skipNested() {
+	for vc.key != nil && vc.value == nil {
+		vc.key, vc.value = vc.cursor.Next()
+	}
+}
+
+// diffBuckets compares base and other key by key and returns their differences with the keys rendered for the keys mode keys, either bucket may be nil.
+// Both cursors are walked side by side in key order, so no bucket has to be held in memory.
+func diffBuckets(base *bolt.Bucket, other *bolt.Bucket, keys string) *BucketDiff {
+	bucketDiff := &BucketDiff{
+		Added:   []string{},
+		Removed: []string{},
+		Changed: []string{},
+	}
+
+	baseCursor := newValueCursor(base)
+	otherCursor := newValueCursor(other)
+	for baseCursor.key != nil || otherCursor.key != nil {
+		switch {
+		case otherCursor.key == nil || (baseCursor.key != nil && bytes.Compare(baseCursor.key, otherCursor.key) < 0):
+			bucketDiff.Removed = append(bucketDiff.Removed, RenderKey(baseCursor.key, keys))
+			baseCursor.next()
+		case baseCursor.key == nil || bytes.Compare(baseCursor.key, otherCursor.key) > 0:
+			bucketDiff.Added = append(bucketDiff.Added, RenderKey(otherCursor.key, keys))
+			otherCursor.next()
+		default:
+			if !bytes.Equal(baseCursor.value, otherCursor.value) {
+				bucketDiff.Changed = append(bucketDiff.Changed, RenderKey(baseCursor.key, keys))
+			}
+			baseCursor.next()
+			otherCursor.next()
+		}
+	}
+
+	return bucketDiff
+}
+
+// isEmpty reports whether the bucket is identical in both databases.
+func (d *BucketDiff) isEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// isSameFile reports whether the paths a and b lead to the same existing file.
+func isSameFile(a string, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
+
+// DiffDbs takes the paths to two bbolt databases and returns which buckets and keys were added, removed or changed in otherPath compared to dbPath. keys is one of the Keys modes.
+func DiffDbs(dbPath string, otherPath string, keys string) (*DbDiff, error) {
+	// bolt locks the file, opening the same file twice would wait forever, also through a symlink, a hard link or another spelling of the path
+	if filepath.Clean(dbPath) == filepath.Clean(otherPath) || isSameFile(dbPath, otherPath) {
+		return nil, fmt.Errorf("Refusing to diff database %v with itself\n", dbPath)
+	}
+
+	// open databases
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeDb()
+	otherInstance, closeOther, err := OpenDb(otherPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeOther()
+
+	dbDiff := &DbDiff{
+		Path:           dbPath,
+		Other:          otherPath,
+		AddedBuckets:   []string{},
+		RemovedBuckets: []string{},
+		Buckets:        make(map[string]*BucketDiff),
+	}
+
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		return otherInstance.View(func(otherTx *bolt.Tx) error {
+			baseNames := bucketNames(tx)
+			otherNames := bucketNames(otherTx)
+			for bucketName := range otherNames {
+				if !baseNames[bucketName] {
+					dbDiff.AddedBuckets = append(dbDiff.AddedBuckets, bucketName)
+					baseNames[bucketName] = true
+				}
+			}
+
+			for bucketName := range baseNames {
+				if !otherNames[bucketName] {
+					dbDiff.RemovedBuckets = append(dbDiff.RemovedBuckets, bucketName)
+				}
+				bucketDiff := diffBuckets(tx.Bucket([]byte(bucketName)), otherTx.Bucket([]byte(bucketName)), keys)
+				if !bucketDiff.isEmpty() {
+					dbDiff.Buckets[bucketName] = bucketDiff
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to diff databases due to error: %v\n", err)
+	}
+
+	return dbDiff, nil
+}
+
+// DiffDbsAsJson is like DiffDbs but returns the differences as a serialized JSON object of DbDiff.
+func DiffDbsAsJson(dbPath string, otherPath string, keys string) ([]byte, error) {
+	dbDiff, err := DiffDbs(dbPath, otherPath, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// serialize dbDiff to json
+	dbDiffJson, err := json.Marshal(dbDiff)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to serialize object to json: %v\n", err)
+	}
+
+	return dbDiffJson, nil
+}
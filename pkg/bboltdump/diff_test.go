@@ -0,0 +1,39 @@
+package bboltdump
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffDbsWithItself(t *testing.T) {
+	dbPath := createTestDb(t, map[string]map[string]string{"users": {"alice": "1"}})
+	dir := filepath.Dir(dbPath)
+	symlinkPath := filepath.Join(dir, "symlink.db")
+	if err := os.Symlink(dbPath, symlinkPath); err != nil {
+		t.Skip("symlinks are not supported:", err)
+	}
+	hardLinkPath := filepath.Join(dir, "hardlink.db")
+	if err := os.Link(dbPath, hardLinkPath); err != nil {
+		t.Skip("hard links are not supported:", err)
+	}
+
+	tests := []struct {
+		name      string
+		otherPath string
+	}{
+		{"same path", dbPath},
+		{"other spelling", filepath.Join(dir, ".", filepath.Base(dbPath))},
+		{"symlink", symlinkPath},
+		{"hard link", hardLinkPath},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := DiffDbs(dbPath, test.otherPath, KeysText)
+			if err == nil || !strings.Contains(err.Error(), "with itself") {
+				t.Errorf("DiffDbs = %v, want the refusal to diff with itself", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package bboltdump
+
+import (
+	"errors"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Preview is a struct representing what a write run as a dry run would have changed. The write ran in a read-write transaction like always, which was rolled back instead of committed.
+type Preview struct {
+	Keys         int   `json:"keys"`         // keys that would have been written or deleted, a moved key counts twice
+	BytesWritten int64 `json:"bytesWritten"` // bytes of the keys and values that would have been written
+	BytesDeleted int64 `json:"bytesDeleted"` // bytes of the keys and values that would have been deleted or overwritten
+}
+
+// errDryRun is returned by the function of a dry run transaction, so bolt rolls back everything it wrote.
+var errDryRun = errors.New("dry run")
+
+var previewsMu sync.Mutex
+var previews = make(map[*bolt.Tx]*Preview) // of the transactions of running dry runs
+
+// UpdateOrDryRun runs fn in a read-write transaction of dbInstance like bolt.DB.Update. With dryRun set the transaction is rolled back once fn succeeded, and the Preview of every key fn passed to Journal is returned, otherwise the Preview is nil.
+// The journal, indexes, views and expiries fn writes are rolled back too, and the hooks of OnChange are not called.
+func UpdateOrDryRun(dbInstance *bolt.DB, dryRun bool, fn func(tx *bolt.Tx) error) (*Preview, error) {
+	if !dryRun {
+		return nil, dbInstance.Update(fn)
+	}
+	preview := &Preview{}
+	err := dbInstance.Update(func(tx *bolt.Tx) error {
+		previewsMu.Lock()
+		previews[tx] = preview
+		previewsMu.Unlock()
+		defer func() {
+			previewsMu.Lock()
+			delete(previews, tx)
+			previewsMu.Unlock()
+		}()
+
+		err := fn(tx)
+		if err != nil {
+			return err
+		}
+		return errDryRun
+	})
+	if errors.Is(err, errDryRun) {
+		return preview, nil
+	}
+	return nil, err
+}
+
+// addToPreview counts the write of keyBytes changing its value from oldValue to newValue into the Preview of tx, if tx belongs to a dry run. A nil value means the key did not exist before or does not exist anymore.
+func addToPreview(tx *bolt.Tx, keyBytes []byte, oldValue []byte, newValue []byte) {
+	previewsMu.Lock()
+	defer previewsMu.Unlock()
+	preview, found := previews[tx]
+	if !found {
+		return
+	}
+	preview.Keys++
+	if oldValue != nil {
+		preview.BytesDeleted += int64(len(keyBytes) + len(oldValue))
+	}
+	if newValue != nil {
+		preview.BytesWritten += int64(len(keyBytes) + len(newValue))
+	}
+}
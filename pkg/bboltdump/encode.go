@@ -0,0 +1,176 @@
+package bboltdump
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// encodings of EncodeValue, they tell how the value a client sends is turned into the bytes that are stored
+const (
+	EncodingRaw     = "raw"     // the value is stored as it is
+	EncodingBase64  = "base64"  // the value is standard base64 of the bytes to store, for binary values
+	EncodingJson    = "json"    // the value is JSON, stored compacted
+	EncodingMsgpack = "msgpack" // the value is JSON, stored as MessagePack
+	EncodingGob     = "gob"     // the value is JSON, stored as gob of the type registered for the bucket with RegisterGobType
+)
+
+// Encodings are all encodings of EncodeValue.
+var Encodings = []string{EncodingRaw, EncodingBase64, EncodingJson, EncodingMsgpack, EncodingGob}
+
+// EncodeValue returns the bytes to store for value written to the bucket bucketPath in encoding, so clients in any language end up with the same bytes on disk. An empty encoding means EncodingRaw.
+// EncodingGob needs a type registered for the bucket with RegisterGobType or a gob type hint of the config, the JSON is decoded into that type first so its field names and types are checked.
+func EncodeValue(bucketPath string, value string, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", EncodingRaw:
+		return []byte(value), nil
+
+	case EncodingBase64:
+		valueBytes, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("Value is not valid base64: %v\n", err)
+		}
+		return valueBytes, nil
+
+	case EncodingJson:
+		var compacted bytes.Buffer
+		err := json.Compact(&compacted, []byte(value))
+		if err != nil {
+			return nil, fmt.Errorf("Value is not valid JSON: %v\n", err)
+		}
+		return compacted.Bytes(), nil
+
+	case EncodingMsgpack:
+		decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+		decoder.UseNumber() // keeps integers from turning into floats
+		var decoded any
+		err := decoder.Decode(&decoded)
+		if err != nil {
+			return nil, fmt.Errorf("Value is not valid JSON: %v\n", err)
+		}
+		return appendMsgpack(nil, decoded)
+
+	case EncodingGob:
+		valueType, found := gobTypeFor(bucketPath)
+		if !found {
+			return nil, fmt.Errorf("No gob type is registered for bucket %v\n", bucketPath)
+		}
+		decoded := reflect.New(valueType)
+		decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(decoded.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("Value does not match the gob type of bucket %v: %v\n", bucketPath, err)
+		}
+		var encoded bytes.Buffer
+		err = gob.NewEncoder(&encoded).EncodeValue(decoded.Elem())
+		if err != nil {
+			return nil, fmt.Errorf("Failed to encode gob value: %v\n", err)
+		}
+		return encoded.Bytes(), nil
+	}
+	return nil, fmt.Errorf("Unsupported encoding %v, use one of %v\n", encoding, Encodings)
+}
+
+// appendMsgpack appends the MessagePack encoding of value, as decoded from JSON with json.Decoder.UseNumber, to buf.
+// Integers get the smallest format that holds them, other numbers are float64, and the keys of objects are sorted so the same JSON always gives the same bytes.
+func appendMsgpack(buf []byte, value any) ([]byte, error) {
+	switch value := value.(type) {
+	case nil:
+		return append(buf, 0xc0), nil
+
+	case bool:
+		if value {
+			return append(buf, 0xc3), nil
+		}
+		return append(buf, 0xc2), nil
+
+	case json.Number:
+		if integer, err := value.Int64(); err == nil {
+			return appendMsgpackInt(buf, integer), nil
+		}
+		float, err := value.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("Number %v does not fit into MessagePack\n", value)
+		}
+		buf = append(buf, 0xcb)
+		return binary.BigEndian.AppendUint64(buf, math.Float64bits(float)), nil
+
+	case string:
+		buf = appendMsgpackLength(buf, len(value), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		return append(buf, value...), nil
+
+	case []any:
+		buf = appendMsgpackLength(buf, len(value), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, element := range value {
+			var err error
+			buf, err = appendMsgpack(buf, element)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf = appendMsgpackLength(buf, len(value), 0x80, 15, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			buf, _ = appendMsgpack(buf, key)
+			var err error
+			buf, err = appendMsgpack(buf, value[key])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	}
+	return nil, fmt.Errorf("Value of type %T cannot be encoded as MessagePack\n", value)
+}
+
+// appendMsgpackInt appends integer in the smallest MessagePack format that holds it.
+func appendMsgpackInt(buf []byte, integer int64) []byte {
+	switch {
+	case integer >= 0 && integer <= 127:
+		return append(buf, byte(integer))
+	case integer < 0 && integer >= -32:
+		return append(buf, byte(integer))
+	case integer >= 0 && integer <= math.MaxUint8:
+		return append(buf, 0xcc, byte(integer))
+	case integer >= 0 && integer <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xcd), uint16(integer))
+	case integer >= 0 && integer <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xce), uint32(integer))
+	case integer >= 0:
+		return binary.BigEndian.AppendUint64(append(buf, 0xcf), uint64(integer))
+	case integer >= math.MinInt8:
+		return append(buf, 0xd0, byte(integer))
+	case integer >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xd1), uint16(integer))
+	case integer >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xd2), uint32(integer))
+	}
+	return binary.BigEndian.AppendUint64(append(buf, 0xd3), uint64(integer))
+}
+
+// appendMsgpackLength appends the header of a string, array or map of length n: the fix format with the bits of n if n is at most fixMax, otherwise the format with an 8, 16 or 32 bit length. Arrays and maps have no 8 bit format, format8 is 0 for them.
+func appendMsgpackLength(buf []byte, n int, fixFormat byte, fixMax int, format8 byte, format16 byte, format32 byte) []byte {
+	switch {
+	case n <= fixMax:
+		return append(buf, fixFormat|byte(n))
+	case format8 != 0 && n <= math.MaxUint8:
+		return append(buf, format8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, format16), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(buf, format32), uint32(n))
+}
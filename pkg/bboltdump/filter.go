@@ -0,0 +1,408 @@
+package bboltdump
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter is a parsed filter expression like `key startsWith "a:" AND valueSize > 1024 AND value contains "error"`, which decides entry by entry whether a scan returns it.
+//
+// A comparison is a field, an operator and a value. The fields are key, value and bucket, which are compared to a quoted string, and keySize and valueSize, which are compared to an integer.
+// A field of JSON values is named by its path like json.status or json.user.name. It is compared to a quoted string, which matches JSON strings by their content and other JSON values by their JSON text, or to a number, which only matches JSON numbers.
+// Values that are not JSON objects or lack the field match no comparison of it.
+// The operators are =, !=, <, <=, > and >= for all fields, and startsWith, endsWith, contains and matches (a regular expression) for key, value, bucket and JSON fields compared to a string.
+// Strings are quoted like in Go, so "\x00\xff" matches binary keys. Comparisons are combined with AND, OR, NOT and parentheses; AND binds stronger than OR.
+type Filter struct {
+	expression string
+	root       filterNode
+}
+
+// filterNode is a node of the syntax tree of a Filter.
+type filterNode interface {
+	matches(bucketName string, keyBytes []byte, valueBytes []byte) bool
+}
+
+type andNode struct{ left, right filterNode }
+type orNode struct{ left, right filterNode }
+type notNode struct{ node filterNode }
+
+func (n andNode) matches(bucketName string, keyBytes []byte, valueBytes []byte) bool {
+	return n.left.matches(bucketName, keyBytes, valueBytes) && n.right.matches(bucketName, keyBytes, valueBytes)
+}
+
+func (n orNode) matches(bucketName string, keyBytes []byte, valueBytes []byte) bool {
+	return n.left.matches(bucketName, keyBytes, valueBytes) || n.right.matches(bucketName, keyBytes, valueBytes)
+}
+
+func (n notNode) matches(bucketName string, keyBytes []byte, valueBytes []byte) bool {
+	return !n.node.matches(bucketName, keyBytes, valueBytes)
+}
+
+// comparisonNode is a struct representing a comparison of a field of the entry, either with text or with number.
+type comparisonNode struct {
+	field    string
+	operator string
+	text     []byte
+	number   int64
+	pattern  *regexp.Regexp // compiled text of matches
+
+	jsonPath   []string // names of the nested objects and the field a json comparison reads, the field is "json"
+	jsonNumber *float64 // number a json field is compared to, nil if it is compared to text
+}
+
+func (n comparisonNode) matches(bucketName string, keyBytes []byte, valueBytes []byte) bool {
+	var fieldBytes []byte
+	switch n.field {
+	case "keySize":
+		return compareOrdered(int64(len(keyBytes)), n.number, n.operator)
+	case "valueSize":
+		return compareOrdered(int64(len(valueBytes)), n.number, n.operator)
+	case "key":
+		fieldBytes = keyBytes
+	case "value":
+		fieldBytes = valueBytes
+	case "bucket":
+		fieldBytes = []byte(bucketName)
+	case "json":
+		fieldValue, found := jsonField(valueBytes, n.jsonPath)
+		if !found {
+			return false
+		}
+		if n.jsonNumber != nil {
+			number, isNumber := fieldValue.(json.Number)
+			if !isNumber {
+				return false
+			}
+			fieldNumber, err := number.Float64()
+			if err != nil {
+				return false
+			}
+			return compareOrdered(int64(cmp.Compare(fieldNumber, *n.jsonNumber)), 0, n.operator)
+		}
+		if text, isText := fieldValue.(string); isText {
+			fieldBytes = []byte(text)
+		} else {
+			fieldBytes, _ = json.Marshal(fieldValue)
+		}
+	}
+
+	switch n.operator {
+	case "startsWith":
+		return bytes.HasPrefix(fieldBytes, n.text)
+	case "endsWith":
+		return bytes.HasSuffix(fieldBytes, n.text)
+	case "contains":
+		return bytes.Contains(fieldBytes, n.text)
+	case "matches":
+		return n.pattern.Match(fieldBytes)
+	}
+	return compareOrdered(int64(bytes.Compare(fieldBytes, n.text)), 0, n.operator)
+}
+
+// jsonField returns the field at jsonPath of the JSON object valueBytes, with numbers as json.Number, and whether there is one.
+func jsonField(valueBytes []byte, jsonPath []string) (any, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(valueBytes))
+	decoder.UseNumber()
+	var fieldValue any
+	if decoder.Decode(&fieldValue) != nil {
+		return nil, false
+	}
+	for _, name := range jsonPath {
+		object, isObject := fieldValue.(map[string]any)
+		if !isObject {
+			return nil, false
+		}
+		fieldValue, isObject = object[name]
+		if !isObject {
+			return nil, false
+		}
+	}
+	return fieldValue, true
+}
+
+// compareOrdered reports whether a relates to b as operator says.
+func compareOrdered(a int64, b int64, operator string) bool {
+	switch operator {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+// filter operators and fields
+var orderOperators = []string{"=", "!=", "<", "<=", ">", ">="}
+var textOperators = []string{"startsWith", "endsWith", "contains", "matches"}
+var textFields = []string{"key", "value", "bucket"}
+var sizeFields = []string{"keySize", "valueSize"}
+
+// ParseFilter parses the filter expression expression, see Filter for its syntax.
+func ParseFilter(expression string) (*Filter, error) {
+	tokens, err := tokenizeFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+	parser := &filterParser{tokens: tokens}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.position < len(tokens) {
+		return nil, fmt.Errorf("Unexpected %v in filter\n", tokens[parser.position].text)
+	}
+	return &Filter{expression: expression, root: root}, nil
+}
+
+// Matches reports whether the entry keyBytes/valueBytes of the bucket bucketName passes the filter. A nil Filter passes everything.
+func (f *Filter) Matches(bucketName string, keyBytes []byte, valueBytes []byte) bool {
+	return f == nil || f.root.matches(bucketName, keyBytes, valueBytes)
+}
+
+func (f *Filter) String() string {
+	return f.expression
+}
+
+// filterToken is a struct representing a word, operator, parenthesis or string of a filter expression.
+type filterToken struct {
+	text   string
+	quoted bool // text is the unquoted content of a string
+}
+
+// tokenizeFilter splits expression into its tokens.
+func tokenizeFilter(expression string) ([]filterToken, error) {
+	tokens := []filterToken{}
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, filterToken{text: string(c)})
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expression) && expression[end] != '"' {
+				if expression[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expression) {
+				return nil, fmt.Errorf("Unterminated string in filter\n")
+			}
+			text, err := strconv.Unquote(expression[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid string %v in filter\n", expression[i:end+1])
+			}
+			tokens = append(tokens, filterToken{text: text, quoted: true})
+			i = end + 1
+		case strings.ContainsRune("=!<>", rune(c)):
+			end := i + 1
+			if end < len(expression) && expression[end] == '=' {
+				end++
+			}
+			operator := expression[i:end]
+			if operator == "==" {
+				operator = "="
+			}
+			if operator == "!" {
+				return nil, fmt.Errorf("Unknown operator ! in filter, use != or NOT\n")
+			}
+			tokens = append(tokens, filterToken{text: operator})
+			i = end
+		case c == '-' || c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			end := i + 1
+			for end < len(expression) && (expression[end] == '_' || expression[end] == '.' || unicode.IsLetter(rune(expression[end])) || unicode.IsDigit(rune(expression[end]))) {
+				end++
+			}
+			tokens = append(tokens, filterToken{text: expression[i:end]})
+			i = end
+		default:
+			return nil, fmt.Errorf("Unexpected character %q in filter\n", c)
+		}
+	}
+	return tokens, nil
+}
+
+// filterParser is a recursive descent parser of filter tokens.
+type filterParser struct {
+	tokens   []filterToken
+	position int
+}
+
+// peekKeyword reports whether the next token is the unquoted keyword, in any case.
+func (p *filterParser) peekKeyword(keyword string) bool {
+	return p.position < len(p.tokens) && !p.tokens[p.position].quoted && strings.EqualFold(p.tokens[p.position].text, keyword)
+}
+
+// next returns the next token, or an error if there is none.
+func (p *filterParser) next(expected string) (filterToken, error) {
+	if p.position == len(p.tokens) {
+		return filterToken{}, fmt.Errorf("Filter ended where %v was expected\n", expected)
+	}
+	p.position++
+	return p.tokens[p.position-1], nil
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.peekKeyword("OR") {
+		p.position++
+		var right filterNode
+		right, err = p.parseAnd()
+		left = orNode{left, right}
+	}
+	return left, err
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.peekKeyword("AND") {
+		p.position++
+		var right filterNode
+		right, err = p.parseUnary()
+		left = andNode{left, right}
+	}
+	return left, err
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.peekKeyword("NOT") {
+		p.position++
+		node, err := p.parseUnary()
+		return notNode{node}, err
+	}
+	if p.peekKeyword("(") {
+		p.position++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		token, err := p.next(")")
+		if err != nil {
+			return nil, err
+		}
+		if token.quoted || token.text != ")" {
+			return nil, fmt.Errorf("Expected ) instead of %v in filter\n", token.text)
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	fieldToken, err := p.next("a field")
+	if err != nil {
+		return nil, err
+	}
+	operatorToken, err := p.next("an operator")
+	if err != nil {
+		return nil, err
+	}
+	valueToken, err := p.next("a value")
+	if err != nil {
+		return nil, err
+	}
+
+	field := fieldToken.text
+	operator := operatorToken.text
+	isTextField := !fieldToken.quoted && containsFold(textFields, field)
+	isSizeField := !fieldToken.quoted && containsFold(sizeFields, field)
+	isJsonField := !fieldToken.quoted && strings.HasPrefix(strings.ToLower(field), "json.")
+	if !isTextField && !isSizeField && !isJsonField {
+		return nil, fmt.Errorf("Unknown field %v in filter, use key, value, bucket, keySize, valueSize or json. and the path of a field\n", field)
+	}
+	isTextOperator := !operatorToken.quoted && containsFold(textOperators, operator)
+	if operatorToken.quoted || !isTextOperator && !containsFold(orderOperators, operator) {
+		return nil, fmt.Errorf("Unknown operator %v in filter\n", operator)
+	}
+	if isJsonField {
+		return parseJsonComparison(field[len("json."):], canonical(append(append([]string{}, orderOperators...), textOperators...), operator), valueToken)
+	}
+	field = canonical(append(append([]string{}, textFields...), sizeFields...), field)
+	operator = canonical(append(append([]string{}, orderOperators...), textOperators...), operator)
+
+	comparison := comparisonNode{field: field, operator: operator}
+	if isSizeField {
+		if isTextOperator {
+			return nil, fmt.Errorf("%v can only be compared with =, !=, <, <=, > and >=\n", field)
+		}
+		number, err := strconv.ParseInt(valueToken.text, 10, 64)
+		if err != nil || valueToken.quoted {
+			return nil, fmt.Errorf("%v must be compared with an integer instead of %v\n", field, valueToken.text)
+		}
+		comparison.number = number
+		return comparison, nil
+	}
+
+	if !valueToken.quoted {
+		return nil, fmt.Errorf("%v must be compared with a quoted string instead of %v\n", field, valueToken.text)
+	}
+	comparison.text = []byte(valueToken.text)
+	if operator == "matches" {
+		comparison.pattern, err = regexp.Compile(valueToken.text)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid regular expression %q in filter: %v\n", valueToken.text, err)
+		}
+	}
+	return comparison, nil
+}
+
+// parseJsonComparison returns the comparison of the JSON field at the dot separated jsonPath with valueToken.
+func parseJsonComparison(jsonPath string, operator string, valueToken filterToken) (filterNode, error) {
+	comparison := comparisonNode{field: "json", operator: operator, jsonPath: strings.Split(jsonPath, ".")}
+	if slices.Contains(comparison.jsonPath, "") {
+		return nil, fmt.Errorf("Invalid JSON field json.%v in filter\n", jsonPath)
+	}
+	if !valueToken.quoted {
+		number, err := strconv.ParseFloat(valueToken.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("json.%v must be compared with a quoted string or a number instead of %v\n", jsonPath, valueToken.text)
+		}
+		if containsFold(textOperators, operator) {
+			return nil, fmt.Errorf("json.%v can only be compared with a number with =, !=, <, <=, > and >=\n", jsonPath)
+		}
+		comparison.jsonNumber = &number
+		return comparison, nil
+	}
+	comparison.text = []byte(valueToken.text)
+	if operator == "matches" {
+		var err error
+		comparison.pattern, err = regexp.Compile(valueToken.text)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid regular expression %q in filter: %v\n", valueToken.text, err)
+		}
+	}
+	return comparison, nil
+}
+
+// containsFold reports whether words contains word in any case.
+func containsFold(words []string, word string) bool {
+	return canonical(words, word) != ""
+}
+
+// canonical returns the spelling of word in words, or "" if words does not contain it in any case.
+func canonical(words []string, word string) string {
+	for _, candidate := range words {
+		if strings.EqualFold(candidate, word) {
+			return candidate
+		}
+	}
+	return ""
+}
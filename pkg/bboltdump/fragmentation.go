@@ -0,0 +1,80 @@
+package bboltdump
+
+import (
+	"fmt"
+	"os"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// FragmentationReport is a struct representing how much of a database file is unused, to tell whether compacting it is worthwhile.
+type FragmentationReport struct {
+	Size                   int64   `json:"size"`                   // size of the file in bytes
+	PageSize               int     `json:"pageSize"`               // size of a page in bytes
+	Pages                  uint64  `json:"pages"`                  // high water mark, pages up to the last one bolt stores data in
+	FreePages              int     `json:"freePages"`              // pages on the freelist that can be reused
+	PendingPages           int     `json:"pendingPages"`           // pages freed by transactions of this process that are reused once no read transaction needs them, 0 unless the handle cache holds the database
+	FreelistBytes          int     `json:"freelistBytes"`          // bytes the freelist takes up in the file, 0 if it is not synced to the file
+	FreeBytes              int64   `json:"freeBytes"`              // bytes of the free and pending pages
+	InuseBytes             int64   `json:"inuseBytes"`             // bytes of the pages that hold keys, values and the bucket structure
+	Fragmentation          float64 `json:"fragmentation"`          // share of the pages up to the high water mark that are free or pending, from 0 to 1
+	EstimatedCompactedSize int64   `json:"estimatedCompactedSize"` // size of a compacted copy, assuming its pages are filled to bolt.DefaultFillPercent
+	ReclaimableBytes       int64   `json:"reclaimableBytes"`       // bytes compaction would likely give back, Size minus EstimatedCompactedSize
+}
+
+// GetFragmentationReport takes the path to a bbolt database and returns its FragmentationReport along with an error.
+// The free and pending pages are taken from bolt's freelist, the bytes in use from the page statistics of all buckets. Compaction rewrites every bucket in key order, which fills its pages to the fill percent, so the compacted size can only be estimated.
+func GetFragmentationReport(dbPath string) (FragmentationReport, error) {
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return FragmentationReport{}, err
+	}
+	defer closeDb()
+
+	fileInfo, err := os.Stat(dbInstance.Path())
+	if err != nil {
+		return FragmentationReport{}, fmt.Errorf("Failed to read database file info: %v\n", err)
+	}
+	metaPages, err := ReadMetaPages(dbInstance.Path())
+	if err != nil {
+		return FragmentationReport{}, err
+	}
+	current := currentMetaPage(metaPages)
+	freelist, err := readFreelistPage(dbInstance.Path(), *current)
+	if err != nil {
+		return FragmentationReport{}, err
+	}
+
+	dbStats := dbInstance.Stats()
+	report := FragmentationReport{
+		Size:          fileInfo.Size(),
+		PageSize:      int(current.PageSize),
+		Pages:         current.Pages,
+		FreePages:     dbStats.FreePageN,
+		PendingPages:  dbStats.PendingPageN,
+		FreelistBytes: freelist.bytes(),
+	}
+	report.FreeBytes = int64(report.FreePages+report.PendingPages) * int64(report.PageSize)
+
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(_ []byte, b *bolt.Bucket) error {
+			bucketStats := b.Stats() // includes the buckets nested in b
+			report.InuseBytes += int64(bucketStats.BranchInuse + bucketStats.LeafInuse)
+			return nil
+		})
+	})
+	if err != nil {
+		return FragmentationReport{}, fmt.Errorf("Failed to read page statistics of database due to error: %v\n", err)
+	}
+
+	if report.Pages > 0 {
+		report.Fragmentation = float64(report.FreePages+report.PendingPages) / float64(report.Pages)
+	}
+	// two meta pages, the freelist and the root bucket are always there
+	fixedPages := int64(3 + max(freelist.pages, 1))
+	dataPages := (report.InuseBytes + int64(float64(report.PageSize)*bolt.DefaultFillPercent) - 1) / int64(float64(report.PageSize)*bolt.DefaultFillPercent)
+	report.EstimatedCompactedSize = (fixedPages + dataPages) * int64(report.PageSize)
+	report.ReclaimableBytes = max(report.Size-report.EstimatedCompactedSize, 0)
+	return report, nil
+}
@@ -0,0 +1,92 @@
+package bboltdump
+
+import (
+	"fmt"
+)
+
+// KeyGlob is a parsed glob pattern like "user:*:settings" that keys are matched against byte by byte.
+// * matches any amount of bytes including none, ? matches exactly one byte and \ makes the character after it match itself, so "\*" matches a literal *.
+// Unlike path.Match a * also matches slashes, keys are not paths.
+type KeyGlob struct {
+	pattern string
+	tokens  []globToken
+}
+
+// globToken is a part of a KeyGlob, either a literal byte or a wildcard.
+type globToken struct {
+	wildcard byte // '*' or '?', 0 for a literal
+	literal  byte
+}
+
+// ParseKeyGlob parses the glob pattern pattern, see KeyGlob for its syntax.
+func ParseKeyGlob(pattern string) (*KeyGlob, error) {
+	glob := &KeyGlob{pattern: pattern}
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*':
+			// a run of stars matches the same as one
+			if len(glob.tokens) == 0 || glob.tokens[len(glob.tokens)-1].wildcard != '*' {
+				glob.tokens = append(glob.tokens, globToken{wildcard: '*'})
+			}
+		case '?':
+			glob.tokens = append(glob.tokens, globToken{wildcard: '?'})
+		case '\\':
+			if i == len(pattern)-1 {
+				return nil, fmt.Errorf("Pattern %q ends with an unfinished escape\n", pattern)
+			}
+			i++
+			glob.tokens = append(glob.tokens, globToken{literal: pattern[i]})
+		default:
+			glob.tokens = append(glob.tokens, globToken{literal: pattern[i]})
+		}
+	}
+	return glob, nil
+}
+
+// Prefix returns the literal bytes the pattern starts with, every matching key starts with them, so a scan only has to visit the keys from there on.
+func (g *KeyGlob) Prefix() []byte {
+	if g == nil {
+		return nil
+	}
+	prefix := []byte{}
+	for _, token := range g.tokens {
+		if token.wildcard != 0 {
+			break
+		}
+		prefix = append(prefix, token.literal)
+	}
+	return prefix
+}
+
+// Matches reports whether keyBytes matches the whole pattern. A nil KeyGlob matches every key.
+func (g *KeyGlob) Matches(keyBytes []byte) bool {
+	if g == nil {
+		return true
+	}
+	// on a mismatch the last * takes one more byte and matching goes on after it
+	tokenIndex, keyIndex := 0, 0
+	starIndex, starKeyIndex := -1, 0
+	for keyIndex < len(keyBytes) {
+		switch {
+		case tokenIndex < len(g.tokens) && g.tokens[tokenIndex].wildcard == '*':
+			starIndex, starKeyIndex = tokenIndex, keyIndex
+			tokenIndex++
+		case tokenIndex < len(g.tokens) && (g.tokens[tokenIndex].wildcard == '?' || g.tokens[tokenIndex].wildcard == 0 && g.tokens[tokenIndex].literal == keyBytes[keyIndex]):
+			tokenIndex++
+			keyIndex++
+		case starIndex >= 0:
+			starKeyIndex++
+			tokenIndex, keyIndex = starIndex+1, starKeyIndex
+		default:
+			return false
+		}
+	}
+	for tokenIndex < len(g.tokens) && g.tokens[tokenIndex].wildcard == '*' {
+		tokenIndex++
+	}
+	return tokenIndex == len(g.tokens)
+}
+
+func (g *KeyGlob) String() string {
+	return g.pattern
+}
@@ -0,0 +1,54 @@
+package bboltdump
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+)
+
+// GobTransformer returns a Transformer that decodes gob encoded values into valueType and writes them as JSON.
+// gob matches struct fields by name, so valueType only needs the fields that should be dumped and may be built with reflect.StructOf when the Go type of the app is not at hand.
+func GobTransformer(valueType reflect.Type) Transformer {
+	return func(valueBytes []byte) ([]byte, error) {
+		decoded := reflect.New(valueType)
+		err := gob.NewDecoder(bytes.NewReader(valueBytes)).Decode(decoded.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode gob value: %v\n", err)
+		}
+		return json.Marshal(decoded.Elem().Interface())
+	}
+}
+
+// gobTypeRule is a struct representing the type of the gob encoded values of the buckets matching bucketPattern.
+type gobTypeRule struct {
+	bucketPattern string
+	valueType     reflect.Type
+}
+
+// gobTypeRules are tried in the order they were registered, the first one whose pattern matches a bucket wins.
+var gobTypeRules []gobTypeRule
+
+// RegisterGobType makes full dumps decode the gob encoded values of all buckets matching bucketPattern into the type of prototype, like RegisterTransformer.
+// Values written to those buckets with EncodingGob are encoded from that type.
+func RegisterGobType(bucketPattern string, prototype any) error {
+	valueType := reflect.TypeOf(prototype)
+	err := RegisterTransformer(bucketPattern, GobTransformer(valueType))
+	if err != nil {
+		return err
+	}
+	gobTypeRules = append(gobTypeRules, gobTypeRule{bucketPattern: bucketPattern, valueType: valueType})
+	return nil
+}
+
+// gobTypeFor returns the type registered for the gob encoded values of the bucket bucketPath.
+func gobTypeFor(bucketPath string) (reflect.Type, bool) {
+	for _, rule := range gobTypeRules {
+		if matched, _ := path.Match(rule.bucketPattern, bucketPath); matched {
+			return rule.valueType, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,87 @@
+package bboltdump
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// gunzippedCopy is a struct representing the decompressed copy of a gzipped database.
+type gunzippedCopy struct {
+	path    string    // path of the decompressed file
+	size    int64     // size of the gzipped file when it was decompressed
+	modTime time.Time // modification time of the gzipped file when it was decompressed
+}
+
+// gunzippedCopies keeps the decompressed copies of gzipped databases, so a snapshot is only decompressed again if it changed.
+var gunzippedCopies = struct {
+	mu     sync.Mutex
+	copies map[string]gunzippedCopy // keyed by absolute path of the gzipped file
+}{copies: make(map[string]gunzippedCopy)}
+
+// gunzip returns the path of a decompressed copy of the gzipped database at gzPath, decompressing it into the temp directory if there is no up to date copy yet.
+func gunzip(gzPath string) (string, error) {
+	absolutePath, err := filepath.Abs(gzPath)
+	if err != nil {
+		return "", fmt.Errorf("Failed to resolve database path: %v\n", err)
+	}
+	fileInfo, err := os.Stat(absolutePath)
+	if err != nil {
+		return "", fmt.Errorf("Failed to open database: %v\n", err)
+	}
+
+	gunzippedCopies.mu.Lock()
+	defer gunzippedCopies.mu.Unlock()
+
+	cached, found := gunzippedCopies.copies[absolutePath]
+	if found && cached.size == fileInfo.Size() && cached.modTime.Equal(fileInfo.ModTime()) {
+		return cached.path, nil
+	}
+
+	copyPath, err := decompress(absolutePath)
+	if err != nil {
+		return "", fmt.Errorf("Failed to decompress database %v: %v\n", gzPath, err)
+	}
+	if found {
+		os.Remove(cached.path) // open handles keep working on the removed file
+	}
+	gunzippedCopies.copies[absolutePath] = gunzippedCopy{
+		path:    copyPath,
+		size:    fileInfo.Size(),
+		modTime: fileInfo.ModTime(),
+	}
+	return copyPath, nil
+}
+
+// decompress writes the decompressed content of the gzipped file at gzPath to a new file in the temp directory and returns its path.
+func decompress(gzPath string) (string, error) {
+	gzFile, err := os.Open(gzPath)
+	if err != nil {
+		return "", err
+	}
+	defer gzFile.Close()
+	gzReader, err := gzip.NewReader(gzFile)
+	if err != nil {
+		return "", err
+	}
+	defer gzReader.Close()
+
+	copyFile, err := os.CreateTemp("", "bboltdump-*.db")
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(copyFile, gzReader)
+	closeErr := copyFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(copyFile.Name())
+		return "", err
+	}
+	return copyFile.Name(), nil
+}
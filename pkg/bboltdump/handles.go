@@ -0,0 +1,179 @@
+package bboltdump
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// HandleInfo is a struct representing a cached database handle.
+type HandleInfo struct {
+	Path     string    `json:"path"`     // absolute path to db file
+	Users    int       `json:"users"`    // amount of callers currently using the handle
+	OpenedAt time.Time `json:"openedAt"` // time the database was opened
+	LastUsed time.Time `json:"lastUsed"` // time the handle was last handed out or given back
+}
+
+// cachedHandle is a struct representing an open database shared by all callers that ask for the same path.
+// It is put into the cache before the database is opened, so callers asking for the same path meanwhile wait for opened instead of opening it again.
+type cachedHandle struct {
+	db       *bolt.DB // nil until opened is closed, and if opening failed
+	err      error    // why opening failed
+	opened   chan struct{}
+	users    int
+	openedAt time.Time
+	lastUsed time.Time
+}
+
+// handleCache keeps databases open between calls. bolt holds a file lock while a database is open, so every open of a cached path has to go through the cache.
+// mu is never held while a database is opened, waiting for the file lock of one database must not stall the others.
+type handleCache struct {
+	mu          sync.Mutex
+	handles     map[string]*cachedHandle
+	idleTimeout time.Duration // handles nobody used for this long are closed
+}
+
+// cache is nil unless EnableHandleCache was called, in which case OpenDb and OpenDbForWriting hand out shared handles.
+var cache *handleCache
+
+// EnableHandleCache makes OpenDb and OpenDbForWriting keep databases open and share them between callers instead of opening the file on every call. Handles that were not used for idleTimeout are closed.
+// It must be called before the first database is opened.
+func EnableHandleCache(idleTimeout time.Duration) {
+	cache = &handleCache{
+		handles:     make(map[string]*cachedHandle),
+		idleTimeout: idleTimeout,
+	}
+	go cache.closeIdleHandles()
+}
+
+// acquire returns the cached handle of dbPath, opening it with open if it is not cached yet, along with the function that gives it back.
+// Callers that ask for a path while it is being opened wait for that open and share its outcome.
+func (c *handleCache) acquire(dbPath string, open func(string) (*bolt.DB, error)) (*bolt.DB, func(), error) {
+	absolutePath, err := filepath.Abs(dbPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to resolve database path: %v\n", err)
+	}
+
+	c.mu.Lock()
+	handle, found := c.handles[absolutePath]
+	if !found {
+		handle = &cachedHandle{opened: make(chan struct{})}
+		c.handles[absolutePath] = handle
+	}
+	handle.users++
+	c.mu.Unlock()
+
+	if !found {
+		dbInstance, err := open(absolutePath)
+		c.mu.Lock()
+		handle.db, handle.err, handle.openedAt = dbInstance, err, time.Now()
+		if err != nil && c.handles[absolutePath] == handle {
+			delete(c.handles, absolutePath) // the next caller tries again
+		}
+		c.mu.Unlock()
+		close(handle.opened)
+	}
+	<-handle.opened
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if handle.err != nil {
+		handle.users--
+		return nil, nil, handle.err
+	}
+	handle.lastUsed = time.Now()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			handle.users--
+			handle.lastUsed = time.Now()
+		})
+	}
+	return handle.db, release, nil
+}
+
+// closeIdleHandles periodically closes the handles nobody used for idleTimeout. It never returns.
+func (c *handleCache) closeIdleHandles() {
+	ticker := time.NewTicker(c.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		c.mu.Lock()
+		for handlePath, handle := range c.handles {
+			if handle.db != nil && handle.users == 0 && time.Since(handle.lastUsed) >= c.idleTimeout {
+				delete(c.handles, handlePath)
+				handle.db.Close()
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+// CachedHandles returns the currently cached database handles sorted by path, databases that are still being opened are left out. It returns an empty list if the cache is not enabled.
+func CachedHandles() []HandleInfo {
+	handleInfos := []HandleInfo{}
+	if cache == nil {
+		return handleInfos
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	for handlePath, handle := range cache.handles {
+		if handle.db == nil {
+			continue
+		}
+		handleInfos = append(handleInfos, HandleInfo{
+			Path:     handlePath,
+			Users:    handle.users,
+			OpenedAt: handle.openedAt,
+			LastUsed: handle.lastUsed,
+		})
+	}
+	sort.Slice(handleInfos, func(i, j int) bool {
+		return handleInfos[i].Path < handleInfos[j].Path
+	})
+	return handleInfos
+}
+
+// CloseCachedHandle removes the handle of dbPath from the cache and closes it, even if it is still in use. Closing waits for running transactions to finish, callers still holding the handle get bolt.ErrDatabaseNotOpen on their next transaction.
+// Once it returns the file lock is released, so the file can be replaced or deleted. The returned HandleInfo describes the handle as it was before closing, found is false if dbPath was not cached or is still being opened.
+func CloseCachedHandle(dbPath string) (HandleInfo, bool, error) {
+	if cache == nil {
+		return HandleInfo{}, false, nil
+	}
+	absolutePath, err := filepath.Abs(dbPath)
+	if err != nil {
+		return HandleInfo{}, false, fmt.Errorf("Failed to resolve database path: %v\n", err)
+	}
+
+	cache.mu.Lock()
+	handle, found := cache.handles[absolutePath]
+	found = found && handle.db != nil
+	var handleInfo HandleInfo
+	if found {
+		delete(cache.handles, absolutePath)
+		handleInfo = HandleInfo{
+			Path:     absolutePath,
+			Users:    handle.users,
+			OpenedAt: handle.openedAt,
+			LastUsed: handle.lastUsed,
+		}
+	}
+	cache.mu.Unlock()
+	if !found {
+		return HandleInfo{}, false, nil
+	}
+
+	err = handle.db.Close()
+	if err != nil {
+		return handleInfo, true, fmt.Errorf("Failed to close database %v: %v\n", absolutePath, err)
+	}
+	return handleInfo, true, nil
+}
@@ -0,0 +1,98 @@
+package bboltdump
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestHandleCacheAcquire(t *testing.T) {
+	c := &handleCache{handles: make(map[string]*cachedHandle), idleTimeout: time.Minute}
+	dir := t.TempDir()
+	lockedPath := filepath.Join(dir, "locked.db")
+	freePath := filepath.Join(dir, "free.db")
+
+	// the open of lockedPath waits like for a file lock until unlock is closed
+	unlock := make(chan struct{})
+	var opensMu sync.Mutex
+	opens := make(map[string]int)
+	open := func(dbPath string) (*bolt.DB, error) {
+		opensMu.Lock()
+		opens[filepath.Base(dbPath)]++
+		opensMu.Unlock()
+		if dbPath == lockedPath {
+			<-unlock
+		}
+		return bolt.Open(dbPath, 0600, nil)
+	}
+
+	lockedDbs := make(chan *bolt.DB, 2)
+	for range 2 {
+		go func() {
+			dbInstance, release, err := c.acquire(lockedPath, open)
+			if err != nil {
+				t.Error(err)
+				lockedDbs <- nil
+				return
+			}
+			defer release()
+			lockedDbs <- dbInstance
+		}()
+	}
+
+	// other paths and the listing of the cache do not wait for the locked one
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, release, err := c.acquire(freePath, open)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		release()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("acquire of another path waited for the locked database")
+	}
+
+	close(unlock)
+	first, second := <-lockedDbs, <-lockedDbs
+	if first == nil || first != second {
+		t.Errorf("concurrent acquires got handles %p and %p, want the same", first, second)
+	}
+	if opens["locked.db"] != 1 {
+		t.Errorf("locked database was opened %v times, want 1", opens["locked.db"])
+	}
+
+	for _, handle := range c.handles {
+		handle.db.Close()
+	}
+}
+
+func TestHandleCacheAcquireFailure(t *testing.T) {
+	c := &handleCache{handles: make(map[string]*cachedHandle), idleTimeout: time.Minute}
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	openErr := errors.New("locked")
+
+	_, _, err := c.acquire(dbPath, func(string) (*bolt.DB, error) { return nil, openErr })
+	if err != openErr {
+		t.Fatalf("acquire error = %v, want %v", err, openErr)
+	}
+	if len(c.handles) != 0 {
+		t.Fatalf("failed open stayed in the cache")
+	}
+
+	// the next caller tries again
+	dbInstance, release, err := c.acquire(dbPath, func(dbPath string) (*bolt.DB, error) { return bolt.Open(dbPath, 0600, nil) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	dbInstance.Close()
+}
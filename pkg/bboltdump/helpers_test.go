@@ -0,0 +1,93 @@
+package bboltdump
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// createTestDb creates a database in a temporary directory with the key-value pairs per bucket path of buckets and returns its path.
+func createTestDb(t *testing.T, buckets map[string]map[string]string) string {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	dbInstance, err := bolt.Open(dbPath, 0600, nil)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer dbInstance.Close()
+
+	err = dbInstance.Update(func(tx *bolt.Tx) error {
+		for bucketPath, pairs := range buckets {
+			b, err := CreateBucketPath(tx, bucketPath)
+			if err != nil {
+				return err
+			}
+			for key, value := range pairs {
+				err = b.Put([]byte(key), []byte(value))
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to fill database: %v", err)
+	}
+	return dbPath
+}
+
+// expireTestKey attaches expiresAt to the key of the bucket bucketPath of the database at dbPath.
+func expireTestKey(t *testing.T, dbPath string, bucketPath string, key string, expiresAt time.Time) {
+	t.Helper()
+	updateTestDb(t, dbPath, func(tx *bolt.Tx) error {
+		return SetExpiry(tx, bucketPath, []byte(key), expiresAt)
+	})
+}
+
+// updateTestDb runs fn in a read-write transaction of the database at dbPath.
+func updateTestDb(t *testing.T, dbPath string, fn func(tx *bolt.Tx) error) {
+	t.Helper()
+	dbInstance, closeDb, err := OpenDbForWriting(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer closeDb()
+	err = dbInstance.Update(fn)
+	if err != nil {
+		t.Fatalf("Failed to update database: %v", err)
+	}
+}
+
+// readTestDb returns the key-value pairs per bucket path of the database at dbPath, nested buckets included and expiries ignored.
+func readTestDb(t *testing.T, dbPath string) map[string]map[string]string {
+	t.Helper()
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer closeDb()
+
+	buckets := make(map[string]map[string]string)
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		return forEachBucketPath(tx, "", func(b *bolt.Bucket, path string) []string {
+			nestedBuckets := []string{}
+			buckets[path] = make(map[string]string)
+			b.ForEach(func(keyBytes []byte, valueBytes []byte) error {
+				if valueBytes == nil {
+					nestedBuckets = append(nestedBuckets, string(keyBytes))
+				} else {
+					buckets[path][string(keyBytes)] = string(valueBytes)
+				}
+				return nil
+			})
+			return nestedBuckets
+		})
+	})
+	if err != nil {
+		t.Fatalf("Failed to read database: %v", err)
+	}
+	return buckets
+}
@@ -0,0 +1,100 @@
+package bboltdump
+
+import (
+	"fmt"
+	"math/bits"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BucketHistogram is a struct representing how the key lengths and value sizes of a bucket are distributed.
+type BucketHistogram struct {
+	Bucket         string         `json:"bucket"`         // name or path of the bucket
+	Keys           int            `json:"keys"`           // amount of values of the bucket, without its nested buckets
+	KeyBytes       int64          `json:"keyBytes"`       // sum of the key lengths
+	ValueBytes     int64          `json:"valueBytes"`     // sum of the value sizes
+	AllocatedBytes int            `json:"allocatedBytes"` // bytes of the pages bolt allocated for the bucket and the buckets nested in it, which includes the page overhead and unused space
+	KeyLengths     []HistogramBin `json:"keyLengths"`     // key lengths by powers of two
+	ValueSizes     []HistogramBin `json:"valueSizes"`     // value sizes by powers of two
+}
+
+// HistogramBin is a struct representing the entries whose size lies between Min and Max, both inclusive. Bins are 0, 1, 2-3, 4-7, 8-15 and so on, empty bins are left out.
+type HistogramBin struct {
+	Min   int   `json:"min"`
+	Max   int   `json:"max"`
+	Count int   `json:"count"` // amount of entries in the bin
+	Bytes int64 `json:"bytes"` // sum of their sizes
+}
+
+// histogram is a power of two histogram that is filled during the pass over a bucket.
+type histogram [65]HistogramBin
+
+// add counts size in its bin.
+func (h *histogram) add(size int) {
+	bin := bits.Len(uint(size)) // 0 for 0, 1 for 1, 2 for 2-3, 3 for 4-7
+	h[bin].Count++
+	h[bin].Bytes += int64(size)
+}
+
+// bins returns the bins of h that are not empty, in ascending order.
+func (h *histogram) bins() []HistogramBin {
+	bins := []HistogramBin{}
+	for bin := range h {
+		if h[bin].Count == 0 {
+			continue
+		}
+		histogramBin := h[bin]
+		if bin > 0 {
+			histogramBin.Min = 1 << (bin - 1)
+			histogramBin.Max = 1<<bin - 1
+		}
+		bins = append(bins, histogramBin)
+	}
+	return bins
+}
+
+// GetHistograms takes the path to a bbolt database and returns the BucketHistogram of the bucket bucketName and of the buckets nested in it, or of all buckets if bucketName is empty, along with an error.
+// Every bucket is read with a single cursor pass that only looks at the lengths of keys and values, which makes this the cheap way to find the bucket a file's bloat comes from.
+func GetHistograms(dbPath string, bucketName string) ([]BucketHistogram, error) {
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeDb()
+
+	bucketHistograms := []BucketHistogram{}
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		checker := newExpiryChecker(tx)
+		return forEachBucketPath(tx, bucketName, func(b *bolt.Bucket, path string) []string {
+			bucketHistogram := BucketHistogram{Bucket: path}
+			var keyLengths, valueSizes histogram
+			nestedBuckets := []string{}
+			cursor := b.Cursor()
+			for keyBytes, valueBytes := cursor.First(); keyBytes != nil; keyBytes, valueBytes = cursor.Next() {
+				if valueBytes == nil {
+					nestedBuckets = append(nestedBuckets, string(keyBytes))
+					continue
+				}
+				if checker.isExpired([]byte(path), keyBytes) {
+					continue
+				}
+				bucketHistogram.Keys++
+				bucketHistogram.KeyBytes += int64(len(keyBytes))
+				bucketHistogram.ValueBytes += int64(len(valueBytes))
+				keyLengths.add(len(keyBytes))
+				valueSizes.add(len(valueBytes))
+			}
+			bucketStats := b.Stats()
+			bucketHistogram.AllocatedBytes = bucketStats.BranchAlloc + bucketStats.LeafAlloc
+			bucketHistogram.KeyLengths = keyLengths.bins()
+			bucketHistogram.ValueSizes = valueSizes.bins()
+			bucketHistograms = append(bucketHistograms, bucketHistogram)
+			return nestedBuckets
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read histograms of database due to error: %v\n", err)
+	}
+	return bucketHistograms, nil
+}
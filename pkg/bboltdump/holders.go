@@ -0,0 +1,203 @@
+package bboltdump
+
+import (
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// HolderInfo is a struct representing a caller that currently holds a database open.
+type HolderInfo struct {
+	Holder   string    `json:"holder"`   // function that opened the database, like "bboltdump.GetBucketPageAsJson"
+	Writable bool      `json:"writable"` // true if the database was opened for writing
+	Waiting  bool      `json:"waiting"`  // true while the holder still waits for the database to be opened, e.g. for the file lock
+	Since    time.Time `json:"since"`    // time the holder asked for the database
+	Age      string    `json:"age"`      // how long the holder has been holding or waiting, like "1.5s"
+}
+
+// RequestInfo is a struct representing a request of the server working on a database, or a transaction that spans several requests.
+type RequestInfo struct {
+	Method      string    `json:"method,omitempty"` // method of the request, empty for transactions
+	Path        string    `json:"path,omitempty"`   // path of the request, empty for transactions
+	Who         string    `json:"who"`              // identity of the client like the audit log names it
+	Transaction bool      `json:"transaction"`      // true for a transaction session that holds the database between requests
+	Writable    bool      `json:"writable"`         // true for a read-write transaction
+	Since       time.Time `json:"since"`            // time the request arrived or the transaction began
+	Age         string    `json:"age"`              // how long the request has been running or the transaction open, like "1.5s"
+}
+
+// OpenDbInfo is a struct representing a database that is currently open or being opened, along with who holds it.
+type OpenDbInfo struct {
+	Path             string        `json:"path"`             // absolute path to db file
+	Cached           bool          `json:"cached"`           // true if the handle cache keeps the database open
+	ReadTransactions int           `json:"readTransactions"` // amount of read transactions currently running on the database
+	Holders          []HolderInfo  `json:"holders"`          // callers holding the database, oldest first
+	Requests         []RequestInfo `json:"requests"`         // requests and transactions of the server working on the database, oldest first
+}
+
+// holder is a struct representing one call of OpenDb or OpenDbForWriting whose close function was not called yet.
+type holder struct {
+	path     string
+	name     string
+	writable bool
+	since    time.Time
+	db       *bolt.DB // nil while the database is still being opened
+}
+
+// trackedRequest is a struct representing a call of TrackRequest whose function was not called yet.
+type trackedRequest struct {
+	path    string
+	request RequestInfo
+}
+
+// holders keeps track of everyone holding a database open and of the requests they serve, it is always enabled so stuck callers can be diagnosed.
+var holders = struct {
+	mu       sync.Mutex
+	byId     map[int]*holder
+	requests map[int]*trackedRequest // by the id they share with holders
+	nextId   int
+}{byId: make(map[int]*holder), requests: make(map[int]*trackedRequest)}
+
+// callerName returns the name of the function that called the function calling callerName, shortened to its package name like "export.ToSqlite".
+func callerName() string {
+	pc, _, _, ok := runtime.Caller(2)
+	if !ok {
+		return "unknown"
+	}
+	name := runtime.FuncForPC(pc).Name()
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+// registerHolder adds a holder that is about to open dbPath and returns its id.
+func registerHolder(dbPath string, writable bool, holderName string) int {
+	absolutePath, err := filepath.Abs(dbPath)
+	if err != nil {
+		absolutePath = dbPath
+	}
+
+	holders.mu.Lock()
+	defer holders.mu.Unlock()
+	holders.nextId++
+	holders.byId[holders.nextId] = &holder{
+		path:     absolutePath,
+		name:     holderName,
+		writable: writable,
+		since:    time.Now(),
+	}
+	return holders.nextId
+}
+
+// holderOpened records that the holder with holderId got its database.
+func holderOpened(holderId int, dbInstance *bolt.DB) {
+	holders.mu.Lock()
+	defer holders.mu.Unlock()
+	holders.byId[holderId].db = dbInstance
+}
+
+// unregisterHolder removes the holder with holderId.
+func unregisterHolder(holderId int) {
+	holders.mu.Lock()
+	defer holders.mu.Unlock()
+	delete(holders.byId, holderId)
+}
+
+// TrackRequest records that request works on the database at dbPath until the returned function is called, so OpenDatabases and the LockedError of other callers can tell which requests hold the database, not only which functions of the package opened it.
+// The server calls it for every request that names a database and for the lifetime of every transaction session.
+func TrackRequest(dbPath string, request RequestInfo) func() {
+	absolutePath, err := filepath.Abs(dbPath)
+	if err != nil {
+		absolutePath = dbPath
+	}
+	if request.Since.IsZero() {
+		request.Since = time.Now()
+	}
+
+	holders.mu.Lock()
+	holders.nextId++
+	requestId := holders.nextId
+	holders.requests[requestId] = &trackedRequest{path: absolutePath, request: request}
+	holders.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			holders.mu.Lock()
+			defer holders.mu.Unlock()
+			delete(holders.requests, requestId)
+		})
+	}
+}
+
+// OpenDatabases returns all databases that are currently open or being opened sorted by path, including cached handles nobody is using right now.
+func OpenDatabases() []OpenDbInfo {
+	now := time.Now()
+	openDbInfos := make(map[string]*OpenDbInfo)
+	handlesOfPath := make(map[string]map[*bolt.DB]bool)
+	infoOfPath := func(dbPath string) *OpenDbInfo {
+		if openDbInfos[dbPath] == nil {
+			openDbInfos[dbPath] = &OpenDbInfo{Path: dbPath, Holders: []HolderInfo{}}
+			handlesOfPath[dbPath] = make(map[*bolt.DB]bool)
+		}
+		return openDbInfos[dbPath]
+	}
+
+	holders.mu.Lock()
+	for _, currentHolder := range holders.byId {
+		openDbInfo := infoOfPath(currentHolder.path)
+		openDbInfo.Holders = append(openDbInfo.Holders, HolderInfo{
+			Holder:   currentHolder.name,
+			Writable: currentHolder.writable,
+			Waiting:  currentHolder.db == nil,
+			Since:    currentHolder.since,
+			Age:      now.Sub(currentHolder.since).Round(time.Millisecond).String(),
+		})
+		if currentHolder.db != nil {
+			handlesOfPath[currentHolder.path][currentHolder.db] = true
+		}
+	}
+	requestsOfPath := make(map[string][]RequestInfo)
+	for _, tracked := range holders.requests {
+		request := tracked.request
+		request.Age = now.Sub(request.Since).Round(time.Millisecond).String()
+		requestsOfPath[tracked.path] = append(requestsOfPath[tracked.path], request)
+	}
+	holders.mu.Unlock()
+
+	if cache != nil {
+		cache.mu.Lock()
+		for handlePath, handle := range cache.handles {
+			if handle.db == nil {
+				continue // still being opened, its callers are listed as waiting holders
+			}
+			infoOfPath(handlePath).Cached = true
+			handlesOfPath[handlePath][handle.db] = true
+		}
+		cache.mu.Unlock()
+	}
+
+	result := []OpenDbInfo{}
+	for dbPath, openDbInfo := range openDbInfos {
+		// requests that work on a database nobody opened are not stuck on it
+		openDbInfo.Requests = append([]RequestInfo{}, requestsOfPath[dbPath]...)
+		sort.Slice(openDbInfo.Requests, func(i, j int) bool {
+			return openDbInfo.Requests[i].Since.Before(openDbInfo.Requests[j].Since)
+		})
+		// without the cache every holder has its own handle
+		for dbInstance := range handlesOfPath[dbPath] {
+			openDbInfo.ReadTransactions += dbInstance.Stats().OpenTxN
+		}
+		sort.Slice(openDbInfo.Holders, func(i, j int) bool {
+			return openDbInfo.Holders[i].Since.Before(openDbInfo.Holders[j].Since)
+		})
+		result = append(result, *openDbInfo)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
@@ -0,0 +1,58 @@
+package bboltdump
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenDatabasesListsRequests(t *testing.T) {
+	dbPath := createTestDb(t, map[string]map[string]string{"users": {"alice": "1"}})
+	otherPath := filepath.Join(t.TempDir(), "other.db")
+
+	untrackPage := TrackRequest(dbPath, RequestInfo{Method: "POST", Path: "/bbolt/page", Who: "alice"})
+	untrackTx := TrackRequest(dbPath, RequestInfo{Who: "bob", Transaction: true, Writable: true})
+	defer untrackTx()
+	untrackOther := TrackRequest(otherPath, RequestInfo{Method: "POST", Path: "/bbolt", Who: "carol"})
+	defer untrackOther()
+
+	// nothing is open yet
+	if openDbInfos := OpenDatabases(); len(openDbInfos) != 0 {
+		t.Fatalf("OpenDatabases = %v, want none", openDbInfos)
+	}
+
+	_, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDb()
+
+	tests := []struct {
+		name         string
+		untrack      func()
+		wantRequests []string // who of the requests, oldest first
+	}{
+		{"request and transaction", func() {}, []string{"alice", "bob"}},
+		{"request finished", untrackPage, []string{"bob"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.untrack()
+			openDbInfos := OpenDatabases()
+			if len(openDbInfos) != 1 {
+				t.Fatalf("OpenDatabases = %v, want one database", openDbInfos)
+			}
+			requests := openDbInfos[0].Requests
+			if len(requests) != len(test.wantRequests) {
+				t.Fatalf("Requests = %v, want %v", requests, test.wantRequests)
+			}
+			for i, who := range test.wantRequests {
+				if requests[i].Who != who || requests[i].Age == "" {
+					t.Errorf("Requests[%v] = %+v, want one of %v", i, requests[i], who)
+				}
+			}
+			if len(openDbInfos[0].Holders) != 1 || openDbInfos[0].Holders[0].Holder != "bboltdump.TestOpenDatabasesListsRequests" {
+				t.Errorf("Holders = %v, want the test", openDbInfos[0].Holders)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package bboltdump
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// IdempotencyBucket is the top level bucket that holds the responses of the writes sent with an idempotency key.
+// Its keys are the client followed by a NUL byte and the idempotency key, its values the responses as JSON.
+const IdempotencyBucket = "__idempotency"
+
+// StoredResponse is a struct representing the response of a completed write, which is sent again instead of repeating the write, or the marker of a write that was begun but has no response yet.
+type StoredResponse struct {
+	Time        string `json:"time"`        // RFC 3339 time of the write in UTC
+	Operation   string `json:"operation"`   // method and path of the request like "POST /bbolt/move"
+	PayloadHash string `json:"payloadHash"` // hex encoded SHA-256 of the payload, a retry has to send the same
+	Status      int    `json:"status"`      // HTTP status code of the response, 0 while the write is pending
+	ContentType string `json:"contentType"` // Content-Type header of the response
+	Body        []byte `json:"body"`        // body of the response
+}
+
+// IsPending reports whether response only marks a write that was begun, which either is still running or stopped without its response being stored, so whether it wrote is unknown.
+func (response StoredResponse) IsPending() bool {
+	return response.Status == 0
+}
+
+// idempotencyKey returns the key under which the response to the idempotency key of client is stored in IdempotencyBucket.
+func idempotencyKey(client string, key string) []byte {
+	return []byte(client + "\x00" + key)
+}
+
+// LookupResponse returns the response stored for the idempotency key of client in the database at dbPath, and whether there is one that is younger than retention.
+// It reads the live file even if a read replica is set, a replica older than the stored response would let the write run again.
+func LookupResponse(dbPath string, client string, key string, retention time.Duration) (StoredResponse, bool, error) {
+	var response StoredResponse
+	found := false
+
+	// open database
+	dbInstance, closeDb, err := OpenLiveDb(dbPath)
+	if err != nil {
+		return response, false, err
+	}
+	defer closeDb()
+
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		idempotencyBucket := tx.Bucket([]byte(IdempotencyBucket))
+		if idempotencyBucket == nil {
+			return nil
+		}
+		responseJson := idempotencyBucket.Get(idempotencyKey(client, key))
+		if responseJson == nil {
+			return nil
+		}
+		err := json.Unmarshal(responseJson, &response)
+		if err != nil {
+			return fmt.Errorf("Stored response of idempotency key %v is corrupt: %v\n", key, err)
+		}
+		storedAt, err := time.Parse(time.RFC3339, response.Time)
+		found = err == nil && time.Since(storedAt) < retention
+		return nil
+	})
+	return response, found, err
+}
+
+// StoreResponse stores response for the idempotency key of client in the database at dbPath, and removes the responses that are older than retention since nobody retries them anymore.
+// It runs in a transaction of its own, so a write is marked with a pending response before it begins and gets its real response once it committed, see IsPending.
+func StoreResponse(dbPath string, client string, key string, response StoredResponse, retention time.Duration) error {
+	now := time.Now().UTC()
+	response.Time = now.Format(time.RFC3339)
+	responseJson, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	// open database
+	dbInstance, closeDb, err := OpenDbForWriting(dbPath)
+	if err != nil {
+		return err
+	}
+	defer closeDb()
+
+	return dbInstance.Update(func(tx *bolt.Tx) error {
+		idempotencyBucket, err := tx.CreateBucketIfNotExists([]byte(IdempotencyBucket))
+		if err != nil {
+			return fmt.Errorf("Failed to create bucket %v: %v\n", IdempotencyBucket, err)
+		}
+
+		// collect first, bolt does not allow deleting while iterating
+		var expiredKeys [][]byte
+		err = idempotencyBucket.ForEach(func(k []byte, v []byte) error {
+			var stored StoredResponse
+			if json.Unmarshal(v, &stored) != nil {
+				return nil
+			}
+			storedAt, err := time.Parse(time.RFC3339, stored.Time)
+			if err == nil && now.Sub(storedAt) >= retention {
+				expiredKeys = append(expiredKeys, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, expiredKey := range expiredKeys {
+			err = idempotencyBucket.Delete(expiredKey)
+			if err != nil {
+				return err
+			}
+		}
+
+		return idempotencyBucket.Put(idempotencyKey(client, key), responseJson)
+	})
+}
+
+// ForgetResponse removes the response stored for the idempotency key of client in the database at dbPath, like the pending marker of a write that failed and may be retried.
+func ForgetResponse(dbPath string, client string, key string) error {
+	// open database
+	dbInstance, closeDb, err := OpenDbForWriting(dbPath)
+	if err != nil {
+		return err
+	}
+	defer closeDb()
+
+	return dbInstance.Update(func(tx *bolt.Tx) error {
+		idempotencyBucket := tx.Bucket([]byte(IdempotencyBucket))
+		if idempotencyBucket == nil {
+			return nil
+		}
+		return idempotencyBucket.Delete(idempotencyKey(client, key))
+	})
+}
@@ -0,0 +1,331 @@
+package bboltdump
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// IndexBucket is the top level bucket that holds the secondary indexes of a database, one nested bucket per index named after it.
+// Every index holds its IndexDefinition as JSON under the key "definition" and its entries in the nested bucket "entries", whose keys are the big endian uint16 length of the indexed value followed by the value and the primary key.
+const IndexBucket = "__indexes"
+
+// ErrIndexNotFound is returned when the index an operation should use does not exist.
+var ErrIndexNotFound = errors.New("index does not exist")
+
+// IndexDefinition is a struct representing what a secondary index indexes.
+type IndexDefinition struct {
+	Name   string `json:"name"`   // name of the index
+	Bucket string `json:"bucket"` // path of the bucket whose entries are indexed
+	Field  string `json:"field"`  // path of the JSON field of the values that is indexed like "email" or "user.id", the whole value if empty. See ParseRefPath
+}
+
+// IndexInfo is a struct representing a secondary index and its size.
+type IndexInfo struct {
+	IndexDefinition
+	Entries int `json:"entries"` // amount of entries of the bucket that have the field
+}
+
+// IndexLookup is a struct representing the entries found by looking up a value in a secondary index.
+type IndexLookup struct {
+	Index     string  `json:"index"`     // name of the index
+	Bucket    string  `json:"bucket"`    // path of the bucket the entries belong to
+	Entries   []Entry `json:"entries"`   // entries whose field has the value, in key order
+	Truncated bool    `json:"truncated"` // more entries have the value, the lookup stopped once limit entries were found
+}
+
+// indexEntryKey returns the key of the entry of an index that maps the indexed value to primaryKey.
+func indexEntryKey(indexedValue []byte, primaryKey []byte) []byte {
+	entryKey := make([]byte, 2, 2+len(indexedValue)+len(primaryKey))
+	binary.BigEndian.PutUint16(entryKey, uint16(len(indexedValue)))
+	entryKey = append(entryKey, indexedValue...)
+	return append(entryKey, primaryKey...)
+}
+
+// indexedValue returns the value of the field of definition in valueBytes, and whether valueBytes has it and it is short enough to be indexed.
+func indexedValue(definition IndexDefinition, valueBytes []byte) ([]byte, bool) {
+	if valueBytes == nil {
+		return nil, false
+	}
+	jsonPath, err := ParseRefPath(definition.Field)
+	if err != nil {
+		return nil, false
+	}
+	fieldValue, found := refKey(valueBytes, jsonPath)
+	return fieldValue, found && len(fieldValue) <= 0xFFFF
+}
+
+// readIndexDefinition returns the definition stored in the bucket of an index.
+func readIndexDefinition(indexBucket *bolt.Bucket) (IndexDefinition, error) {
+	var definition IndexDefinition
+	err := json.Unmarshal(indexBucket.Get([]byte("definition")), &definition)
+	if err != nil {
+		return definition, fmt.Errorf("Definition of index is corrupt: %v\n", err)
+	}
+	return definition, nil
+}
+
+// updateIndexes moves the entries of the indexes of the bucket bucketName for keyBytes from what oldValue has to what newValue has. A nil value means the key did not exist before or does not exist anymore.
+// It has to be called in the same read-write transaction that writes the key, Journal does for every writer.
+func updateIndexes(tx *bolt.Tx, bucketName string, keyBytes []byte, oldValue []byte, newValue []byte) error {
+	if bucketName == IndexBucket {
+		return fmt.Errorf("Bucket %v holds the indexes and cannot be written\n", IndexBucket)
+	}
+	indexesBucket := tx.Bucket([]byte(IndexBucket))
+	if indexesBucket == nil {
+		return nil
+	}
+	return indexesBucket.ForEach(func(name []byte, v []byte) error {
+		if v != nil {
+			return nil
+		}
+		indexBucket := indexesBucket.Bucket(name)
+		definition, err := readIndexDefinition(indexBucket)
+		if err != nil || definition.Bucket != bucketName {
+			return err
+		}
+		entriesBucket := indexBucket.Bucket([]byte("entries"))
+		oldIndexed, hadOld := indexedValue(definition, oldValue)
+		newIndexed, hasNew := indexedValue(definition, newValue)
+		if hadOld && hasNew && bytes.Equal(oldIndexed, newIndexed) {
+			return nil
+		}
+		if hadOld {
+			err = entriesBucket.Delete(indexEntryKey(oldIndexed, keyBytes))
+			if err != nil {
+				return err
+			}
+		}
+		if hasNew {
+			return entriesBucket.Put(indexEntryKey(newIndexed, keyBytes), []byte{})
+		}
+		return nil
+	})
+}
+
+// renameIndexedBucket points the indexes of the bucket bucketPath and of the buckets nested in it to their new path below toPath. Their entries stay valid, renaming does not change keys.
+func renameIndexedBucket(tx *bolt.Tx, bucketPath string, toPath string) error {
+	indexesBucket := tx.Bucket([]byte(IndexBucket))
+	if indexesBucket == nil {
+		return nil
+	}
+	return indexesBucket.ForEach(func(name []byte, v []byte) error {
+		if v != nil {
+			return nil
+		}
+		indexBucket := indexesBucket.Bucket(name)
+		definition, err := readIndexDefinition(indexBucket)
+		if err != nil {
+			return err
+		}
+		nestedPath, nested := strings.CutPrefix(definition.Bucket, bucketPath+"/")
+		switch {
+		case definition.Bucket == bucketPath:
+			definition.Bucket = toPath
+		case nested:
+			definition.Bucket = toPath + "/" + nestedPath
+		default:
+			return nil
+		}
+		definitionJson, err := json.Marshal(definition)
+		if err != nil {
+			return err
+		}
+		return indexBucket.Put([]byte("definition"), definitionJson)
+	})
+}
+
+// BuildIndex takes the path to a bbolt database and creates the index definition within one transaction by reading every entry of its bucket, and returns its IndexInfo along with an error.
+// It fails with ErrBucketNotFound if the bucket does not exist. An index with the same name is built again from scratch, so this also repairs indexes of writes that did not go through the package or Journal. From then on every such write keeps the index up to date.
+func BuildIndex(dbPath string, definition IndexDefinition) (IndexInfo, error) {
+	if definition.Name == "" || definition.Bucket == "" {
+		return IndexInfo{}, fmt.Errorf("An index needs a name and a bucket\n")
+	}
+	if IsMetaBucket(definition.Bucket) {
+		return IndexInfo{}, fmt.Errorf("Bucket %v holds metadata and cannot be indexed\n", definition.Bucket)
+	}
+	if _, err := ParseRefPath(definition.Field); err != nil {
+		return IndexInfo{}, err
+	}
+	definitionJson, err := json.Marshal(definition)
+	if err != nil {
+		return IndexInfo{}, err
+	}
+
+	// open database
+	dbInstance, closeDb, err := OpenDbForWriting(dbPath)
+	if err != nil {
+		return IndexInfo{}, err
+	}
+	defer closeDb()
+
+	indexInfo := IndexInfo{IndexDefinition: definition}
+	err = dbInstance.Update(func(tx *bolt.Tx) error {
+		b := ResolveBucket(tx, definition.Bucket)
+		if b == nil {
+			return ErrBucketNotFound
+		}
+		indexesBucket, err := tx.CreateBucketIfNotExists([]byte(IndexBucket))
+		if err != nil {
+			return fmt.Errorf("Failed to create bucket %v: %v\n", IndexBucket, err)
+		}
+		if indexesBucket.Bucket([]byte(definition.Name)) != nil {
+			err = indexesBucket.DeleteBucket([]byte(definition.Name))
+			if err != nil {
+				return err
+			}
+		}
+		indexBucket, err := indexesBucket.CreateBucket([]byte(definition.Name))
+		if err != nil {
+			return err
+		}
+		err = indexBucket.Put([]byte("definition"), definitionJson)
+		if err != nil {
+			return err
+		}
+		entriesBucket, err := indexBucket.CreateBucket([]byte("entries"))
+		if err != nil {
+			return err
+		}
+
+		checker := newExpiryChecker(tx)
+		// creating the index may have touched the parent of the bucket, so look it up again
+		return ResolveBucket(tx, definition.Bucket).ForEach(func(keyBytes []byte, valueBytes []byte) error {
+			if valueBytes == nil || checker.isExpired([]byte(definition.Bucket), keyBytes) {
+				return nil
+			}
+			fieldValue, found := indexedValue(definition, valueBytes)
+			if !found {
+				return nil
+			}
+			indexInfo.Entries++
+			return entriesBucket.Put(indexEntryKey(fieldValue, keyBytes), []byte{})
+		})
+	})
+	if errors.Is(err, ErrBucketNotFound) {
+		return IndexInfo{}, err
+	}
+	if err != nil {
+		return IndexInfo{}, fmt.Errorf("Failed to build index %v due to error: %v\n", definition.Name, err)
+	}
+	return indexInfo, nil
+}
+
+// DropIndex removes the index name from the database at dbPath. It fails with ErrIndexNotFound if there is no such index.
+func DropIndex(dbPath string, name string) error {
+	// open database
+	dbInstance, closeDb, err := OpenDbForWriting(dbPath)
+	if err != nil {
+		return err
+	}
+	defer closeDb()
+
+	return dbInstance.Update(func(tx *bolt.Tx) error {
+		indexesBucket := tx.Bucket([]byte(IndexBucket))
+		if indexesBucket == nil || indexesBucket.Bucket([]byte(name)) == nil {
+			return ErrIndexNotFound
+		}
+		return indexesBucket.DeleteBucket([]byte(name))
+	})
+}
+
+// ListIndexes takes the path to a bbolt database and returns the IndexInfo of all its indexes by name along with an error.
+func ListIndexes(dbPath string) ([]IndexInfo, error) {
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeDb()
+
+	indexInfos := []IndexInfo{}
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		indexesBucket := tx.Bucket([]byte(IndexBucket))
+		if indexesBucket == nil {
+			return nil
+		}
+		return indexesBucket.ForEach(func(name []byte, v []byte) error {
+			if v != nil {
+				return nil
+			}
+			indexBucket := indexesBucket.Bucket(name)
+			definition, err := readIndexDefinition(indexBucket)
+			if err != nil {
+				return err
+			}
+			indexInfos = append(indexInfos, IndexInfo{IndexDefinition: definition, Entries: indexBucket.Bucket([]byte("entries")).Stats().KeyN})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list indexes due to error: %v\n", err)
+	}
+	return indexInfos, nil
+}
+
+// LookupIndex takes the path to a bbolt database and returns up to limit entries of the bucket of the index name whose indexed field has the value value as an IndexLookup along with an error.
+// A JSON string field has the value of its content, a JSON number field the value of its JSON text like "42". The index and the entries are read in one read transaction, expired entries and entries whose field changed without the index knowing are left out.
+// values is one of the Values modes and tells what to return for each value, keys one of the Keys modes. LookupIndex fails with ErrIndexNotFound if there is no such index.
+func LookupIndex(dbPath string, name string, value []byte, limit int, values string, keys string) (IndexLookup, error) {
+	if len(value) > 0xFFFF {
+		return IndexLookup{Index: name, Entries: []Entry{}}, nil // too long to be indexed, so no entry has it
+	}
+
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return IndexLookup{}, err
+	}
+	defer closeDb()
+
+	indexLookup := IndexLookup{Index: name, Entries: []Entry{}}
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		indexesBucket := tx.Bucket([]byte(IndexBucket))
+		if indexesBucket == nil || indexesBucket.Bucket([]byte(name)) == nil {
+			return ErrIndexNotFound
+		}
+		indexBucket := indexesBucket.Bucket([]byte(name))
+		definition, err := readIndexDefinition(indexBucket)
+		if err != nil {
+			return err
+		}
+		indexLookup.Bucket = definition.Bucket
+		b := ResolveBucket(tx, definition.Bucket)
+		if b == nil {
+			return nil // the bucket was deleted, so nothing has the value anymore
+		}
+		checker := newExpiryChecker(tx)
+
+		prefix := indexEntryKey(value, nil)
+		c := indexBucket.Bucket([]byte("entries")).Cursor()
+		for entryKey, _ := c.Seek(prefix); entryKey != nil && bytes.HasPrefix(entryKey, prefix); entryKey, _ = c.Next() {
+			primaryKey := entryKey[len(prefix):]
+			valueBytes := b.Get(primaryKey)
+			if valueBytes == nil || checker.isExpired([]byte(definition.Bucket), primaryKey) {
+				continue
+			}
+			// writes that did not go through Journal, like those of the TTL sweeper, leave stale entries behind
+			if fieldValue, found := indexedValue(definition, valueBytes); !found || !bytes.Equal(fieldValue, value) {
+				continue
+			}
+			if len(indexLookup.Entries) == limit {
+				indexLookup.Truncated = true
+				return nil
+			}
+			indexLookup.Entries = append(indexLookup.Entries, NewEntry(primaryKey, valueBytes, values, keys))
+		}
+		return nil
+	})
+	if errors.Is(err, ErrIndexNotFound) {
+		return IndexLookup{}, err
+	}
+	if err != nil {
+		return IndexLookup{}, fmt.Errorf("Failed to look up %x in index %v due to error: %v\n", value, name, err)
+	}
+	return indexLookup, nil
+}
@@ -0,0 +1,184 @@
+package bboltdump
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"time"
+)
+
+// MetaPage is a struct representing one of the two meta pages at the start of a bbolt file, bolt writes them in turns.
+type MetaPage struct {
+	Page     int    `json:"page"`     // 0 or 1
+	Valid    bool   `json:"valid"`    // magic number and checksum are correct, the other fields are zero otherwise
+	Version  uint32 `json:"version"`  // format version of the file
+	PageSize uint32 `json:"pageSize"` // size of a page in bytes
+	Root     uint64 `json:"root"`     // page of the root bucket
+	Freelist uint64 `json:"freelist"` // page of the freelist, the highest uint64 if the freelist is not synced to the file
+	Pages    uint64 `json:"pages"`    // high water mark, amount of pages the file holds data in
+	TxId     uint64 `json:"txId"`     // id of the transaction that wrote the meta page
+}
+
+// DbFileInfo is a struct representing the file level information about a database that bbolt info reports, without any of its buckets.
+type DbFileInfo struct {
+	Size          int64      `json:"size"`          // size of the file in bytes, bolt grows it ahead of the data
+	ModifiedAt    time.Time  `json:"modifiedAt"`    // last modification of the file
+	PageSize      uint32     `json:"pageSize"`      // size of a page in bytes, taken from the current meta page
+	Version       uint32     `json:"version"`       // format version of the file, taken from the current meta page
+	TxId          uint64     `json:"txId"`          // id of the last committed transaction
+	Metas         []MetaPage `json:"metas"`         // both meta pages, the valid one with the higher txId is the current one
+	FreePages     int        `json:"freePages"`     // pages on the freelist that can be reused
+	FreelistPages int        `json:"freelistPages"` // pages the freelist itself takes up in the file, 0 if it is not synced to the file
+}
+
+// GetDbFileInfoAsJson is like GetDbFileInfo but returns the info as a serialized JSON object of DbFileInfo.
+func GetDbFileInfoAsJson(dbPath string) ([]byte, error) {
+	dbFileInfo, err := GetDbFileInfo(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// serialize dbFileInfo to json
+	dbFileInfoJson, err := json.Marshal(dbFileInfo)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to serialize object to json: %v\n", err)
+	}
+
+	return dbFileInfoJson, nil
+}
+
+// GetDbFileInfo takes the path to a bbolt database and returns the size, modification time and meta pages of its file along with the size of its freelist, without reading any bucket.
+func GetDbFileInfo(dbPath string) (DbFileInfo, error) {
+	// open database, the freelist is only known to bolt
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return DbFileInfo{}, err
+	}
+	defer closeDb()
+
+	// the path of the opened file, gzipped and remote databases are read from a local copy
+	fileInfo, err := os.Stat(dbInstance.Path())
+	if err != nil {
+		return DbFileInfo{}, fmt.Errorf("Failed to read database file info: %v\n", err)
+	}
+	metaPages, err := ReadMetaPages(dbInstance.Path())
+	if err != nil {
+		return DbFileInfo{}, err
+	}
+
+	current := currentMetaPage(metaPages)
+	freelist, err := readFreelistPage(dbInstance.Path(), *current)
+	if err != nil {
+		return DbFileInfo{}, err
+	}
+	return DbFileInfo{
+		Size:          fileInfo.Size(),
+		ModifiedAt:    fileInfo.ModTime().UTC(),
+		PageSize:      current.PageSize,
+		Version:       current.Version,
+		TxId:          current.TxId,
+		Metas:         metaPages,
+		FreePages:     dbInstance.Stats().FreePageN, // loaded from the file when it was opened
+		FreelistPages: freelist.pages,
+	}, nil
+}
+
+// freelistPage is a struct representing the header of the freelist page a meta page points to.
+type freelistPage struct {
+	pages int // the page itself plus its overflow pages
+	ids   int // amount of page ids on the freelist, including the ones that were pending when it was written
+}
+
+// bytes returns how many bytes of its pages the freelist uses.
+func (f freelistPage) bytes() int {
+	if f.pages == 0 {
+		return 0
+	}
+	ids := f.ids
+	if ids >= 0xFFFF {
+		ids++ // the first id holds the amount, it does not fit the count of the header
+	}
+	return 16 + 8*ids
+}
+
+// readFreelistPage returns the header of the freelist the meta page refers to, bolt keeps the freelist in the file unless NoFreelistSync is set.
+func readFreelistPage(dbPath string, meta MetaPage) (freelistPage, error) {
+	if meta.Freelist == ^uint64(0) {
+		return freelistPage{}, nil // NoFreelistSync, bolt rebuilds the freelist when opening the file
+	}
+	dbFile, err := os.Open(dbPath)
+	if err != nil {
+		return freelistPage{}, fmt.Errorf("Failed to open database file: %v\n", err)
+	}
+	defer dbFile.Close()
+
+	header := make([]byte, 24) // id, flags, count and overflow of the page, followed by the first id
+	_, err = dbFile.ReadAt(header, int64(meta.Freelist)*int64(meta.PageSize))
+	if err != nil {
+		return freelistPage{}, fmt.Errorf("Failed to read freelist page: %v\n", err)
+	}
+	ids := int(binary.LittleEndian.Uint16(header[10:12]))
+	if ids == 0xFFFF {
+		ids = int(binary.LittleEndian.Uint64(header[16:24]))
+	}
+	return freelistPage{pages: 1 + int(binary.LittleEndian.Uint32(header[12:16])), ids: ids}, nil
+}
+
+// ReadMetaPages returns both meta pages of the database at dbPath by reading them from the file, so unlike opening it with bolt it never waits for the file lock.
+func ReadMetaPages(dbPath string) ([]MetaPage, error) {
+	dbFile, err := os.Open(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open database file: %v\n", err)
+	}
+	defer dbFile.Close()
+
+	metaPages := make([]MetaPage, 0, 2)
+	pageSize := int64(0)
+	for metaPage := int64(0); metaPage < 2; metaPage++ {
+		meta := make([]byte, 80) // 16 byte page header followed by the 64 byte meta
+		_, err = dbFile.ReadAt(meta, metaPage*pageSize)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("Failed to read meta page: %v\n", err)
+		}
+		checksum := fnv.New64a()
+		checksum.Write(meta[16:72])
+		if binary.LittleEndian.Uint32(meta[16:20]) != 0xED0CDAED || checksum.Sum64() != binary.LittleEndian.Uint64(meta[72:80]) {
+			if pageSize == 0 {
+				pageSize = int64(os.Getpagesize()) // bolt falls back to the OS page size if the first meta page is corrupted
+			}
+			metaPages = append(metaPages, MetaPage{Page: int(metaPage)})
+			continue
+		}
+		if pageSize == 0 {
+			pageSize = int64(binary.LittleEndian.Uint32(meta[24:28]))
+		}
+		metaPages = append(metaPages, MetaPage{
+			Page:     int(metaPage),
+			Valid:    true,
+			Version:  binary.LittleEndian.Uint32(meta[20:24]),
+			PageSize: binary.LittleEndian.Uint32(meta[24:28]),
+			Root:     binary.LittleEndian.Uint64(meta[32:40]),
+			Freelist: binary.LittleEndian.Uint64(meta[48:56]),
+			Pages:    binary.LittleEndian.Uint64(meta[56:64]),
+			TxId:     binary.LittleEndian.Uint64(meta[64:72]),
+		})
+	}
+	if currentMetaPage(metaPages) == nil {
+		return nil, fmt.Errorf("%v is not a bbolt database\n", dbPath)
+	}
+	return metaPages, nil
+}
+
+// currentMetaPage returns the meta page bolt uses, the valid one with the higher txid, or nil if neither is valid.
+func currentMetaPage(metaPages []MetaPage) *MetaPage {
+	var current *MetaPage
+	for i := range metaPages {
+		if metaPages[i].Valid && (current == nil || metaPages[i].TxId > current.TxId) {
+			current = &metaPages[i]
+		}
+	}
+	return current
+}
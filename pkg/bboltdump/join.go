@@ -0,0 +1,124 @@
+package bboltdump
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// JoinedEntry is a struct representing an entry of the bucket a join walks together with the entry its value refers to.
+type JoinedEntry struct {
+	Entry
+	Ref    string `json:"ref,omitempty"` // key the entry refers to rendered like the keys of the entries, empty if its value has no reference
+	Joined *Entry `json:"joined"`        // entry of the joined bucket under Ref, nil if there is none
+}
+
+// JoinPage is a struct representing one page of entries of a bucket joined with the entries they refer to in another bucket.
+type JoinPage struct {
+	Bucket     string        `json:"bucket"`               // name of the bucket the entries belong to
+	JoinBucket string        `json:"joinBucket"`           // name of the bucket the references are looked up in
+	Entries    []JoinedEntry `json:"entries"`              // entries of this page in key order
+	NextCursor string        `json:"nextCursor,omitempty"` // opaque token to request the next page, empty if there are no more entries
+}
+
+// ParseRefPath splits the path of the JSON field that holds a reference, like "user.id" or "$.user.id", into the names of the nested objects and the field.
+// An empty refPath means the whole value is the referenced key and returns nil.
+func ParseRefPath(refPath string) ([]string, error) {
+	refPath = strings.TrimPrefix(strings.TrimPrefix(refPath, "$"), ".")
+	if refPath == "" {
+		return nil, nil
+	}
+	names := strings.Split(refPath, ".")
+	for _, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("Invalid reference path %q\n", refPath)
+		}
+	}
+	return names, nil
+}
+
+// refKey returns the key valueBytes refers to, the value itself if jsonPath is empty or the JSON string or number at jsonPath, and whether there is one.
+func refKey(valueBytes []byte, jsonPath []string) ([]byte, bool) {
+	if len(jsonPath) == 0 {
+		return valueBytes, len(valueBytes) > 0
+	}
+	fieldValue, found := jsonField(valueBytes, jsonPath)
+	switch ref := fieldValue.(type) {
+	case string:
+		return []byte(ref), found && ref != ""
+	case json.Number:
+		return []byte(ref), found
+	}
+	return nil, false
+}
+
+// JoinBuckets takes the path to a bbolt database and returns up to limit entries of the bucket bucketName following the key encoded in cursorToken, each with the entry of the bucket joinBucketName its value refers to, as a JoinPage along with an error.
+// The reference is the JSON string or number at refPath (see ParseRefPath) of the value, or the whole value if refPath is empty. Both buckets are read in one read transaction, so the joined entries are consistent with the page.
+// Entries without a reference or whose reference does not exist are returned with Joined unset. values is one of the Values modes and tells what to return for each value of both buckets, keys one of the Keys modes.
+func JoinBuckets(dbPath string, bucketName string, joinBucketName string, refPath string, limit int, cursorToken string, values string, keys string) (JoinPage, error) {
+	jsonPath, err := ParseRefPath(refPath)
+	if err != nil {
+		return JoinPage{}, err
+	}
+	lastKey, err := DecodeCursor(cursorToken)
+	if err != nil {
+		return JoinPage{}, err
+	}
+
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return JoinPage{}, err
+	}
+	defer closeDb()
+
+	joinPage := JoinPage{
+		Bucket:     bucketName,
+		JoinBucket: joinBucketName,
+		Entries:    []JoinedEntry{},
+	}
+
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		b := ResolveBucket(tx, bucketName)
+		if b == nil {
+			return fmt.Errorf("Bucket %v does not exist\n", bucketName)
+		}
+		joinBucket := ResolveBucket(tx, joinBucketName)
+		if joinBucket == nil {
+			return fmt.Errorf("Bucket %v does not exist\n", joinBucketName)
+		}
+		checker := newExpiryChecker(tx)
+
+		// like fillPage, with the referenced entry looked up for every entry of the page
+		keyBytes, valueBytes, advance := seekAfter(b.Cursor(), lastKey, OrderAsc)
+		var lastKeySeen []byte
+		for ; keyBytes != nil; keyBytes, valueBytes = advance() {
+			if valueBytes == nil || checker.isExpired([]byte(bucketName), keyBytes) {
+				continue
+			}
+			if len(joinPage.Entries) == limit {
+				joinPage.NextCursor = EncodeCursor(lastKeySeen)
+				return nil
+			}
+			lastKeySeen = keyBytes
+
+			joinedEntry := JoinedEntry{Entry: NewEntry(keyBytes, valueBytes, values, keys)}
+			if ref, found := refKey(valueBytes, jsonPath); found {
+				joinedEntry.Ref = RenderKey(ref, keys)
+				joinedValue := joinBucket.Get(ref)
+				if joinedValue != nil && !checker.isExpired([]byte(joinBucketName), ref) {
+					joined := NewEntry(ref, joinedValue, values, keys)
+					joinedEntry.Joined = &joined
+				}
+			}
+			joinPage.Entries = append(joinPage.Entries, joinedEntry)
+		}
+		return nil
+	})
+	if err != nil {
+		return JoinPage{}, fmt.Errorf("Failed to join bucket %v with bucket %v due to error: %v\n", bucketName, joinBucketName, err)
+	}
+	return joinPage, nil
+}
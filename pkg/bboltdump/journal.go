@@ -0,0 +1,225 @@
+package bboltdump
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// JournalBucket is the top level bucket that holds the journal of the writes to a database it is enabled for.
+// Its keys are the big endian uint64 sequence numbers of the entries, its values the entries as JSON.
+const JournalBucket = "__journal"
+
+// JournalEntry is a struct representing one write recorded in the journal.
+// Every entry carries the hash of the entry before it, so changing or removing an entry anywhere but at the end breaks the chain that VerifyJournal checks.
+type JournalEntry struct {
+	Seq       uint64 `json:"seq"`                // position in the journal, starting at 1
+	Time      string `json:"time"`               // RFC 3339 time of the write in UTC
+	Actor     string `json:"actor"`              // who wrote, like the identity of the audit log
+	Operation string `json:"operation"`          // ChangePut, ChangeDelete or ChangeRenameBucket
+	Bucket    string `json:"bucket"`             // path of the bucket
+	Key       string `json:"key,omitempty"`      // hex encoded key whatever keys mode the write was made with, since the hash chain covers it. Empty for ChangeRenameBucket
+	ToBucket  string `json:"toBucket,omitempty"` // new path of a renamed bucket
+	OldHash   string `json:"oldHash,omitempty"`  // hex encoded SHA-256 of the value before the write, empty if the key did not exist
+	NewHash   string `json:"newHash,omitempty"`  // hex encoded SHA-256 of the value after the write, empty if the key was deleted
+	Prev      string `json:"prev"`               // hash of the entry before, empty for the first one
+	Hash      string `json:"hash"`               // hex encoded SHA-256 of prev followed by the entry as JSON without its hash
+}
+
+// JournalPage is a struct representing a range of entries of the journal.
+type JournalPage struct {
+	Entries   []JournalEntry `json:"entries"`             // entries in the order they were written
+	NextAfter uint64         `json:"nextAfter,omitempty"` // seq to pass as after to read the next page, 0 if there are no more entries
+	Total     uint64         `json:"total"`               // amount of entries of the whole journal
+}
+
+// JournalVerification is a struct representing the outcome of checking the hash chain of a journal.
+type JournalVerification struct {
+	Entries  int    `json:"entries"`            // amount of entries checked
+	Valid    bool   `json:"valid"`              // true if every entry matches its hash and the hash of the entry before it
+	BrokenAt uint64 `json:"brokenAt,omitempty"` // seq of the first entry that does not match, 0 if the journal is valid
+	LastHash string `json:"lastHash,omitempty"` // hash of the last entry, keeping it elsewhere also reveals entries removed from the end
+}
+
+var journalsMu sync.RWMutex
+var journals = make(map[string]bool) // by absolute path of the db file
+
+// EnableJournal makes every write to the database at dbPath that goes through the package or Journal add entries to its JournalBucket within the same transaction.
+func EnableJournal(dbPath string) {
+	journalsMu.Lock()
+	defer journalsMu.Unlock()
+	journals[absolutePathOf(dbPath)] = true
+}
+
+// journalEnabled reports whether writes within tx are journaled.
+func journalEnabled(tx *bolt.Tx) bool {
+	journalsMu.RLock()
+	defer journalsMu.RUnlock()
+	return journals[absolutePathOf(tx.DB().Path())]
+}
+
+// valueHash returns the hex encoded SHA-256 of valueBytes, or an empty string if there is no value.
+func valueHash(valueBytes []byte) string {
+	if valueBytes == nil {
+		return ""
+	}
+	hash := sha256.Sum256(valueBytes)
+	return hex.EncodeToString(hash[:])
+}
+
+// entryHash returns the hash of entry as it is chained to the entry before it.
+func entryHash(entry JournalEntry) (string, error) {
+	entry.Hash = ""
+	entryJson, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(append([]byte(entry.Prev), entryJson...))
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// appendJournal adds entry to the journal of tx after filling in its position, time and hashes, if the journal is enabled for the database.
+func appendJournal(tx *bolt.Tx, entry JournalEntry) error {
+	if !journalEnabled(tx) {
+		return nil
+	}
+	if entry.Bucket == JournalBucket || entry.ToBucket == JournalBucket {
+		return fmt.Errorf("Bucket %v holds the journal and cannot be written\n", JournalBucket)
+	}
+
+	journalBucket, err := tx.CreateBucketIfNotExists([]byte(JournalBucket))
+	if err != nil {
+		return fmt.Errorf("Failed to create journal bucket: %v\n", err)
+	}
+	_, lastJson := journalBucket.Cursor().Last()
+	if lastJson != nil {
+		var last JournalEntry
+		err = json.Unmarshal(lastJson, &last)
+		if err != nil {
+			return fmt.Errorf("Failed to read last journal entry: %v\n", err)
+		}
+		entry.Prev = last.Hash
+	}
+	entry.Seq, err = journalBucket.NextSequence()
+	if err != nil {
+		return fmt.Errorf("Failed to number journal entry: %v\n", err)
+	}
+	entry.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	entry.Hash, err = entryHash(entry)
+	if err != nil {
+		return fmt.Errorf("Failed to hash journal entry: %v\n", err)
+	}
+
+	entryJson, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("Failed to serialize journal entry: %v\n", err)
+	}
+	return journalBucket.Put(binary.BigEndian.AppendUint64(nil, entry.Seq), entryJson)
+}
+
+// Journal records that actor wrote keyBytes in the bucket bucketName with operation, changing its value from oldValue to newValue, in the journal of tx. A nil value means the key did not exist before or does not exist anymore.
+// The journal is only written if it is enabled for the database, the indexes and maintained views of the bucket are always updated, and so is the Preview of a dry run. Writers of their own transactions call it for every key they write, in the same transaction.
+func Journal(tx *bolt.Tx, actor string, operation string, bucketName string, keyBytes []byte, oldValue []byte, newValue []byte) error {
+	addToPreview(tx, keyBytes, oldValue, newValue)
+	err := updateIndexes(tx, bucketName, keyBytes, oldValue, newValue)
+	if err == nil {
+		err = updateViews(tx, bucketName, keyBytes, oldValue, newValue)
+	}
+	if err != nil {
+		return err
+	}
+	return appendJournal(tx, JournalEntry{
+		Actor:     actor,
+		Operation: operation,
+		Bucket:    bucketName,
+		Key:       hex.EncodeToString(keyBytes),
+		OldHash:   valueHash(oldValue),
+		NewHash:   valueHash(newValue),
+	})
+}
+
+// ReadJournal returns at most limit entries of the journal of the database at dbPath that come after the entry with seq after. A limit of 0 means DefaultPageLimit.
+func ReadJournal(dbPath string, after uint64, limit int) (JournalPage, error) {
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	journalPage := JournalPage{Entries: []JournalEntry{}}
+
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return journalPage, err
+	}
+	defer closeDb()
+
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		journalBucket := tx.Bucket([]byte(JournalBucket))
+		if journalBucket == nil {
+			return nil
+		}
+		journalPage.Total = journalBucket.Sequence() // entries are never removed, so the last seq is their amount
+		cursor := journalBucket.Cursor()
+		for seqBytes, entryJson := cursor.Seek(binary.BigEndian.AppendUint64(nil, after+1)); seqBytes != nil; seqBytes, entryJson = cursor.Next() {
+			if len(journalPage.Entries) == limit {
+				journalPage.NextAfter = journalPage.Entries[limit-1].Seq
+				return nil
+			}
+			var entry JournalEntry
+			err := json.Unmarshal(entryJson, &entry)
+			if err != nil {
+				return fmt.Errorf("Failed to read journal entry %x: %v\n", seqBytes, err)
+			}
+			journalPage.Entries = append(journalPage.Entries, entry)
+		}
+		return nil
+	})
+	return journalPage, err
+}
+
+// VerifyJournal checks that every entry of the journal of the database at dbPath is stored under its seq, matches its hash and chains to the entry before it, and that no entry was removed from the end since the last was written.
+func VerifyJournal(dbPath string) (JournalVerification, error) {
+	verification := JournalVerification{Valid: true}
+
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return verification, err
+	}
+	defer closeDb()
+
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		journalBucket := tx.Bucket([]byte(JournalBucket))
+		if journalBucket == nil {
+			return nil
+		}
+		var last JournalEntry
+		cursor := journalBucket.Cursor()
+		for seqBytes, entryJson := cursor.First(); seqBytes != nil; seqBytes, entryJson = cursor.Next() {
+			verification.Entries++
+			var entry JournalEntry
+			err := json.Unmarshal(entryJson, &entry)
+			hash, hashErr := entryHash(entry)
+			if err != nil || hashErr != nil || len(seqBytes) != 8 || binary.BigEndian.Uint64(seqBytes) != entry.Seq ||
+				entry.Seq != last.Seq+1 || entry.Prev != last.Hash || entry.Hash != hash {
+				verification.Valid = false
+				verification.BrokenAt = last.Seq + 1
+				return nil
+			}
+			last = entry
+		}
+		verification.LastHash = last.Hash
+		// the sequence only grows, so a lower last seq means entries were removed from the end
+		if last.Seq != journalBucket.Sequence() {
+			verification.Valid = false
+			verification.BrokenAt = last.Seq + 1
+		}
+		return nil
+	})
+	return verification, err
+}
@@ -0,0 +1,88 @@
+package bboltdump
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	KeysText = "text" // return keys as KeyText renders them, readable unless they are binary, the default. Entries carry the hex encoded key too
+	KeysHex  = "hex"  // return keys hex encoded only
+	KeysTime = "time" // return keys like KeysText and also keys that are 8 byte big endian Unix timestamps as RFC 3339 times
+)
+
+// IsValidKeyMode reports whether keys is a supported way of returning keys. An empty mode defaults to KeysText.
+func IsValidKeyMode(keys string) bool {
+	return keys == "" || keys == KeysHex || keys == KeysTime || keys == KeysText
+}
+
+// keyTextHexPrefix marks a key KeyText could not return as text.
+const keyTextHexPrefix = "0x"
+
+// KeyText returns keyBytes as it is if it is printable UTF-8 like "settings" or "user:42", otherwise "0x" followed by its hex encoding.
+// Printable keys that start with "0x" are hex encoded as well, so ParseKeyText always gets the same bytes back.
+func KeyText(keyBytes []byte) string {
+	if !utf8.Valid(keyBytes) || strings.HasPrefix(string(keyBytes), keyTextHexPrefix) || strings.IndexFunc(string(keyBytes), func(r rune) bool { return !unicode.IsPrint(r) }) >= 0 {
+		return keyTextHexPrefix + hex.EncodeToString(keyBytes)
+	}
+	return string(keyBytes)
+}
+
+// ParseKeyText returns the key keyText stands for, it is the inverse of KeyText.
+func ParseKeyText(keyText string) ([]byte, error) {
+	hexKey, found := strings.CutPrefix(keyText, keyTextHexPrefix)
+	if !found {
+		return []byte(keyText), nil
+	}
+	keyBytes, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("Key %q starts with 0x but is not hex encoded\n", keyText)
+	}
+	return keyBytes, nil
+}
+
+// RenderKey returns keyBytes in the form the keys mode keys asks for, hex encoded for KeysHex and as KeyText renders it otherwise. Every key the package returns is rendered by it.
+func RenderKey(keyBytes []byte, keys string) string {
+	if keys == KeysHex {
+		return hex.EncodeToString(keyBytes)
+	}
+	return KeyText(keyBytes)
+}
+
+// plausible times a timestamp key may stand for, they tell the units apart and keep small integers like sequence numbers from being taken for times in 1970
+var (
+	minKeyTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxKeyTime = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// KeyTime returns the time keyBytes stands for as an RFC 3339 string in UTC if it is an 8 byte big endian Unix timestamp in seconds, milliseconds, microseconds or nanoseconds between 2000 and 2100, otherwise "".
+// The ranges of the units do not overlap within these years, so the unit is told by the magnitude of the key.
+func KeyTime(keyBytes []byte) string {
+	if len(keyBytes) != 8 {
+		return ""
+	}
+	timestamp := binary.BigEndian.Uint64(keyBytes)
+	for _, unit := range []time.Duration{time.Second, time.Millisecond, time.Microsecond, time.Nanosecond} {
+		if timestamp >= uint64(maxKeyTime.UnixNano()/int64(unit)) {
+			continue // too late in this unit, and converting it could overflow
+		}
+		keyTime := time.Unix(0, int64(timestamp)*int64(unit)).UTC()
+		if !keyTime.Before(minKeyTime) {
+			return keyTime.Format(time.RFC3339Nano)
+		}
+	}
+	return ""
+}
+
+// describeKey returns the time keyBytes stands for in KeysTime mode, or "" if only the hex form is returned.
+func describeKey(keyBytes []byte, keys string) string {
+	if keys != KeysTime {
+		return ""
+	}
+	return KeyTime(keyBytes)
+}
@@ -0,0 +1,32 @@
+package bboltdump
+
+import "testing"
+
+func TestNewEntryKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        string
+		keys       string
+		wantKey    string
+		wantKeyHex string
+	}{
+		{"text by default", "alice", "", "alice", "616c696365"},
+		{"text", "alice", KeysText, "alice", "616c696365"},
+		{"binary as text", "\x00\x01", "", "0x0001", "0001"},
+		{"0x prefix as text", "0x1", KeysText, "0x307831", "307831"},
+		{"time renders text", "alice", KeysTime, "alice", "616c696365"},
+		{"hex", "alice", KeysHex, "616c696365", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry := NewEntry([]byte(test.key), []byte("1"), ValuesRaw, test.keys)
+			if entry.Key != test.wantKey || entry.KeyHex != test.wantKeyHex {
+				t.Errorf("Key, KeyHex = %q, %q, want %q, %q", entry.Key, entry.KeyHex, test.wantKey, test.wantKeyHex)
+			}
+			keyBytes, err := ParseKeyText(entry.Key)
+			if test.keys != KeysHex && (err != nil || string(keyBytes) != test.key) {
+				t.Errorf("ParseKeyText(%q) = %q, %v, want %q", entry.Key, keyBytes, err, test.key)
+			}
+		})
+	}
+}
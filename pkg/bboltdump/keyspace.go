@@ -0,0 +1,178 @@
+package bboltdump
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// defaults and limits of GetKeyspaceTree
+const (
+	DefaultKeyspaceSeparator   = ":" // splits keys into the segments of the tree if the caller does not say otherwise
+	DefaultKeyspaceDepth       = 3   // levels of key segments below a bucket if the caller does not say otherwise
+	MaxKeyspaceDepth           = 16
+	DefaultKeyspaceMaxChildren = 50 // children per node if the caller does not say otherwise
+	MaxKeyspaceMaxChildren     = 1000
+
+	maxKeyspacePrefixes = 100000 // prefix nodes of a whole tree, further segments are folded like those beyond MaxChildren so a tree of unique keys stays small
+)
+
+// kinds of KeyspaceNode
+const (
+	KeyspaceDb     = "db"
+	KeyspaceBucket = "bucket"
+	KeyspacePrefix = "prefix" // keys sharing the segments up to and including Name
+	KeyspaceOther  = "other"  // segments that did not fit under MaxChildren anymore, folded into one node
+)
+
+// KeyspaceNode is a struct representing a bucket or a key prefix of a keyspace tree along with the amount and size of the keys below it, like a directory of a disk usage treemap.
+type KeyspaceNode struct {
+	Name       string          `json:"name"`               // name of the bucket or the key segment, empty for the database
+	Kind       string          `json:"kind"`               // KeyspaceDb, KeyspaceBucket, KeyspacePrefix or KeyspaceOther
+	Keys       int64           `json:"keys"`               // values below the node, including those of nested buckets and longer prefixes
+	KeyBytes   int64           `json:"keyBytes"`           // bytes of their keys
+	ValueBytes int64           `json:"valueBytes"`         // bytes of their values
+	Children   []*KeyspaceNode `json:"children,omitempty"` // nested buckets and the next key segments, the largest first
+
+	byName map[string]*KeyspaceNode // children by kind and name while the tree is built
+	other  *KeyspaceNode            // child of kind KeyspaceOther, nil until a node has too many children
+}
+
+// KeyspaceOptions is a struct representing how GetKeyspaceTree aggregates the keys.
+type KeyspaceOptions struct {
+	Separator   string // splits keys into segments, DefaultKeyspaceSeparator if empty
+	Depth       int    // levels of key segments below each bucket, deeper segments are counted to their prefix. 0 means DefaultKeyspaceDepth
+	MaxChildren int    // children per node, further key segments are folded into one node of kind KeyspaceOther. 0 means DefaultKeyspaceMaxChildren
+	Keys        string // one of the Keys modes, the names of the prefix nodes are the key segments as RenderKey renders them
+}
+
+// KeyspaceTree is a struct representing the keyspace of a database aggregated into a tree.
+type KeyspaceTree struct {
+	Root     *KeyspaceNode `json:"root"`
+	TimedOut bool          `json:"timedOut"` // ctx was done before all buckets were read, the tree only counts the keys read before
+}
+
+// child returns the child of n of kind named name, creating it if it does not exist. Prefixes beyond maxChildren, or once prefixesLeft of the whole tree are used up, go to the child of kind KeyspaceOther.
+func (n *KeyspaceNode) child(kind string, name string, maxChildren int, prefixesLeft *int) *KeyspaceNode {
+	if n.byName == nil {
+		n.byName = make(map[string]*KeyspaceNode)
+	}
+	if child, found := n.byName[kind+"/"+name]; found {
+		return child
+	}
+	if kind == KeyspacePrefix && (len(n.byName) >= maxChildren || *prefixesLeft == 0) {
+		if n.other == nil {
+			n.other = &KeyspaceNode{Name: "…", Kind: KeyspaceOther}
+		}
+		return n.other
+	}
+	if kind == KeyspacePrefix {
+		*prefixesLeft--
+	}
+	child := &KeyspaceNode{Name: name, Kind: kind}
+	n.byName[kind+"/"+name] = child
+	return child
+}
+
+// add counts a key and its value to n.
+func (n *KeyspaceNode) add(keySize int, valueSize int) {
+	n.Keys++
+	n.KeyBytes += int64(keySize)
+	n.ValueBytes += int64(valueSize)
+}
+
+// finish sorts the children of n and the nodes below it by their size, largest first, with the folded prefixes last.
+// A bucket only counted its own keys so far, the keys of the buckets nested in it are added to it here, and those of all buckets to the database.
+func (n *KeyspaceNode) finish() {
+	for _, child := range n.byName {
+		child.finish()
+		if child.Kind == KeyspaceBucket {
+			n.Keys += child.Keys
+			n.KeyBytes += child.KeyBytes
+			n.ValueBytes += child.ValueBytes
+		}
+		n.Children = append(n.Children, child)
+	}
+	sort.Slice(n.Children, func(i, j int) bool {
+		sizeI, sizeJ := n.Children[i].KeyBytes+n.Children[i].ValueBytes, n.Children[j].KeyBytes+n.Children[j].ValueBytes
+		if sizeI != sizeJ {
+			return sizeI > sizeJ
+		}
+		return n.Children[i].Name < n.Children[j].Name
+	})
+	if n.other != nil {
+		n.other.finish()
+		n.Children = append(n.Children, n.other)
+	}
+	n.byName = nil
+}
+
+// GetKeyspaceTree takes the path to a bbolt database and returns the keyspace of the bucket bucketName, or of all buckets if bucketName is empty, as a tree of its buckets and key prefixes with their key counts and byte totals.
+// Every bucket is read once and only sizes are kept, no values. Once ctx is done the tree is returned as far as it got with TimedOut set.
+func GetKeyspaceTree(ctx context.Context, dbPath string, bucketName string, options KeyspaceOptions) (KeyspaceTree, error) {
+	if options.Separator == "" {
+		options.Separator = DefaultKeyspaceSeparator
+	}
+	if options.Depth == 0 {
+		options.Depth = DefaultKeyspaceDepth
+	}
+	if options.MaxChildren == 0 {
+		options.MaxChildren = DefaultKeyspaceMaxChildren
+	}
+
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return KeyspaceTree{}, err
+	}
+	defer closeDb()
+
+	tree := KeyspaceTree{Root: &KeyspaceNode{Kind: KeyspaceDb}}
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		checker := newExpiryChecker(tx)
+		bucketNodes := make(map[string]*KeyspaceNode) // of the nested buckets not visited yet, by path
+		prefixesLeft := maxKeyspacePrefixes
+		return forEachBucketPath(tx, bucketName, func(b *bolt.Bucket, path string) []string {
+			bucketNode, found := bucketNodes[path]
+			if !found {
+				bucketNode = tree.Root.child(KeyspaceBucket, path, options.MaxChildren, &prefixesLeft) // a top level bucket or the bucket asked for
+			}
+			delete(bucketNodes, path)
+
+			// only the keys of the bucket itself are counted here, finish adds those of its nested buckets
+			nestedBuckets := []string{}
+			cursor := b.Cursor()
+			for keyBytes, valueBytes := cursor.First(); keyBytes != nil; keyBytes, valueBytes = cursor.Next() {
+				if ctx.Err() != nil {
+					tree.TimedOut = true
+					return nil
+				}
+				if valueBytes == nil {
+					nestedBuckets = append(nestedBuckets, string(keyBytes))
+					bucketNodes[path+"/"+string(keyBytes)] = bucketNode.child(KeyspaceBucket, string(keyBytes), options.MaxChildren, &prefixesLeft)
+					continue
+				}
+				if checker.isExpired([]byte(path), keyBytes) {
+					continue
+				}
+				bucketNode.add(len(keyBytes), len(valueBytes))
+
+				// the last segment is the rest of the key, keys are not listed one by one
+				node := bucketNode
+				segments := bytes.SplitN(keyBytes, []byte(options.Separator), options.Depth+1)
+				for _, segment := range segments[:len(segments)-1] {
+					node = node.child(KeyspacePrefix, RenderKey(segment, options.Keys), options.MaxChildren, &prefixesLeft)
+					node.add(len(keyBytes), len(valueBytes))
+				}
+			}
+			return nestedBuckets
+		})
+	})
+	if err != nil {
+		return KeyspaceTree{}, err
+	}
+	tree.Root.finish()
+	return tree, nil
+}
@@ -0,0 +1,77 @@
+package bboltdump
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// LargeValue is a struct representing where a large value is stored, without the value itself.
+type LargeValue struct {
+	Bucket string `json:"bucket"` // name or path of the bucket
+	Key    string `json:"key"`    // key as RenderKey renders it
+	Size   int    `json:"size"`   // length of the value in bytes
+}
+
+// largeValueHeap is a min-heap of LargeValue by size, its root is the smallest of the largest values seen so far.
+type largeValueHeap []LargeValue
+
+func (h largeValueHeap) Len() int           { return len(h) }
+func (h largeValueHeap) Less(i, j int) bool { return h[i].Size < h[j].Size }
+func (h largeValueHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *largeValueHeap) Push(x any)        { *h = append(*h, x.(LargeValue)) }
+func (h *largeValueHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// GetLargestValues takes the path to a bbolt database and returns the count largest values of the bucket bucketName and the buckets nested in it, or of all buckets if bucketName is empty, largest first along with an error.
+// Only the sizes are looked at and only count keys are held in memory, so this finds the storage hogs of a database of any size. keys is one of the Keys modes.
+func GetLargestValues(dbPath string, bucketName string, count int, keys string) ([]LargeValue, error) {
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeDb()
+
+	largest := make(largeValueHeap, 0, count)
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		checker := newExpiryChecker(tx)
+		return forEachBucketPath(tx, bucketName, func(b *bolt.Bucket, path string) []string {
+			nestedBuckets := []string{}
+			cursor := b.Cursor()
+			for keyBytes, valueBytes := cursor.First(); keyBytes != nil; keyBytes, valueBytes = cursor.Next() {
+				if valueBytes == nil {
+					nestedBuckets = append(nestedBuckets, string(keyBytes))
+					continue
+				}
+				// most values are smaller than all values kept, they are skipped before the key is rendered
+				if len(largest) == count && len(valueBytes) <= largest[0].Size || checker.isExpired([]byte(path), keyBytes) {
+					continue
+				}
+				largeValue := LargeValue{Bucket: path, Key: RenderKey(keyBytes, keys), Size: len(valueBytes)}
+				if len(largest) == count {
+					largest[0] = largeValue
+					heap.Fix(&largest, 0)
+				} else {
+					heap.Push(&largest, largeValue)
+				}
+			}
+			return nestedBuckets
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find largest values of database due to error: %v\n", err)
+	}
+
+	largestValues := []LargeValue(largest)
+	sort.SliceStable(largestValues, func(i, j int) bool {
+		return largestValues[i].Size > largestValues[j].Size
+	})
+	return largestValues, nil
+}
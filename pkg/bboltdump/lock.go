@@ -0,0 +1,101 @@
+package bboltdump
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// LockPolicy is a struct representing how long opening a database waits for its file lock, which bolt holds for as long as a database is open in another process or handle.
+type LockPolicy struct {
+	Timeout time.Duration // how long one attempt waits for the lock, zero waits forever
+	Retries int           // attempts after the first one timed out
+	Backoff time.Duration // pause before the first retry, doubled before every further retry
+}
+
+// DefaultLockPolicy is the LockPolicy until SetLockPolicy is called, it gives up after about 7.5s.
+var DefaultLockPolicy = LockPolicy{Timeout: time.Second, Retries: 3, Backoff: 500 * time.Millisecond}
+
+var lockPolicy = DefaultLockPolicy
+
+// SetLockPolicy makes OpenDb and OpenDbForWriting wait for locked databases as policy says. It must be called before the first database is opened.
+func SetLockPolicy(policy LockPolicy) {
+	lockPolicy = policy
+}
+
+// LockedError is the error of OpenDb and OpenDbForWriting if the database was still locked after all attempts of the LockPolicy.
+type LockedError struct {
+	Path    string        // path to db file
+	Waited  time.Duration // time spent waiting for the lock
+	Holders []string      // who likely holds the lock, like "pid 4242 (myapp)" or "export.ToSqlite in this process since 2s", empty if that cannot be told
+}
+
+func (e *LockedError) Error() string {
+	message := fmt.Sprintf("Database %v is locked, gave up after waiting %v", e.Path, e.Waited.Round(time.Millisecond))
+	if len(e.Holders) > 0 {
+		message += ", likely held by " + strings.Join(e.Holders, ", ")
+	}
+	return message + "\n"
+}
+
+// openWithLockPolicy opens the database at dbPath like bolt.Open with its OpenOptions, but retries while the file is locked and returns a LockedError once the LockPolicy gives up.
+func openWithLockPolicy(dbPath string, mode os.FileMode) (*bolt.DB, error) {
+	options := boltOptionsFor(dbPath)
+	options.Timeout = lockPolicy.Timeout
+	options.ReadOnly = readOnly
+	backoff := lockPolicy.Backoff
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		dbInstance, err := bolt.Open(dbPath, mode, &options)
+		if !errors.Is(err, bolt.ErrTimeout) {
+			return dbInstance, err
+		}
+		if attempt == lockPolicy.Retries {
+			return nil, &LockedError{Path: dbPath, Waited: time.Since(start)}
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// lockHolders returns who likely holds the file lock of dbPath: the callers of this process that have it open and, where the system tells, other processes.
+func lockHolders(dbPath string) []string {
+	absolutePath, err := filepath.Abs(dbPath)
+	if err != nil {
+		absolutePath = dbPath
+	}
+
+	lockHolders := []string{}
+	for _, openDbInfo := range OpenDatabases() {
+		if openDbInfo.Path != absolutePath && openDbInfo.Path != dbPath {
+			continue
+		}
+		if openDbInfo.Cached {
+			lockHolders = append(lockHolders, "the handle cache of this process")
+		}
+		heldHere := openDbInfo.Cached
+		for _, holderInfo := range openDbInfo.Holders {
+			if !holderInfo.Waiting {
+				heldHere = true
+				lockHolders = append(lockHolders, fmt.Sprintf("%v in this process since %v", holderInfo.Holder, holderInfo.Age))
+			}
+		}
+		if !heldHere {
+			continue
+		}
+		// the requests those holders serve, the request of the caller is among them since a request is not tied to the holders it opens
+		for _, requestInfo := range openDbInfo.Requests {
+			if requestInfo.Transaction {
+				lockHolders = append(lockHolders, fmt.Sprintf("a transaction of %v open since %v", requestInfo.Who, requestInfo.Age))
+			} else {
+				lockHolders = append(lockHolders, fmt.Sprintf("%v %v of %v running since %v", requestInfo.Method, requestInfo.Path, requestInfo.Who, requestInfo.Age))
+			}
+		}
+	}
+	return append(lockHolders, lockingProcesses(dbPath)...)
+}
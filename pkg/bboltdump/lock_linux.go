@@ -0,0 +1,46 @@
+package bboltdump
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// lockingProcesses returns the other processes that hold a lock on the file at dbPath according to /proc/locks, like "pid 4242 (myapp)".
+func lockingProcesses(dbPath string) []string {
+	fileInfo, err := os.Stat(dbPath)
+	if err != nil {
+		return nil
+	}
+	stat, ok := fileInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	locks, err := os.ReadFile("/proc/locks")
+	if err != nil {
+		return nil
+	}
+
+	// lines look like "1: FLOCK  ADVISORY  WRITE 4242 fd:01:1234567 0 EOF", waiting locks have a "->" after the number
+	var processes []string
+	inode := ":" + strconv.FormatUint(stat.Ino, 10)
+	for _, line := range strings.Split(string(locks), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 6 || fields[1] == "->" || !strings.HasSuffix(fields[5], inode) {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[4])
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		processName, err := os.ReadFile(fmt.Sprintf("/proc/%v/comm", pid))
+		if err != nil {
+			processes = append(processes, fmt.Sprintf("pid %v", pid))
+			continue
+		}
+		processes = append(processes, fmt.Sprintf("pid %v (%v)", pid, strings.TrimSpace(string(processName))))
+	}
+	return processes
+}
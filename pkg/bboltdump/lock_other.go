@@ -0,0 +1,8 @@
+//go:build !linux
+
+package bboltdump
+
+// lockingProcesses returns nothing, only Linux tells which process holds a file lock.
+func lockingProcesses(dbPath string) []string {
+	return nil
+}
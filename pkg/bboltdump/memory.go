@@ -0,0 +1,93 @@
+package bboltdump
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+)
+
+// DefaultMemoryLimit is the limit of the bytes all dumps buffer together until SetMemoryLimit is called.
+const DefaultMemoryLimit = 256 << 20
+
+// ErrMemoryLimit is returned when a dump would have to buffer more than the memory limit allows and cannot stream instead.
+var ErrMemoryLimit = errors.New("memory limit reached")
+
+// buffered counts the bytes all running dumps hold in memory besides what they stream.
+var buffered = struct {
+	mu    sync.Mutex
+	bytes int64
+	limit int64 // 0 means no limit
+}{limit: DefaultMemoryLimit}
+
+// SetMemoryLimit makes dumps stop buffering once all of them together hold limit bytes. Parallel dumps stream the buckets that do not fit anymore one after another, dumps that split keys fail with ErrMemoryLimit.
+// It is a soft limit: it bounds what dumps buffer, not the memory of the process. 0 means no limit.
+func SetMemoryLimit(limit int64) {
+	buffered.mu.Lock()
+	defer buffered.mu.Unlock()
+	buffered.limit = limit
+}
+
+// BufferedBytes returns the bytes all running dumps hold in memory.
+func BufferedBytes() int64 {
+	buffered.mu.Lock()
+	defer buffered.mu.Unlock()
+	return buffered.bytes
+}
+
+// memoryAccount is a struct representing the bytes one dump buffers, it is shared by the workers of the dump.
+type memoryAccount struct {
+	mu    sync.Mutex
+	bytes int64
+}
+
+// reserve counts n more bytes to the account, or returns ErrMemoryLimit if they do not fit under the limit anymore. A nil account reserves nothing.
+func (a *memoryAccount) reserve(n int64) error {
+	if a == nil {
+		return nil
+	}
+	buffered.mu.Lock()
+	defer buffered.mu.Unlock()
+	if buffered.limit > 0 && buffered.bytes+n > buffered.limit {
+		return ErrMemoryLimit
+	}
+	buffered.bytes += n
+	a.mu.Lock()
+	a.bytes += n
+	a.mu.Unlock()
+	return nil
+}
+
+// release gives back n bytes of the account.
+func (a *memoryAccount) release(n int64) {
+	if a == nil {
+		return
+	}
+	buffered.mu.Lock()
+	defer buffered.mu.Unlock()
+	buffered.bytes -= n
+	a.mu.Lock()
+	a.bytes -= n
+	a.mu.Unlock()
+}
+
+// close gives back everything the account still holds, once the dump is done.
+func (a *memoryAccount) close() {
+	a.mu.Lock()
+	remaining := a.bytes
+	a.mu.Unlock()
+	a.release(remaining)
+}
+
+// accountedBuffer is a bytes.Buffer whose writes are reserved in a memoryAccount first, a write that does not fit fails with ErrMemoryLimit.
+type accountedBuffer struct {
+	bytes.Buffer
+	account *memoryAccount
+}
+
+func (b *accountedBuffer) Write(p []byte) (int, error) {
+	err := b.account.reserve(int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,339 @@
+package bboltdump
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// conflict policies of Merge, for keys that exist in both databases with different values
+const (
+	MergeSkip      = "skip"      // keep the value of the target
+	MergeOverwrite = "overwrite" // replace it by the value of the source
+	MergeFail      = "fail"      // fail the merge, before anything is written if possible
+)
+
+// MergePolicies are all conflict policies of Merge.
+var MergePolicies = []string{MergeSkip, MergeOverwrite, MergeFail}
+
+const mergeBatchSize = 10000 // amount of keys written per bolt transaction, keeps single transactions from growing unbounded
+
+// MergeOptions is a struct representing what Merge merges and how it resolves conflicts.
+type MergeOptions struct {
+	Buckets        []string          // buckets of the source to merge along with their nested buckets, all buckets if empty
+	Policy         string            // conflict policy of the buckets without one in BucketPolicies, MergeSkip if empty
+	BucketPolicies map[string]string // conflict policy by bucket path, it applies to the buckets nested in the bucket too unless they have their own
+	DryRun         bool              // roll every batch back instead of committing it
+	Actor          string            // who merges, for the journal
+}
+
+// BucketMerge is a struct representing what Merge did to one bucket.
+type BucketMerge struct {
+	Bucket      string `json:"bucket"`      // path of the bucket
+	Policy      string `json:"policy"`      // conflict policy the bucket was merged with
+	Added       int    `json:"added"`       // keys that did not exist in the target
+	Overwritten int    `json:"overwritten"` // conflicting keys that got the value of the source
+	Skipped     int    `json:"skipped"`     // conflicting keys that kept the value of the target
+	Unchanged   int    `json:"unchanged"`   // keys that have the same value in both databases
+}
+
+// MergeResult is a struct representing the outcome of a merge.
+type MergeResult struct {
+	Path    string        `json:"path"`    // path to the db file that was merged into
+	Other   string        `json:"other"`   // path to the db file that was merged from
+	Buckets []BucketMerge `json:"buckets"` // every bucket of the source that was merged, by path
+	Batches int           `json:"batches"` // transactions the keys were written in
+
+	DryRun  bool     `json:"dryRun"`            // true if nothing was actually written
+	Preview *Preview `json:"preview,omitempty"` // what the dry run would have changed, only set with DryRun
+}
+
+// MergeConflictError is returned when a key of a bucket merged with MergeFail exists in both databases with different values.
+type MergeConflictError struct {
+	Bucket string // path of the bucket
+	Key    []byte // the conflicting key
+}
+
+func (e *MergeConflictError) Error() string {
+	return fmt.Sprintf("Key %x of bucket %v has different values in both databases\n", e.Key, e.Bucket)
+}
+
+// IsMetaBucket reports whether the top level bucket bucketName holds what the package keeps beside the data, like expiries or the journal.
+// Dumps, exports and merges leave them out unless they are asked for by name, expired keys are left out instead of exporting their expiries.
+func IsMetaBucket(bucketName string) bool {
+	return bucketName == TtlBucket || bucketName == JournalBucket || bucketName == IdempotencyBucket || bucketName == IndexBucket || isViewBucket(bucketName)
+}
+
+// policy returns the conflict policy of the bucket bucketPath, that of the closest parent in BucketPolicies if it has none itself.
+func (o MergeOptions) policy(bucketPath string) string {
+	path := bucketPath
+	for {
+		if policy, found := o.BucketPolicies[path]; found {
+			return policy
+		}
+		parentPath, _, nested := cutLast(path, "/")
+		if !nested {
+			break
+		}
+		path = parentPath
+	}
+	if o.Policy == "" {
+		return MergeSkip
+	}
+	return o.Policy
+}
+
+// mergeEntry is a struct representing a key of the source to write into the target, or a bucket to create if key is nil.
+type mergeEntry struct {
+	bucket    string
+	key       []byte
+	value     []byte
+	expiresAt time.Time
+}
+
+// Merge writes the keys of the database at otherPath into the database at dbPath, bucket by bucket, creating the buckets that do not exist in it yet. Expiries of the keys are merged along with them, expired keys are not merged.
+// A key that exists in both databases with different values is a conflict, which the policy of its bucket resolves. Buckets merged with MergeFail are compared first, so a conflict there fails the merge with a MergeConflictError before anything is written.
+// The keys are written in batches of mergeBatchSize per transaction, so a merge that fails later may leave the already committed batches behind. If the journal is enabled for dbPath every written key is journaled by options.Actor.
+// With options.DryRun set every batch is rolled back instead of committed and the result has the Preview of all of them.
+func Merge(dbPath string, otherPath string, options MergeOptions) (MergeResult, error) {
+	mergeResult := MergeResult{
+		Path:    dbPath,
+		Other:   otherPath,
+		Buckets: []BucketMerge{},
+		DryRun:  options.DryRun,
+	}
+	if options.DryRun {
+		mergeResult.Preview = &Preview{}
+	}
+
+	// bolt locks the file, opening the same file twice would wait forever
+	if filepath.Clean(dbPath) == filepath.Clean(otherPath) {
+		return mergeResult, fmt.Errorf("Refusing to merge database %v into itself\n", dbPath)
+	}
+	policies := []string{options.Policy}
+	for _, policy := range options.BucketPolicies {
+		policies = append(policies, policy)
+	}
+	for _, policy := range policies {
+		if policy != "" && !slices.Contains(MergePolicies, policy) {
+			return mergeResult, fmt.Errorf("Unsupported conflict policy %v, use one of %v\n", policy, MergePolicies)
+		}
+	}
+	for _, bucketName := range options.Buckets {
+		topLevelBucket, _, _ := strings.Cut(bucketName, "/")
+		if IsMetaBucket(bucketName) || IsMetaBucket(topLevelBucket) {
+			return mergeResult, fmt.Errorf("Bucket %v is kept by the package and cannot be merged\n", bucketName)
+		}
+	}
+
+	// open databases
+	otherInstance, closeOther, err := OpenDb(otherPath)
+	if err != nil {
+		return mergeResult, err
+	}
+	defer closeOther()
+	dbInstance, closeDb, err := OpenDbForWriting(dbPath)
+	if err != nil {
+		return mergeResult, err
+	}
+	defer closeDb()
+
+	bucketMerges := make(map[string]*BucketMerge)
+	bucketMergeOf := func(bucketPath string) *BucketMerge {
+		bucketMerge, found := bucketMerges[bucketPath]
+		if !found {
+			bucketMerge = &BucketMerge{Bucket: bucketPath, Policy: options.policy(bucketPath)}
+			bucketMerges[bucketPath] = bucketMerge
+		}
+		return bucketMerge
+	}
+
+	// writes the collected batch in one transaction, the counts only go into the result once it committed
+	batch := make([]mergeEntry, 0, mergeBatchSize)
+	flush := func() error {
+		counts := make(map[string]*BucketMerge)
+		preview, err := UpdateOrDryRun(dbInstance, options.DryRun, func(tx *bolt.Tx) error {
+			clear(counts)
+			checker := newExpiryChecker(tx)
+			buckets := make(map[string]*bolt.Bucket) // resolving a nested bucket path walks all its parents
+			changes := make(map[string][][]byte)     // written keys by bucket
+			for _, entry := range batch {
+				b, found := buckets[entry.bucket]
+				if !found {
+					var err error
+					b, err = CreateBucketPath(tx, entry.bucket)
+					if err != nil {
+						return err
+					}
+					buckets[entry.bucket] = b
+				}
+				count, found := counts[entry.bucket]
+				if !found {
+					count = &BucketMerge{}
+					counts[entry.bucket] = count
+				}
+				if entry.key == nil {
+					continue
+				}
+
+				existing := b.Get(entry.key)
+				if existing != nil && checker.isExpired([]byte(entry.bucket), entry.key) {
+					existing = nil
+				}
+				if existing == nil && b.Bucket(entry.key) != nil {
+					return fmt.Errorf("Key %x of bucket %v is a nested bucket in the target\n", entry.key, entry.bucket)
+				}
+				switch {
+				case existing == nil:
+					count.Added++
+				case bytes.Equal(existing, entry.value):
+					count.Unchanged++
+					continue
+				case options.policy(entry.bucket) == MergeSkip:
+					count.Skipped++
+					continue
+				case options.policy(entry.bucket) == MergeFail:
+					return &MergeConflictError{Bucket: entry.bucket, Key: entry.key}
+				default:
+					count.Overwritten++
+				}
+
+				err := Journal(tx, options.Actor, ChangePut, entry.bucket, entry.key, existing, entry.value)
+				if err == nil {
+					err = b.Put(entry.key, entry.value)
+				}
+				if err == nil {
+					err = SetExpiry(tx, entry.bucket, entry.key, entry.expiresAt)
+				}
+				if err != nil {
+					return fmt.Errorf("Failed to write key %x of bucket %v: %v\n", entry.key, entry.bucket, err)
+				}
+				changes[entry.bucket] = append(changes[entry.bucket], entry.key)
+			}
+			for bucketPath, keysBytes := range changes {
+				RecordChanges(tx, NewChange(ChangePut, bucketPath, keysBytes...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for bucketPath, count := range counts {
+			bucketMerge := bucketMergeOf(bucketPath)
+			bucketMerge.Added += count.Added
+			bucketMerge.Overwritten += count.Overwritten
+			bucketMerge.Skipped += count.Skipped
+			bucketMerge.Unchanged += count.Unchanged
+		}
+		if preview != nil {
+			mergeResult.Preview.Keys += preview.Keys
+			mergeResult.Preview.BytesWritten += preview.BytesWritten
+			mergeResult.Preview.BytesDeleted += preview.BytesDeleted
+		}
+		mergeResult.Batches++
+		batch = batch[:0]
+		return nil
+	}
+
+	err = otherInstance.View(func(otherTx *bolt.Tx) error {
+		mergedBuckets := options.Buckets
+		if len(mergedBuckets) == 0 {
+			for bucketName := range bucketNames(otherTx) {
+				if !IsMetaBucket(bucketName) {
+					mergedBuckets = append(mergedBuckets, bucketName)
+				}
+			}
+			sort.Strings(mergedBuckets)
+		}
+		for _, bucketName := range mergedBuckets {
+			if ResolveBucket(otherTx, bucketName) == nil {
+				return ErrBucketNotFound
+			}
+		}
+		otherChecker := newExpiryChecker(otherTx)
+
+		// calls fn for the bucket bucketPath and every bucket nested in it, with the unexpired keys of each
+		var walk func(b *bolt.Bucket, bucketPath string, fn func(bucketPath string, keyBytes []byte, valueBytes []byte) error) error
+		walk = func(b *bolt.Bucket, bucketPath string, fn func(bucketPath string, keyBytes []byte, valueBytes []byte) error) error {
+			err := fn(bucketPath, nil, nil)
+			if err != nil {
+				return err
+			}
+			return b.ForEach(func(keyBytes []byte, valueBytes []byte) error {
+				if valueBytes == nil {
+					return walk(b.Bucket(keyBytes), bucketPath+"/"+string(keyBytes), fn)
+				}
+				if otherChecker.isExpired([]byte(bucketPath), keyBytes) {
+					return nil
+				}
+				return fn(bucketPath, keyBytes, valueBytes)
+			})
+		}
+
+		// compare the buckets merged with MergeFail before writing anything
+		err := dbInstance.View(func(tx *bolt.Tx) error {
+			checker := newExpiryChecker(tx)
+			for _, bucketName := range mergedBuckets {
+				err := walk(ResolveBucket(otherTx, bucketName), bucketName, func(bucketPath string, keyBytes []byte, valueBytes []byte) error {
+					if keyBytes == nil || options.policy(bucketPath) != MergeFail {
+						return nil
+					}
+					b := ResolveBucket(tx, bucketPath)
+					if b == nil {
+						return nil
+					}
+					existing := b.Get(keyBytes)
+					if existing != nil && !bytes.Equal(existing, valueBytes) && !checker.isExpired([]byte(bucketPath), keyBytes) {
+						return &MergeConflictError{Bucket: bucketPath, Key: append([]byte{}, keyBytes...)}
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, bucketName := range mergedBuckets {
+			err = walk(ResolveBucket(otherTx, bucketName), bucketName, func(bucketPath string, keyBytes []byte, valueBytes []byte) error {
+				bucketMergeOf(bucketPath)
+				// copy, the slices are only valid while otherTx is open and the batch is written in another transaction
+				batch = append(batch, mergeEntry{
+					bucket:    bucketPath,
+					key:       bytes.Clone(keyBytes),
+					value:     bytes.Clone(valueBytes),
+					expiresAt: otherChecker.expiry([]byte(bucketPath), keyBytes),
+				})
+				if len(batch) == mergeBatchSize {
+					return flush()
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if len(batch) > 0 {
+			return flush()
+		}
+		return nil
+	})
+	for _, bucketMerge := range bucketMerges {
+		mergeResult.Buckets = append(mergeResult.Buckets, *bucketMerge)
+	}
+	sort.Slice(mergeResult.Buckets, func(i, j int) bool { return mergeResult.Buckets[i].Bucket < mergeResult.Buckets[j].Bucket })
+	if err != nil {
+		return mergeResult, err
+	}
+	return mergeResult, nil
+}
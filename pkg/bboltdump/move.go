@@ -0,0 +1,253 @@
+package bboltdump
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrKeyNotFound is returned when the key an operation should act on does not exist.
+var ErrKeyNotFound = errors.New("key does not exist")
+
+// ErrKeyExists is returned when an operation would overwrite a key without being allowed to.
+var ErrKeyExists = errors.New("key already exists")
+
+// ErrBucketNotFound is returned when the bucket an operation should act on does not exist.
+var ErrBucketNotFound = errors.New("bucket does not exist")
+
+// ErrBucketExists is returned when an operation would overwrite an existing bucket.
+var ErrBucketExists = errors.New("bucket already exists")
+
+// MoveKey moves the value of keyBytes in the bucket bucketName to toKeyBytes in the bucket toBucketName within one transaction, so readers either see the old or the new key but never both or neither.
+// The target bucket is created if it does not exist, an expiry of the key moves along with it. If the target key exists MoveKey fails with ErrKeyExists unless overwrite is set, if the source key does not exist it fails with ErrKeyNotFound.
+// If the journal is enabled for the database the move is journaled as a delete and a put by actor. With dryRun set the move is rolled back and its Preview returned, otherwise the Preview is nil.
+func MoveKey(dbPath string, bucketName string, keyBytes []byte, toBucketName string, toKeyBytes []byte, overwrite bool, dryRun bool, actor string) (*Preview, error) {
+	if bucketName == toBucketName && bytes.Equal(keyBytes, toKeyBytes) {
+		return nil, fmt.Errorf("Source and target of the move are the same key\n")
+	}
+
+	// open database
+	dbInstance, closeDb, err := OpenDbForWriting(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeDb()
+
+	return UpdateOrDryRun(dbInstance, dryRun, func(tx *bolt.Tx) error {
+		b := ResolveBucket(tx, bucketName)
+		if b == nil {
+			return ErrKeyNotFound
+		}
+		valueBytes := b.Get(keyBytes)
+		checker := newExpiryChecker(tx)
+		if valueBytes == nil || checker.isExpired([]byte(bucketName), keyBytes) {
+			return ErrKeyNotFound
+		}
+		// copy, bolt keeps referencing the slice until the transaction is committed
+		valueBytes = append([]byte{}, valueBytes...)
+
+		expiresAt := checker.expiry([]byte(bucketName), keyBytes)
+
+		toBucket, err := CreateBucketPath(tx, toBucketName)
+		if err != nil {
+			return err
+		}
+		existing := toBucket.Get(toKeyBytes)
+		if existing != nil && !overwrite && !checker.isExpired([]byte(toBucketName), toKeyBytes) {
+			return ErrKeyExists
+		}
+		if existing == nil && toBucket.Bucket(toKeyBytes) != nil {
+			return fmt.Errorf("Target key %x is a nested bucket\n", toKeyBytes)
+		}
+
+		err = toBucket.Put(toKeyBytes, valueBytes)
+		if err == nil {
+			err = SetExpiry(tx, toBucketName, toKeyBytes, expiresAt)
+		}
+		if err == nil {
+			// the target bucket may have been created, so look the source bucket up again
+			err = ResolveBucket(tx, bucketName).Delete(keyBytes)
+		}
+		if err == nil {
+			err = SetExpiry(tx, bucketName, keyBytes, time.Time{})
+		}
+		if err == nil {
+			err = Journal(tx, actor, ChangeDelete, bucketName, keyBytes, valueBytes, nil)
+		}
+		if err == nil {
+			err = Journal(tx, actor, ChangePut, toBucketName, toKeyBytes, existing, valueBytes)
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to move key %x due to error: %v\n", keyBytes, err)
+		}
+		RecordChanges(tx, NewChange(ChangeDelete, bucketName, keyBytes), NewChange(ChangePut, toBucketName, toKeyBytes))
+		return nil
+	})
+}
+
+// copyBucket copies all entries of src including its nested buckets into dst.
+func copyBucket(src *bolt.Bucket, dst *bolt.Bucket) error {
+	err := dst.SetSequence(src.Sequence())
+	if err != nil {
+		return err
+	}
+	return src.ForEach(func(keyBytes []byte, valueBytes []byte) error {
+		if valueBytes != nil {
+			// copy, bolt keeps referencing the slice until the transaction is committed
+			return dst.Put(keyBytes, append([]byte{}, valueBytes...))
+		}
+		nestedDst, err := dst.CreateBucket(keyBytes)
+		if err != nil {
+			return err
+		}
+		return copyBucket(src.Bucket(keyBytes), nestedDst)
+	})
+}
+
+// deleteBucketPath deletes the bucket bucketPath refers to like ResolveBucket, along with everything in it.
+func deleteBucketPath(tx *bolt.Tx, bucketPath string) error {
+	if tx.Bucket([]byte(bucketPath)) != nil {
+		return tx.DeleteBucket([]byte(bucketPath))
+	}
+	parentPath, name, _ := cutLast(bucketPath, "/")
+	parent := ResolveBucket(tx, parentPath)
+	if parent == nil {
+		return ErrBucketNotFound
+	}
+	return parent.DeleteBucket([]byte(name))
+}
+
+// cutLast slices s around the last instance of sep like strings.Cut does around the first.
+func cutLast(s string, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+// moveExpiries rewrites the expiries of all keys below the bucket path fromPath, including its nested buckets, to toPath.
+// Expiries left below toPath by a bucket that was there before are removed, they must not apply to the copied keys.
+func moveExpiries(tx *bolt.Tx, fromPath string, toPath string) error {
+	ttlBucket := tx.Bucket([]byte(TtlBucket))
+	if ttlBucket == nil {
+		return nil
+	}
+
+	// collect first, a bucket must not be modified while iterating over it
+	type expiryEntry struct{ metaKey, bucketName, keyBytes, expiryBytes []byte }
+	entries := []expiryEntry{}
+	err := ttlBucket.ForEach(func(metaKey []byte, expiryBytes []byte) error {
+		bucketName, keyBytes, ok := splitTtlKey(metaKey)
+		if ok && (string(bucketName) == toPath || strings.HasPrefix(string(bucketName), toPath+"/")) {
+			entries = append(entries, expiryEntry{metaKey: append([]byte{}, metaKey...)}) // stale, deleted below without a replacement
+			return nil
+		}
+		if ok && (string(bucketName) == fromPath || strings.HasPrefix(string(bucketName), fromPath+"/")) {
+			entries = append(entries, expiryEntry{
+				metaKey:     append([]byte{}, metaKey...),
+				bucketName:  append([]byte{}, bucketName...),
+				keyBytes:    append([]byte{}, keyBytes...),
+				expiryBytes: append([]byte{}, expiryBytes...),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.bucketName == nil {
+			err = ttlBucket.Delete(entry.metaKey)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		newBucketName := toPath + strings.TrimPrefix(string(entry.bucketName), fromPath)
+		if len(newBucketName) > 0xFFFF {
+			return fmt.Errorf("Bucket name is too long to keep the expiry of key %x\n", entry.keyBytes)
+		}
+		err = ttlBucket.Delete(entry.metaKey)
+		if err == nil {
+			err = ttlBucket.Put(ttlKey([]byte(newBucketName), entry.keyBytes), entry.expiryBytes)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenameBucket renames the bucket bucketPath to toPath within one transaction by copying all its entries, including nested buckets, and deleting the original, since bolt cannot rename buckets.
+// Both may be paths of nested buckets, so a bucket can also be moved below another one. Missing parents of toPath are created. RenameBucket fails with ErrBucketNotFound if bucketPath does not exist and with ErrBucketExists if toPath does.
+// The whole bucket is copied in one transaction, so renaming a large bucket needs as much free memory as the bucket is big. If the journal is enabled for the database the rename is journaled as one entry by actor.
+// With dryRun set the rename is rolled back and its Preview returned, which counts every key of the bucket as deleted and written again. Otherwise the Preview is nil.
+func RenameBucket(dbPath string, bucketPath string, toPath string, dryRun bool, actor string) (*Preview, error) {
+	if bucketPath == toPath || strings.HasPrefix(toPath, bucketPath+"/") {
+		return nil, fmt.Errorf("Cannot rename bucket %v to itself or into one of its nested buckets\n", bucketPath)
+	}
+	for _, path := range []string{bucketPath, toPath} {
+		if IsMetaBucket(path) {
+			return nil, fmt.Errorf("Bucket %v holds metadata and cannot be renamed\n", path)
+		}
+	}
+
+	// open database
+	dbInstance, closeDb, err := OpenDbForWriting(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer closeDb()
+
+	return UpdateOrDryRun(dbInstance, dryRun, func(tx *bolt.Tx) error {
+		src := ResolveBucket(tx, bucketPath)
+		if src == nil {
+			return ErrBucketNotFound
+		}
+		if ResolveBucket(tx, toPath) != nil {
+			return ErrBucketExists
+		}
+
+		dst, err := CreateBucketPath(tx, toPath)
+		if err != nil {
+			return err
+		}
+		// creating the target may have touched the parent of the source, so look it up again
+		src = ResolveBucket(tx, bucketPath)
+		err = copyBucket(src, dst)
+		if err == nil {
+			// the entries are copied without Journal, so a dry run counts them here
+			err = WalkBucket(src, "", func(_ string, keyBytes []byte, valueBytes []byte) error {
+				addToPreview(tx, keyBytes, valueBytes, nil)
+				addToPreview(tx, keyBytes, nil, valueBytes)
+				return nil
+			})
+		}
+		if err == nil {
+			err = deleteBucketPath(tx, bucketPath)
+		}
+		if err == nil {
+			err = moveExpiries(tx, bucketPath, toPath)
+		}
+		if err == nil {
+			err = renameIndexedBucket(tx, bucketPath, toPath)
+		}
+		if err == nil {
+			err = computeRenamedViews(tx, bucketPath, toPath)
+		}
+		if err == nil {
+			err = appendJournal(tx, JournalEntry{Actor: actor, Operation: ChangeRenameBucket, Bucket: bucketPath, ToBucket: toPath})
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to rename bucket %v due to error: %v\n", bucketPath, err)
+		}
+		RecordChanges(tx, Change{Operation: ChangeRenameBucket, Bucket: bucketPath, ToBucket: toPath})
+		return nil
+	})
+}
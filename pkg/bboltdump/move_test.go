@@ -0,0 +1,201 @@
+package bboltdump
+
+import (
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestMoveKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		bucket    string
+		key       string
+		toBucket  string
+		toKey     string
+		overwrite bool
+		dryRun    bool
+		wantErr   error // nil if the move must succeed
+		want      map[string]map[string]string
+	}{
+		{
+			name: "rename key", bucket: "users", key: "alice", toBucket: "users", toKey: "alicia",
+			want: map[string]map[string]string{"users": {"alicia": "1", "bob": "2"}, "archive": {"bob": "old"}},
+		},
+		{
+			name: "into new nested bucket", bucket: "users", key: "alice", toBucket: "archive/2024", toKey: "alice",
+			want: map[string]map[string]string{"users": {"bob": "2"}, "archive": {"bob": "old"}, "archive/2024": {"alice": "1"}},
+		},
+		{
+			name: "existing target", bucket: "users", key: "bob", toBucket: "archive", toKey: "bob",
+			wantErr: ErrKeyExists,
+		},
+		{
+			name: "overwrite target", bucket: "users", key: "bob", toBucket: "archive", toKey: "bob", overwrite: true,
+			want: map[string]map[string]string{"users": {"alice": "1"}, "archive": {"bob": "2"}},
+		},
+		{
+			name: "missing key", bucket: "users", key: "carol", toBucket: "archive", toKey: "carol",
+			wantErr: ErrKeyNotFound,
+		},
+		{
+			name: "missing bucket", bucket: "missing", key: "alice", toBucket: "archive", toKey: "alice",
+			wantErr: ErrKeyNotFound,
+		},
+		{
+			name: "dry run", bucket: "users", key: "alice", toBucket: "archive", toKey: "alice", dryRun: true,
+			want: map[string]map[string]string{"users": {"alice": "1", "bob": "2"}, "archive": {"bob": "old"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbPath := createTestDb(t, map[string]map[string]string{
+				"users":   {"alice": "1", "bob": "2"},
+				"archive": {"bob": "old"},
+			})
+			preview, err := MoveKey(dbPath, test.bucket, []byte(test.key), test.toBucket, []byte(test.toKey), test.overwrite, test.dryRun, "test")
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("MoveKey error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (preview != nil) != test.dryRun {
+				t.Errorf("MoveKey preview = %v with dry run %v", preview, test.dryRun)
+			}
+			if got := readTestDb(t, dbPath); !maps.EqualFunc(got, test.want, maps.Equal) {
+				t.Errorf("database after move = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMoveKeyKeepsExpiry(t *testing.T) {
+	dbPath := createTestDb(t, map[string]map[string]string{"sessions": {"s1": "alice"}})
+	expiresAt := time.Now().Add(time.Hour)
+	expireTestKey(t, dbPath, "sessions", "s1", expiresAt)
+
+	_, err := MoveKey(dbPath, "sessions", []byte("s1"), "sessions", []byte("s2"), false, false, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDb()
+	dbInstance.View(func(tx *bolt.Tx) error {
+		checker := newExpiryChecker(tx)
+		if got := checker.expiry([]byte("sessions"), []byte("s2")); !got.Equal(time.Unix(0, expiresAt.UnixNano())) {
+			t.Errorf("expiry of the moved key = %v, want %v", got, expiresAt)
+		}
+		if got := checker.expiry([]byte("sessions"), []byte("s1")); !got.IsZero() {
+			t.Errorf("expiry of the source key = %v, want none", got)
+		}
+		return nil
+	})
+}
+
+func TestRenameBucket(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		wantErr error
+		want    map[string]map[string]string
+	}{
+		{
+			name: "top level", from: "config", to: "settings",
+			want: map[string]map[string]string{"settings": {"a": "1"}, "settings/devices": {"ios": "on"}, "users": {}},
+		},
+		{
+			name: "below another bucket", from: "config", to: "users/config",
+			want: map[string]map[string]string{"users": {}, "users/config": {"a": "1"}, "users/config/devices": {"ios": "on"}},
+		},
+		{name: "missing bucket", from: "missing", to: "other", wantErr: ErrBucketNotFound},
+		{name: "existing target", from: "config", to: "users", wantErr: ErrBucketExists},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbPath := createTestDb(t, map[string]map[string]string{
+				"config":         {"a": "1"},
+				"config/devices": {"ios": "on"},
+				"users":          {},
+			})
+			_, err := RenameBucket(dbPath, test.from, test.to, false, "test")
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("RenameBucket error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readTestDb(t, dbPath); !maps.EqualFunc(got, test.want, maps.Equal) {
+				t.Errorf("database after rename = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRenameBucketDropsStaleExpiries(t *testing.T) {
+	dbPath := createTestDb(t, map[string]map[string]string{"config": {"a": "1", "b": "2"}})
+	expireTestKey(t, dbPath, "config", "b", time.Now().Add(time.Hour))
+	// left behind by an earlier bucket settings
+	expireTestKey(t, dbPath, "settings", "a", time.Now().Add(-time.Second))
+
+	_, err := RenameBucket(dbPath, "config", "settings", false, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDb()
+	dbInstance.View(func(tx *bolt.Tx) error {
+		if IsExpired(tx, "settings", []byte("a")) {
+			t.Error("renamed key a expired by the expiry of the earlier bucket")
+		}
+		if got := newExpiryChecker(tx).expiry([]byte("settings"), []byte("b")); got.IsZero() {
+			t.Error("renamed key b lost its expiry")
+		}
+		return nil
+	})
+}
+
+func TestRenameBucketUpdatesViews(t *testing.T) {
+	dbPath := createTestDb(t, map[string]map[string]string{
+		"orders": {"alice:1": "a", "alice:2": "b", "bob:1": "c"},
+	})
+	definitions := []ViewDefinition{
+		{Name: "oldOrders", Bucket: "orders", Kind: ViewCount, Separator: ":"},
+		{Name: "newOrders", Bucket: "archive/orders", Kind: ViewLatest, Separator: ":"},
+	}
+	for _, definition := range definitions {
+		MaintainView(dbPath, definition)
+	}
+	_, err := RefreshView(dbPath, definitions[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = RenameBucket(dbPath, "orders", "archive/orders", false, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := readTestDb(t, dbPath)
+	if len(got[ViewBucket+"/oldOrders"]) != 0 {
+		t.Errorf("view of the old path after rename = %v, want empty", got[ViewBucket+"/oldOrders"])
+	}
+	if want := map[string]string{"alice": "b", "bob": "c"}; !maps.Equal(got[ViewBucket+"/newOrders"], want) {
+		t.Errorf("view of the new path after rename = %v, want %v", got[ViewBucket+"/newOrders"], want)
+	}
+}
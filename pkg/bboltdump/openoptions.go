@@ -0,0 +1,58 @@
+package bboltdump
+
+import (
+	"path/filepath"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// OpenOptions is a struct representing the bolt.Options a database is opened with instead of bolt's defaults, which are wrong for some very large databases.
+type OpenOptions struct {
+	PageSize        int    // page size of files that are created, existing files keep theirs. Zero uses the OS page size
+	NoFreelistSync  bool   // do not write the freelist to the file, it is rebuilt on every open instead
+	FreelistType    string // "array" or "hashmap", which is faster for huge freelists. Empty uses "array"
+	Mlock           bool   // lock the mapped file in memory so it is never swapped out
+	InitialMmapSize int    // initial size of the memory map in bytes, read transactions never block writes until the file grows beyond it
+	PreLoadFreelist bool   // load the freelist into memory when the file is opened, even read-only
+}
+
+var openOptionsMu sync.RWMutex
+var openOptions = make(map[string]OpenOptions) // by absolute path of the db file
+
+// SetOpenOptions makes every later open of the database at dbPath use options. Databases already held by the handle cache keep their options until they are closed.
+func SetOpenOptions(dbPath string, options OpenOptions) {
+	openOptionsMu.Lock()
+	defer openOptionsMu.Unlock()
+	openOptions[absolutePathOf(dbPath)] = options
+}
+
+// boltOptionsFor returns the bolt.Options to open dbPath with, bolt's defaults changed by its OpenOptions if there are any.
+func boltOptionsFor(dbPath string) bolt.Options {
+	boltOptions := *bolt.DefaultOptions
+	openOptionsMu.RLock()
+	options, found := openOptions[absolutePathOf(dbPath)]
+	openOptionsMu.RUnlock()
+	if !found {
+		return boltOptions
+	}
+
+	boltOptions.PageSize = options.PageSize
+	boltOptions.NoFreelistSync = options.NoFreelistSync
+	if options.FreelistType != "" {
+		boltOptions.FreelistType = bolt.FreelistType(options.FreelistType)
+	}
+	boltOptions.Mlock = options.Mlock
+	boltOptions.InitialMmapSize = options.InitialMmapSize
+	boltOptions.PreLoadFreelist = options.PreLoadFreelist
+	return boltOptions
+}
+
+// absolutePathOf returns dbPath as an absolute path, or as it is if that fails.
+func absolutePathOf(dbPath string) string {
+	absolutePath, err := filepath.Abs(dbPath)
+	if err != nil {
+		return dbPath
+	}
+	return absolutePath
+}
@@ -0,0 +1,73 @@
+package bboltdump
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+)
+
+// DumpOptions is a struct representing the settings of a full dump. The zero value dumps everything.
+type DumpOptions struct {
+	Include          []string `json:"include"`          // only dump buckets whose name matches one of these exact names or globs like "user*", all buckets if empty
+	Exclude          []string `json:"exclude"`          // skip buckets whose name matches one of these exact names or globs, wins over Include
+	MaxDepth         int      `json:"maxDepth"`         // levels of nested buckets to descend into, 1 only dumps top level buckets, 0 means no limit
+	MaxKeysPerBucket int      `json:"maxKeysPerBucket"` // most keys dumped per bucket, 0 means no limit. Reading a bucket stops at the limit, so nested buckets that come after it are left out too
+	Values           string   `json:"values"`           // one of the Values modes, all but ValuesRaw dump a BboltDbInfo instead of a BboltDb
+	Workers          int      `json:"workers"`          // amount of top level buckets read concurrently within one read transaction, 0 or 1 reads them one after another
+	Keys             string   `json:"keys"`             // one of the Keys modes, KeysTime lists the times of timestamp keys under keyTimes and KeysText keys the values by KeyText instead of the hex encoded key
+	KeySeparator     string   `json:"keySeparator"`     // splits keys like "user:123:settings" on it and nests their values in one object per part, {"user":{"123":{"settings":...}}}, instead of listing them by hex encoded key. Such dumps do not decode into a BboltDb
+
+	NoTransform bool `json:"noTransform"` // dump the values of buckets that have a Transformer as they are stored
+	Internal    bool `json:"internal"`    // also dump the buckets the package keeps beside the data, see IsMetaBucket. Without it they are only dumped if Include names them exactly
+	Partial     bool `json:"partial"`     // report buckets that fail to be read under bucketErrors and go on with the rest of the dump instead of failing it
+
+	Decrypter *Decrypter      `json:"-"` // decrypts every value before it is dumped, if set. Never part of a request since it holds the key
+	Context   context.Context `json:"-"` // stops reading once it is done and lists the buckets that were not read completely under timedOut, if set
+}
+
+// Validate checks that all bucket filters are valid glob patterns, the limits are not negative and the values and keys modes are known.
+func (o DumpOptions) Validate() error {
+	if o.MaxDepth < 0 || o.MaxKeysPerBucket < 0 || o.Workers < 0 {
+		return fmt.Errorf("maxDepth, maxKeysPerBucket and workers must not be negative\n")
+	}
+	if !IsValidValueMode(o.Values) {
+		return fmt.Errorf("Unknown values mode %q\n", o.Values)
+	}
+	if !IsValidKeyMode(o.Keys) {
+		return fmt.Errorf("Unknown keys mode %q\n", o.Keys)
+	}
+	for _, pattern := range append(append([]string{}, o.Include...), o.Exclude...) {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("Invalid bucket filter %q: %v\n", pattern, err)
+		}
+	}
+	return nil
+}
+
+// timedOut reports whether the Context of the dump is done.
+func (o DumpOptions) timedOut() bool {
+	return o.Context != nil && o.Context.Err() != nil
+}
+
+// includesBucket reports whether the top level bucket bucketName passes the include and exclude filters. Meta buckets only pass if they are asked for.
+func (o DumpOptions) includesBucket(bucketName string) bool {
+	if IsMetaBucket(bucketName) && !o.Internal && !slices.Contains(o.Include, bucketName) {
+		return false
+	}
+	for _, pattern := range o.Exclude {
+		if matched, _ := path.Match(pattern, bucketName); matched {
+			return false
+		}
+	}
+	if len(o.Include) == 0 {
+		return true
+	}
+	for _, pattern := range o.Include {
+		if matched, _ := path.Match(pattern, bucketName); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+package bboltdump
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	DefaultPageLimit = 100   // amount of entries per page if the client does not specify a limit
+	MaxPageLimit     = 10000 // upper bound for the amount of entries a client may request per page
+
+	OrderAsc  = "asc"  // iterate from the first to the last key
+	OrderDesc = "desc" // iterate from the last to the first key
+)
+
+// Entry is a struct representing a single key-value pair of a bucket.
+type Entry struct {
+	Key        string `json:"key"`               // key as RenderKey renders it, hex encoded in KeysHex mode
+	KeyHex     string `json:"keyHex,omitempty"`  // hex encoded key for the requests that take keys hex encoded, not set in KeysHex mode
+	KeyTime    string `json:"keyTime,omitempty"` // RFC 3339 time the key stands for, only set in KeysTime mode for timestamp keys
+	Value      string `json:"value"`             // value as string, empty unless values are returned raw
+	*ValueInfo        // size and type of the value instead of the value itself, nil if values are returned raw
+}
+
+// BucketPage is a struct representing one page of entries of a bucket.
+type BucketPage struct {
+	Bucket     string  `json:"bucket"`               // name of the bucket the entries belong to
+	Entries    []Entry `json:"entries"`              // entries of this page in the requested key order
+	NextCursor string  `json:"nextCursor,omitempty"` // opaque token to request the next page, empty if there are no more entries
+}
+
+// EncodeCursor turns the last key seen into an opaque continuation token.
+func EncodeCursor(lastKey []byte) string {
+	return base64.RawURLEncoding.EncodeToString(lastKey)
+}
+
+// DecodeCursor turns a continuation token back into the last key seen. An empty token means "start from the beginning".
+func DecodeCursor(token string) ([]byte, error) {
+	if token == "" {
+		return nil, nil
+	}
+	lastKey, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(lastKey) == 0 {
+		return nil, fmt.Errorf("Invalid cursor token %q\n", token)
+	}
+	return lastKey, nil
+}
+
+// IsValidOrder reports whether order is a supported iteration order. An empty order defaults to OrderAsc.
+func IsValidOrder(order string) bool {
+	return order == "" || order == OrderAsc || order == OrderDesc
+}
+
+// seekAfter positions cursor on the first entry that comes after lastKey in the given order and returns it along with the function that advances the cursor in that order.
+// If lastKey is nil the cursor is positioned on the first entry in the given order.
+func seekAfter(cursor *bolt.Cursor, lastKey []byte, order string) ([]byte, []byte, func() ([]byte, []byte)) {
+	if order == OrderDesc {
+		if lastKey == nil {
+			keyBytes, valueBytes := cursor.Last()
+			return keyBytes, valueBytes, cursor.Prev
+		}
+		// Seek lands on the first key >= lastKey, so the entry we want is the one before it
+		keyBytes, _ := cursor.Seek(lastKey)
+		if keyBytes == nil {
+			keyBytes, valueBytes := cursor.Last()
+			return keyBytes, valueBytes, cursor.Prev
+		}
+		keyBytes, valueBytes := cursor.Prev()
+		return keyBytes, valueBytes, cursor.Prev
+	}
+
+	if lastKey == nil {
+		keyBytes, valueBytes := cursor.First()
+		return keyBytes, valueBytes, cursor.Next
+	}
+	keyBytes, valueBytes := cursor.Seek(lastKey)
+	if keyBytes != nil && bytes.Equal(keyBytes, lastKey) {
+		keyBytes, valueBytes = cursor.Next()
+	}
+	return keyBytes, valueBytes, cursor.Next
+}
+
+// fillPage appends up to limit entries to bucketPage, starting at the entry keyBytes/valueBytes and moving on with advance.
+// If more entries follow once the page is full, NextCursor is set so the client can continue from there. Expired keys are skipped.
+func fillPage(bucketPage *BucketPage, keyBytes []byte, valueBytes []byte, advance func() ([]byte, []byte), limit int, checker *expiryChecker, values string, keys string) {
+	var lastKeySeen []byte
+	for ; keyBytes != nil; keyBytes, valueBytes = advance() {
+		// skip nested buckets, they have no value
+		if valueBytes == nil || checker.isExpired([]byte(bucketPage.Bucket), keyBytes) {
+			continue
+		}
+		// page is full but there is at least one more entry, so hand out a token
+		if len(bucketPage.Entries) == limit {
+			bucketPage.NextCursor = EncodeCursor(lastKeySeen)
+			return
+		}
+		lastKeySeen = keyBytes
+		bucketPage.Entries = append(bucketPage.Entries, NewEntry(keyBytes, valueBytes, values, keys))
+	}
+}
+
+// NewEntry returns the Entry of a key-value pair, values tells whether the value itself or its ValueInfo is returned and keys how the key is returned.
+func NewEntry(keyBytes []byte, valueBytes []byte, values string, keys string) Entry {
+	entry := Entry{
+		Key:       RenderKey(keyBytes, keys),
+		KeyTime:   describeKey(keyBytes, keys),
+		ValueInfo: describeValue(valueBytes, values),
+	}
+	if keys != KeysHex {
+		entry.KeyHex = hex.EncodeToString(keyBytes)
+	}
+	if entry.ValueInfo == nil {
+		entry.Value = string(valueBytes)
+	}
+	return entry
+}
+
+// GetBucketPageAsJson is like GetBucketPage but returns the page as a serialized JSON object of BucketPage.
+func GetBucketPageAsJson(dbPath string, bucketName string, limit int, cursorToken string, order string, values string, keys string) ([]byte, error) {
+	bucketPage, err := GetBucketPage(dbPath, bucketName, limit, cursorToken, order, values, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// serialize bucketPage to json
+	bucketPageJson, err := json.Marshal(bucketPage)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to serialize object to json: %v\n", err)
+	}
+
+	return bucketPageJson, nil
+}
+
+// GetBucketPage takes the path to a bbolt database, the name of a bucket and returns up to limit entries following the key encoded in cursorToken as a BucketPage along with an error.
+// The cursor is positioned with cursor.Seek, so the cost of a request only depends on the page size and not on how deep into the bucket the page is.
+// If order is OrderDesc the bucket is walked from its last key backwards, which returns the newest entries first for chronologically ordered keys. values is one of the Values modes and tells what to return for each value, keys one of the Keys modes.
+func GetBucketPage(dbPath string, bucketName string, limit int, cursorToken string, order string, values string, keys string) (BucketPage, error) {
+	bucketPage, _, err := GetBucketPageWithLinks(context.Background(), dbPath, bucketName, limit, cursorToken, order, values, keys, false)
+	return bucketPage, err
+}
+
+// SeekBucketAsJson is like SeekBucket but returns the entries as a serialized JSON object of BucketPage.
+func SeekBucketAsJson(dbPath string, bucketName string, seekKey []byte, count int, values string, keys string) ([]byte, error) {
+	bucketPage, err := SeekBucket(dbPath, bucketName, seekKey, count, values, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// serialize bucketPage to json
+	bucketPageJson, err := json.Marshal(bucketPage)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to serialize object to json: %v\n", err)
+	}
+
+	return bucketPageJson, nil
+}
+
+// SeekBucket takes the path to a bbolt database, the name of a bucket and a key and returns the entry at or after that key plus the following count entries as a BucketPage along with an error.
+// The returned NextCursor can be passed to the page endpoint to keep reading from that position.
+func SeekBucket(dbPath string, bucketName string, seekKey []byte, count int, values string, keys string) (BucketPage, error) {
+	bucketPage, _, err := SeekBucketWithLinks(context.Background(), dbPath, bucketName, seekKey, count, values, keys, false)
+	return bucketPage, err
+}
+
+// GetBucketTailAsJson takes the path to a bbolt database, the name of a bucket and returns its last count entries, starting with the very last key, as a serialized JSON object of BucketPage along with an error.
+// The returned NextCursor continues the reverse walk when passed to the page endpoint together with order desc.
+func GetBucketTailAsJson(dbPath string, bucketName string, count int, values string, keys string) ([]byte, error) {
+	return GetBucketPageAsJson(dbPath, bucketName, count, "", OrderDesc, values, keys)
+}
+
+// GetBucketTail is like GetBucketTailAsJson but returns the entries as a BucketPage.
+func GetBucketTail(dbPath string, bucketName string, count int, values string, keys string) (BucketPage, error) {
+	return GetBucketPage(dbPath, bucketName, count, "", OrderDesc, values, keys)
+}
+
+// PageLinks is a struct representing where a page of a bucket lies among all of its pages, the page endpoints send it as Link and X-Total-Count headers.
+type PageLinks struct {
+	Total      int    // amount of entries of the bucket, without nested buckets and expired keys. -1 unless it was asked for or if counting was stopped by the context
+	HasPrev    bool   // true if entries come before the page in its order
+	PrevCursor string // cursor of the page before, empty if that page is the first one
+}
+
+// countEntries returns the amount of entries of b, without nested buckets and expired keys, or -1 once ctx is done.
+func countEntries(ctx context.Context, b *bolt.Bucket, bucketName string, checker *expiryChecker) int {
+	total := 0
+	cursor := b.Cursor()
+	for keyBytes, valueBytes := cursor.First(); keyBytes != nil; keyBytes, valueBytes = cursor.Next() {
+		if ctx.Err() != nil {
+			return -1
+		}
+		if valueBytes != nil && !checker.isExpired([]byte(bucketName), keyBytes) {
+			total++
+		}
+	}
+	return total
+}
+
+// findPrev sets HasPrev and PrevCursor of pageLinks for the page of up to limit entries of b that follows lastKey in order. It walks back from lastKey over at most limit entries.
+func findPrev(pageLinks *PageLinks, b *bolt.Bucket, bucketName string, lastKey []byte, limit int, order string, checker *expiryChecker) {
+	if lastKey == nil {
+		return // the first page
+	}
+
+	// walk back from the last key of the page before, which the cursor token names, so the page before ends at it
+	cursor := b.Cursor()
+	keyBytes, valueBytes := cursor.Seek(lastKey)
+	advance := cursor.Next
+	if order != OrderDesc {
+		if keyBytes == nil {
+			keyBytes, valueBytes = cursor.Last()
+		} else if !bytes.Equal(keyBytes, lastKey) {
+			keyBytes, valueBytes = cursor.Prev()
+		}
+		advance = cursor.Prev
+	}
+	entriesBefore := 0
+	for ; keyBytes != nil; keyBytes, valueBytes = advance() {
+		if valueBytes == nil || checker.isExpired([]byte(bucketName), keyBytes) {
+			continue
+		}
+		// the page before is full, so it follows this key
+		if entriesBefore == limit {
+			pageLinks.PrevCursor = EncodeCursor(keyBytes)
+			break
+		}
+		entriesBefore++
+	}
+	pageLinks.HasPrev = entriesBefore > 0
+}
+
+// GetBucketPageWithLinks is like GetBucketPage but also returns the PageLinks of the page, read in the same transaction so they always fit the page.
+// Finding the page before walks back over at most limit entries. The entries of the bucket are only counted into Total if count is set, since that walks over every key, and counting stops once ctx is done.
+func GetBucketPageWithLinks(ctx context.Context, dbPath string, bucketName string, limit int, cursorToken string, order string, values string, keys string, count bool) (BucketPage, PageLinks, error) {
+	lastKey, err := DecodeCursor(cursorToken)
+	if err != nil {
+		return BucketPage{}, PageLinks{}, err
+	}
+
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return BucketPage{}, PageLinks{}, err
+	}
+	defer closeDb()
+
+	bucketPage := BucketPage{
+		Bucket:  bucketName,
+		Entries: []Entry{},
+	}
+	pageLinks := PageLinks{Total: -1}
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		b := ResolveBucket(tx, bucketName)
+		if b == nil {
+			return fmt.Errorf("Bucket %v does not exist\n", bucketName)
+		}
+		checker := newExpiryChecker(tx)
+
+		keyBytes, valueBytes, advance := seekAfter(b.Cursor(), lastKey, order)
+		fillPage(&bucketPage, keyBytes, valueBytes, advance, limit, checker, values, keys)
+		findPrev(&pageLinks, b, bucketName, lastKey, limit, order, checker)
+		if count {
+			pageLinks.Total = countEntries(ctx, b, bucketName, checker)
+		}
+		return nil
+	})
+	if err != nil {
+		return BucketPage{}, PageLinks{}, fmt.Errorf("Failed to read page of bucket %v due to error: %v\n", bucketName, err)
+	}
+	return bucketPage, pageLinks, nil
+}
+
+// SeekBucketWithLinks is like SeekBucket but also returns the PageLinks of the entries, read in the same transaction. Seeks only link forward, so only Total is set, and only if count is set like for GetBucketPageWithLinks.
+func SeekBucketWithLinks(ctx context.Context, dbPath string, bucketName string, seekKey []byte, count int, values string, keys string, countTotal bool) (BucketPage, PageLinks, error) {
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return BucketPage{}, PageLinks{}, err
+	}
+	defer closeDb()
+
+	bucketPage := BucketPage{
+		Bucket:  bucketName,
+		Entries: []Entry{},
+	}
+	pageLinks := PageLinks{Total: -1}
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		b := ResolveBucket(tx, bucketName)
+		if b == nil {
+			return fmt.Errorf("Bucket %v does not exist\n", bucketName)
+		}
+		checker := newExpiryChecker(tx)
+
+		// jump to the first key >= seekKey
+		cursor := b.Cursor()
+		keyBytes, valueBytes := cursor.Seek(seekKey)
+		fillPage(&bucketPage, keyBytes, valueBytes, cursor.Next, count+1, checker, values, keys)
+		if countTotal {
+			pageLinks.Total = countEntries(ctx, b, bucketName, checker)
+		}
+		return nil
+	})
+	if err != nil {
+		return BucketPage{}, PageLinks{}, fmt.Errorf("Failed to seek in bucket %v due to error: %v\n", bucketName, err)
+	}
+	return bucketPage, pageLinks, nil
+}
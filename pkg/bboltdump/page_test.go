@@ -0,0 +1,154 @@
+package bboltdump
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// pageKeys returns the keys of the entries of bucketPage in order.
+func pageKeys(bucketPage BucketPage) []string {
+	keys := []string{}
+	for _, entry := range bucketPage.Entries {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
+
+func TestGetBucketPage(t *testing.T) {
+	dbPath := createTestDb(t, map[string]map[string]string{
+		"log":        {"a": "1", "b": "2", "c": "3", "d": "4", "e": "5"},
+		"log/nested": {"x": "y"},
+	})
+	expireTestKey(t, dbPath, "log", "c", time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name  string
+		limit int
+		order string
+		pages [][]string // keys of every page when following NextCursor, in hex
+	}{
+		{"one page", 10, OrderAsc, [][]string{{"61", "62", "64", "65"}}},
+		{"pages of two", 2, OrderAsc, [][]string{{"61", "62"}, {"64", "65"}}},
+		{"pages of three", 3, "", [][]string{{"61", "62", "64"}, {"65"}}},
+		{"descending", 3, OrderDesc, [][]string{{"65", "64", "62"}, {"61"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cursor := ""
+			for i, wantKeys := range test.pages {
+				bucketPage, err := GetBucketPage(dbPath, "log", test.limit, cursor, test.order, "", KeysHex)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Equal(pageKeys(bucketPage), wantKeys) {
+					t.Errorf("page %v has keys %v, want %v", i, pageKeys(bucketPage), wantKeys)
+				}
+				cursor = bucketPage.NextCursor
+				if (cursor == "") != (i == len(test.pages)-1) {
+					t.Fatalf("page %v has cursor %q", i, cursor)
+				}
+			}
+		})
+	}
+
+	_, err := GetBucketPage(dbPath, "missing", 10, "", OrderAsc, "", KeysHex)
+	if err == nil {
+		t.Error("GetBucketPage of a missing bucket succeeded")
+	}
+	_, err = GetBucketPage(dbPath, "log", 10, "not a cursor!", OrderAsc, "", KeysHex)
+	if err == nil {
+		t.Error("GetBucketPage with an invalid cursor succeeded")
+	}
+}
+
+func TestSeekAndTail(t *testing.T) {
+	dbPath := createTestDb(t, map[string]map[string]string{
+		"log": {"a": "1", "b": "2", "c": "3", "d": "4"},
+	})
+
+	tests := []struct {
+		name     string
+		seekKey  string
+		count    int
+		wantKeys []string
+	}{
+		{"exact key", "b", 1, []string{"62", "63"}},
+		{"between keys", "bb", 0, []string{"63"}},
+		{"past the end", "z", 5, []string{}},
+		{"more than left", "c", 5, []string{"63", "64"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bucketPage, err := SeekBucket(dbPath, "log", []byte(test.seekKey), test.count, "", KeysHex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(pageKeys(bucketPage), test.wantKeys) {
+				t.Errorf("SeekBucket(%q, %v) = %v, want %v", test.seekKey, test.count, pageKeys(bucketPage), test.wantKeys)
+			}
+		})
+	}
+
+	bucketPage, err := GetBucketTail(dbPath, "log", 2, "", KeysHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"64", "63"}; !slices.Equal(pageKeys(bucketPage), want) {
+		t.Errorf("GetBucketTail = %v, want %v", pageKeys(bucketPage), want)
+	}
+}
+
+func TestGetBucketPageLinks(t *testing.T) {
+	dbPath := createTestDb(t, map[string]map[string]string{
+		"log": {"a": "1", "b": "2", "c": "3", "d": "4", "e": "5"},
+	})
+
+	tests := []struct {
+		name        string
+		cursorKey   string
+		order       string
+		wantHasPrev bool
+		wantPrev    string // key the PrevCursor encodes, empty if the page before is the first one
+	}{
+		{"first page", "", OrderAsc, false, ""},
+		{"second page", "b", OrderAsc, true, ""},
+		{"third page", "d", OrderAsc, true, "b"},
+		{"second page descending", "d", OrderDesc, true, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cursor := ""
+			if test.cursorKey != "" {
+				cursor = EncodeCursor([]byte(test.cursorKey))
+			}
+			_, pageLinks, err := GetBucketPageWithLinks(context.Background(), dbPath, "log", 2, cursor, test.order, "", "", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pageLinks.Total != 5 {
+				t.Errorf("Total = %v, want 5", pageLinks.Total)
+			}
+			if pageLinks.HasPrev != test.wantHasPrev {
+				t.Errorf("HasPrev = %v, want %v", pageLinks.HasPrev, test.wantHasPrev)
+			}
+			wantPrev := ""
+			if test.wantPrev != "" {
+				wantPrev = EncodeCursor([]byte(test.wantPrev))
+			}
+			if pageLinks.PrevCursor != wantPrev {
+				t.Errorf("PrevCursor = %q, want %q", pageLinks.PrevCursor, wantPrev)
+			}
+		})
+	}
+
+	// without count the entries are not counted
+	_, pageLinks, err := GetBucketPageWithLinks(context.Background(), dbPath, "log", 2, "", OrderAsc, "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pageLinks.Total != -1 {
+		t.Errorf("Total = %v, want -1", pageLinks.Total)
+	}
+}
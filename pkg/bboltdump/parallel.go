@@ -0,0 +1,89 @@
+package bboltdump
+
+import (
+	"bufio"
+	"errors"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// bucketResult is a struct representing the dump of a top level bucket written by a worker.
+type bucketResult struct {
+	json   []byte
+	report dumpReport
+	err    error
+}
+
+// writeBucketsInParallel dumps the top level buckets bucketNames with dumpOptions.Workers goroutines within one read transaction and passes them to emit in the order of bucketNames.
+// A read-only transaction is never modified by reading it, so the workers can share it as long as each uses cursors of its own. At most Workers buckets are read or wait to be emitted at once, which bounds the memory used.
+// The workers buffer their buckets in account, a bucket that does not fit under the memory limit is handed to stream instead, which writes it straight to the client within the transaction once it is its turn.
+func writeBucketsInParallel(dbInstance *bolt.DB, bucketNames []string, dumpOptions DumpOptions, account *memoryAccount, emit func(bucketIndex int, bucketJson []byte, bucketReport dumpReport) error, stream func(bucketIndex int, tx *bolt.Tx) error) error {
+	return dbInstance.View(func(tx *bolt.Tx) error {
+		results := make([]chan bucketResult, len(bucketNames))
+		for i := range results {
+			results[i] = make(chan bucketResult, 1) // workers never block, even if nobody emits their result anymore
+		}
+		slots := make(chan struct{}, dumpOptions.Workers)
+		stop := make(chan struct{})
+		var workers sync.WaitGroup
+
+		// start a worker for the next bucket whenever a slot is free
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for i, bucketName := range bucketNames {
+				select {
+				case slots <- struct{}{}:
+				case <-stop:
+					return
+				}
+				workers.Add(1)
+				go func() {
+					defer workers.Done()
+					bucketJson := &accountedBuffer{account: account}
+					writer := bufio.NewWriter(bucketJson)
+					report, err := writeBucket(writer, tx, bucketName, dumpOptions, account)
+					if err == nil {
+						err = writer.Flush()
+					}
+					if err != nil {
+						account.release(int64(bucketJson.Len())) // nothing of a failed bucket is emitted
+					}
+					results[i] <- bucketResult{json: bucketJson.Bytes(), report: report, err: err}
+				}()
+			}
+		}()
+
+		// the transaction must stay open until all started workers are done with it
+		finish := func() {
+			close(stop)
+			workers.Wait()
+		}
+
+		for i := range bucketNames {
+			result := <-results[i]
+			<-slots
+			if errors.Is(result.err, ErrMemoryLimit) {
+				err := stream(i, tx)
+				if err != nil {
+					finish()
+					return err
+				}
+				continue
+			}
+			if result.err != nil {
+				finish()
+				return result.err
+			}
+			err := emit(i, result.json, result.report)
+			account.release(int64(len(result.json)))
+			if err != nil {
+				finish()
+				return err
+			}
+		}
+		finish()
+		return nil
+	})
+}
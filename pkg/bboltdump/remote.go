@@ -0,0 +1,49 @@
+package bboltdump
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrReadOnlyDb is returned when a database that is only read from a copy, like one in S3 or a gzipped snapshot, is opened for writing, or any database once SetReadOnly was called.
+var ErrReadOnlyDb = errors.New("database is read-only")
+
+// Fetcher returns the path of a local copy of the remote database at dbUrl, downloading it first if the copy is missing or outdated.
+type Fetcher func(dbUrl string) (string, error)
+
+// fetchers maps URL schemes like "s3" to the Fetcher that handles them, db paths with any other scheme are local files.
+var fetchers = make(map[string]Fetcher)
+
+// RegisterFetcher makes all functions of the package accept db paths like "s3://bucket/app.db" whose scheme is scheme, they read from the local copy fetcher returns.
+// It has to be called before the first database is opened.
+func RegisterFetcher(scheme string, fetcher Fetcher) {
+	fetchers[scheme] = fetcher
+}
+
+// localPath returns the path of the file that has to be opened for dbPath, which only differs from dbPath for remote databases and for gzipped databases ending in ".gz".
+func localPath(dbPath string, writable bool) (string, error) {
+	if writable && readOnly {
+		return "", ErrReadOnlyDb
+	}
+	filePath := dbPath
+	scheme, _, found := strings.Cut(dbPath, "://")
+	fetcher, registered := fetchers[scheme]
+	if found && registered {
+		if writable {
+			return "", ErrReadOnlyDb
+		}
+		var err error
+		filePath, err = fetcher(dbPath)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if strings.HasSuffix(dbPath, ".gz") {
+		if writable {
+			return "", ErrReadOnlyDb
+		}
+		return gunzip(filePath)
+	}
+	return filePath, nil
+}
@@ -0,0 +1,38 @@
+package bboltdump
+
+import (
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var readReplicasMu sync.RWMutex
+var readReplicas = make(map[string]string) // path of the copy that is read instead, by absolute path of the db file
+
+// SetReadReplica makes OpenDb open the copy at replicaPath whenever it is asked for dbPath, so reads never contend with the process that holds the lock on the live file.
+// Writes still go to dbPath and only show up in reads once a newer copy is set. An empty replicaPath reads dbPath itself again.
+func SetReadReplica(dbPath string, replicaPath string) {
+	readReplicasMu.Lock()
+	defer readReplicasMu.Unlock()
+	if replicaPath == "" {
+		delete(readReplicas, absolutePathOf(dbPath))
+		return
+	}
+	readReplicas[absolutePathOf(dbPath)] = replicaPath
+}
+
+// readReplicaOf returns the path OpenDb reads for dbPath, which is dbPath itself unless a read replica is set for it.
+func readReplicaOf(dbPath string) string {
+	readReplicasMu.RLock()
+	defer readReplicasMu.RUnlock()
+	replicaPath, found := readReplicas[absolutePathOf(dbPath)]
+	if !found {
+		return dbPath
+	}
+	return replicaPath
+}
+
+// OpenLiveDb is OpenDb for callers that must read the live file even if a read replica is set for it, like those taking the copies.
+func OpenLiveDb(dbPath string) (*bolt.DB, func(), error) {
+	return open(dbPath, false, callerName())
+}
@@ -0,0 +1,66 @@
+package bboltdump
+
+import (
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// RestoreResult is a struct representing a database that was replaced by a copy.
+type RestoreResult struct {
+	Size int64  `json:"size"` // size of the file in bytes after it was restored
+	TxId uint64 `json:"txId"` // id of the last transaction committed to the copy
+}
+
+// Restore replaces the content of the database at dbPath by the database file at srcPath, the file is created if it does not exist yet.
+// Like Compact it holds the database open for writing meanwhile and copies srcPath over it in place, so processes waiting for the lock open the restored file. A LockedError is returned if another process holds the file.
+func Restore(dbPath string, srcPath string) (RestoreResult, error) {
+	srcPath, err := localPath(srcPath, false)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+
+	// a broken copy must never replace a working database
+	srcDb, err := bolt.Open(srcPath, 0600, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("Failed to open copy to restore from: %v\n", err)
+	}
+	var txId uint64
+	err = srcDb.View(func(tx *bolt.Tx) error {
+		txId = uint64(tx.ID())
+		return nil
+	})
+	srcDb.Close()
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("Failed to read copy to restore from: %v\n", err)
+	}
+
+	dbPath, err = localPath(dbPath, true)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	_, _, err = CloseCachedHandle(dbPath) // the cache would hold the file lock otherwise
+	if err != nil {
+		return RestoreResult{}, err
+	}
+
+	// open database, privately since nobody may use it while it is replaced
+	holderId := registerHolder(dbPath, true, "bboltdump.Restore")
+	defer unregisterHolder(holderId)
+	dbInstance, err := openDbForWriting(dbPath)
+	if err != nil {
+		if lockedError, locked := err.(*LockedError); locked {
+			lockedError.Holders = lockHolders(dbPath)
+		}
+		return RestoreResult{}, err
+	}
+	holderOpened(holderId, dbInstance)
+	defer dbInstance.Close()
+
+	size, err := copyInPlace(srcPath, dbPath)
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("Failed to restore database %v: %v\n", dbPath, err)
+	}
+	return RestoreResult{Size: size, TxId: txId}, nil
+}
@@ -0,0 +1,85 @@
+package bboltdump
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BucketSample is a struct representing a random sample of the entries of a bucket.
+type BucketSample struct {
+	Bucket  string  `json:"bucket"`  // name of the bucket the entries belong to
+	Entries []Entry `json:"entries"` // sampled entries, in no particular order
+	Total   int     `json:"total"`   // amount of entries the sample was drawn from
+}
+
+// SampleBucketAsJson is like SampleBucket but returns the sample as a serialized JSON object of BucketSample.
+func SampleBucketAsJson(dbPath string, bucketName string, size int, keys string) ([]byte, error) {
+	bucketSample, err := SampleBucket(dbPath, bucketName, size, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// serialize bucketSample to json
+	bucketSampleJson, err := json.Marshal(bucketSample)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to serialize object to json: %v\n", err)
+	}
+
+	return bucketSampleJson, nil
+}
+
+// SampleBucket takes the path to a bbolt database, the name of a bucket and returns up to size entries chosen uniformly at random as a BucketSample along with an error.
+// The bucket is walked once with reservoir sampling, so only size entries are held in memory no matter how large the bucket is. keys is one of the Keys modes.
+func SampleBucket(dbPath string, bucketName string, size int, keys string) (BucketSample, error) {
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return BucketSample{}, err
+	}
+	defer closeDb()
+
+	bucketSample := BucketSample{
+		Bucket:  bucketName,
+		Entries: make([]Entry, 0, size),
+	}
+
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		b := ResolveBucket(tx, bucketName)
+		if b == nil {
+			return fmt.Errorf("Bucket %v does not exist\n", bucketName)
+		}
+
+		checker := newExpiryChecker(tx)
+		cursor := b.Cursor()
+		for keyBytes, valueBytes := cursor.First(); keyBytes != nil; keyBytes, valueBytes = cursor.Next() {
+			// skip nested buckets, they have no value
+			if valueBytes == nil || checker.isExpired([]byte(bucketName), keyBytes) {
+				continue
+			}
+			bucketSample.Total++
+
+			// fill the reservoir first, afterwards replace a random slot with probability size/Total
+			slot := len(bucketSample.Entries)
+			if slot == size {
+				slot = rand.Intn(bucketSample.Total)
+				if slot >= size {
+					continue
+				}
+			}
+			entry := NewEntry(keyBytes, valueBytes, ValuesRaw, keys)
+			if slot == len(bucketSample.Entries) {
+				bucketSample.Entries = append(bucketSample.Entries, entry)
+			} else {
+				bucketSample.Entries[slot] = entry
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return BucketSample{}, fmt.Errorf("Failed to sample bucket %v due to error: %v\n", bucketName, err)
+	}
+	return bucketSample, nil
+}
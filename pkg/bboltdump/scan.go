@@ -0,0 +1,50 @@
+package bboltdump
+
+import (
+	"context"
+	"errors"
+)
+
+// ScanEntry is a struct representing an entry found by Scan.
+type ScanEntry struct {
+	Bucket string `json:"bucket"` // name of the bucket the entry belongs to
+	Entry
+}
+
+// ScanResult is a struct representing the entries found by Scan.
+type ScanResult struct {
+	Entries   []ScanEntry `json:"entries"`   // matching entries in key order, bucket by bucket
+	Scanned   int         `json:"scanned"`   // amount of entries the pattern and the filter were evaluated on
+	Truncated bool        `json:"truncated"` // more entries match, the scan stopped once limit entries were found
+	TimedOut  bool        `json:"timedOut"`  // ctx was done before the buckets were read completely, more entries may match
+}
+
+// errScanLimit stops ForEachEntry once a scan has found enough entries.
+var errScanLimit = errors.New("scan limit reached")
+
+// Scan takes the path to a bbolt database and returns up to limit entries of the bucket bucketName, or of all top level buckets if bucketName is empty, whose keys match match and that pass filter as a ScanResult along with an error.
+// The filter is evaluated while the buckets are iterated, so only the matching entries are held in memory. Only the keys starting with the prefix of match are visited at all, a nil match or filter passes everything.
+// values is one of the Values modes and tells what to return for each value, keys one of the Keys modes. Once ctx is done the scan stops and returns the entries found so far with TimedOut set.
+func Scan(ctx context.Context, dbPath string, bucketName string, match *KeyGlob, filter *Filter, limit int, values string, keys string) (ScanResult, error) {
+	scanResult := ScanResult{Entries: []ScanEntry{}}
+	err := ForEachEntryWithPrefix(dbPath, bucketName, match.Prefix(), func(currentBucketName string, keyBytes []byte, valueBytes []byte) error {
+		if ctx.Err() != nil {
+			scanResult.TimedOut = true
+			return errScanLimit
+		}
+		scanResult.Scanned++
+		if !match.Matches(keyBytes) || !filter.Matches(currentBucketName, keyBytes, valueBytes) {
+			return nil
+		}
+		if len(scanResult.Entries) == limit {
+			scanResult.Truncated = true
+			return errScanLimit
+		}
+		scanResult.Entries = append(scanResult.Entries, ScanEntry{Bucket: currentBucketName, Entry: NewEntry(keyBytes, valueBytes, values, keys)})
+		return nil
+	})
+	if err != nil && err != errScanLimit {
+		return ScanResult{}, err
+	}
+	return scanResult, nil
+}
@@ -0,0 +1,41 @@
+package bboltdump
+
+import (
+	"path"
+)
+
+// Transformer turns a value stored in an app specific serialization like protobuf into JSON, which is dumped as it is instead of as a string.
+type Transformer func(valueBytes []byte) ([]byte, error)
+
+// transformerRule is a struct representing a Transformer and the buckets it applies to.
+type transformerRule struct {
+	bucketPattern string
+	transformer   Transformer
+}
+
+// transformerRules are tried in the order they were registered, the first one whose pattern matches a bucket wins.
+var transformerRules []transformerRule
+
+// RegisterTransformer makes full dumps pass the values of all buckets whose name or path matches bucketPattern, like "devices" or "config/*", through transformer.
+// It has to be called before the first dump. Transformers only apply while values are dumped with ValuesRaw.
+func RegisterTransformer(bucketPattern string, transformer Transformer) error {
+	_, err := path.Match(bucketPattern, "")
+	if err != nil {
+		return err
+	}
+	transformerRules = append(transformerRules, transformerRule{bucketPattern: bucketPattern, transformer: transformer})
+	return nil
+}
+
+// transformerFor returns the Transformer for the values of the bucket bucketPath, or nil if they are dumped as strings.
+func (o DumpOptions) transformerFor(bucketPath string) Transformer {
+	if o.NoTransform || (o.Values != "" && o.Values != ValuesRaw) {
+		return nil
+	}
+	for _, rule := range transformerRules {
+		if matched, _ := path.Match(rule.bucketPattern, bucketPath); matched {
+			return rule.transformer
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package bboltdump
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// TtlBucket is the top level bucket that holds the expiry times of keys written with a TTL.
+// Its keys are the big endian uint16 length of the bucket name followed by the bucket name and the key, its values the big endian unix nanoseconds the key expires at.
+const TtlBucket = "__ttl"
+
+// SweeperActor is the actor journal entries of keys deleted by SweepExpired are recorded with.
+const SweeperActor = "ttl-sweeper"
+
+// ttlKey returns the key under which the expiry of keyBytes in the bucket bucketName is stored in TtlBucket.
+func ttlKey(bucketName []byte, keyBytes []byte) []byte {
+	metaKey := make([]byte, 2, 2+len(bucketName)+len(keyBytes))
+	binary.BigEndian.PutUint16(metaKey, uint16(len(bucketName)))
+	metaKey = append(metaKey, bucketName...)
+	return append(metaKey, keyBytes...)
+}
+
+// splitTtlKey is the inverse of ttlKey.
+func splitTtlKey(metaKey []byte) ([]byte, []byte, bool) {
+	if len(metaKey) < 2 {
+		return nil, nil, false
+	}
+	nameLength := int(binary.BigEndian.Uint16(metaKey))
+	if len(metaKey) < 2+nameLength {
+		return nil, nil, false
+	}
+	return metaKey[2 : 2+nameLength], metaKey[2+nameLength:], true
+}
+
+// SetExpiry records that keyBytes in the bucket bucketName expires at expiresAt, a zero expiresAt removes the expiry so the key is kept forever.
+// It has to be called in the same read-write transaction that writes the key.
+func SetExpiry(tx *bolt.Tx, bucketName string, keyBytes []byte, expiresAt time.Time) error {
+	if expiresAt.IsZero() {
+		ttlBucket := tx.Bucket([]byte(TtlBucket))
+		if ttlBucket == nil {
+			return nil
+		}
+		return ttlBucket.Delete(ttlKey([]byte(bucketName), keyBytes))
+	}
+	if len(bucketName) > 0xFFFF {
+		return fmt.Errorf("Bucket name is too long to attach an expiry\n")
+	}
+
+	ttlBucket, err := tx.CreateBucketIfNotExists([]byte(TtlBucket))
+	if err != nil {
+		return fmt.Errorf("Failed to create bucket %v: %v\n", TtlBucket, err)
+	}
+	expiryBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(expiryBytes, uint64(expiresAt.UnixNano()))
+	return ttlBucket.Put(ttlKey([]byte(bucketName), keyBytes), expiryBytes)
+}
+
+// expiryChecker answers whether keys read in a transaction have expired, a nil *expiryChecker treats all keys as alive.
+type expiryChecker struct {
+	ttlBucket *bolt.Bucket
+	now       time.Time
+}
+
+// newExpiryChecker returns the expiryChecker for tx, it is nil if no key of the database has an expiry.
+func newExpiryChecker(tx *bolt.Tx) *expiryChecker {
+	ttlBucket := tx.Bucket([]byte(TtlBucket))
+	if ttlBucket == nil {
+		return nil
+	}
+	return &expiryChecker{ttlBucket: ttlBucket, now: time.Now()}
+}
+
+// expiry returns when keyBytes in the bucket bucketName expires, it is zero if the key has no expiry.
+func (c *expiryChecker) expiry(bucketName []byte, keyBytes []byte) time.Time {
+	if c == nil {
+		return time.Time{}
+	}
+	expiryBytes := c.ttlBucket.Get(ttlKey(bucketName, keyBytes))
+	if len(expiryBytes) != 8 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(expiryBytes)))
+}
+
+// isExpired reports whether keyBytes in the bucket bucketName has expired.
+func (c *expiryChecker) isExpired(bucketName []byte, keyBytes []byte) bool {
+	expiresAt := c.expiry(bucketName, keyBytes)
+	return !expiresAt.IsZero() && !c.now.Before(expiresAt)
+}
+
+// IsExpired reports whether keyBytes in the bucket bucketName has expired but was not deleted by the sweeper yet. Reads should treat expired keys as missing.
+func IsExpired(tx *bolt.Tx, bucketName string, keyBytes []byte) bool {
+	return newExpiryChecker(tx).isExpired([]byte(bucketName), keyBytes)
+}
+
+// SweepExpired deletes all expired keys of the database at dbPath along with their expiry and returns how many keys were deleted.
+// The deletes go through Journal as SweeperActor, so they are journaled and update indexes and maintained views like every other write.
+// The database is only opened for writing if there is something to delete, so sweeping an unchanged database does not modify the file.
+func SweepExpired(dbPath string) (int, error) {
+	// find expired keys first
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return 0, err
+	}
+	expiredKeys := [][]byte{}
+	err = dbInstance.View(func(tx *bolt.Tx) error {
+		checker := newExpiryChecker(tx)
+		if checker == nil {
+			return nil
+		}
+		return checker.ttlBucket.ForEach(func(metaKey []byte, _ []byte) error {
+			bucketName, keyBytes, ok := splitTtlKey(metaKey)
+			if ok && checker.isExpired(bucketName, keyBytes) {
+				expiredKeys = append(expiredKeys, append([]byte{}, metaKey...))
+			}
+			return nil
+		})
+	})
+	closeDb()
+	if err != nil {
+		return 0, fmt.Errorf("Failed to find expired keys due to error: %v\n", err)
+	}
+	if len(expiredKeys) == 0 {
+		return 0, nil
+	}
+
+	// delete them, checking again since a key may have been rewritten in the meantime
+	dbInstance, closeDb, err = OpenDbForWriting(dbPath)
+	if err != nil {
+		return 0, err
+	}
+	defer closeDb()
+	deleted := 0
+	err = dbInstance.Update(func(tx *bolt.Tx) error {
+		checker := newExpiryChecker(tx)
+		deletedKeys := make(map[string][][]byte) // by bucket
+		bucketNames := []string{}                // in the order they were first seen
+		for _, metaKey := range expiredKeys {
+			bucketName, keyBytes, _ := splitTtlKey(metaKey)
+			if !checker.isExpired(bucketName, keyBytes) {
+				continue
+			}
+			if b := ResolveBucket(tx, string(bucketName)); b != nil && b.Get(keyBytes) != nil {
+				err := Journal(tx, SweeperActor, ChangeDelete, string(bucketName), keyBytes, b.Get(keyBytes), nil)
+				if err == nil {
+					err = b.Delete(keyBytes)
+				}
+				if err != nil {
+					return err
+				}
+				deleted++
+				if deletedKeys[string(bucketName)] == nil {
+					bucketNames = append(bucketNames, string(bucketName))
+				}
+				deletedKeys[string(bucketName)] = append(deletedKeys[string(bucketName)], keyBytes)
+			}
+			err := checker.ttlBucket.Delete(metaKey)
+			if err != nil {
+				return err
+			}
+		}
+
+		changes := []Change{}
+		for _, bucketName := range bucketNames {
+			changes = append(changes, NewChange(ChangeDelete, bucketName, deletedKeys[bucketName]...))
+		}
+		RecordChanges(tx, changes...)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Failed to delete expired keys due to error: %v\n", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,135 @@
+package bboltdump
+
+import (
+	"bytes"
+	"maps"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestTtlKey(t *testing.T) {
+	tests := []struct {
+		bucketName string
+		key        string
+	}{
+		{"sessions", "s1"},
+		{"config/devices", "ios"},
+		{"", "key"},
+		{"bucket", ""},
+	}
+	for _, test := range tests {
+		bucketName, keyBytes, ok := splitTtlKey(ttlKey([]byte(test.bucketName), []byte(test.key)))
+		if !ok || string(bucketName) != test.bucketName || !bytes.Equal(keyBytes, []byte(test.key)) {
+			t.Errorf("splitTtlKey(ttlKey(%q, %q)) = %q, %q, %v", test.bucketName, test.key, bucketName, keyBytes, ok)
+		}
+	}
+	if _, _, ok := splitTtlKey([]byte{0, 9, 'a'}); ok {
+		t.Error("splitTtlKey accepted a key shorter than its bucket name")
+	}
+}
+
+func TestIsExpired(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		want      bool
+	}{
+		{"no expiry", time.Time{}, false},
+		{"expires later", now.Add(time.Hour), false},
+		{"expired", now.Add(-time.Second), true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbPath := createTestDb(t, map[string]map[string]string{"sessions": {"s1": "alice"}})
+			expireTestKey(t, dbPath, "sessions", "s1", test.expiresAt)
+			dbInstance, closeDb, err := OpenDb(dbPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closeDb()
+			dbInstance.View(func(tx *bolt.Tx) error {
+				if got := IsExpired(tx, "sessions", []byte("s1")); got != test.want {
+					t.Errorf("IsExpired = %v, want %v", got, test.want)
+				}
+				if IsExpired(tx, "sessions", []byte("other")) {
+					t.Error("key without expiry is expired")
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestSetExpiryZeroRemovesExpiry(t *testing.T) {
+	dbPath := createTestDb(t, map[string]map[string]string{"sessions": {"s1": "alice"}})
+	expireTestKey(t, dbPath, "sessions", "s1", time.Now().Add(-time.Second))
+	expireTestKey(t, dbPath, "sessions", "s1", time.Time{})
+
+	deleted, err := SweepExpired(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 0 {
+		t.Errorf("SweepExpired deleted %v keys, want 0", deleted)
+	}
+}
+
+func TestSweepExpired(t *testing.T) {
+	dbPath := createTestDb(t, map[string]map[string]string{
+		"sessions":        {"s1": "alice", "s2": "bob", "s3": "carol"},
+		"config/sessions": {"s1": "nested"},
+	})
+	expireTestKey(t, dbPath, "sessions", "s1", time.Now().Add(-time.Second))
+	expireTestKey(t, dbPath, "sessions", "s2", time.Now().Add(time.Hour))
+	expireTestKey(t, dbPath, "config/sessions", "s1", time.Now().Add(-time.Second))
+
+	deleted, err := SweepExpired(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Errorf("SweepExpired deleted %v keys, want 2", deleted)
+	}
+	got := readTestDb(t, dbPath)
+	if want := map[string]string{"s2": "bob", "s3": "carol"}; !maps.Equal(got["sessions"], want) {
+		t.Errorf("sessions after sweep = %v, want %v", got["sessions"], want)
+	}
+	if len(got["config/sessions"]) != 0 {
+		t.Errorf("config/sessions after sweep = %v, want empty", got["config/sessions"])
+	}
+	if len(got[TtlBucket]) != 1 {
+		t.Errorf("%v holds %v expiries after sweep, want 1", TtlBucket, len(got[TtlBucket]))
+	}
+
+	// nothing left to sweep
+	deleted, err = SweepExpired(dbPath)
+	if err != nil || deleted != 0 {
+		t.Errorf("second SweepExpired = %v, %v, want 0", deleted, err)
+	}
+}
+
+func TestSweepExpiredUpdatesViews(t *testing.T) {
+	dbPath := createTestDb(t, map[string]map[string]string{
+		"orders": {"alice:1": "a", "alice:2": "b", "bob:1": "c"},
+	})
+	definition := ViewDefinition{Name: "ordersPerCustomer", Bucket: "orders", Kind: ViewCount, Separator: ":"}
+	_, err := RefreshView(dbPath, definition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	MaintainView(dbPath, definition)
+	expireTestKey(t, dbPath, "orders", "alice:2", time.Now().Add(-time.Second))
+	expireTestKey(t, dbPath, "orders", "bob:1", time.Now().Add(-time.Second))
+
+	_, err = SweepExpired(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := readTestDb(t, dbPath)
+	if want := map[string]string{"alice": "1"}; !maps.Equal(got[ViewBucket+"/ordersPerCustomer"], want) {
+		t.Errorf("view after sweep = %v, want %v", got[ViewBucket+"/ordersPerCustomer"], want)
+	}
+}
@@ -0,0 +1,44 @@
+package bboltdump
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	ValuesRaw    = "raw"    // return the values themselves, the default
+	ValuesType   = "type"   // return the size and sniffed content type of each value instead of the value
+	ValuesSha256 = "sha256" // return the size and SHA-256 digest of each value instead of the value, to compare databases without transferring their data
+)
+
+// IsValidValueMode reports whether values is a supported way of returning values. An empty mode defaults to ValuesRaw.
+func IsValidValueMode(values string) bool {
+	return values == "" || values == ValuesRaw || values == ValuesType || values == ValuesSha256
+}
+
+// ValueInfo is a struct representing what is known about a value without returning the value itself.
+// The content type tells text ("text/plain; charset=utf-8"), well known formats like "image/png" or "application/x-gzip" and other binary data like protobuf messages ("application/octet-stream") apart.
+type ValueInfo struct {
+	Size        int    `json:"size"`                  // length of the value in bytes
+	ContentType string `json:"contentType,omitempty"` // MIME type sniffed with http.DetectContentType, only set in ValuesType mode
+	Sha256      string `json:"sha256,omitempty"`      // hex encoded SHA-256 digest of the value, only set in ValuesSha256 mode
+}
+
+// describeValue returns the ValueInfo of valueBytes for the given mode, or nil if the value itself should be returned.
+func describeValue(valueBytes []byte, values string) *ValueInfo {
+	switch values {
+	case ValuesType:
+		return &ValueInfo{
+			Size:        len(valueBytes),
+			ContentType: http.DetectContentType(valueBytes),
+		}
+	case ValuesSha256:
+		digest := sha256.Sum256(valueBytes)
+		return &ValueInfo{
+			Size:   len(valueBytes),
+			Sha256: hex.EncodeToString(digest[:]),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package bboltdump
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ViewBucket is the top level bucket that holds the materialized views of a database, one nested bucket per view named after it.
+// The views are read like any other bucket, e.g. as "__views/ordersPerCustomer", but cannot be written through the package or Journal.
+const ViewBucket = "__views"
+
+// kinds of views, they group the keys of a bucket by prefix and have one key per prefix
+const (
+	ViewCount  = "count"  // the amount of keys with the prefix as decimal text
+	ViewLatest = "latest" // the value of the last key with the prefix in key order, like the newest entry of time ordered keys
+)
+
+// ViewDefinition is a struct representing what a materialized view derives from which bucket.
+type ViewDefinition struct {
+	Name      string `json:"name"`      // name of the view, its keys are in the bucket ViewBucket/Name
+	Bucket    string `json:"bucket"`    // path of the bucket the view is derived from
+	Kind      string `json:"kind"`      // ViewCount or ViewLatest
+	Separator string `json:"separator"` // the prefix of a key is its part before the first separator, like "customer1" of "customer1:order7", keys without it are their own prefix
+}
+
+// ViewInfo is a struct representing a materialized view and its size.
+type ViewInfo struct {
+	ViewDefinition
+	Keys int `json:"keys"` // amount of prefixes the view has a key for
+}
+
+// IsValidViewKind reports whether kind is a known kind of view.
+func IsValidViewKind(kind string) bool {
+	return kind == ViewCount || kind == ViewLatest
+}
+
+// Validate checks that the view has a name that is a single bucket name, a bucket, a separator and a known kind.
+func (v ViewDefinition) Validate() error {
+	if v.Name == "" || strings.Contains(v.Name, "/") {
+		return fmt.Errorf("View name %q must not be empty or contain /\n", v.Name)
+	}
+	if v.Bucket == "" || v.Separator == "" {
+		return fmt.Errorf("View %v needs a bucket and a separator\n", v.Name)
+	}
+	if !IsValidViewKind(v.Kind) {
+		return fmt.Errorf("View %v has unknown kind %q, use count or latest\n", v.Name, v.Kind)
+	}
+	return nil
+}
+
+// prefix returns a copy of the prefix of keyBytes the view groups it under.
+func (v ViewDefinition) prefix(keyBytes []byte) []byte {
+	prefix, _, _ := bytes.Cut(keyBytes, []byte(v.Separator))
+	return append([]byte{}, prefix...)
+}
+
+var maintainedViewsMu sync.RWMutex
+var maintainedViews = make(map[string][]ViewDefinition) // by absolute path of the db file
+
+// MaintainView makes every write to the bucket of definition in the database at dbPath that goes through the package or Journal update the view within the same transaction.
+// Other writes, like those of other processes, are only picked up by RefreshView.
+func MaintainView(dbPath string, definition ViewDefinition) {
+	maintainedViewsMu.Lock()
+	defer maintainedViewsMu.Unlock()
+	absolutePath := absolutePathOf(dbPath)
+	maintainedViews[absolutePath] = append(maintainedViews[absolutePath], definition)
+}
+
+// maintainedViewsOf returns the views that writes within tx update.
+func maintainedViewsOf(tx *bolt.Tx) []ViewDefinition {
+	maintainedViewsMu.RLock()
+	defer maintainedViewsMu.RUnlock()
+	return maintainedViews[absolutePathOf(tx.DB().Path())]
+}
+
+// isViewBucket reports whether bucketName is ViewBucket or one of the views in it.
+func isViewBucket(bucketName string) bool {
+	return bucketName == ViewBucket || strings.HasPrefix(bucketName, ViewBucket+"/")
+}
+
+// latestOfPrefix returns the last entry of b in key order whose key has prefix as its prefix for a view with separator, leaving out excludedKey. It returns nil if there is none.
+func latestOfPrefix(b *bolt.Bucket, prefix []byte, separator string, excludedKey []byte) ([]byte, []byte) {
+	group := append(append([]byte{}, prefix...), separator...)
+
+	// seek behind the last key starting with group, the key right after it is group with its last byte below 0xFF incremented
+	end := append([]byte{}, group...)
+	for len(end) > 0 && end[len(end)-1] == 0xFF {
+		end = end[:len(end)-1]
+	}
+	c := b.Cursor()
+	var keyBytes, valueBytes []byte
+	if len(end) == 0 {
+		keyBytes, valueBytes = c.Last()
+	} else {
+		end[len(end)-1]++
+		keyBytes, _ = c.Seek(end)
+		if keyBytes == nil {
+			keyBytes, valueBytes = c.Last()
+		} else {
+			keyBytes, valueBytes = c.Prev()
+		}
+	}
+	for keyBytes != nil && bytes.HasPrefix(keyBytes, group) && (valueBytes == nil || bytes.Equal(keyBytes, excludedKey)) {
+		keyBytes, valueBytes = c.Prev()
+	}
+	if keyBytes != nil && bytes.HasPrefix(keyBytes, group) {
+		return keyBytes, valueBytes
+	}
+
+	// the prefix itself sorts before all keys that continue with the separator
+	if bytes.Equal(prefix, excludedKey) {
+		return nil, nil
+	}
+	valueBytes = b.Get(prefix)
+	if valueBytes == nil {
+		return nil, nil
+	}
+	return prefix, valueBytes
+}
+
+// updateViews updates the maintained views of the bucket bucketName for keyBytes changing its value from oldValue to newValue. A nil value means the key did not exist before or does not exist anymore.
+// It has to be called in the same read-write transaction before the key is written, Journal does for every writer.
+func updateViews(tx *bolt.Tx, bucketName string, keyBytes []byte, oldValue []byte, newValue []byte) error {
+	if isViewBucket(bucketName) {
+		return fmt.Errorf("Bucket %v holds materialized views and cannot be written\n", bucketName)
+	}
+	for _, definition := range maintainedViewsOf(tx) {
+		if definition.Bucket != bucketName {
+			continue
+		}
+		viewsBucket, err := tx.CreateBucketIfNotExists([]byte(ViewBucket))
+		if err != nil {
+			return fmt.Errorf("Failed to create bucket %v: %v\n", ViewBucket, err)
+		}
+		viewBucket, err := viewsBucket.CreateBucketIfNotExists([]byte(definition.Name))
+		if err != nil {
+			return fmt.Errorf("Failed to create view %v: %v\n", definition.Name, err)
+		}
+		prefix := definition.prefix(keyBytes)
+
+		switch definition.Kind {
+		case ViewCount:
+			delta := 0
+			if oldValue == nil && newValue != nil {
+				delta = 1
+			} else if oldValue != nil && newValue == nil {
+				delta = -1
+			}
+			if delta == 0 {
+				continue
+			}
+			count, _ := strconv.Atoi(string(viewBucket.Get(prefix)))
+			count += delta
+			if count <= 0 {
+				err = viewBucket.Delete(prefix)
+			} else {
+				err = viewBucket.Put(prefix, []byte(strconv.Itoa(count)))
+			}
+
+		case ViewLatest:
+			// the key is not written yet, so the bucket still holds what was there before
+			b := ResolveBucket(tx, bucketName)
+			if b == nil {
+				continue
+			}
+			latestKey, latestValue := latestOfPrefix(b, prefix, definition.Separator, keyBytes)
+			switch {
+			case newValue != nil && (latestKey == nil || bytes.Compare(keyBytes, latestKey) > 0):
+				err = viewBucket.Put(prefix, newValue)
+			case latestKey == nil:
+				err = viewBucket.Delete(prefix)
+			default:
+				// copy, bolt keeps referencing the slice until the transaction is committed
+				err = viewBucket.Put(prefix, append([]byte{}, latestValue...))
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to update view %v: %v\n", definition.Name, err)
+		}
+	}
+	return nil
+}
+
+// RefreshView takes the path to a bbolt database and computes the view definition from scratch within one transaction by reading every entry of its bucket, and returns its ViewInfo along with an error.
+// Expired keys are left out. It fails with ErrBucketNotFound if the bucket does not exist.
+func RefreshView(dbPath string, definition ViewDefinition) (ViewInfo, error) {
+	err := definition.Validate()
+	if err != nil {
+		return ViewInfo{}, err
+	}
+
+	// open database
+	dbInstance, closeDb, err := OpenDbForWriting(dbPath)
+	if err != nil {
+		return ViewInfo{}, err
+	}
+	defer closeDb()
+
+	viewInfo := ViewInfo{ViewDefinition: definition}
+	err = dbInstance.Update(func(tx *bolt.Tx) error {
+		if ResolveBucket(tx, definition.Bucket) == nil {
+			return ErrBucketNotFound
+		}
+		keys, err := computeView(tx, definition)
+		viewInfo.Keys = keys
+		return err
+	})
+	if errors.Is(err, ErrBucketNotFound) {
+		return ViewInfo{}, err
+	}
+	if err != nil {
+		return ViewInfo{}, fmt.Errorf("Failed to refresh view %v due to error: %v\n", definition.Name, err)
+	}
+	return viewInfo, nil
+}
+
+// computeView computes the view definition from scratch within tx and returns its amount of keys. The view is empty if its bucket does not exist.
+func computeView(tx *bolt.Tx, definition ViewDefinition) (int, error) {
+	viewsBucket, err := tx.CreateBucketIfNotExists([]byte(ViewBucket))
+	if err != nil {
+		return 0, fmt.Errorf("Failed to create bucket %v: %v\n", ViewBucket, err)
+	}
+	if viewsBucket.Bucket([]byte(definition.Name)) != nil {
+		err = viewsBucket.DeleteBucket([]byte(definition.Name))
+		if err != nil {
+			return 0, err
+		}
+	}
+	viewBucket, err := viewsBucket.CreateBucket([]byte(definition.Name))
+	if err != nil {
+		return 0, err
+	}
+
+	// creating the view may have touched the parent of the bucket, so look it up now
+	b := ResolveBucket(tx, definition.Bucket)
+	if b == nil {
+		return 0, nil
+	}
+
+	// keys are read in order, so the last key seen per prefix is the latest one
+	checker := newExpiryChecker(tx)
+	counts := make(map[string]int)
+	latestKeys := make(map[string][]byte)
+	err = b.ForEach(func(keyBytes []byte, valueBytes []byte) error {
+		if valueBytes == nil || checker.isExpired([]byte(definition.Bucket), keyBytes) {
+			return nil
+		}
+		prefix := string(definition.prefix(keyBytes))
+		counts[prefix]++
+		latestKeys[prefix] = keyBytes
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for prefix, count := range counts {
+		viewValue := []byte(strconv.Itoa(count))
+		if definition.Kind == ViewLatest {
+			// copy, bolt keeps referencing the slice until the transaction is committed
+			viewValue = append([]byte{}, b.Get(latestKeys[prefix])...)
+		}
+		err = viewBucket.Put([]byte(prefix), viewValue)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(counts), nil
+}
+
+// computeRenamedViews computes the maintained views of the buckets at bucketPath and toPath and below them from scratch within tx, after the bucket at bucketPath was renamed to toPath by copying its entries without Journal.
+func computeRenamedViews(tx *bolt.Tx, bucketPath string, toPath string) error {
+	for _, definition := range maintainedViewsOf(tx) {
+		if !isSameOrNestedBucket(definition.Bucket, bucketPath) && !isSameOrNestedBucket(definition.Bucket, toPath) {
+			continue
+		}
+		_, err := computeView(tx, definition)
+		if err != nil {
+			return fmt.Errorf("Failed to update view %v: %v\n", definition.Name, err)
+		}
+	}
+	return nil
+}
+
+// isSameOrNestedBucket reports whether the bucket path bucketName is parentPath or one of the buckets nested in it.
+func isSameOrNestedBucket(bucketName string, parentPath string) bool {
+	return bucketName == parentPath || strings.HasPrefix(bucketName, parentPath+"/")
+}
@@ -0,0 +1,160 @@
+package bboltdump
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// WalkBucket calls fn for every key-value pair of b and of all buckets nested in it.
+// path is the slash separated path of b below its top level bucket, it is empty for the top level bucket itself.
+func WalkBucket(b *bolt.Bucket, path string, fn func(path string, keyBytes []byte, valueBytes []byte) error) error {
+	return b.ForEach(func(keyBytes []byte, valueBytes []byte) error {
+		if valueBytes != nil {
+			return fn(path, keyBytes, valueBytes)
+		}
+
+		// nil value means the key is a nested bucket
+		nestedPath := string(keyBytes)
+		if path != "" {
+			nestedPath = path + "/" + nestedPath
+		}
+		return WalkBucket(b.Bucket(keyBytes), nestedPath, fn)
+	})
+}
+
+// NdjsonEntry is a struct representing one line of a newline delimited JSON dump.
+type NdjsonEntry struct {
+	Bucket string `json:"bucket"` // name of the bucket the entry belongs to
+	Key    string `json:"key"`    // key as RenderKey renders it
+	Value  string `json:"value"`  // value as string
+}
+
+// ForEachEntry calls fn for every key-value pair of the bucket bucketName, or of all top level buckets but the meta buckets if bucketName is empty. bucketName may be the path of a nested bucket like "config/devices" or a meta bucket like TtlBucket. Nested buckets and expired keys are skipped like in GetDbContentAsJson.
+func ForEachEntry(dbPath string, bucketName string, fn func(bucketName string, keyBytes []byte, valueBytes []byte) error) error {
+	return ForEachEntryAfter(dbPath, bucketName, "", nil, time.Now(), fn)
+}
+
+// ForEachEntryAfter is ForEachEntry for the entries that come after the key afterKey of the bucket afterBucket, in the order ForEachEntry visits them. An empty afterBucket starts at the first entry.
+// Keys count as expired if they had expired at the time at, so the same entries are visited every time a copy of a database is walked, no matter when.
+func ForEachEntryAfter(dbPath string, bucketName string, afterBucket string, afterKey []byte, at time.Time, fn func(bucketName string, keyBytes []byte, valueBytes []byte) error) error {
+	return forEachEntry(dbPath, bucketName, afterBucket, afterKey, nil, at, fn)
+}
+
+// ForEachEntryWithPrefix is ForEachEntry for the keys that start with prefix. The cursor seeks to prefix and stops at the first key past it, so keys before and after are not visited at all.
+func ForEachEntryWithPrefix(dbPath string, bucketName string, prefix []byte, fn func(bucketName string, keyBytes []byte, valueBytes []byte) error) error {
+	return forEachEntry(dbPath, bucketName, "", nil, prefix, time.Now(), fn)
+}
+
+// forEachEntry is ForEachEntryAfter for the keys that start with prefix, all keys if prefix is empty.
+func forEachEntry(dbPath string, bucketName string, afterBucket string, afterKey []byte, prefix []byte, at time.Time, fn func(bucketName string, keyBytes []byte, valueBytes []byte) error) error {
+	// open database
+	dbInstance, closeDb, err := OpenDb(dbPath)
+	if err != nil {
+		return err
+	}
+	defer closeDb()
+
+	return dbInstance.View(func(tx *bolt.Tx) error {
+		checker := newExpiryChecker(tx)
+		if checker != nil {
+			checker.now = at
+		}
+		// visits the values of b after the key startAfter, all of them if startAfter is nil
+		forEachValue := func(currentBucketName []byte, b *bolt.Bucket, startAfter []byte) error {
+			cursor := b.Cursor()
+			keyBytes, valueBytes := cursor.First()
+			if len(prefix) > 0 {
+				keyBytes, valueBytes = cursor.Seek(prefix)
+			}
+			if startAfter != nil && bytes.Compare(startAfter, keyBytes) >= 0 {
+				keyBytes, valueBytes = cursor.Seek(startAfter)
+				if bytes.Equal(keyBytes, startAfter) {
+					keyBytes, valueBytes = cursor.Next()
+				}
+			}
+			for ; keyBytes != nil && bytes.HasPrefix(keyBytes, prefix); keyBytes, valueBytes = cursor.Next() {
+				if valueBytes == nil || checker.isExpired(currentBucketName, keyBytes) {
+					continue
+				}
+				err := fn(string(currentBucketName), keyBytes, valueBytes)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if bucketName != "" {
+			b := ResolveBucket(tx, bucketName)
+			if b == nil {
+				return nil
+			}
+			var startAfter []byte
+			if afterBucket == bucketName {
+				startAfter = afterKey
+			}
+			return forEachValue([]byte(bucketName), b, startAfter)
+		}
+
+		// the top level buckets are the keys of the root bucket, so the walk can start at afterBucket
+		cursor := tx.Cursor()
+		currentBucketName, _ := cursor.First()
+		if afterBucket != "" {
+			currentBucketName, _ = cursor.Seek([]byte(afterBucket))
+		}
+		for ; currentBucketName != nil; currentBucketName, _ = cursor.Next() {
+			if IsMetaBucket(string(currentBucketName)) {
+				continue
+			}
+			var startAfter []byte
+			if afterBucket != "" && string(currentBucketName) == afterBucket {
+				startAfter = afterKey
+			}
+			err := forEachValue(currentBucketName, tx.Bucket(currentBucketName), startAfter)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Dump writes the content of the database at dbPath to out, either as a single BboltDb JSON object like GetDbContentAsJson ("json") or as one NdjsonEntry per line ("ndjson").
+// If bucketName is not empty only that bucket is written. keys is one of the Keys modes, KeysTime writes the keys like KeysText.
+func Dump(out io.Writer, dbPath string, bucketName string, format string, keys string) error {
+	switch format {
+	case "json":
+		bboltDbObject := BboltDb{
+			Path:    dbPath,
+			Buckets: make(map[string]map[string]string),
+		}
+		err := ForEachEntry(dbPath, bucketName, func(currentBucketName string, keyBytes []byte, valueBytes []byte) error {
+			if bboltDbObject.Buckets[currentBucketName] == nil {
+				bboltDbObject.Buckets[currentBucketName] = make(map[string]string)
+			}
+			bboltDbObject.Buckets[currentBucketName][RenderKey(keyBytes, keys)] = string(valueBytes)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(out).Encode(bboltDbObject)
+
+	case "ndjson":
+		// entries are written as they are read, so the dump never has to fit into memory
+		encoder := json.NewEncoder(out)
+		return ForEachEntry(dbPath, bucketName, func(currentBucketName string, keyBytes []byte, valueBytes []byte) error {
+			return encoder.Encode(NdjsonEntry{
+				Bucket: currentBucketName,
+				Key:    RenderKey(keyBytes, keys),
+				Value:  string(valueBytes),
+			})
+		})
+	}
+	return fmt.Errorf("Unsupported format %v, use json or ndjson\n", format)
+}
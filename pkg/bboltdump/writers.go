@@ -0,0 +1,62 @@
+package bboltdump
+
+import (
+	"os"
+	"time"
+)
+
+// RecentWriteWindow is how recently the file must have been modified for the writer to count as active.
+const RecentWriteWindow = 10 * time.Second
+
+// WriterActivity is a struct representing whether someone else appears to be writing a database while it is dumped, which makes the dump stale as soon as it is finished.
+type WriterActivity struct {
+	Active      bool      `json:"active"`                // another writer appears to be writing the database right now
+	TxId        uint64    `json:"txId"`                  // id of the last transaction committed when the dump began, 0 if the file could not be read directly
+	Changed     bool      `json:"changed"`               // transactions were committed while the dump ran, so its buckets are from different versions of the database
+	LockHolders []string  `json:"lockHolders,omitempty"` // other processes that held a lock on the file right before it was opened, only known on Linux
+	ModifiedAt  time.Time `json:"modifiedAt"`            // last modification of the file
+}
+
+// probeWriters looks at the file dbPath before it is opened: who else holds a lock on it, when it was last modified and which transaction was committed last.
+// It returns nil for files that are not local databases, like gzipped ones.
+func probeWriters(dbPath string) *WriterActivity {
+	fileInfo, err := os.Stat(dbPath)
+	if err != nil {
+		return nil
+	}
+	txId, err := ReadTxId(dbPath)
+	if err != nil {
+		return nil
+	}
+	return &WriterActivity{
+		TxId:        txId,
+		LockHolders: lockingProcesses(dbPath),
+		ModifiedAt:  fileInfo.ModTime().UTC(),
+	}
+}
+
+// ProbeWriters returns whether someone else appears to be writing the database at dbPath right now, judged by the locks on it and its last modification like for a dump. It returns nil for files that are not local databases.
+func ProbeWriters(dbPath string) *WriterActivity {
+	writerActivity := probeWriters(dbPath)
+	if writerActivity != nil {
+		writerActivity.observe(writerActivity.TxId)
+	}
+	return writerActivity
+}
+
+// observe records that a read transaction of the dump saw the database at txId and updates Active.
+func (a *WriterActivity) observe(txId uint64) {
+	if txId != a.TxId {
+		a.Changed = true
+	}
+	a.Active = a.Changed || len(a.LockHolders) > 0 || time.Since(a.ModifiedAt) < RecentWriteWindow
+}
+
+// ReadTxId returns the id of the last transaction committed to the database at dbPath by reading its meta pages, so unlike opening it with bolt it never waits for the file lock.
+func ReadTxId(dbPath string) (uint64, error) {
+	metaPages, err := ReadMetaPages(dbPath)
+	if err != nil {
+		return 0, err
+	}
+	return currentMetaPage(metaPages).TxId, nil
+}
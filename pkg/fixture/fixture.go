@@ -0,0 +1,109 @@
+// Package fixture creates throwaway bbolt databases from JSON fixtures, so tests of apps that talk to the API or read bbolt files do not have to ship binary .db files.
+package fixture
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/downIoads/go-bbolt-apiEndpoint/pkg/bboltdump"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	KeysHex  = "hex"  // the keys of a fixture are hex encoded like in a dump with hex keys, the default
+	KeysText = "text" // the keys of a fixture are the keys themselves, binary keys and keys starting with "0x" hex encoded with "0x" in front like bboltdump.KeyText writes them
+)
+
+// Fixture is a struct representing the content of a database. The full dump of a database is a valid fixture, so a fixture can be taken of any database.
+type Fixture struct {
+	Keys    string                       `json:"keys"`    // KeysHex (default) or KeysText
+	Buckets map[string]map[string]string `json:"buckets"` // key-value pairs per bucket, nested buckets by their path like "config/devices". A bucket without pairs is created empty
+}
+
+// Load reads the fixture at fixturePath.
+func Load(fixturePath string) (Fixture, error) {
+	var fixture Fixture
+	fixtureBytes, err := os.ReadFile(fixturePath)
+	if err != nil {
+		return fixture, fmt.Errorf("Failed to read fixture: %v\n", err)
+	}
+	err = json.Unmarshal(fixtureBytes, &fixture)
+	if err != nil {
+		return fixture, fmt.Errorf("Failed to parse fixture %v: %v\n", fixturePath, err)
+	}
+	if fixture.Keys != "" && fixture.Keys != KeysHex && fixture.Keys != KeysText {
+		return fixture, fmt.Errorf("Fixture %v has unknown keys %v, use hex or text\n", fixturePath, fixture.Keys)
+	}
+	return fixture, nil
+}
+
+// Create creates the database dbPath with the content of fixture. It fails if dbPath exists, so a fixture never overwrites a real database.
+func Create(dbPath string, fixture Fixture) error {
+	file, err := os.OpenFile(dbPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("Failed to create database %v: %v\n", dbPath, err)
+	}
+	file.Close()
+	return populate(dbPath, fixture)
+}
+
+// CreateTemp creates a database with the content of fixture in the directory dir like os.CreateTemp and returns its path. The caller removes the file once it is done with it.
+func CreateTemp(dir string, pattern string, fixture Fixture) (string, error) {
+	file, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create database: %v\n", err)
+	}
+	file.Close()
+	err = populate(file.Name(), fixture)
+	if err != nil {
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// populate writes fixture to the new and empty database dbPath in one transaction, and removes the file if that fails.
+func populate(dbPath string, fixture Fixture) error {
+	dbInstance, err := bolt.Open(dbPath, 0600, nil)
+	if err != nil {
+		os.Remove(dbPath)
+		return fmt.Errorf("Failed to open database %v: %v\n", dbPath, err)
+	}
+	err = dbInstance.Update(func(tx *bolt.Tx) error {
+		for bucketPath, pairs := range fixture.Buckets {
+			b, err := bboltdump.CreateBucketPath(tx, bucketPath)
+			if err != nil {
+				return err
+			}
+			for key, value := range pairs {
+				var keyBytes []byte
+				if fixture.Keys == KeysText {
+					keyBytes, err = bboltdump.ParseKeyText(key)
+					if err != nil {
+						return fmt.Errorf("Key %q of bucket %v starts with 0x but is not hex encoded\n", key, bucketPath)
+					}
+				} else {
+					keyBytes, err = hex.DecodeString(key)
+					if err != nil {
+						return fmt.Errorf("Key %q of bucket %v is not hex encoded\n", key, bucketPath)
+					}
+				}
+				err = b.Put(keyBytes, []byte(value))
+				if err != nil {
+					return fmt.Errorf("Failed to write key %q of bucket %v: %v\n", key, bucketPath, err)
+				}
+			}
+		}
+		return nil
+	})
+	closeErr := dbInstance.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dbPath)
+		return fmt.Errorf("Failed to populate database %v from fixture: %v\n", dbPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+// swift-tools-version:5.5
+import PackageDescription
+
+let package = Package(
+    name: "BboltClient",
+    platforms: [.macOS(.v12), .iOS(.v15)],
+    products: [
+        .library(name: "BboltClient", targets: ["BboltClient"]),
+    ],
+    targets: [
+        .target(name: "BboltClient"),
+    ]
+)
@@ -0,0 +1,1796 @@
+// Code generated by "bbolt-apiEndpoint sdk --lang swift"; DO NOT EDIT.
+
+import Foundation
+#if canImport(FoundationNetworking)
+import FoundationNetworking
+#endif
+
+/// Error thrown when the server answers with a status other than 200, message is the body of the response.
+public struct BboltError: Error {
+    public let status: Int
+    public let message: String
+}
+
+public struct DumpRequestPayload: Codable {
+    public var input: String?
+    public var decryptionKey: String?
+    public var keyId: String?
+    public var include: [String]?
+    public var exclude: [String]?
+    public var maxDepth: Int?
+    public var maxKeysPerBucket: Int?
+    public var values: String?
+    public var workers: Int?
+    public var keys: String?
+    public var keySeparator: String?
+    public var noTransform: Bool?
+    public var internal: Bool?
+    public var partial: Bool?
+
+    public init(input: String? = nil, decryptionKey: String? = nil, keyId: String? = nil, include: [String]? = nil, exclude: [String]? = nil, maxDepth: Int? = nil, maxKeysPerBucket: Int? = nil, values: String? = nil, workers: Int? = nil, keys: String? = nil, keySeparator: String? = nil, noTransform: Bool? = nil, internal: Bool? = nil, partial: Bool? = nil) {
+        self.input = input
+        self.decryptionKey = decryptionKey
+        self.keyId = keyId
+        self.include = include
+        self.exclude = exclude
+        self.maxDepth = maxDepth
+        self.maxKeysPerBucket = maxKeysPerBucket
+        self.values = values
+        self.workers = workers
+        self.keys = keys
+        self.keySeparator = keySeparator
+        self.noTransform = noTransform
+        self.internal = internal
+        self.partial = partial
+    }
+}
+
+public struct BboltDb: Codable {
+    public var path: String
+    public var buckets: [String: [String: String]]?
+    public var truncated: [String]?
+    public var timedOut: [String]?
+    public var decryptionFailed: [String: [String]]?
+    public var transformFailed: [String: [String]]?
+    public var keyTimes: [String: [String: String]]?
+    public var bucketErrors: [String: String]?
+    public var writers: WriterActivity?
+
+    public init(path: String, buckets: [String: [String: String]]? = nil, truncated: [String]? = nil, timedOut: [String]? = nil, decryptionFailed: [String: [String]]? = nil, transformFailed: [String: [String]]? = nil, keyTimes: [String: [String: String]]? = nil, bucketErrors: [String: String]? = nil, writers: WriterActivity? = nil) {
+        self.path = path
+        self.buckets = buckets
+        self.truncated = truncated
+        self.timedOut = timedOut
+        self.decryptionFailed = decryptionFailed
+        self.transformFailed = transformFailed
+        self.keyTimes = keyTimes
+        self.bucketErrors = bucketErrors
+        self.writers = writers
+    }
+}
+
+public struct WriterActivity: Codable {
+    public var active: Bool
+    public var txId: Int
+    public var changed: Bool
+    public var lockHolders: [String]?
+    public var modifiedAt: String
+
+    public init(active: Bool, txId: Int, changed: Bool, lockHolders: [String]? = nil, modifiedAt: String) {
+        self.active = active
+        self.txId = txId
+        self.changed = changed
+        self.lockHolders = lockHolders
+        self.modifiedAt = modifiedAt
+    }
+}
+
+public struct PageRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+    public var limit: Int?
+    public var cursor: String?
+    public var order: String?
+    public var values: String?
+    public var keys: String?
+
+    public init(input: String? = nil, bucket: String? = nil, limit: Int? = nil, cursor: String? = nil, order: String? = nil, values: String? = nil, keys: String? = nil) {
+        self.input = input
+        self.bucket = bucket
+        self.limit = limit
+        self.cursor = cursor
+        self.order = order
+        self.values = values
+        self.keys = keys
+    }
+}
+
+public struct BucketPage: Codable {
+    public var bucket: String
+    public var entries: [Entry]?
+    public var nextCursor: String?
+
+    public init(bucket: String, entries: [Entry]? = nil, nextCursor: String? = nil) {
+        self.bucket = bucket
+        self.entries = entries
+        self.nextCursor = nextCursor
+    }
+}
+
+public struct Entry: Codable {
+    public var key: String
+    public var keyHex: String?
+    public var keyTime: String?
+    public var value: String
+    public var size: Int?
+    public var contentType: String?
+    public var sha256: String?
+
+    public init(key: String, keyHex: String? = nil, keyTime: String? = nil, value: String, size: Int? = nil, contentType: String? = nil, sha256: String? = nil) {
+        self.key = key
+        self.keyHex = keyHex
+        self.keyTime = keyTime
+        self.value = value
+        self.size = size
+        self.contentType = contentType
+        self.sha256 = sha256
+    }
+}
+
+public struct SeekRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+    public var key: String?
+    public var count: Int?
+    public var values: String?
+    public var keys: String?
+
+    public init(input: String? = nil, bucket: String? = nil, key: String? = nil, count: Int? = nil, values: String? = nil, keys: String? = nil) {
+        self.input = input
+        self.bucket = bucket
+        self.key = key
+        self.count = count
+        self.values = values
+        self.keys = keys
+    }
+}
+
+public struct TailRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+    public var count: Int?
+    public var values: String?
+    public var keys: String?
+
+    public init(input: String? = nil, bucket: String? = nil, count: Int? = nil, values: String? = nil, keys: String? = nil) {
+        self.input = input
+        self.bucket = bucket
+        self.count = count
+        self.values = values
+        self.keys = keys
+    }
+}
+
+public struct SampleRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+    public var size: Int?
+    public var keys: String?
+
+    public init(input: String? = nil, bucket: String? = nil, size: Int? = nil, keys: String? = nil) {
+        self.input = input
+        self.bucket = bucket
+        self.size = size
+        self.keys = keys
+    }
+}
+
+public struct BucketSample: Codable {
+    public var bucket: String
+    public var entries: [Entry]?
+    public var total: Int
+
+    public init(bucket: String, entries: [Entry]? = nil, total: Int) {
+        self.bucket = bucket
+        self.entries = entries
+        self.total = total
+    }
+}
+
+public struct BatchGetRequestPayload: Codable {
+    public var input: String?
+    public var lookups: [KeyRefPayload]?
+    public var buckets: [String]?
+    public var key: String?
+    public var values: String?
+    public var keys: String?
+
+    public init(input: String? = nil, lookups: [KeyRefPayload]? = nil, buckets: [String]? = nil, key: String? = nil, values: String? = nil, keys: String? = nil) {
+        self.input = input
+        self.lookups = lookups
+        self.buckets = buckets
+        self.key = key
+        self.values = values
+        self.keys = keys
+    }
+}
+
+public struct KeyRefPayload: Codable {
+    public var bucket: String?
+    public var key: String?
+
+    public init(bucket: String? = nil, key: String? = nil) {
+        self.bucket = bucket
+        self.key = key
+    }
+}
+
+public struct BatchEntry: Codable {
+    public var bucket: String
+    public var found: Bool
+    public var key: String
+    public var keyHex: String?
+    public var keyTime: String?
+    public var value: String
+    public var size: Int?
+    public var contentType: String?
+    public var sha256: String?
+
+    public init(bucket: String, found: Bool, key: String, keyHex: String? = nil, keyTime: String? = nil, value: String, size: Int? = nil, contentType: String? = nil, sha256: String? = nil) {
+        self.bucket = bucket
+        self.found = found
+        self.key = key
+        self.keyHex = keyHex
+        self.keyTime = keyTime
+        self.value = value
+        self.size = size
+        self.contentType = contentType
+        self.sha256 = sha256
+    }
+}
+
+public struct ExistsRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+    public var key: String?
+
+    public init(input: String? = nil, bucket: String? = nil, key: String? = nil) {
+        self.input = input
+        self.bucket = bucket
+        self.key = key
+    }
+}
+
+public struct ScanRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+    public var match: String?
+    public var where: String?
+    public var limit: Int?
+    public var values: String?
+    public var keys: String?
+
+    public init(input: String? = nil, bucket: String? = nil, match: String? = nil, where: String? = nil, limit: Int? = nil, values: String? = nil, keys: String? = nil) {
+        self.input = input
+        self.bucket = bucket
+        self.match = match
+        self.where = where
+        self.limit = limit
+        self.values = values
+        self.keys = keys
+    }
+}
+
+public struct ScanResult: Codable {
+    public var entries: [ScanEntry]?
+    public var scanned: Int
+    public var truncated: Bool
+    public var timedOut: Bool
+
+    public init(entries: [ScanEntry]? = nil, scanned: Int, truncated: Bool, timedOut: Bool) {
+        self.entries = entries
+        self.scanned = scanned
+        self.truncated = truncated
+        self.timedOut = timedOut
+    }
+}
+
+public struct ScanEntry: Codable {
+    public var bucket: String
+    public var key: String
+    public var keyHex: String?
+    public var keyTime: String?
+    public var value: String
+    public var size: Int?
+    public var contentType: String?
+    public var sha256: String?
+
+    public init(bucket: String, key: String, keyHex: String? = nil, keyTime: String? = nil, value: String, size: Int? = nil, contentType: String? = nil, sha256: String? = nil) {
+        self.bucket = bucket
+        self.key = key
+        self.keyHex = keyHex
+        self.keyTime = keyTime
+        self.value = value
+        self.size = size
+        self.contentType = contentType
+        self.sha256 = sha256
+    }
+}
+
+public struct JoinRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+    public var joinBucket: String?
+    public var ref: String?
+    public var limit: Int?
+    public var cursor: String?
+    public var values: String?
+    public var keys: String?
+
+    public init(input: String? = nil, bucket: String? = nil, joinBucket: String? = nil, ref: String? = nil, limit: Int? = nil, cursor: String? = nil, values: String? = nil, keys: String? = nil) {
+        self.input = input
+        self.bucket = bucket
+        self.joinBucket = joinBucket
+        self.ref = ref
+        self.limit = limit
+        self.cursor = cursor
+        self.values = values
+        self.keys = keys
+    }
+}
+
+public struct JoinPage: Codable {
+    public var bucket: String
+    public var joinBucket: String
+    public var entries: [JoinedEntry]?
+    public var nextCursor: String?
+
+    public init(bucket: String, joinBucket: String, entries: [JoinedEntry]? = nil, nextCursor: String? = nil) {
+        self.bucket = bucket
+        self.joinBucket = joinBucket
+        self.entries = entries
+        self.nextCursor = nextCursor
+    }
+}
+
+public struct JoinedEntry: Codable {
+    public var key: String
+    public var keyHex: String?
+    public var keyTime: String?
+    public var value: String
+    public var size: Int?
+    public var contentType: String?
+    public var sha256: String?
+    public var ref: String?
+    public var joined: Entry?
+
+    public init(key: String, keyHex: String? = nil, keyTime: String? = nil, value: String, size: Int? = nil, contentType: String? = nil, sha256: String? = nil, ref: String? = nil, joined: Entry? = nil) {
+        self.key = key
+        self.keyHex = keyHex
+        self.keyTime = keyTime
+        self.value = value
+        self.size = size
+        self.contentType = contentType
+        self.sha256 = sha256
+        self.ref = ref
+        self.joined = joined
+    }
+}
+
+public struct LookupRequestPayload: Codable {
+    public var input: String?
+    public var index: String?
+    public var value: String?
+    public var limit: Int?
+    public var values: String?
+    public var keys: String?
+
+    public init(input: String? = nil, index: String? = nil, value: String? = nil, limit: Int? = nil, values: String? = nil, keys: String? = nil) {
+        self.input = input
+        self.index = index
+        self.value = value
+        self.limit = limit
+        self.values = values
+        self.keys = keys
+    }
+}
+
+public struct IndexLookup: Codable {
+    public var index: String
+    public var bucket: String
+    public var entries: [Entry]?
+    public var truncated: Bool
+
+    public init(index: String, bucket: String, entries: [Entry]? = nil, truncated: Bool) {
+        self.index = index
+        self.bucket = bucket
+        self.entries = entries
+        self.truncated = truncated
+    }
+}
+
+public struct AnalyzeRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+    public var sampleSize: Int?
+
+    public init(input: String? = nil, bucket: String? = nil, sampleSize: Int? = nil) {
+        self.input = input
+        self.bucket = bucket
+        self.sampleSize = sampleSize
+    }
+}
+
+public struct DbAnalysis: Codable {
+    public var buckets: [BucketAnalysis]?
+
+    public init(buckets: [BucketAnalysis]? = nil) {
+        self.buckets = buckets
+    }
+}
+
+public struct BucketAnalysis: Codable {
+    public var bucket: String
+    public var keys: Int
+    public var sampled: Int
+    public var valueFormats: [String: Int]?
+    public var jsonFields: [String: Int]?
+    public var keyPatterns: [String: Int]?
+    public var keySizes: SizeStats
+    public var valueSizes: SizeStats
+
+    public init(bucket: String, keys: Int, sampled: Int, valueFormats: [String: Int]? = nil, jsonFields: [String: Int]? = nil, keyPatterns: [String: Int]? = nil, keySizes: SizeStats, valueSizes: SizeStats) {
+        self.bucket = bucket
+        self.keys = keys
+        self.sampled = sampled
+        self.valueFormats = valueFormats
+        self.jsonFields = jsonFields
+        self.keyPatterns = keyPatterns
+        self.keySizes = keySizes
+        self.valueSizes = valueSizes
+    }
+}
+
+public struct SizeStats: Codable {
+    public var min: Int
+    public var max: Int
+    public var mean: Double
+    public var p50: Int
+    public var p90: Int
+    public var p99: Int
+    public var total: Int
+
+    public init(min: Int, max: Int, mean: Double, p50: Int, p90: Int, p99: Int, total: Int) {
+        self.min = min
+        self.max = max
+        self.mean = mean
+        self.p50 = p50
+        self.p90 = p90
+        self.p99 = p99
+        self.total = total
+    }
+}
+
+public struct KeyspaceRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+    public var separator: String?
+    public var depth: Int?
+    public var maxChildren: Int?
+    public var keys: String?
+
+    public init(input: String? = nil, bucket: String? = nil, separator: String? = nil, depth: Int? = nil, maxChildren: Int? = nil, keys: String? = nil) {
+        self.input = input
+        self.bucket = bucket
+        self.separator = separator
+        self.depth = depth
+        self.maxChildren = maxChildren
+        self.keys = keys
+    }
+}
+
+public struct KeyspaceTree: Codable {
+    public var root: KeyspaceNode?
+    public var timedOut: Bool
+
+    public init(root: KeyspaceNode? = nil, timedOut: Bool) {
+        self.root = root
+        self.timedOut = timedOut
+    }
+}
+
+public struct KeyspaceNode: Codable {
+    public var name: String
+    public var kind: String
+    public var keys: Int
+    public var keyBytes: Int
+    public var valueBytes: Int
+    public var children: [KeyspaceNode]?
+
+    public init(name: String, kind: String, keys: Int, keyBytes: Int, valueBytes: Int, children: [KeyspaceNode]? = nil) {
+        self.name = name
+        self.kind = kind
+        self.keys = keys
+        self.keyBytes = keyBytes
+        self.valueBytes = valueBytes
+        self.children = children
+    }
+}
+
+public struct HistogramRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+
+    public init(input: String? = nil, bucket: String? = nil) {
+        self.input = input
+        self.bucket = bucket
+    }
+}
+
+public struct BucketHistogram: Codable {
+    public var bucket: String
+    public var keys: Int
+    public var keyBytes: Int
+    public var valueBytes: Int
+    public var allocatedBytes: Int
+    public var keyLengths: [HistogramBin]?
+    public var valueSizes: [HistogramBin]?
+
+    public init(bucket: String, keys: Int, keyBytes: Int, valueBytes: Int, allocatedBytes: Int, keyLengths: [HistogramBin]? = nil, valueSizes: [HistogramBin]? = nil) {
+        self.bucket = bucket
+        self.keys = keys
+        self.keyBytes = keyBytes
+        self.valueBytes = valueBytes
+        self.allocatedBytes = allocatedBytes
+        self.keyLengths = keyLengths
+        self.valueSizes = valueSizes
+    }
+}
+
+public struct HistogramBin: Codable {
+    public var min: Int
+    public var max: Int
+    public var count: Int
+    public var bytes: Int
+
+    public init(min: Int, max: Int, count: Int, bytes: Int) {
+        self.min = min
+        self.max = max
+        self.count = count
+        self.bytes = bytes
+    }
+}
+
+public struct LargestRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+    public var count: Int?
+    public var keys: String?
+
+    public init(input: String? = nil, bucket: String? = nil, count: Int? = nil, keys: String? = nil) {
+        self.input = input
+        self.bucket = bucket
+        self.count = count
+        self.keys = keys
+    }
+}
+
+public struct LargeValue: Codable {
+    public var bucket: String
+    public var key: String
+    public var size: Int
+
+    public init(bucket: String, key: String, size: Int) {
+        self.bucket = bucket
+        self.key = key
+        self.size = size
+    }
+}
+
+public struct DiffRequestPayload: Codable {
+    public var input: String?
+    public var other: String?
+    public var keys: String?
+
+    public init(input: String? = nil, other: String? = nil, keys: String? = nil) {
+        self.input = input
+        self.other = other
+        self.keys = keys
+    }
+}
+
+public struct DbDiff: Codable {
+    public var path: String
+    public var other: String
+    public var addedBuckets: [String]?
+    public var removedBuckets: [String]?
+    public var buckets: [String: BucketDiff]?
+
+    public init(path: String, other: String, addedBuckets: [String]? = nil, removedBuckets: [String]? = nil, buckets: [String: BucketDiff]? = nil) {
+        self.path = path
+        self.other = other
+        self.addedBuckets = addedBuckets
+        self.removedBuckets = removedBuckets
+        self.buckets = buckets
+    }
+}
+
+public struct BucketDiff: Codable {
+    public var added: [String]?
+    public var removed: [String]?
+    public var changed: [String]?
+
+    public init(added: [String]? = nil, removed: [String]? = nil, changed: [String]? = nil) {
+        self.added = added
+        self.removed = removed
+        self.changed = changed
+    }
+}
+
+public struct ExportRequestPayload: Codable {
+    public var input: String?
+    public var where: String?
+    public var internal: Bool?
+
+    public init(input: String? = nil, where: String? = nil, internal: Bool? = nil) {
+        self.input = input
+        self.where = where
+        self.internal = internal
+    }
+}
+
+public struct NdjsonExportRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+    public var where: String?
+    public var export: String?
+    public var keys: String?
+
+    public init(input: String? = nil, bucket: String? = nil, where: String? = nil, export: String? = nil, keys: String? = nil) {
+        self.input = input
+        self.bucket = bucket
+        self.where = where
+        self.export = export
+        self.keys = keys
+    }
+}
+
+public struct ShardExportRequestPayload: Codable {
+    public var input: String?
+    public var shards: Int?
+    public var partition: String?
+    public var where: String?
+
+    public init(input: String? = nil, shards: Int? = nil, partition: String? = nil, where: String? = nil) {
+        self.input = input
+        self.shards = shards
+        self.partition = partition
+        self.where = where
+    }
+}
+
+public struct ImportRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+    public var format: String?
+    public var source: String?
+    public var fillPercent: Double?
+    public var dryRun: Bool?
+
+    public init(input: String? = nil, bucket: String? = nil, format: String? = nil, source: String? = nil, fillPercent: Double? = nil, dryRun: Bool? = nil) {
+        self.input = input
+        self.bucket = bucket
+        self.format = format
+        self.source = source
+        self.fillPercent = fillPercent
+        self.dryRun = dryRun
+    }
+}
+
+public struct ImportResult: Codable {
+    public var path: String
+    public var bucket: String
+    public var imported: Int
+    public var dryRun: Bool
+    public var preview: Preview?
+
+    public init(path: String, bucket: String, imported: Int, dryRun: Bool, preview: Preview? = nil) {
+        self.path = path
+        self.bucket = bucket
+        self.imported = imported
+        self.dryRun = dryRun
+        self.preview = preview
+    }
+}
+
+public struct Preview: Codable {
+    public var keys: Int
+    public var bytesWritten: Int
+    public var bytesDeleted: Int
+
+    public init(keys: Int, bytesWritten: Int, bytesDeleted: Int) {
+        self.keys = keys
+        self.bytesWritten = bytesWritten
+        self.bytesDeleted = bytesDeleted
+    }
+}
+
+public struct MergeRequestPayload: Codable {
+    public var input: String?
+    public var other: String?
+    public var buckets: [String]?
+    public var policy: String?
+    public var bucketPolicies: [String: String]?
+    public var dryRun: Bool?
+
+    public init(input: String? = nil, other: String? = nil, buckets: [String]? = nil, policy: String? = nil, bucketPolicies: [String: String]? = nil, dryRun: Bool? = nil) {
+        self.input = input
+        self.other = other
+        self.buckets = buckets
+        self.policy = policy
+        self.bucketPolicies = bucketPolicies
+        self.dryRun = dryRun
+    }
+}
+
+public struct MergeResult: Codable {
+    public var path: String
+    public var other: String
+    public var buckets: [BucketMerge]?
+    public var batches: Int
+    public var dryRun: Bool
+    public var preview: Preview?
+
+    public init(path: String, other: String, buckets: [BucketMerge]? = nil, batches: Int, dryRun: Bool, preview: Preview? = nil) {
+        self.path = path
+        self.other = other
+        self.buckets = buckets
+        self.batches = batches
+        self.dryRun = dryRun
+        self.preview = preview
+    }
+}
+
+public struct BucketMerge: Codable {
+    public var bucket: String
+    public var policy: String
+    public var added: Int
+    public var overwritten: Int
+    public var skipped: Int
+    public var unchanged: Int
+
+    public init(bucket: String, policy: String, added: Int, overwritten: Int, skipped: Int, unchanged: Int) {
+        self.bucket = bucket
+        self.policy = policy
+        self.added = added
+        self.overwritten = overwritten
+        self.skipped = skipped
+        self.unchanged = unchanged
+    }
+}
+
+public struct MoveRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+    public var key: String?
+    public var toBucket: String?
+    public var toKey: String?
+    public var overwrite: Bool?
+    public var dryRun: Bool?
+
+    public init(input: String? = nil, bucket: String? = nil, key: String? = nil, toBucket: String? = nil, toKey: String? = nil, overwrite: Bool? = nil, dryRun: Bool? = nil) {
+        self.input = input
+        self.bucket = bucket
+        self.key = key
+        self.toBucket = toBucket
+        self.toKey = toKey
+        self.overwrite = overwrite
+        self.dryRun = dryRun
+    }
+}
+
+public struct PreviewResult: Codable {
+    public var preview: Preview?
+
+    public init(preview: Preview? = nil) {
+        self.preview = preview
+    }
+}
+
+public struct DeleteRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+    public var prefix: String?
+    public var start: String?
+    public var end: String?
+    public var dryRun: Bool?
+
+    public init(input: String? = nil, bucket: String? = nil, prefix: String? = nil, start: String? = nil, end: String? = nil, dryRun: Bool? = nil) {
+        self.input = input
+        self.bucket = bucket
+        self.prefix = prefix
+        self.start = start
+        self.end = end
+        self.dryRun = dryRun
+    }
+}
+
+public struct DeleteResult: Codable {
+    public var bucket: String
+    public var deleted: Int
+    public var dryRun: Bool
+    public var preview: Preview?
+
+    public init(bucket: String, deleted: Int, dryRun: Bool, preview: Preview? = nil) {
+        self.bucket = bucket
+        self.deleted = deleted
+        self.dryRun = dryRun
+        self.preview = preview
+    }
+}
+
+public struct SnapshotRequestPayload: Codable {
+    public var db: String?
+    public var snapshot: String?
+    public var keys: String?
+
+    public init(db: String? = nil, snapshot: String? = nil, keys: String? = nil) {
+        self.db = db
+        self.snapshot = snapshot
+        self.keys = keys
+    }
+}
+
+public struct Snapshot: Codable {
+    public var id: String
+    public var time: String
+    public var size: Int
+
+    public init(id: String, time: String, size: Int) {
+        self.id = id
+        self.time = time
+        self.size = size
+    }
+}
+
+public struct RequestPayload: Codable {
+    public var input: String?
+
+    public init(input: String? = nil) {
+        self.input = input
+    }
+}
+
+public struct Pin: Codable {
+    public var id: String
+    public var input: String
+    public var db: String
+    public var time: String
+    public var size: Int
+    public var expiresAt: String
+
+    public init(id: String, input: String, db: String, time: String, size: Int, expiresAt: String) {
+        self.id = id
+        self.input = input
+        self.db = db
+        self.time = time
+        self.size = size
+        self.expiresAt = expiresAt
+    }
+}
+
+public struct EmptyResult: Codable {
+
+    public init() {
+    }
+}
+
+public struct BackupRequestPayload: Codable {
+    public var db: String?
+    public var backup: String?
+
+    public init(db: String? = nil, backup: String? = nil) {
+        self.db = db
+        self.backup = backup
+    }
+}
+
+public struct Backup: Codable {
+    public var id: String
+    public var name: String
+    public var time: String
+    public var size: Int
+
+    public init(id: String, name: String, time: String, size: Int) {
+        self.id = id
+        self.name = name
+        self.time = time
+        self.size = size
+    }
+}
+
+public struct JournalRequestPayload: Codable {
+    public var input: String?
+    public var after: Int?
+    public var limit: Int?
+
+    public init(input: String? = nil, after: Int? = nil, limit: Int? = nil) {
+        self.input = input
+        self.after = after
+        self.limit = limit
+    }
+}
+
+public struct JournalPage: Codable {
+    public var entries: [JournalEntry]?
+    public var nextAfter: Int?
+    public var total: Int
+
+    public init(entries: [JournalEntry]? = nil, nextAfter: Int? = nil, total: Int) {
+        self.entries = entries
+        self.nextAfter = nextAfter
+        self.total = total
+    }
+}
+
+public struct JournalEntry: Codable {
+    public var seq: Int
+    public var time: String
+    public var actor: String
+    public var operation: String
+    public var bucket: String
+    public var key: String?
+    public var toBucket: String?
+    public var oldHash: String?
+    public var newHash: String?
+    public var prev: String
+    public var hash: String
+
+    public init(seq: Int, time: String, actor: String, operation: String, bucket: String, key: String? = nil, toBucket: String? = nil, oldHash: String? = nil, newHash: String? = nil, prev: String, hash: String) {
+        self.seq = seq
+        self.time = time
+        self.actor = actor
+        self.operation = operation
+        self.bucket = bucket
+        self.key = key
+        self.toBucket = toBucket
+        self.oldHash = oldHash
+        self.newHash = newHash
+        self.prev = prev
+        self.hash = hash
+    }
+}
+
+public struct JournalVerification: Codable {
+    public var entries: Int
+    public var valid: Bool
+    public var brokenAt: Int?
+    public var lastHash: String?
+
+    public init(entries: Int, valid: Bool, brokenAt: Int? = nil, lastHash: String? = nil) {
+        self.entries = entries
+        self.valid = valid
+        self.brokenAt = brokenAt
+        self.lastHash = lastHash
+    }
+}
+
+public struct RegisteredDb: Codable {
+    public var name: String
+    public var path: String
+    public var options: BoltOptions?
+    public var journal: Bool
+    public var fixture: String?
+
+    public init(name: String, path: String, options: BoltOptions? = nil, journal: Bool, fixture: String? = nil) {
+        self.name = name
+        self.path = path
+        self.options = options
+        self.journal = journal
+        self.fixture = fixture
+    }
+}
+
+public struct BoltOptions: Codable {
+    public var pageSize: Int
+    public var noFreelistSync: Bool
+    public var freelistType: String
+    public var mlock: Bool
+    public var initialMmapSize: Int
+    public var preLoadFreelist: Bool
+
+    public init(pageSize: Int, noFreelistSync: Bool, freelistType: String, mlock: Bool, initialMmapSize: Int, preLoadFreelist: Bool) {
+        self.pageSize = pageSize
+        self.noFreelistSync = noFreelistSync
+        self.freelistType = freelistType
+        self.mlock = mlock
+        self.initialMmapSize = initialMmapSize
+        self.preLoadFreelist = preLoadFreelist
+    }
+}
+
+public struct BucketInfo: Codable {
+    public var name: String
+    public var keys: Int
+
+    public init(name: String, keys: Int) {
+        self.name = name
+        self.keys = keys
+    }
+}
+
+public struct DbFileInfo: Codable {
+    public var size: Int
+    public var modifiedAt: String
+    public var pageSize: Int
+    public var version: Int
+    public var txId: Int
+    public var metas: [MetaPage]?
+    public var freePages: Int
+    public var freelistPages: Int
+
+    public init(size: Int, modifiedAt: String, pageSize: Int, version: Int, txId: Int, metas: [MetaPage]? = nil, freePages: Int, freelistPages: Int) {
+        self.size = size
+        self.modifiedAt = modifiedAt
+        self.pageSize = pageSize
+        self.version = version
+        self.txId = txId
+        self.metas = metas
+        self.freePages = freePages
+        self.freelistPages = freelistPages
+    }
+}
+
+public struct MetaPage: Codable {
+    public var page: Int
+    public var valid: Bool
+    public var version: Int
+    public var pageSize: Int
+    public var root: Int
+    public var freelist: Int
+    public var pages: Int
+    public var txId: Int
+
+    public init(page: Int, valid: Bool, version: Int, pageSize: Int, root: Int, freelist: Int, pages: Int, txId: Int) {
+        self.page = page
+        self.valid = valid
+        self.version = version
+        self.pageSize = pageSize
+        self.root = root
+        self.freelist = freelist
+        self.pages = pages
+        self.txId = txId
+    }
+}
+
+public struct FragmentationReport: Codable {
+    public var size: Int
+    public var pageSize: Int
+    public var pages: Int
+    public var freePages: Int
+    public var pendingPages: Int
+    public var freelistBytes: Int
+    public var freeBytes: Int
+    public var inuseBytes: Int
+    public var fragmentation: Double
+    public var estimatedCompactedSize: Int
+    public var reclaimableBytes: Int
+
+    public init(size: Int, pageSize: Int, pages: Int, freePages: Int, pendingPages: Int, freelistBytes: Int, freeBytes: Int, inuseBytes: Int, fragmentation: Double, estimatedCompactedSize: Int, reclaimableBytes: Int) {
+        self.size = size
+        self.pageSize = pageSize
+        self.pages = pages
+        self.freePages = freePages
+        self.pendingPages = pendingPages
+        self.freelistBytes = freelistBytes
+        self.freeBytes = freeBytes
+        self.inuseBytes = inuseBytes
+        self.fragmentation = fragmentation
+        self.estimatedCompactedSize = estimatedCompactedSize
+        self.reclaimableBytes = reclaimableBytes
+    }
+}
+
+public struct RenameBucketRequestPayload: Codable {
+    public var input: String?
+    public var bucket: String?
+    public var toBucket: String?
+    public var dryRun: Bool?
+
+    public init(input: String? = nil, bucket: String? = nil, toBucket: String? = nil, dryRun: Bool? = nil) {
+        self.input = input
+        self.bucket = bucket
+        self.toBucket = toBucket
+        self.dryRun = dryRun
+    }
+}
+
+public struct HandleInfo: Codable {
+    public var path: String
+    public var users: Int
+    public var openedAt: String
+    public var lastUsed: String
+
+    public init(path: String, users: Int, openedAt: String, lastUsed: String) {
+        self.path = path
+        self.users = users
+        self.openedAt = openedAt
+        self.lastUsed = lastUsed
+    }
+}
+
+public struct OpenDbInfo: Codable {
+    public var path: String
+    public var cached: Bool
+    public var readTransactions: Int
+    public var holders: [HolderInfo]?
+    public var requests: [RequestInfo]?
+
+    public init(path: String, cached: Bool, readTransactions: Int, holders: [HolderInfo]? = nil, requests: [RequestInfo]? = nil) {
+        self.path = path
+        self.cached = cached
+        self.readTransactions = readTransactions
+        self.holders = holders
+        self.requests = requests
+    }
+}
+
+public struct HolderInfo: Codable {
+    public var holder: String
+    public var writable: Bool
+    public var waiting: Bool
+    public var since: String
+    public var age: String
+
+    public init(holder: String, writable: Bool, waiting: Bool, since: String, age: String) {
+        self.holder = holder
+        self.writable = writable
+        self.waiting = waiting
+        self.since = since
+        self.age = age
+    }
+}
+
+public struct RequestInfo: Codable {
+    public var method: String?
+    public var path: String?
+    public var who: String
+    public var transaction: Bool
+    public var writable: Bool
+    public var since: String
+    public var age: String
+
+    public init(method: String? = nil, path: String? = nil, who: String, transaction: Bool, writable: Bool, since: String, age: String) {
+        self.method = method
+        self.path = path
+        self.who = who
+        self.transaction = transaction
+        self.writable = writable
+        self.since = since
+        self.age = age
+    }
+}
+
+public struct Status: Codable {
+    public var db: String
+    public var cron: String
+    public var nextRun: String
+    public var lastAttempt: Attempt?
+    public var compactions: Int
+    public var skips: Int
+    public var failures: Int
+    public var reclaimedBytes: Int
+
+    public init(db: String, cron: String, nextRun: String, lastAttempt: Attempt? = nil, compactions: Int, skips: Int, failures: Int, reclaimedBytes: Int) {
+        self.db = db
+        self.cron = cron
+        self.nextRun = nextRun
+        self.lastAttempt = lastAttempt
+        self.compactions = compactions
+        self.skips = skips
+        self.failures = failures
+        self.reclaimedBytes = reclaimedBytes
+    }
+}
+
+public struct Attempt: Codable {
+    public var time: String
+    public var outcome: String
+    public var reason: String?
+    public var sizeBefore: Int?
+    public var sizeAfter: Int?
+    public var duration: String?
+
+    public init(time: String, outcome: String, reason: String? = nil, sizeBefore: Int? = nil, sizeAfter: Int? = nil, duration: String? = nil) {
+        self.time = time
+        self.outcome = outcome
+        self.reason = reason
+        self.sizeBefore = sizeBefore
+        self.sizeAfter = sizeAfter
+        self.duration = duration
+    }
+}
+
+public struct DbStats: Codable {
+    public var db: String
+    public var lastRequest: String
+    public var requests: Int
+    public var clientErrors: Int
+    public var errors: Int
+    public var errorRate: Double
+    public var latency: RequestLatency
+    public var operations: [OperationStats]?
+
+    public init(db: String, lastRequest: String, requests: Int, clientErrors: Int, errors: Int, errorRate: Double, latency: RequestLatency, operations: [OperationStats]? = nil) {
+        self.db = db
+        self.lastRequest = lastRequest
+        self.requests = requests
+        self.clientErrors = clientErrors
+        self.errors = errors
+        self.errorRate = errorRate
+        self.latency = latency
+        self.operations = operations
+    }
+}
+
+public struct RequestLatency: Codable {
+    public var p50Ms: Double
+    public var p90Ms: Double
+    public var p99Ms: Double
+    public var maxMs: Double
+
+    public init(p50Ms: Double, p90Ms: Double, p99Ms: Double, maxMs: Double) {
+        self.p50Ms = p50Ms
+        self.p90Ms = p90Ms
+        self.p99Ms = p99Ms
+        self.maxMs = maxMs
+    }
+}
+
+public struct OperationStats: Codable {
+    public var operation: String
+    public var requests: Int
+    public var clientErrors: Int
+    public var errors: Int
+    public var errorRate: Double
+    public var latency: RequestLatency
+
+    public init(operation: String, requests: Int, clientErrors: Int, errors: Int, errorRate: Double, latency: RequestLatency) {
+        self.operation = operation
+        self.requests = requests
+        self.clientErrors = clientErrors
+        self.errors = errors
+        self.errorRate = errorRate
+        self.latency = latency
+    }
+}
+
+public struct BenchmarkRequestPayload: Codable {
+    public var input: String?
+    public var workload: String?
+    public var bucket: String?
+    public var duration: String?
+    public var concurrency: Int?
+    public var prefixLength: Int?
+    public var limit: Int?
+
+    public init(input: String? = nil, workload: String? = nil, bucket: String? = nil, duration: String? = nil, concurrency: Int? = nil, prefixLength: Int? = nil, limit: Int? = nil) {
+        self.input = input
+        self.workload = workload
+        self.bucket = bucket
+        self.duration = duration
+        self.concurrency = concurrency
+        self.prefixLength = prefixLength
+        self.limit = limit
+    }
+}
+
+public struct Result: Codable {
+    public var workload: String
+    public var concurrency: Int
+    public var duration: String
+    public var operations: Int
+    public var errors: Int
+    public var firstError: String?
+    public var opsPerSecond: Double
+    public var bytesPerSecond: Double
+    public var latency: Latency
+
+    public init(workload: String, concurrency: Int, duration: String, operations: Int, errors: Int, firstError: String? = nil, opsPerSecond: Double, bytesPerSecond: Double, latency: Latency) {
+        self.workload = workload
+        self.concurrency = concurrency
+        self.duration = duration
+        self.operations = operations
+        self.errors = errors
+        self.firstError = firstError
+        self.opsPerSecond = opsPerSecond
+        self.bytesPerSecond = bytesPerSecond
+        self.latency = latency
+    }
+}
+
+public struct Latency: Codable {
+    public var minMs: Double
+    public var meanMs: Double
+    public var p50Ms: Double
+    public var p90Ms: Double
+    public var p99Ms: Double
+    public var maxMs: Double
+
+    public init(minMs: Double, meanMs: Double, p50Ms: Double, p90Ms: Double, p99Ms: Double, maxMs: Double) {
+        self.minMs = minMs
+        self.meanMs = meanMs
+        self.p50Ms = p50Ms
+        self.p90Ms = p90Ms
+        self.p99Ms = p99Ms
+        self.maxMs = maxMs
+    }
+}
+
+public struct IndexInfo: Codable {
+    public var name: String
+    public var bucket: String
+    public var field: String
+    public var entries: Int
+
+    public init(name: String, bucket: String, field: String, entries: Int) {
+        self.name = name
+        self.bucket = bucket
+        self.field = field
+        self.entries = entries
+    }
+}
+
+public struct IndexRequestPayload: Codable {
+    public var input: String?
+    public var index: String?
+    public var bucket: String?
+    public var field: String?
+
+    public init(input: String? = nil, index: String? = nil, bucket: String? = nil, field: String? = nil) {
+        self.input = input
+        self.index = index
+        self.bucket = bucket
+        self.field = field
+    }
+}
+
+public struct ViewConfig: Codable {
+    public var db: String
+    public var name: String
+    public var bucket: String
+    public var kind: String
+    public var separator: String
+    public var refresh: String
+
+    public init(db: String, name: String, bucket: String, kind: String, separator: String, refresh: String) {
+        self.db = db
+        self.name = name
+        self.bucket = bucket
+        self.kind = kind
+        self.separator = separator
+        self.refresh = refresh
+    }
+}
+
+public struct ViewRefreshRequestPayload: Codable {
+    public var db: String?
+    public var view: String?
+
+    public init(db: String? = nil, view: String? = nil) {
+        self.db = db
+        self.view = view
+    }
+}
+
+public struct ViewInfo: Codable {
+    public var name: String
+    public var bucket: String
+    public var kind: String
+    public var separator: String
+    public var keys: Int
+
+    public init(name: String, bucket: String, kind: String, separator: String, keys: Int) {
+        self.name = name
+        self.bucket = bucket
+        self.kind = kind
+        self.separator = separator
+        self.keys = keys
+    }
+}
+
+public struct RestoreRequestPayload: Codable {
+    public var db: String?
+    public var source: String?
+
+    public init(db: String? = nil, source: String? = nil) {
+        self.db = db
+        self.source = source
+    }
+}
+
+public struct RestoreResult: Codable {
+    public var size: Int
+    public var txId: Int
+
+    public init(size: Int, txId: Int) {
+        self.size = size
+        self.txId = txId
+    }
+}
+
+public struct TxBeginRequestPayload: Codable {
+    public var writable: Bool?
+    public var fillPercent: Double?
+
+    public init(writable: Bool? = nil, fillPercent: Double? = nil) {
+        self.writable = writable
+        self.fillPercent = fillPercent
+    }
+}
+
+public struct TxInfo: Codable {
+    public var token: String
+    public var db: String
+    public var writable: Bool
+
+    public init(token: String, db: String, writable: Bool) {
+        self.token = token
+        self.db = db
+        self.writable = writable
+    }
+}
+
+public struct TxKeyRequestPayload: Codable {
+    public var bucket: String?
+    public var key: String?
+    public var value: String?
+    public var encoding: String?
+    public var ttl: String?
+    public var keys: String?
+
+    public init(bucket: String? = nil, key: String? = nil, value: String? = nil, encoding: String? = nil, ttl: String? = nil, keys: String? = nil) {
+        self.bucket = bucket
+        self.key = key
+        self.value = value
+        self.encoding = encoding
+        self.ttl = ttl
+        self.keys = keys
+    }
+}
+
+/// Client of a bbolt-apiEndpoint server. Every method sends one POST request and throws a BboltError if the server rejects it.
+public final class BboltClient {
+    public let baseURL: URL
+    public let apiEndpoint: String
+    public var headers: [String: String]
+    private let session: URLSession
+
+    /// baseURL is the address of the server like "http://localhost:8085", apiEndpoint the path the API is served below.
+    public init(baseURL: URL, apiEndpoint: String = "/bbolt", headers: [String: String] = [:], session: URLSession = .shared) {
+        self.baseURL = baseURL
+        self.apiEndpoint = apiEndpoint
+        self.headers = headers
+        self.session = session
+    }
+
+    /// Sends body to path and returns the body of the response, for requests the typed methods do not cover like dumps whose values are not strings.
+    public func post(_ path: String, body: Data?) async throws -> Data {
+        var base = baseURL.absoluteString
+        if base.hasSuffix("/") {
+            base.removeLast()
+        }
+        guard let url = URL(string: base + path) else {
+            throw URLError(.badURL)
+        }
+        var urlRequest = URLRequest(url: url)
+        urlRequest.httpMethod = "POST"
+        urlRequest.httpBody = body
+        urlRequest.setValue("application/json", forHTTPHeaderField: "Content-Type")
+        for (name, value) in headers {
+            urlRequest.setValue(value, forHTTPHeaderField: name)
+        }
+        let (data, response) = try await session.data(for: urlRequest)
+        let status = (response as? HTTPURLResponse)?.statusCode ?? 0
+        guard status == 200 else {
+            throw BboltError(status: status, message: String(decoding: data, as: UTF8.self).trimmingCharacters(in: .whitespacesAndNewlines))
+        }
+        return data
+    }
+
+    /// Results are requested in raw mode, so the body of the response is the result itself.
+    private func call<Request: Encodable, Result: Decodable>(_ path: String, _ request: Request) async throws -> Result {
+        return try JSONDecoder().decode(Result.self, from: try await post(path + "?raw=true", body: try JSONEncoder().encode(request)))
+    }
+
+    private func call<Result: Decodable>(_ path: String) async throws -> Result {
+        return try JSONDecoder().decode(Result.self, from: try await post(path + "?raw=true", body: nil))
+    }
+
+    /// Escapes a path parameter.
+    private func escape(_ parameter: String) -> String {
+        var allowed = CharacterSet.urlPathAllowed
+        allowed.remove("/")
+        return parameter.addingPercentEncoding(withAllowedCharacters: allowed) ?? parameter
+    }
+
+    /// Dumps all buckets of a database.
+    public func dump(_ request: DumpRequestPayload) async throws -> BboltDb {
+        return try await call(apiEndpoint, request)
+    }
+
+    /// Returns one page of the entries of a bucket, with Link headers to the first, previous and next page and, with ?count=true, the amount of entries in X-Total-Count. Also answers GET requests that pass the payload as query parameters, which the links point to.
+    public func page(_ request: PageRequestPayload) async throws -> BucketPage {
+        return try await call(apiEndpoint + "/page", request)
+    }
+
+    /// Returns the entries of a bucket starting at a key.
+    public func seek(_ request: SeekRequestPayload) async throws -> BucketPage {
+        return try await call(apiEndpoint + "/seek", request)
+    }
+
+    /// Returns the last entries of a bucket.
+    public func tail(_ request: TailRequestPayload) async throws -> BucketPage {
+        return try await call(apiEndpoint + "/tail", request)
+    }
+
+    /// Returns a random sample of the entries of a bucket.
+    public func sample(_ request: SampleRequestPayload) async throws -> BucketSample {
+        return try await call(apiEndpoint + "/sample", request)
+    }
+
+    /// Returns the values of several keys from one read transaction.
+    public func getKeys(_ request: BatchGetRequestPayload) async throws -> [BatchEntry] {
+        return try await call(apiEndpoint + "/get", request)
+    }
+
+    /// Tells whether a key exists with the status and the size and type of its value in the X-Bbolt-Value-Size and X-Bbolt-Value-Type headers, without a body. Also answers HEAD requests that pass the payload as query parameters.
+    public func keyExists(_ request: ExistsRequestPayload) async throws -> Data {
+        return try await post(apiEndpoint + "/exists", body: try JSONEncoder().encode(request))
+    }
+
+    /// Returns the entries of a bucket or database that match a filter expression.
+    public func scan(_ request: ScanRequestPayload) async throws -> ScanResult {
+        return try await call(apiEndpoint + "/scan", request)
+    }
+
+    /// Returns one page of the entries of a bucket, each with the entry of another bucket its value refers to by key.
+    public func join(_ request: JoinRequestPayload) async throws -> JoinPage {
+        return try await call(apiEndpoint + "/join", request)
+    }
+
+    /// Returns the entries whose field indexed by a secondary index has a value.
+    public func lookup(_ request: LookupRequestPayload) async throws -> IndexLookup {
+        return try await call(apiEndpoint + "/lookup", request)
+    }
+
+    /// Reports the key patterns, value formats and sizes of the buckets of a database.
+    public func analyze(_ request: AnalyzeRequestPayload) async throws -> DbAnalysis {
+        return try await call(apiEndpoint + "/analyze", request)
+    }
+
+    /// Aggregates the keyspace of a database into a tree of its buckets and key prefixes with their key counts and byte totals, without transferring any values.
+    public func keyspaceTree(_ request: KeyspaceRequestPayload) async throws -> KeyspaceTree {
+        return try await call(apiEndpoint + "/keyspace", request)
+    }
+
+    /// Returns histograms of the key lengths and value sizes of the buckets of a database.
+    public func histogram(_ request: HistogramRequestPayload) async throws -> [BucketHistogram] {
+        return try await call(apiEndpoint + "/histogram", request)
+    }
+
+    /// Returns the bucket, key and size of the largest values of a database.
+    public func largestValues(_ request: LargestRequestPayload) async throws -> [LargeValue] {
+        return try await call(apiEndpoint + "/largest", request)
+    }
+
+    /// Compares two databases.
+    public func diff(_ request: DiffRequestPayload) async throws -> DbDiff {
+        return try await call(apiEndpoint + "/diff", request)
+    }
+
+    /// Returns a database converted to a SQLite file.
+    public func exportSqlite(_ request: ExportRequestPayload) async throws -> Data {
+        return try await post(apiEndpoint + "/export/sqlite", body: try JSONEncoder().encode(request))
+    }
+
+    /// Returns a database converted to a Parquet file with the columns bucket, key, value and value_size.
+    public func exportParquet(_ request: ExportRequestPayload) async throws -> Data {
+        return try await post(apiEndpoint + "/export/parquet", body: try JSONEncoder().encode(request))
+    }
+
+    /// Returns a copy of a database as NDJSON, a Range header resumes an export that was started before.
+    public func exportNdjson(_ request: NdjsonExportRequestPayload) async throws -> Data {
+        return try await post(apiEndpoint + "/export/ndjson", body: try JSONEncoder().encode(request))
+    }
+
+    /// Returns a database split into several bbolt files by bucket or by key hash, as a tar archive with a manifest.
+    public func exportShards(_ request: ShardExportRequestPayload) async throws -> Data {
+        return try await post(apiEndpoint + "/export/shards", body: try JSONEncoder().encode(request))
+    }
+
+    /// Loads a LevelDB or Badger database into a bucket.
+    public func importDatabase(_ request: ImportRequestPayload) async throws -> ImportResult {
+        return try await call(apiEndpoint + "/import", request)
+    }
+
+    /// Merges the buckets of one database into another, resolving keys with different values in both by a conflict policy per bucket.
+    public func mergeDatabases(_ request: MergeRequestPayload) async throws -> MergeResult {
+        return try await call(apiEndpoint + "/merge", request)
+    }
+
+    /// Renames a key or moves it to another bucket.
+    public func moveKey(_ request: MoveRequestPayload) async throws -> PreviewResult {
+        return try await call(apiEndpoint + "/move", request)
+    }
+
+    /// Deletes the keys of a bucket matching a prefix or range.
+    public func deleteKeys(_ request: DeleteRequestPayload) async throws -> DeleteResult {
+        return try await call(apiEndpoint + "/delete", request)
+    }
+
+    /// Lists the snapshots of a registered database.
+    public func listSnapshots(_ request: SnapshotRequestPayload) async throws -> [Snapshot] {
+        return try await call(apiEndpoint + "/snapshots", request)
+    }
+
+    /// Compares a snapshot with the current state of its database.
+    public func diffSnapshot(_ request: SnapshotRequestPayload) async throws -> DbDiff {
+        return try await call(apiEndpoint + "/snapshots/diff", request)
+    }
+
+    /// Copies a database as it is now, reads of the input of the pin see that copy until it is released or expires.
+    public func pinDatabase(_ request: RequestPayload) async throws -> Pin {
+        return try await call(apiEndpoint + "/pins", request)
+    }
+
+    /// Deletes a pin before it expires.
+    public func releasePin(_ request: RequestPayload) async throws -> EmptyResult {
+        return try await call(apiEndpoint + "/pins/release", request)
+    }
+
+    /// Lists the stored backups of a registered database.
+    public func listBackups(_ request: BackupRequestPayload) async throws -> [Backup] {
+        return try await call(apiEndpoint + "/backups", request)
+    }
+
+    /// Returns a stored backup of a registered database as a bolt file.
+    public func downloadBackup(_ request: BackupRequestPayload) async throws -> Data {
+        return try await post(apiEndpoint + "/backups/download", body: try JSONEncoder().encode(request))
+    }
+
+    /// Returns a range of the journaled writes of a database, with Link headers to the first, previous and next range and the amount of entries in X-Total-Count. Also answers GET requests that pass the payload as query parameters, which the links point to.
+    public func readJournal(_ request: JournalRequestPayload) async throws -> JournalPage {
+        return try await call(apiEndpoint + "/journal", request)
+    }
+
+    /// Checks the hash chain of the journal of a database.
+    public func verifyJournal(_ request: RequestPayload) async throws -> JournalVerification {
+        return try await call(apiEndpoint + "/journal/verify", request)
+    }
+
+    /// Lists the registered databases.
+    public func listDatabases() async throws -> [RegisteredDb] {
+        return try await call(apiEndpoint + "/databases")
+    }
+
+    /// Lists the top level buckets of a database.
+    public func listBuckets(_ request: RequestPayload) async throws -> [BucketInfo] {
+        return try await call(apiEndpoint + "/buckets", request)
+    }
+
+    /// Returns the file size, modification time, meta pages and freelist size of a database without dumping it.
+    public func dbInfo(_ request: RequestPayload) async throws -> DbFileInfo {
+        return try await call(apiEndpoint + "/info", request)
+    }
+
+    /// Reports the free pages of a database and how much compacting it would likely reclaim.
+    public func fragmentation(_ request: RequestPayload) async throws -> FragmentationReport {
+        return try await call(apiEndpoint + "/fragmentation", request)
+    }
+
+    /// Renames a bucket or moves it below another bucket.
+    public func renameBucket(_ request: RenameBucketRequestPayload) async throws -> PreviewResult {
+        return try await call(apiEndpoint + "/buckets/rename", request)
+    }
+
+    /// Lists the handles of the handle cache.
+    public func listHandles() async throws -> [HandleInfo] {
+        return try await call(apiEndpoint + "/admin/handles")
+    }
+
+    /// Closes the cached handle of a database.
+    public func closeHandle(_ request: RequestPayload) async throws -> HandleInfo {
+        return try await call(apiEndpoint + "/admin/handles/close", request)
+    }
+
+    /// Lists the databases that are open and who holds them.
+    public func listOpenDatabases() async throws -> [OpenDbInfo] {
+        return try await call(apiEndpoint + "/admin/open")
+    }
+
+    /// Lists the compaction schedules of the registered databases and how their last compaction went.
+    public func listCompactions() async throws -> [Status] {
+        return try await call(apiEndpoint + "/admin/compactions")
+    }
+
+    /// Lists the request counts, error rates and latency percentiles of every database since the server started, the most requested first.
+    public func dbStats() async throws -> [DbStats] {
+        return try await call(apiEndpoint + "/admin/stats")
+    }
+
+    /// Runs random gets, prefix scans or full dumps against a database for a while and reports the throughput and latency percentiles.
+    public func benchmark(_ request: BenchmarkRequestPayload) async throws -> Result {
+        return try await call(apiEndpoint + "/admin/benchmark", request)
+    }
+
+    /// Lists the secondary indexes of a database.
+    public func listIndexes(_ request: RequestPayload) async throws -> [IndexInfo] {
+        return try await call(apiEndpoint + "/admin/indexes", request)
+    }
+
+    /// Builds a secondary index of a JSON field of the values of a bucket, which writes through the API keep up to date.
+    public func buildIndex(_ request: IndexRequestPayload) async throws -> IndexInfo {
+        return try await call(apiEndpoint + "/admin/indexes/build", request)
+    }
+
+    /// Removes a secondary index.
+    public func dropIndex(_ request: IndexRequestPayload) async throws -> EmptyResult {
+        return try await call(apiEndpoint + "/admin/indexes/drop", request)
+    }
+
+    /// Lists the materialized views of the registered databases.
+    public func listViews() async throws -> [ViewConfig] {
+        return try await call(apiEndpoint + "/admin/views")
+    }
+
+    /// Computes the materialized views of a registered database from scratch.
+    public func refreshViews(_ request: ViewRefreshRequestPayload) async throws -> [ViewInfo] {
+        return try await call(apiEndpoint + "/admin/views/refresh", request)
+    }
+
+    /// Lists the pins the server holds.
+    public func listPins() async throws -> [Pin] {
+        return try await call(apiEndpoint + "/admin/pins")
+    }
+
+    /// Loads the config file again, registered databases, access control rules, tenants and limits apply to the following requests.
+    public func reloadConfig() async throws -> EmptyResult {
+        return try await call(apiEndpoint + "/admin/config/reload")
+    }
+
+    /// Replaces a registered database by a copy uploaded as the backup file of a multipart form.
+    public func restoreDatabase(_ request: RestoreRequestPayload) async throws -> RestoreResult {
+        return try await call(apiEndpoint + "/admin/restore", request)
+    }
+
+    /// Begins a transaction.
+    public func beginTx(db: String, _ request: TxBeginRequestPayload) async throws -> TxInfo {
+        return try await call("/v1/dbs/\(escape(db))/tx", request)
+    }
+
+    /// Reads a key within a transaction.
+    public func txGet(db: String, token: String, _ request: TxKeyRequestPayload) async throws -> Entry {
+        return try await call("/v1/dbs/\(escape(db))/tx/\(escape(token))/get", request)
+    }
+
+    /// Writes a key within a transaction.
+    public func txPut(db: String, token: String, _ request: TxKeyRequestPayload) async throws -> EmptyResult {
+        return try await call("/v1/dbs/\(escape(db))/tx/\(escape(token))/put", request)
+    }
+
+    /// Deletes a key within a transaction.
+    public func txDelete(db: String, token: String, _ request: TxKeyRequestPayload) async throws -> EmptyResult {
+        return try await call("/v1/dbs/\(escape(db))/tx/\(escape(token))/delete", request)
+    }
+
+    /// Commits a transaction.
+    public func txCommit(db: String, token: String) async throws -> EmptyResult {
+        return try await call("/v1/dbs/\(escape(db))/tx/\(escape(token))/commit")
+    }
+
+    /// Rolls back a transaction.
+    public func txRollback(db: String, token: String) async throws -> EmptyResult {
+        return try await call("/v1/dbs/\(escape(db))/tx/\(escape(token))/rollback")
+    }
+}
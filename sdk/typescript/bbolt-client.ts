@@ -0,0 +1,1055 @@
+// Code generated by "bbolt-apiEndpoint sdk --lang typescript"; DO NOT EDIT.
+
+/** Error thrown when the server answers with a status other than 200, the message is the body of the response. */
+export class BboltError extends Error {
+  constructor(readonly status: number, message: string) {
+    super(message);
+    this.name = "BboltError";
+  }
+}
+
+export interface DumpRequestPayload {
+  input?: string;
+  decryptionKey?: string;
+  keyId?: string;
+  include?: string[] | null;
+  exclude?: string[] | null;
+  maxDepth?: number;
+  maxKeysPerBucket?: number;
+  values?: string;
+  workers?: number;
+  keys?: string;
+  keySeparator?: string;
+  noTransform?: boolean;
+  internal?: boolean;
+  partial?: boolean;
+}
+
+export interface BboltDb {
+  path: string;
+  buckets?: Record<string, Record<string, string>> | null;
+  truncated?: string[] | null;
+  timedOut?: string[] | null;
+  decryptionFailed?: Record<string, string[]> | null;
+  transformFailed?: Record<string, string[]> | null;
+  keyTimes?: Record<string, Record<string, string>> | null;
+  bucketErrors?: Record<string, string> | null;
+  writers?: WriterActivity | null;
+}
+
+export interface WriterActivity {
+  active: boolean;
+  txId: number;
+  changed: boolean;
+  lockHolders?: string[] | null;
+  modifiedAt: string;
+}
+
+export interface PageRequestPayload {
+  input?: string;
+  bucket?: string;
+  limit?: number;
+  cursor?: string;
+  order?: string;
+  values?: string;
+  keys?: string;
+}
+
+export interface BucketPage {
+  bucket: string;
+  entries?: Entry[] | null;
+  nextCursor?: string;
+}
+
+export interface Entry {
+  key: string;
+  keyHex?: string;
+  keyTime?: string;
+  value: string;
+  size?: number;
+  contentType?: string;
+  sha256?: string;
+}
+
+export interface SeekRequestPayload {
+  input?: string;
+  bucket?: string;
+  key?: string;
+  count?: number;
+  values?: string;
+  keys?: string;
+}
+
+export interface TailRequestPayload {
+  input?: string;
+  bucket?: string;
+  count?: number;
+  values?: string;
+  keys?: string;
+}
+
+export interface SampleRequestPayload {
+  input?: string;
+  bucket?: string;
+  size?: number;
+  keys?: string;
+}
+
+export interface BucketSample {
+  bucket: string;
+  entries?: Entry[] | null;
+  total: number;
+}
+
+export interface BatchGetRequestPayload {
+  input?: string;
+  lookups?: KeyRefPayload[] | null;
+  buckets?: string[] | null;
+  key?: string;
+  values?: string;
+  keys?: string;
+}
+
+export interface KeyRefPayload {
+  bucket?: string;
+  key?: string;
+}
+
+export interface BatchEntry {
+  bucket: string;
+  found: boolean;
+  key: string;
+  keyHex?: string;
+  keyTime?: string;
+  value: string;
+  size?: number;
+  contentType?: string;
+  sha256?: string;
+}
+
+export interface ExistsRequestPayload {
+  input?: string;
+  bucket?: string;
+  key?: string;
+}
+
+export interface ScanRequestPayload {
+  input?: string;
+  bucket?: string;
+  match?: string;
+  where?: string;
+  limit?: number;
+  values?: string;
+  keys?: string;
+}
+
+export interface ScanResult {
+  entries?: ScanEntry[] | null;
+  scanned: number;
+  truncated: boolean;
+  timedOut: boolean;
+}
+
+export interface ScanEntry {
+  bucket: string;
+  key: string;
+  keyHex?: string;
+  keyTime?: string;
+  value: string;
+  size?: number;
+  contentType?: string;
+  sha256?: string;
+}
+
+export interface JoinRequestPayload {
+  input?: string;
+  bucket?: string;
+  joinBucket?: string;
+  ref?: string;
+  limit?: number;
+  cursor?: string;
+  values?: string;
+  keys?: string;
+}
+
+export interface JoinPage {
+  bucket: string;
+  joinBucket: string;
+  entries?: JoinedEntry[] | null;
+  nextCursor?: string;
+}
+
+export interface JoinedEntry {
+  key: string;
+  keyHex?: string;
+  keyTime?: string;
+  value: string;
+  size?: number;
+  contentType?: string;
+  sha256?: string;
+  ref?: string;
+  joined?: Entry | null;
+}
+
+export interface LookupRequestPayload {
+  input?: string;
+  index?: string;
+  value?: string;
+  limit?: number;
+  values?: string;
+  keys?: string;
+}
+
+export interface IndexLookup {
+  index: string;
+  bucket: string;
+  entries?: Entry[] | null;
+  truncated: boolean;
+}
+
+export interface AnalyzeRequestPayload {
+  input?: string;
+  bucket?: string;
+  sampleSize?: number;
+}
+
+export interface DbAnalysis {
+  buckets?: BucketAnalysis[] | null;
+}
+
+export interface BucketAnalysis {
+  bucket: string;
+  keys: number;
+  sampled: number;
+  valueFormats?: Record<string, number> | null;
+  jsonFields?: Record<string, number> | null;
+  keyPatterns?: Record<string, number> | null;
+  keySizes: SizeStats;
+  valueSizes: SizeStats;
+}
+
+export interface SizeStats {
+  min: number;
+  max: number;
+  mean: number;
+  p50: number;
+  p90: number;
+  p99: number;
+  total: number;
+}
+
+export interface KeyspaceRequestPayload {
+  input?: string;
+  bucket?: string;
+  separator?: string;
+  depth?: number;
+  maxChildren?: number;
+  keys?: string;
+}
+
+export interface KeyspaceTree {
+  root?: KeyspaceNode | null;
+  timedOut: boolean;
+}
+
+export interface KeyspaceNode {
+  name: string;
+  kind: string;
+  keys: number;
+  keyBytes: number;
+  valueBytes: number;
+  children?: KeyspaceNode[] | null;
+}
+
+export interface HistogramRequestPayload {
+  input?: string;
+  bucket?: string;
+}
+
+export interface BucketHistogram {
+  bucket: string;
+  keys: number;
+  keyBytes: number;
+  valueBytes: number;
+  allocatedBytes: number;
+  keyLengths?: HistogramBin[] | null;
+  valueSizes?: HistogramBin[] | null;
+}
+
+export interface HistogramBin {
+  min: number;
+  max: number;
+  count: number;
+  bytes: number;
+}
+
+export interface LargestRequestPayload {
+  input?: string;
+  bucket?: string;
+  count?: number;
+  keys?: string;
+}
+
+export interface LargeValue {
+  bucket: string;
+  key: string;
+  size: number;
+}
+
+export interface DiffRequestPayload {
+  input?: string;
+  other?: string;
+  keys?: string;
+}
+
+export interface DbDiff {
+  path: string;
+  other: string;
+  addedBuckets?: string[] | null;
+  removedBuckets?: string[] | null;
+  buckets?: Record<string, BucketDiff> | null;
+}
+
+export interface BucketDiff {
+  added?: string[] | null;
+  removed?: string[] | null;
+  changed?: string[] | null;
+}
+
+export interface ExportRequestPayload {
+  input?: string;
+  where?: string;
+  internal?: boolean;
+}
+
+export interface NdjsonExportRequestPayload {
+  input?: string;
+  bucket?: string;
+  where?: string;
+  export?: string;
+  keys?: string;
+}
+
+export interface ShardExportRequestPayload {
+  input?: string;
+  shards?: number;
+  partition?: string;
+  where?: string;
+}
+
+export interface ImportRequestPayload {
+  input?: string;
+  bucket?: string;
+  format?: string;
+  source?: string;
+  fillPercent?: number;
+  dryRun?: boolean;
+}
+
+export interface ImportResult {
+  path: string;
+  bucket: string;
+  imported: number;
+  dryRun: boolean;
+  preview?: Preview | null;
+}
+
+export interface Preview {
+  keys: number;
+  bytesWritten: number;
+  bytesDeleted: number;
+}
+
+export interface MergeRequestPayload {
+  input?: string;
+  other?: string;
+  buckets?: string[] | null;
+  policy?: string;
+  bucketPolicies?: Record<string, string> | null;
+  dryRun?: boolean;
+}
+
+export interface MergeResult {
+  path: string;
+  other: string;
+  buckets?: BucketMerge[] | null;
+  batches: number;
+  dryRun: boolean;
+  preview?: Preview | null;
+}
+
+export interface BucketMerge {
+  bucket: string;
+  policy: string;
+  added: number;
+  overwritten: number;
+  skipped: number;
+  unchanged: number;
+}
+
+export interface MoveRequestPayload {
+  input?: string;
+  bucket?: string;
+  key?: string;
+  toBucket?: string;
+  toKey?: string;
+  overwrite?: boolean;
+  dryRun?: boolean;
+}
+
+export interface PreviewResult {
+  preview?: Preview | null;
+}
+
+export interface DeleteRequestPayload {
+  input?: string;
+  bucket?: string;
+  prefix?: string;
+  start?: string;
+  end?: string;
+  dryRun?: boolean;
+}
+
+export interface DeleteResult {
+  bucket: string;
+  deleted: number;
+  dryRun: boolean;
+  preview?: Preview | null;
+}
+
+export interface SnapshotRequestPayload {
+  db?: string;
+  snapshot?: string;
+  keys?: string;
+}
+
+export interface Snapshot {
+  id: string;
+  time: string;
+  size: number;
+}
+
+export interface RequestPayload {
+  input?: string;
+}
+
+export interface Pin {
+  id: string;
+  input: string;
+  db: string;
+  time: string;
+  size: number;
+  expiresAt: string;
+}
+
+export type EmptyResult = Record<string, never>;
+
+export interface BackupRequestPayload {
+  db?: string;
+  backup?: string;
+}
+
+export interface Backup {
+  id: string;
+  name: string;
+  time: string;
+  size: number;
+}
+
+export interface JournalRequestPayload {
+  input?: string;
+  after?: number;
+  limit?: number;
+}
+
+export interface JournalPage {
+  entries?: JournalEntry[] | null;
+  nextAfter?: number;
+  total: number;
+}
+
+export interface JournalEntry {
+  seq: number;
+  time: string;
+  actor: string;
+  operation: string;
+  bucket: string;
+  key?: string;
+  toBucket?: string;
+  oldHash?: string;
+  newHash?: string;
+  prev: string;
+  hash: string;
+}
+
+export interface JournalVerification {
+  entries: number;
+  valid: boolean;
+  brokenAt?: number;
+  lastHash?: string;
+}
+
+export interface RegisteredDb {
+  name: string;
+  path: string;
+  options?: BoltOptions | null;
+  journal: boolean;
+  fixture?: string;
+}
+
+export interface BoltOptions {
+  pageSize: number;
+  noFreelistSync: boolean;
+  freelistType: string;
+  mlock: boolean;
+  initialMmapSize: number;
+  preLoadFreelist: boolean;
+}
+
+export interface BucketInfo {
+  name: string;
+  keys: number;
+}
+
+export interface DbFileInfo {
+  size: number;
+  modifiedAt: string;
+  pageSize: number;
+  version: number;
+  txId: number;
+  metas?: MetaPage[] | null;
+  freePages: number;
+  freelistPages: number;
+}
+
+export interface MetaPage {
+  page: number;
+  valid: boolean;
+  version: number;
+  pageSize: number;
+  root: number;
+  freelist: number;
+  pages: number;
+  txId: number;
+}
+
+export interface FragmentationReport {
+  size: number;
+  pageSize: number;
+  pages: number;
+  freePages: number;
+  pendingPages: number;
+  freelistBytes: number;
+  freeBytes: number;
+  inuseBytes: number;
+  fragmentation: number;
+  estimatedCompactedSize: number;
+  reclaimableBytes: number;
+}
+
+export interface RenameBucketRequestPayload {
+  input?: string;
+  bucket?: string;
+  toBucket?: string;
+  dryRun?: boolean;
+}
+
+export interface HandleInfo {
+  path: string;
+  users: number;
+  openedAt: string;
+  lastUsed: string;
+}
+
+export interface OpenDbInfo {
+  path: string;
+  cached: boolean;
+  readTransactions: number;
+  holders?: HolderInfo[] | null;
+  requests?: RequestInfo[] | null;
+}
+
+export interface HolderInfo {
+  holder: string;
+  writable: boolean;
+  waiting: boolean;
+  since: string;
+  age: string;
+}
+
+export interface RequestInfo {
+  method?: string;
+  path?: string;
+  who: string;
+  transaction: boolean;
+  writable: boolean;
+  since: string;
+  age: string;
+}
+
+export interface Status {
+  db: string;
+  cron: string;
+  nextRun: string;
+  lastAttempt?: Attempt | null;
+  compactions: number;
+  skips: number;
+  failures: number;
+  reclaimedBytes: number;
+}
+
+export interface Attempt {
+  time: string;
+  outcome: string;
+  reason?: string;
+  sizeBefore?: number;
+  sizeAfter?: number;
+  duration?: string;
+}
+
+export interface DbStats {
+  db: string;
+  lastRequest: string;
+  requests: number;
+  clientErrors: number;
+  errors: number;
+  errorRate: number;
+  latency: RequestLatency;
+  operations?: OperationStats[] | null;
+}
+
+export interface RequestLatency {
+  p50Ms: number;
+  p90Ms: number;
+  p99Ms: number;
+  maxMs: number;
+}
+
+export interface OperationStats {
+  operation: string;
+  requests: number;
+  clientErrors: number;
+  errors: number;
+  errorRate: number;
+  latency: RequestLatency;
+}
+
+export interface BenchmarkRequestPayload {
+  input?: string;
+  workload?: string;
+  bucket?: string;
+  duration?: string;
+  concurrency?: number;
+  prefixLength?: number;
+  limit?: number;
+}
+
+export interface Result {
+  workload: string;
+  concurrency: number;
+  duration: string;
+  operations: number;
+  errors: number;
+  firstError?: string;
+  opsPerSecond: number;
+  bytesPerSecond: number;
+  latency: Latency;
+}
+
+export interface Latency {
+  minMs: number;
+  meanMs: number;
+  p50Ms: number;
+  p90Ms: number;
+  p99Ms: number;
+  maxMs: number;
+}
+
+export interface IndexInfo {
+  name: string;
+  bucket: string;
+  field: string;
+  entries: number;
+}
+
+export interface IndexRequestPayload {
+  input?: string;
+  index?: string;
+  bucket?: string;
+  field?: string;
+}
+
+export interface ViewConfig {
+  db: string;
+  name: string;
+  bucket: string;
+  kind: string;
+  separator: string;
+  refresh: string;
+}
+
+export interface ViewRefreshRequestPayload {
+  db?: string;
+  view?: string;
+}
+
+export interface ViewInfo {
+  name: string;
+  bucket: string;
+  kind: string;
+  separator: string;
+  keys: number;
+}
+
+export interface RestoreRequestPayload {
+  db?: string;
+  source?: string;
+}
+
+export interface RestoreResult {
+  size: number;
+  txId: number;
+}
+
+export interface TxBeginRequestPayload {
+  writable?: boolean;
+  fillPercent?: number;
+}
+
+export interface TxInfo {
+  token: string;
+  db: string;
+  writable: boolean;
+}
+
+export interface TxKeyRequestPayload {
+  bucket?: string;
+  key?: string;
+  value?: string;
+  encoding?: string;
+  ttl?: string;
+  keys?: string;
+}
+
+export interface BboltClientOptions {
+  /** Path the API is served below, "/bbolt" by default. */
+  apiEndpoint?: string;
+  /** Headers sent with every request, like an API key. */
+  headers?: Record<string, string>;
+  /** fetch implementation to use instead of the global one. */
+  fetch?: typeof fetch;
+}
+
+/** Client of a bbolt-apiEndpoint server. Every method sends one POST request and rejects with a BboltError if the server rejects it. */
+export class BboltClient {
+  readonly baseUrl: string;
+  readonly apiEndpoint: string;
+  headers: Record<string, string>;
+  private readonly fetchFn: typeof fetch;
+
+  /** baseUrl is the address of the server like "http://localhost:8085". */
+  constructor(baseUrl: string, options: BboltClientOptions = {}) {
+    this.baseUrl = baseUrl.replace(/\/$/, "");
+    this.apiEndpoint = options.apiEndpoint ?? "/bbolt";
+    this.headers = options.headers ?? {};
+    this.fetchFn = options.fetch ?? fetch.bind(globalThis);
+  }
+
+  /** Sends body to path and returns the response, for requests the typed methods do not cover like dumps whose values are not strings. */
+  async post(path: string, body?: unknown): Promise<Response> {
+    const response = await this.fetchFn(this.baseUrl + path, {
+      method: "POST",
+      headers: { "Content-Type": "application/json", ...this.headers },
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    if (response.status !== 200) {
+      throw new BboltError(response.status, (await response.text()).trim());
+    }
+    return response;
+  }
+
+  /** Results are requested in raw mode, so the body of the response is the result itself. */
+  private async call<T>(path: string, body?: unknown): Promise<T> {
+    return (await (await this.post(path + "?raw=true", body)).json()) as T;
+  }
+
+  /** Dumps all buckets of a database. */
+  dump(request: DumpRequestPayload): Promise<BboltDb> {
+    return this.call(this.apiEndpoint, request);
+  }
+
+  /** Returns one page of the entries of a bucket, with Link headers to the first, previous and next page and, with ?count=true, the amount of entries in X-Total-Count. Also answers GET requests that pass the payload as query parameters, which the links point to. */
+  page(request: PageRequestPayload): Promise<BucketPage> {
+    return this.call(this.apiEndpoint + `/page`, request);
+  }
+
+  /** Returns the entries of a bucket starting at a key. */
+  seek(request: SeekRequestPayload): Promise<BucketPage> {
+    return this.call(this.apiEndpoint + `/seek`, request);
+  }
+
+  /** Returns the last entries of a bucket. */
+  tail(request: TailRequestPayload): Promise<BucketPage> {
+    return this.call(this.apiEndpoint + `/tail`, request);
+  }
+
+  /** Returns a random sample of the entries of a bucket. */
+  sample(request: SampleRequestPayload): Promise<BucketSample> {
+    return this.call(this.apiEndpoint + `/sample`, request);
+  }
+
+  /** Returns the values of several keys from one read transaction. */
+  getKeys(request: BatchGetRequestPayload): Promise<BatchEntry[]> {
+    return this.call(this.apiEndpoint + `/get`, request);
+  }
+
+  /** Tells whether a key exists with the status and the size and type of its value in the X-Bbolt-Value-Size and X-Bbolt-Value-Type headers, without a body. Also answers HEAD requests that pass the payload as query parameters. */
+  async keyExists(request: ExistsRequestPayload): Promise<ArrayBuffer> {
+    return (await this.post(this.apiEndpoint + `/exists`, request)).arrayBuffer();
+  }
+
+  /** Returns the entries of a bucket or database that match a filter expression. */
+  scan(request: ScanRequestPayload): Promise<ScanResult> {
+    return this.call(this.apiEndpoint + `/scan`, request);
+  }
+
+  /** Returns one page of the entries of a bucket, each with the entry of another bucket its value refers to by key. */
+  join(request: JoinRequestPayload): Promise<JoinPage> {
+    return this.call(this.apiEndpoint + `/join`, request);
+  }
+
+  /** Returns the entries whose field indexed by a secondary index has a value. */
+  lookup(request: LookupRequestPayload): Promise<IndexLookup> {
+    return this.call(this.apiEndpoint + `/lookup`, request);
+  }
+
+  /** Reports the key patterns, value formats and sizes of the buckets of a database. */
+  analyze(request: AnalyzeRequestPayload): Promise<DbAnalysis> {
+    return this.call(this.apiEndpoint + `/analyze`, request);
+  }
+
+  /** Aggregates the keyspace of a database into a tree of its buckets and key prefixes with their key counts and byte totals, without transferring any values. */
+  keyspaceTree(request: KeyspaceRequestPayload): Promise<KeyspaceTree> {
+    return this.call(this.apiEndpoint + `/keyspace`, request);
+  }
+
+  /** Returns histograms of the key lengths and value sizes of the buckets of a database. */
+  histogram(request: HistogramRequestPayload): Promise<BucketHistogram[]> {
+    return this.call(this.apiEndpoint + `/histogram`, request);
+  }
+
+  /** Returns the bucket, key and size of the largest values of a database. */
+  largestValues(request: LargestRequestPayload): Promise<LargeValue[]> {
+    return this.call(this.apiEndpoint + `/largest`, request);
+  }
+
+  /** Compares two databases. */
+  diff(request: DiffRequestPayload): Promise<DbDiff> {
+    return this.call(this.apiEndpoint + `/diff`, request);
+  }
+
+  /** Returns a database converted to a SQLite file. */
+  async exportSqlite(request: ExportRequestPayload): Promise<ArrayBuffer> {
+    return (await this.post(this.apiEndpoint + `/export/sqlite`, request)).arrayBuffer();
+  }
+
+  /** Returns a database converted to a Parquet file with the columns bucket, key, value and value_size. */
+  async exportParquet(request: ExportRequestPayload): Promise<ArrayBuffer> {
+    return (await this.post(this.apiEndpoint + `/export/parquet`, request)).arrayBuffer();
+  }
+
+  /** Returns a copy of a database as NDJSON, a Range header resumes an export that was started before. */
+  async exportNdjson(request: NdjsonExportRequestPayload): Promise<ArrayBuffer> {
+    return (await this.post(this.apiEndpoint + `/export/ndjson`, request)).arrayBuffer();
+  }
+
+  /** Returns a database split into several bbolt files by bucket or by key hash, as a tar archive with a manifest. */
+  async exportShards(request: ShardExportRequestPayload): Promise<ArrayBuffer> {
+    return (await this.post(this.apiEndpoint + `/export/shards`, request)).arrayBuffer();
+  }
+
+  /** Loads a LevelDB or Badger database into a bucket. */
+  importDatabase(request: ImportRequestPayload): Promise<ImportResult> {
+    return this.call(this.apiEndpoint + `/import`, request);
+  }
+
+  /** Merges the buckets of one database into another, resolving keys with different values in both by a conflict policy per bucket. */
+  mergeDatabases(request: MergeRequestPayload): Promise<MergeResult> {
+    return this.call(this.apiEndpoint + `/merge`, request);
+  }
+
+  /** Renames a key or moves it to another bucket. */
+  moveKey(request: MoveRequestPayload): Promise<PreviewResult> {
+    return this.call(this.apiEndpoint + `/move`, request);
+  }
+
+  /** Deletes the keys of a bucket matching a prefix or range. */
+  deleteKeys(request: DeleteRequestPayload): Promise<DeleteResult> {
+    return this.call(this.apiEndpoint + `/delete`, request);
+  }
+
+  /** Lists the snapshots of a registered database. */
+  listSnapshots(request: SnapshotRequestPayload): Promise<Snapshot[]> {
+    return this.call(this.apiEndpoint + `/snapshots`, request);
+  }
+
+  /** Compares a snapshot with the current state of its database. */
+  diffSnapshot(request: SnapshotRequestPayload): Promise<DbDiff> {
+    return this.call(this.apiEndpoint + `/snapshots/diff`, request);
+  }
+
+  /** Copies a database as it is now, reads of the input of the pin see that copy until it is released or expires. */
+  pinDatabase(request: RequestPayload): Promise<Pin> {
+    return this.call(this.apiEndpoint + `/pins`, request);
+  }
+
+  /** Deletes a pin before it expires. */
+  releasePin(request: RequestPayload): Promise<EmptyResult> {
+    return this.call(this.apiEndpoint + `/pins/release`, request);
+  }
+
+  /** Lists the stored backups of a registered database. */
+  listBackups(request: BackupRequestPayload): Promise<Backup[]> {
+    return this.call(this.apiEndpoint + `/backups`, request);
+  }
+
+  /** Returns a stored backup of a registered database as a bolt file. */
+  async downloadBackup(request: BackupRequestPayload): Promise<ArrayBuffer> {
+    return (await this.post(this.apiEndpoint + `/backups/download`, request)).arrayBuffer();
+  }
+
+  /** Returns a range of the journaled writes of a database, with Link headers to the first, previous and next range and the amount of entries in X-Total-Count. Also answers GET requests that pass the payload as query parameters, which the links point to. */
+  readJournal(request: JournalRequestPayload): Promise<JournalPage> {
+    return this.call(this.apiEndpoint + `/journal`, request);
+  }
+
+  /** Checks the hash chain of the journal of a database. */
+  verifyJournal(request: RequestPayload): Promise<JournalVerification> {
+    return this.call(this.apiEndpoint + `/journal/verify`, request);
+  }
+
+  /** Lists the registered databases. */
+  listDatabases(): Promise<RegisteredDb[]> {
+    return this.call(this.apiEndpoint + `/databases`);
+  }
+
+  /** Lists the top level buckets of a database. */
+  listBuckets(request: RequestPayload): Promise<BucketInfo[]> {
+    return this.call(this.apiEndpoint + `/buckets`, request);
+  }
+
+  /** Returns the file size, modification time, meta pages and freelist size of a database without dumping it. */
+  dbInfo(request: RequestPayload): Promise<DbFileInfo> {
+    return this.call(this.apiEndpoint + `/info`, request);
+  }
+
+  /** Reports the free pages of a database and how much compacting it would likely reclaim. */
+  fragmentation(request: RequestPayload): Promise<FragmentationReport> {
+    return this.call(this.apiEndpoint + `/fragmentation`, request);
+  }
+
+  /** Renames a bucket or moves it below another bucket. */
+  renameBucket(request: RenameBucketRequestPayload): Promise<PreviewResult> {
+    return this.call(this.apiEndpoint + `/buckets/rename`, request);
+  }
+
+  /** Lists the handles of the handle cache. */
+  listHandles(): Promise<HandleInfo[]> {
+    return this.call(this.apiEndpoint + `/admin/handles`);
+  }
+
+  /** Closes the cached handle of a database. */
+  closeHandle(request: RequestPayload): Promise<HandleInfo> {
+    return this.call(this.apiEndpoint + `/admin/handles/close`, request);
+  }
+
+  /** Lists the databases that are open and who holds them. */
+  listOpenDatabases(): Promise<OpenDbInfo[]> {
+    return this.call(this.apiEndpoint + `/admin/open`);
+  }
+
+  /** Lists the compaction schedules of the registered databases and how their last compaction went. */
+  listCompactions(): Promise<Status[]> {
+    return this.call(this.apiEndpoint + `/admin/compactions`);
+  }
+
+  /** Lists the request counts, error rates and latency percentiles of every database since the server started, the most requested first. */
+  dbStats(): Promise<DbStats[]> {
+    return this.call(this.apiEndpoint + `/admin/stats`);
+  }
+
+  /** Runs random gets, prefix scans or full dumps against a database for a while and reports the throughput and latency percentiles. */
+  benchmark(request: BenchmarkRequestPayload): Promise<Result> {
+    return this.call(this.apiEndpoint + `/admin/benchmark`, request);
+  }
+
+  /** Lists the secondary indexes of a database. */
+  listIndexes(request: RequestPayload): Promise<IndexInfo[]> {
+    return this.call(this.apiEndpoint + `/admin/indexes`, request);
+  }
+
+  /** Builds a secondary index of a JSON field of the values of a bucket, which writes through the API keep up to date. */
+  buildIndex(request: IndexRequestPayload): Promise<IndexInfo> {
+    return this.call(this.apiEndpoint + `/admin/indexes/build`, request);
+  }
+
+  /** Removes a secondary index. */
+  dropIndex(request: IndexRequestPayload): Promise<EmptyResult> {
+    return this.call(this.apiEndpoint + `/admin/indexes/drop`, request);
+  }
+
+  /** Lists the materialized views of the registered databases. */
+  listViews(): Promise<ViewConfig[]> {
+    return this.call(this.apiEndpoint + `/admin/views`);
+  }
+
+  /** Computes the materialized views of a registered database from scratch. */
+  refreshViews(request: ViewRefreshRequestPayload): Promise<ViewInfo[]> {
+    return this.call(this.apiEndpoint + `/admin/views/refresh`, request);
+  }
+
+  /** Lists the pins the server holds. */
+  listPins(): Promise<Pin[]> {
+    return this.call(this.apiEndpoint + `/admin/pins`);
+  }
+
+  /** Loads the config file again, registered databases, access control rules, tenants and limits apply to the following requests. */
+  reloadConfig(): Promise<EmptyResult> {
+    return this.call(this.apiEndpoint + `/admin/config/reload`);
+  }
+
+  /** Replaces a registered database by a copy uploaded as the backup file of a multipart form. */
+  restoreDatabase(request: RestoreRequestPayload): Promise<RestoreResult> {
+    return this.call(this.apiEndpoint + `/admin/restore`, request);
+  }
+
+  /** Begins a transaction. */
+  beginTx(db: string, request: TxBeginRequestPayload): Promise<TxInfo> {
+    return this.call(`/v1/dbs/${encodeURIComponent(db)}/tx`, request);
+  }
+
+  /** Reads a key within a transaction. */
+  txGet(db: string, token: string, request: TxKeyRequestPayload): Promise<Entry> {
+    return this.call(`/v1/dbs/${encodeURIComponent(db)}/tx/${encodeURIComponent(token)}/get`, request);
+  }
+
+  /** Writes a key within a transaction. */
+  txPut(db: string, token: string, request: TxKeyRequestPayload): Promise<EmptyResult> {
+    return this.call(`/v1/dbs/${encodeURIComponent(db)}/tx/${encodeURIComponent(token)}/put`, request);
+  }
+
+  /** Deletes a key within a transaction. */
+  txDelete(db: string, token: string, request: TxKeyRequestPayload): Promise<EmptyResult> {
+    return this.call(`/v1/dbs/${encodeURIComponent(db)}/tx/${encodeURIComponent(token)}/delete`, request);
+  }
+
+  /** Commits a transaction. */
+  txCommit(db: string, token: string): Promise<EmptyResult> {
+    return this.call(`/v1/dbs/${encodeURIComponent(db)}/tx/${encodeURIComponent(token)}/commit`);
+  }
+
+  /** Rolls back a transaction. */
+  txRollback(db: string, token: string): Promise<EmptyResult> {
+    return this.call(`/v1/dbs/${encodeURIComponent(db)}/tx/${encodeURIComponent(token)}/rollback`);
+  }
+}